@@ -0,0 +1,377 @@
+package classification
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Поддерживаемые схемы классификации
+const (
+	SchemeBBK = "bbk"
+	SchemeUDK = "udk"
+)
+
+//go:embed data/*.tsv
+var dataFS embed.FS
+
+// Node представляет раздел таблицы классификации
+type Node struct {
+	Index    string
+	Title    string
+	Level    int
+	Parent   *Node
+	Children []*Node
+
+	// key — цифры индекса без точек; для диапазона "6/8" заполняются lo и hi
+	key string
+	lo  string
+	hi  string
+}
+
+// Table представляет загруженную таблицу классификации
+type Table struct {
+	Scheme  string
+	Roots   []*Node
+	byIndex map[string]*Node
+	nodes   []*Node
+}
+
+// Count содержит количество книг в разделе
+type Count struct {
+	Total     int
+	Available int
+}
+
+var tables = map[string]*Table{}
+
+// Load загружает таблицы ББК и УДК. Если dir не пуст и содержит файлы bbk.tsv/udk.tsv,
+// используются они, иначе — встроенные таблицы
+func Load(dir string) error {
+	for _, scheme := range []string{SchemeBBK, SchemeUDK} {
+		data, err := readData(dir, scheme+".tsv")
+		if err != nil {
+			return err
+		}
+
+		table, err := parseTable(scheme, data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", scheme, err)
+		}
+		tables[scheme] = table
+	}
+	return nil
+}
+
+// readData читает файл таблицы из каталога или из встроенных данных
+func readData(dir, name string) ([]byte, error) {
+	if dir != "" {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err == nil {
+			return data, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return dataFS.ReadFile("data/" + name)
+}
+
+// Get возвращает таблицу по названию схемы
+func Get(scheme string) (*Table, bool) {
+	table, ok := tables[scheme]
+	return table, ok
+}
+
+// parseTable разбирает файл таблицы и строит иерархию разделов
+func parseTable(scheme string, data []byte) (*Table, error) {
+	table := &Table{Scheme: scheme, byIndex: map[string]*Node{}}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "\t", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("line %d: expected index and title separated by tab", lineNo)
+		}
+
+		node := &Node{Index: strings.TrimSpace(parts[0]), Title: strings.TrimSpace(parts[1])}
+		if lo, hi, ok := strings.Cut(node.Index, "/"); ok {
+			if len(lo) != len(hi) || !isDigits(lo) || !isDigits(hi) || lo > hi {
+				return nil, fmt.Errorf("line %d: invalid range %q", lineNo, node.Index)
+			}
+			node.lo, node.hi = lo, hi
+		} else {
+			node.key = strings.ReplaceAll(node.Index, ".", "")
+			if !isDigits(node.key) {
+				return nil, fmt.Errorf("line %d: invalid index %q", lineNo, node.Index)
+			}
+		}
+
+		if _, exists := table.byIndex[node.Index]; exists {
+			return nil, fmt.Errorf("line %d: duplicate index %q", lineNo, node.Index)
+		}
+		table.byIndex[node.Index] = node
+		table.nodes = append(table.nodes, node)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Родитель — наиболее специфичный из охватывающих разделов
+	for _, node := range table.nodes {
+		var parent *Node
+		for _, candidate := range table.nodes {
+			if candidate == node || candidate.specificity() >= node.specificity() {
+				continue
+			}
+			if !candidate.covers(node.digits()) {
+				continue
+			}
+			if parent == nil || candidate.specificity() > parent.specificity() {
+				parent = candidate
+			}
+		}
+
+		node.Parent = parent
+		if parent == nil {
+			table.Roots = append(table.Roots, node)
+		} else {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+
+	for _, root := range table.Roots {
+		root.setLevel(1)
+	}
+	sortNodes(table.Roots)
+
+	return table, nil
+}
+
+func (n *Node) setLevel(level int) {
+	n.Level = level
+	sortNodes(n.Children)
+	for _, child := range n.Children {
+		child.setLevel(level + 1)
+	}
+}
+
+// digits возвращает цифровую часть индекса раздела
+func (n *Node) digits() string {
+	if n.lo != "" {
+		return n.lo
+	}
+	return n.key
+}
+
+// specificity возвращает «глубину» индекса: диапазон менее специфичен, чем индекс той же длины
+func (n *Node) specificity() int {
+	if n.lo != "" {
+		return len(n.lo)*2 - 1
+	}
+	return len(n.key) * 2
+}
+
+// covers проверяет, входит ли индекс с данными цифрами в раздел
+func (n *Node) covers(digits string) bool {
+	if n.lo != "" {
+		if len(digits) < len(n.lo) {
+			return false
+		}
+		head := digits[:len(n.lo)]
+		return head >= n.lo && head <= n.hi
+	}
+	return strings.HasPrefix(digits, n.key)
+}
+
+// Ancestor возвращает раздел указанного уровня, в который входит данный раздел
+func (n *Node) Ancestor(level int) *Node {
+	node := n
+	for node.Level > level && node.Parent != nil {
+		node = node.Parent
+	}
+	return node
+}
+
+// Path возвращает цепочку разделов от корня до данного раздела
+func (n *Node) Path() []*Node {
+	var path []*Node
+	for node := n; node != nil; node = node.Parent {
+		path = append([]*Node{node}, path...)
+	}
+	return path
+}
+
+// Lookup возвращает раздел по точному индексу из таблицы
+func (t *Table) Lookup(index string) (*Node, bool) {
+	node, ok := t.byIndex[index]
+	return node, ok
+}
+
+// Resolve возвращает наиболее специфичный раздел таблицы, к которому относится индекс.
+// Индекс должен быть предварительно нормализован
+func (t *Table) Resolve(index string) *Node {
+	digits := strings.ReplaceAll(mainNumber(index), ".", "")
+	if digits == "" {
+		return nil
+	}
+
+	var best *Node
+	for _, node := range t.nodes {
+		if node.covers(digits) && (best == nil || node.specificity() > best.specificity()) {
+			best = node
+		}
+	}
+	return best
+}
+
+// Normalize проверяет индекс и приводит его к единому написанию.
+// Пустой индекс допустим и возвращается как есть
+func (t *Table) Normalize(index string) (string, error) {
+	// Убираем пробелы: "84 (2Рос=Рус) 6" и "84(2Рос=Рус)6" — один индекс
+	index = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, index)
+	if index == "" {
+		return "", nil
+	}
+
+	main := mainNumber(strings.ReplaceAll(index, ",", "."))
+	tail := index[len(main):]
+	if main == "" {
+		return "", errors.New("индекс должен начинаться с цифры")
+	}
+
+	// Схлопываем повторяющиеся точки и убираем точку в конце основного деления
+	for strings.Contains(main, "..") {
+		main = strings.ReplaceAll(main, "..", ".")
+	}
+	main = strings.TrimSuffix(main, ".")
+	if strings.HasPrefix(main, ".") {
+		return "", errors.New("индекс должен начинаться с цифры")
+	}
+
+	if err := validateTail(tail); err != nil {
+		return "", err
+	}
+
+	normalized := main + tail
+	if t.Resolve(normalized) == nil {
+		return "", fmt.Errorf("индекс %s не относится ни к одному разделу таблицы %s", normalized, strings.ToUpper(t.Scheme))
+	}
+
+	return normalized, nil
+}
+
+// Tally распределяет количество книг по индексам на разделы таблицы с учетом вложенности.
+// Возвращает суммы по разделам и количество книг, которые не удалось отнести ни к одному разделу
+func (t *Table) Tally(counts map[string]Count) (map[*Node]Count, Count) {
+	result := map[*Node]Count{}
+	var unresolved Count
+
+	for index, count := range counts {
+		node := t.Resolve(index)
+		if node == nil {
+			unresolved.Total += count.Total
+			unresolved.Available += count.Available
+			continue
+		}
+		for ; node != nil; node = node.Parent {
+			sum := result[node]
+			sum.Total += count.Total
+			sum.Available += count.Available
+			result[node] = sum
+		}
+	}
+
+	return result, unresolved
+}
+
+// mainNumber возвращает основное цифровое деление индекса (до определителей)
+func mainNumber(index string) string {
+	end := 0
+	for end < len(index) && (index[end] >= '0' && index[end] <= '9' || index[end] == '.') {
+		end++
+	}
+	return index[:end]
+}
+
+// validateTail проверяет определители индекса: скобки, знаки и допустимые символы
+func validateTail(tail string) error {
+	depth := 0
+	quotes := 0
+	for _, r := range tail {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+			if depth < 0 {
+				return errors.New("несогласованные скобки в индексе")
+			}
+		case r == '"':
+			quotes++
+		case unicode.IsDigit(r), unicode.IsLetter(r):
+		case strings.ContainsRune(".=-+:/'*№", r):
+		default:
+			return fmt.Errorf("недопустимый символ %q в индексе", r)
+		}
+	}
+	if depth != 0 {
+		return errors.New("несогласованные скобки в индексе")
+	}
+	if quotes%2 != 0 {
+		return errors.New("несогласованные кавычки в индексе")
+	}
+	return nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// sortNodes упорядочивает разделы по цифрам индекса
+func sortNodes(nodes []*Node) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].digits() < nodes[j].digits()
+	})
+}
+
+// Nodes возвращает все разделы таблицы в порядке обхода дерева
+func (t *Table) Nodes() []*Node {
+	var nodes []*Node
+	var walk func([]*Node)
+	walk = func(level []*Node) {
+		for _, node := range level {
+			nodes = append(nodes, node)
+			walk(node.Children)
+		}
+	}
+	walk(t.Roots)
+	return nodes
+}
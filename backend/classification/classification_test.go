@@ -0,0 +1,234 @@
+package classification
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// mustTable загружает встроенные таблицы и возвращает таблицу схемы
+func mustTable(t *testing.T, scheme string) *Table {
+	t.Helper()
+	if err := Load(""); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	table, ok := Get(scheme)
+	if !ok {
+		t.Fatalf("table %s not loaded", scheme)
+	}
+	return table
+}
+
+// indexes возвращает индексы цепочки разделов
+func indexes(nodes []*Node) []string {
+	var result []string
+	for _, node := range nodes {
+		result = append(result, node.Index)
+	}
+	return result
+}
+
+func equalIndexes(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestParseTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"comments and blank lines", "# ББК\n\n1\tОбщее\n2\tЕстественные науки\n", false},
+		{"range", "6/8\tОбщественные науки\n63\tИстория\n", false},
+		{"no tab", "1 Общее\n", true},
+		{"letters in index", "2a\tЕстественные науки\n", true},
+		{"duplicate index", "1\tОбщее\n1\tЕще раз\n", true},
+		{"reversed range", "8/6\tОбщественные науки\n", true},
+		{"range of different length", "6/80\tОбщественные науки\n", true},
+		{"range with letters", "6/x\tОбщественные науки\n", true},
+	}
+	for _, tt := range tests {
+		_, err := parseTable(SchemeBBK, []byte(tt.data))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: parseTable error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestHierarchy(t *testing.T) {
+	table, err := parseTable(SchemeBBK, []byte("6/8\tОбщественные науки\n63.3\tИстория\n63\tИсторические науки\n84\tХудожественная литература\n9\tОбщее\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Родитель определяется по префиксу, а не по порядку строк; диапазон охватывает 63 и 84
+	if got := indexes(table.Roots); !equalIndexes(got, []string{"6/8", "9"}) {
+		t.Errorf("roots = %v", got)
+	}
+	history, _ := table.Lookup("63.3")
+	if got := indexes(history.Path()); !equalIndexes(got, []string{"6/8", "63", "63.3"}) {
+		t.Errorf("path of 63.3 = %v", got)
+	}
+	if history.Level != 3 {
+		t.Errorf("level of 63.3 = %d, want 3", history.Level)
+	}
+	if got := history.Ancestor(1).Index; got != "6/8" {
+		t.Errorf("level 1 ancestor of 63.3 = %s", got)
+	}
+	if got := history.Ancestor(5).Index; got != "63.3" {
+		t.Errorf("ancestor below the node = %s, want the node itself", got)
+	}
+	if got := indexes(table.Nodes()); !equalIndexes(got, []string{"6/8", "63", "63.3", "84", "9"}) {
+		t.Errorf("nodes = %v", got)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	table := mustTable(t, SchemeBBK)
+
+	tests := []struct {
+		index string
+		found bool
+	}{
+		{"84", true},
+		{"6/8", true},
+		{"22.1", true},
+		{"221", false},
+		{"84(2Рос=Рус)6", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		node, ok := table.Lookup(tt.index)
+		if ok != tt.found || (ok && node.Index != tt.index) {
+			t.Errorf("Lookup(%q) = %v, %v; want found %v", tt.index, node, ok, tt.found)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	bbk := mustTable(t, SchemeBBK)
+	udk := mustTable(t, SchemeUDK)
+
+	tests := []struct {
+		table *Table
+		index string
+		want  string
+	}{
+		{bbk, "84", "84"},
+		{bbk, "84(2Рос=Рус)6", "84"},
+		{bbk, "22.151.0", "22.1"},
+		{bbk, "85.314", "85.31"},
+		{bbk, "64", "6/8"},
+		{bbk, "63.3(2)", "63.3"},
+		{udk, "004.43", "004"},
+		{udk, "159.922", "159.9"},
+		{udk, "821.161.1", "82"},
+	}
+	for _, tt := range tests {
+		node := tt.table.Resolve(tt.index)
+		if node == nil || node.Index != tt.want {
+			t.Errorf("%s Resolve(%q) = %v, want %s", tt.table.Scheme, tt.index, node, tt.want)
+		}
+	}
+
+	for _, index := range []string{"", "(2Рос)", "Ш"} {
+		if node := bbk.Resolve(index); node != nil {
+			t.Errorf("Resolve(%q) = %s, want nil", index, node.Index)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	table := mustTable(t, SchemeBBK)
+
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"84 (2Рос=Рус) 6", "84(2Рос=Рус)6", false},
+		{"22,1", "22.1", false},
+		{"22..1.", "22.1", false},
+		{"63.3(2)\"19\"", "63.3(2)\"19\"", false},
+		{"Ш", "", true},
+		{".84", "", true},
+		{"84(2Рос", "", true},
+		{"84)2Рос(", "", true},
+		{"63.3\"19", "", true},
+		{"84;6", "", true},
+		{"(2Рос)84", "", true},
+	}
+	for _, tt := range tests {
+		got, err := table.Normalize(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Normalize(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestTally(t *testing.T) {
+	table := mustTable(t, SchemeBBK)
+
+	sums, unresolved := table.Tally(map[string]Count{
+		"84(2Рос=Рус)6": {Total: 5, Available: 2},
+		"83.3":          {Total: 3, Available: 3},
+		"63.3":          {Total: 1, Available: 0},
+		"Ш":             {Total: 4, Available: 1},
+	})
+
+	tests := []struct {
+		index string
+		want  Count
+	}{
+		{"84", Count{5, 2}},
+		{"83", Count{3, 3}},
+		{"80", Count{}},
+		{"6/8", Count{9, 5}},
+	}
+	for _, tt := range tests {
+		node, _ := table.Lookup(tt.index)
+		if got := sums[node]; got != tt.want {
+			t.Errorf("sum of %s = %+v, want %+v", tt.index, got, tt.want)
+		}
+	}
+	if unresolved != (Count{4, 1}) {
+		t.Errorf("unresolved = %+v", unresolved)
+	}
+}
+
+func TestLoadFromDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "udk.tsv"), []byte("5\tЕстественные науки\n51\tМатематика\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Load("") })
+
+	// Файл из каталога заменяет встроенную таблицу, недостающая берется из встроенных данных
+	if err := Load(dir); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	udk, _ := Get(SchemeUDK)
+	if got := len(udk.Nodes()); got != 2 {
+		t.Errorf("udk nodes from the directory = %d, want 2", got)
+	}
+	bbk, _ := Get(SchemeBBK)
+	if _, ok := bbk.Lookup("84"); !ok {
+		t.Error("embedded bbk table not loaded")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "bbk.tsv"), []byte("x\tОшибка\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Load(dir); err == nil {
+		t.Error("invalid table loaded without error")
+	}
+}
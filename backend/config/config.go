@@ -2,21 +2,83 @@ package config
 
 import (
 	"os"
+	"strconv"
 )
 
 // Config содержит настройки приложения
 type Config struct {
 	Port         string
 	DatabasePath string
-	JWTSecret    string
+	// JWTSecret — ключ HS256 для подписи токенов, если не заданы JWTKeys
+	JWTSecret string
+	// JWTKeys — ключи подписи для смены без выхода пользователей: kid:алгоритм:источник через запятую
+	// (HS256 — сам ключ, EdDSA и RS256 — путь к PEM-файлу)
+	JWTKeys string
+	// JWTSigningKey — kid ключа для новых токенов; пусто — первый ключ из JWTKeys
+	JWTSigningKey string
+	// JWTIssuer, JWTAudience и JWTPortalAudience — обязательные iss и aud токенов
+	// сотрудников и личного кабинета читателя
+	JWTIssuer         string
+	JWTAudience       string
+	JWTPortalAudience string
+	// AccessTTLMinutes — срок действия токена доступа сотрудника
+	AccessTTLMinutes int
+	// RefreshTTLDays — сколько сессия сотрудника живет без обращений
+	RefreshTTLDays int
+	// ClassificationDir — каталог с таблицами bbk.tsv/udk.tsv; пусто — встроенные таблицы
+	ClassificationDir string
+	// CoverStorage — хранилище обложек: fs (каталог CoverDir) или sqlite (таблица в базе)
+	CoverStorage string
+	CoverDir     string
+	// CoverMaxSizeMB — максимальный размер загружаемой обложки или фотографии читателя
+	CoverMaxSizeMB int
+	// MaxUploadSizeMB — максимальный размер тела запроса
+	MaxUploadSizeMB int
+	// PIIKey — ключ шифрования персональных данных читателей (32 байта в base64 или hex).
+	// Без ключа сервер не запускается, если не задан PIIAllowPlaintext
+	PIIKey string
+	// PIIAllowPlaintext — явное согласие хранить персональные данные открытым текстом без PIIKey
+	PIIAllowPlaintext bool
+	// PIIRoles — роли через запятую, которым персональные данные показываются полностью
+	PIIRoles string
+	// SMTP — сервер для писем читателям (коды входа в личный кабинет); пустой SMTPHost отключает отправку
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+	// ProxyHeader — заголовок с адресом клиента (например, X-Forwarded-For), если сервер
+	// работает за обратным прокси; по адресу считаются неудачные входы
+	ProxyHeader string
 }
 
 // Load загружает конфигурацию из переменных окружения
 func Load() *Config {
 	return &Config{
-		Port:         getEnv("PORT", "3000"),
-		DatabasePath: getEnv("DATABASE_PATH", "./library.db"),
-		JWTSecret:    getEnv("JWT_SECRET", "your-secret-key-change-this-in-production"),
+		Port:              getEnv("PORT", "3000"),
+		DatabasePath:      getEnv("DATABASE_PATH", "./library.db"),
+		JWTSecret:         getEnv("JWT_SECRET", ""),
+		JWTKeys:           getEnv("JWT_KEYS", ""),
+		JWTSigningKey:     getEnv("JWT_SIGNING_KEY", ""),
+		JWTIssuer:         getEnv("JWT_ISSUER", "library-management"),
+		JWTAudience:       getEnv("JWT_AUDIENCE", "library-staff"),
+		JWTPortalAudience: getEnv("JWT_PORTAL_AUDIENCE", "library-portal"),
+		AccessTTLMinutes:  getEnvInt("ACCESS_TOKEN_TTL_MINUTES", 15),
+		RefreshTTLDays:    getEnvInt("REFRESH_TOKEN_TTL_DAYS", 14),
+		ClassificationDir: getEnv("CLASSIFICATION_DIR", ""),
+		CoverStorage:      getEnv("COVER_STORAGE", "fs"),
+		CoverDir:          getEnv("COVER_DIR", "./covers"),
+		CoverMaxSizeMB:    getEnvInt("COVER_MAX_SIZE_MB", 5),
+		MaxUploadSizeMB:   getEnvInt("MAX_UPLOAD_SIZE_MB", 32),
+		PIIKey:            getEnv("PII_KEY", ""),
+		PIIAllowPlaintext: getEnvBool("PII_ALLOW_PLAINTEXT", false),
+		PIIRoles:          getEnv("PII_ROLES", "admin"),
+		SMTPHost:          getEnv("SMTP_HOST", ""),
+		SMTPPort:          getEnvInt("SMTP_PORT", 587),
+		SMTPUsername:      getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:      getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:          getEnv("SMTP_FROM", ""),
+		ProxyHeader:       getEnv("PROXY_HEADER", ""),
 	}
 }
 
@@ -27,3 +89,19 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvBool возвращает логическое значение переменной окружения (true, 1, t) или значение по умолчанию
+func getEnvBool(key string, defaultValue bool) bool {
+	if value, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvInt возвращает целочисленное значение переменной окружения или значение по умолчанию
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrReaderArchived — читатель уже в архиве
+	ErrReaderArchived = errors.New("reader is archived")
+	// ErrReaderNotArchived — читатель не в архиве
+	ErrReaderNotArchived = errors.New("reader is not archived")
+	// ErrReaderHasDebts — у читателя есть невозвращенные книги или диски
+	ErrReaderHasDebts = errors.New("reader has active loans")
+	// ErrReaderHasClearances — читателю выданы обходные листы; их проверка должна работать и дальше
+	ErrReaderHasClearances = errors.New("reader has clearance certificates")
+)
+
+// readerStatusCondition возвращает условие отбора читателей по статусу.
+// По умолчанию показываются только действующие читатели, архивные — по запросу
+func readerStatusCondition(status string) string {
+	switch status {
+	case "all":
+		return ""
+	case "archived":
+		return " AND r.status = 'archived'"
+	default:
+		return " AND r.status = 'active'"
+	}
+}
+
+// ReaderHasLoanHistory проверяет, выдавались ли читателю книги или диски
+func ReaderHasLoanHistory(readerID int) (bool, error) {
+	var exists bool
+	err := db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM loans WHERE reader_id = ?)
+		    OR EXISTS (SELECT 1 FROM disk_loans WHERE reader_id = ?)
+	`, readerID, readerID).Scan(&exists)
+	return exists, err
+}
+
+// ArchiveReader переводит читателя в архив (выбыл из школы). Читатель с невозвращенными
+// книгами и дисками архивируется только со списанием долгов: выдачи закрываются
+// со статусом written_off и остаются в истории, брони снимаются. Возвращает число списанных выдач
+func ArchiveReader(id int, archivedAt time.Time, reason string, writeOff bool, userID int) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var status string
+	if err := tx.QueryRow("SELECT status FROM readers WHERE id = ?", id).Scan(&status); err != nil {
+		return 0, err
+	}
+	if status == "archived" {
+		return 0, ErrReaderArchived
+	}
+
+	var debts int
+	err = tx.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM loans WHERE reader_id = ? AND status = 'active')
+		     + (SELECT COUNT(*) FROM disk_loans WHERE reader_id = ? AND status = 'active')
+	`, id, id).Scan(&debts)
+	if err != nil {
+		return 0, err
+	}
+	if debts > 0 && !writeOff {
+		return 0, ErrReaderHasDebts
+	}
+
+	if debts > 0 {
+		for _, table := range []string{"loans", "disk_loans"} {
+			_, err := tx.Exec(`
+				UPDATE `+table+` SET status = 'written_off', return_date = CURRENT_TIMESTAMP, returned_by = ?
+				WHERE reader_id = ? AND status = 'active'
+			`, userID, id)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	_, err = tx.Exec(
+		"UPDATE readers SET status = 'archived', archived_at = ?, archive_reason = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+		archivedAt, reason, id,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := revokePortalTokens(tx, id); err != nil {
+		return 0, err
+	}
+
+	// Брони выбывшего читателя больше не нужны
+	cancelledHolds, err := cancelReaderHolds(tx, id, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	details := map[string]interface{}{
+		"reason":          reason,
+		"archived_at":     archivedAt.Format("2006-01-02"),
+		"written_off":     debts,
+		"cancelled_holds": cancelledHolds,
+	}
+	if err := WriteAudit(tx, "reader", id, "archive", details, userID); err != nil {
+		return 0, err
+	}
+
+	return debts, tx.Commit()
+}
+
+// RestoreReader возвращает читателя из архива. Списанные выдачи не восстанавливаются
+func RestoreReader(id int, userID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status, reason string
+	err = tx.QueryRow("SELECT status, COALESCE(archive_reason, '') FROM readers WHERE id = ?", id).Scan(&status, &reason)
+	if err != nil {
+		return err
+	}
+	if status != "archived" {
+		return ErrReaderNotArchived
+	}
+
+	_, err = tx.Exec("UPDATE readers SET status = 'active', archived_at = NULL, archive_reason = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	if err := WriteAudit(tx, "reader", id, "restore", map[string]string{"archive_reason": reason}, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"library-management/backend/models"
+)
+
+// execer — общий интерфейс sql.DB и sql.Tx для выполнения запросов
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// WriteAudit записывает операцию в журнал. details сериализуется в JSON.
+// Чтобы запись попала в ту же транзакцию, что и сама операция, передайте tx
+func WriteAudit(ex execer, entityType string, entityID int, action string, details interface{}, userID int) error {
+	if ex == nil {
+		ex = db
+	}
+
+	data, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	var user interface{}
+	if userID > 0 {
+		user = userID
+	}
+
+	_, err = ex.Exec(
+		"INSERT INTO audit_log (entity_type, entity_id, action, details, user_id) VALUES (?, ?, ?, ?, ?)",
+		entityType, entityID, action, string(data), user,
+	)
+	return err
+}
+
+// GetAuditLog возвращает записи журнала с фильтрами по типу и ID объекта
+func GetAuditLog(entityType string, entityID, limit int) ([]models.AuditEntry, error) {
+	query := `
+		SELECT al.id, al.entity_type, COALESCE(al.entity_id, 0), al.action,
+			COALESCE(al.details, 'null'), al.user_id, COALESCE(u.username, ''), al.created_at
+		FROM audit_log al
+		LEFT JOIN users u ON al.user_id = u.id
+		WHERE 1=1
+	`
+
+	var args []interface{}
+	if entityType != "" {
+		query += " AND al.entity_type = ?"
+		args = append(args, entityType)
+	}
+
+	if entityID > 0 {
+		query += " AND al.entity_id = ?"
+		args = append(args, entityID)
+	}
+
+	query += " ORDER BY al.id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		var details string
+		err := rows.Scan(
+			&entry.ID, &entry.EntityType, &entry.EntityID, &entry.Action,
+			&details, &entry.UserID, &entry.Username, &entry.CreatedAt,
+		)
+		if err != nil {
+			continue
+		}
+		entry.Details = json.RawMessage(details)
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,468 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"library-management/backend/labels"
+	"library-management/backend/models"
+	"strconv"
+	"strings"
+)
+
+// ErrBarcodeRangeExhausted возвращается, если в диапазоне не осталось свободных номеров
+var ErrBarcodeRangeExhausted = errors.New("barcode range exhausted")
+
+// barcodeOwnersQuery — записи всех типов, которым принадлежит штрих-код
+const barcodeOwnersQuery = `
+	SELECT 'book', id, title FROM books WHERE barcode = ?
+	UNION ALL
+	SELECT 'reader', id, last_name || ' ' || first_name FROM readers WHERE barcode = ?
+	UNION ALL
+	SELECT 'reader', r.id, r.last_name || ' ' || r.first_name
+	FROM reader_barcode_aliases a JOIN readers r ON a.reader_id = r.id WHERE a.barcode = ?
+	UNION ALL
+	SELECT 'disk', id, title FROM disks WHERE barcode = ?
+	UNION ALL
+	SELECT 'location', id, name FROM locations WHERE barcode = ?
+`
+
+// FormatBarcode формирует штрих-код диапазона с номером n
+func FormatBarcode(r *models.BarcodeRange, n int) string {
+	body := fmt.Sprintf("%s%0*d", r.Prefix, r.Digits, n)
+	switch r.CheckDigit {
+	case "ean13":
+		return body + string(labels.EAN13CheckDigit(body))
+	case "luhn":
+		return body + string(labels.LuhnCheckDigit(fmt.Sprintf("%0*d", r.Digits, n)))
+	}
+	return body
+}
+
+const barcodeRangeColumns = `
+	r.id, r.entity_type, r.prefix, r.start_number, r.end_number, r.digits,
+	r.check_digit, r.next_number, COALESCE(r.comments, ''), COALESCE(r.created_by, 0), r.created_at,
+	(SELECT COUNT(*) FROM barcode_allocations WHERE range_id = r.id AND status = 'reserved'),
+	(SELECT COUNT(*) FROM barcode_allocations WHERE range_id = r.id AND status = 'bound')
+`
+
+func scanBarcodeRange(row rowScanner) (*models.BarcodeRange, error) {
+	var r models.BarcodeRange
+	err := row.Scan(
+		&r.ID, &r.EntityType, &r.Prefix, &r.StartNumber, &r.EndNumber, &r.Digits,
+		&r.CheckDigit, &r.NextNumber, &r.Comments, &r.CreatedBy, &r.CreatedAt,
+		&r.Reserved, &r.Bound,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Remaining = r.EndNumber - r.NextNumber + 1
+	if r.Remaining < 0 {
+		r.Remaining = 0
+	}
+	r.Sample = FormatBarcode(&r, r.NextNumber)
+	return &r, nil
+}
+
+// GetBarcodeRanges возвращает диапазоны штрих-кодов
+func GetBarcodeRanges() ([]models.BarcodeRange, error) {
+	rows, err := db.Query("SELECT " + barcodeRangeColumns + " FROM barcode_ranges r ORDER BY r.entity_type, r.prefix, r.start_number")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ranges := []models.BarcodeRange{}
+	for rows.Next() {
+		r, err := scanBarcodeRange(rows)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, *r)
+	}
+	return ranges, rows.Err()
+}
+
+// GetBarcodeRangeByID возвращает диапазон штрих-кодов по ID
+func GetBarcodeRangeByID(id int) (*models.BarcodeRange, error) {
+	return scanBarcodeRange(db.QueryRow("SELECT "+barcodeRangeColumns+" FROM barcode_ranges r WHERE r.id = ?", id))
+}
+
+// CreateBarcodeRange создает диапазон штрих-кодов
+func CreateBarcodeRange(r *models.BarcodeRange) (int, error) {
+	result, err := db.Exec(`
+		INSERT INTO barcode_ranges (
+			entity_type, prefix, start_number, end_number, digits,
+			check_digit, next_number, comments, created_by
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		r.EntityType, r.Prefix, r.StartNumber, r.EndNumber, r.Digits,
+		r.CheckDigit, r.StartNumber, r.Comments, r.CreatedBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// UpdateBarcodeRange обновляет диапазон штрих-кодов
+func UpdateBarcodeRange(r *models.BarcodeRange) error {
+	_, err := db.Exec(`
+		UPDATE barcode_ranges SET
+			entity_type = ?, prefix = ?, start_number = ?, end_number = ?,
+			digits = ?, check_digit = ?, comments = ?,
+			next_number = MAX(next_number, ?)
+		WHERE id = ?
+	`,
+		r.EntityType, r.Prefix, r.StartNumber, r.EndNumber,
+		r.Digits, r.CheckDigit, r.Comments,
+		r.StartNumber, r.ID,
+	)
+	return err
+}
+
+// DeleteBarcodeRange удаляет диапазон штрих-кодов
+func DeleteBarcodeRange(id int) error {
+	_, err := db.Exec("DELETE FROM barcode_ranges WHERE id = ?", id)
+	return err
+}
+
+// BarcodeRangeHasAllocations проверяет, выделялись ли штрих-коды из диапазона
+func BarcodeRangeHasAllocations(id int) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM barcode_allocations WHERE range_id = ?", id).Scan(&count)
+	return count > 0, err
+}
+
+// BarcodeRangeOverlaps проверяет, пересекается ли диапазон с другим диапазоном того же формата
+func BarcodeRangeOverlaps(r *models.BarcodeRange) (bool, error) {
+	var count int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM barcode_ranges
+		WHERE id <> ? AND prefix = ? AND digits = ?
+		AND start_number <= ? AND end_number >= ?
+	`, r.ID, r.Prefix, r.Digits, r.EndNumber, r.StartNumber).Scan(&count)
+	return count > 0, err
+}
+
+// MatchBarcodeRange возвращает диапазон, под формат которого подходит штрих-код, или nil
+func MatchBarcodeRange(barcode string) (*models.BarcodeRange, error) {
+	ranges, err := GetBarcodeRanges()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range ranges {
+		r := &ranges[i]
+		length := len(r.Prefix) + r.Digits
+		if r.CheckDigit != "" {
+			length++
+		}
+		if len(barcode) != length || !strings.HasPrefix(barcode, r.Prefix) {
+			continue
+		}
+		n, err := strconv.Atoi(barcode[len(r.Prefix) : len(r.Prefix)+r.Digits])
+		if err != nil || n < r.StartNumber || n > r.EndNumber {
+			continue
+		}
+		return r, nil
+	}
+	return nil, nil
+}
+
+// AllocateBarcodes резервирует партию из count штрих-кодов диапазона.
+// Номера, штрих-коды которых уже заняты любой записью, пропускаются
+func AllocateBarcodes(rangeID, count, userID int) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	r, err := scanBarcodeRange(tx.QueryRow("SELECT "+barcodeRangeColumns+" FROM barcode_ranges r WHERE r.id = ?", rangeID))
+	if err != nil {
+		return 0, err
+	}
+
+	var user interface{}
+	if userID > 0 {
+		user = userID
+	}
+	result, err := tx.Exec("INSERT INTO barcode_batches (range_id, created_by) VALUES (?, ?)", rangeID, user)
+	if err != nil {
+		return 0, err
+	}
+	batchID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	n := r.NextNumber
+	for allocated := 0; allocated < count; n++ {
+		if n > r.EndNumber {
+			return 0, ErrBarcodeRangeExhausted
+		}
+
+		barcode := FormatBarcode(r, n)
+		inUse, err := barcodeInUse(tx, barcode)
+		if err != nil {
+			return 0, err
+		}
+		if inUse {
+			continue
+		}
+
+		if _, err := tx.Exec(
+			"INSERT INTO barcode_allocations (range_id, batch_id, barcode, entity_type) VALUES (?, ?, ?, ?)",
+			rangeID, batchID, barcode, r.EntityType,
+		); err != nil {
+			return 0, err
+		}
+		allocated++
+	}
+
+	if _, err := tx.Exec("UPDATE barcode_ranges SET next_number = ? WHERE id = ?", n, rangeID); err != nil {
+		return 0, err
+	}
+
+	return int(batchID), tx.Commit()
+}
+
+// barcodeInUse проверяет, занят ли штрих-код записью или уже выделен
+func barcodeInUse(tx *sql.Tx, barcode string) (bool, error) {
+	var count int
+	err := tx.QueryRow(
+		"SELECT (SELECT COUNT(*) FROM ("+barcodeOwnersQuery+")) + (SELECT COUNT(*) FROM barcode_allocations WHERE barcode = ?)",
+		barcode, barcode, barcode, barcode, barcode, barcode,
+	).Scan(&count)
+	return count > 0, err
+}
+
+const barcodeBatchColumns = `
+	bt.id, bt.range_id, r.entity_type, COALESCE(bt.created_by, 0), bt.created_at,
+	COALESCE(MIN(a.barcode), ''), COALESCE(MAX(a.barcode), ''), COUNT(a.id),
+	COALESCE(SUM(CASE WHEN a.status = 'reserved' THEN 1 ELSE 0 END), 0),
+	COALESCE(SUM(CASE WHEN a.status = 'bound' THEN 1 ELSE 0 END), 0),
+	COALESCE(SUM(CASE WHEN a.status = 'void' THEN 1 ELSE 0 END), 0)
+	FROM barcode_batches bt
+	JOIN barcode_ranges r ON bt.range_id = r.id
+	LEFT JOIN barcode_allocations a ON a.batch_id = bt.id
+`
+
+func scanBarcodeBatch(row rowScanner) (*models.BarcodeBatch, error) {
+	var batch models.BarcodeBatch
+	err := row.Scan(
+		&batch.ID, &batch.RangeID, &batch.EntityType, &batch.CreatedBy, &batch.CreatedAt,
+		&batch.FirstBarcode, &batch.LastBarcode, &batch.Count,
+		&batch.Reserved, &batch.Bound, &batch.Void,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// GetBarcodeBatches возвращает партии штрих-кодов, начиная с последней
+func GetBarcodeBatches(rangeID int) ([]models.BarcodeBatch, error) {
+	query := "SELECT " + barcodeBatchColumns
+	var args []interface{}
+	if rangeID > 0 {
+		query += " WHERE bt.range_id = ?"
+		args = append(args, rangeID)
+	}
+	query += " GROUP BY bt.id ORDER BY bt.id DESC"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	batches := []models.BarcodeBatch{}
+	for rows.Next() {
+		batch, err := scanBarcodeBatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, *batch)
+	}
+	return batches, rows.Err()
+}
+
+// GetBarcodeBatch возвращает партию вместе со списком штрих-кодов
+func GetBarcodeBatch(id int) (*models.BarcodeBatch, error) {
+	batch, err := scanBarcodeBatch(db.QueryRow("SELECT "+barcodeBatchColumns+" WHERE bt.id = ? GROUP BY bt.id", id))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+		SELECT id, range_id, batch_id, barcode, entity_type, status, entity_id, created_at, bound_at
+		FROM barcode_allocations WHERE batch_id = ? ORDER BY id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	batch.Barcodes = []models.BarcodeAllocation{}
+	for rows.Next() {
+		allocation, err := scanBarcodeAllocation(rows)
+		if err != nil {
+			return nil, err
+		}
+		batch.Barcodes = append(batch.Barcodes, *allocation)
+	}
+	return batch, rows.Err()
+}
+
+// VoidBarcodeBatch аннулирует неиспользованные штрих-коды партии (например, испорченные этикетки).
+// Возвращает количество аннулированных штрих-кодов
+func VoidBarcodeBatch(id int) (int, error) {
+	result, err := db.Exec("UPDATE barcode_allocations SET status = 'void' WHERE batch_id = ? AND status = 'reserved'", id)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
+
+func scanBarcodeAllocation(row rowScanner) (*models.BarcodeAllocation, error) {
+	var allocation models.BarcodeAllocation
+	var boundAt sql.NullTime
+	err := row.Scan(
+		&allocation.ID, &allocation.RangeID, &allocation.BatchID, &allocation.Barcode,
+		&allocation.EntityType, &allocation.Status, &allocation.EntityID,
+		&allocation.CreatedAt, &boundAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if boundAt.Valid {
+		allocation.BoundAt = &boundAt.Time
+	}
+	return &allocation, nil
+}
+
+// GetBarcodeAllocation возвращает выделенный штрих-код
+func GetBarcodeAllocation(barcode string) (*models.BarcodeAllocation, error) {
+	return scanBarcodeAllocation(db.QueryRow(`
+		SELECT id, range_id, batch_id, barcode, entity_type, status, entity_id, created_at, bound_at
+		FROM barcode_allocations WHERE barcode = ?
+	`, barcode))
+}
+
+// BindBarcode привязывает выделенный штрих-код к записи. Если у записи был другой
+// выделенный штрих-код, он аннулируется: этикетку с ним уже переклеили
+func BindBarcode(entityType string, entityID int, barcode string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE barcode_allocations SET status = 'void'
+		WHERE entity_type = ? AND entity_id = ? AND status = 'bound' AND barcode <> ?
+	`, entityType, entityID, barcode); err != nil {
+		return err
+	}
+
+	if barcode != "" {
+		if _, err := tx.Exec(`
+			UPDATE barcode_allocations SET status = 'bound', entity_id = ?, bound_at = CURRENT_TIMESTAMP
+			WHERE barcode = ? AND entity_type = ? AND status <> 'void'
+			AND (entity_id IS NULL OR entity_id <> ?)
+		`, entityID, barcode, entityType, entityID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// FindBarcodeOwners возвращает все записи с данным штрих-кодом
+func FindBarcodeOwners(barcode string) ([]models.BarcodeOwner, error) {
+	rows, err := db.Query(barcodeOwnersQuery, barcode, barcode, barcode, barcode, barcode)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	owners := []models.BarcodeOwner{}
+	for rows.Next() {
+		var owner models.BarcodeOwner
+		if err := rows.Scan(&owner.EntityType, &owner.EntityID, &owner.Title); err != nil {
+			return nil, err
+		}
+		owners = append(owners, owner)
+	}
+	return owners, rows.Err()
+}
+
+// LookupBarcode определяет, чему принадлежит отсканированный штрих-код
+func LookupBarcode(barcode string) (*models.BarcodeLookup, error) {
+	owners, err := FindBarcodeOwners(barcode)
+	if err != nil {
+		return nil, err
+	}
+
+	lookup := &models.BarcodeLookup{Barcode: barcode, Owners: owners}
+	allocation, err := GetBarcodeAllocation(barcode)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	lookup.Allocation = allocation
+	return lookup, nil
+}
+
+// GetBarcodeCollisions возвращает штрих-коды, которые встречаются у нескольких записей
+func GetBarcodeCollisions() ([]models.BarcodeLookup, error) {
+	rows, err := db.Query(`
+		SELECT barcode FROM (
+			SELECT barcode FROM books
+			UNION ALL SELECT barcode FROM readers
+			UNION ALL SELECT barcode FROM reader_barcode_aliases
+			UNION ALL SELECT barcode FROM disks
+			UNION ALL SELECT barcode FROM locations
+		)
+		WHERE COALESCE(barcode, '') <> ''
+		GROUP BY barcode
+		HAVING COUNT(*) > 1
+		ORDER BY barcode
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	var barcodes []string
+	for rows.Next() {
+		var barcode string
+		if err := rows.Scan(&barcode); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		barcodes = append(barcodes, barcode)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	collisions := []models.BarcodeLookup{}
+	for _, barcode := range barcodes {
+		lookup, err := LookupBarcode(barcode)
+		if err != nil {
+			return nil, err
+		}
+		collisions = append(collisions, *lookup)
+	}
+	return collisions, nil
+}
@@ -0,0 +1,260 @@
+package database
+
+import (
+	"database/sql"
+	"library-management/backend/models"
+	"strings"
+)
+
+// bulkSampleSize — количество записей, показываемых при предпросмотре группового изменения
+const bulkSampleSize = 10
+
+// SelectBookIDs возвращает ID книг, выбранных списком и/или фильтром каталога
+func SelectBookIDs(ids []int, filter *models.BookFilter) ([]int, error) {
+	var f models.BookFilter
+	if filter != nil {
+		f = *filter
+	}
+
+	conditions, err := bookFilterConditions(&f)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) > 0 {
+		placeholders, args := inPlaceholders(ids)
+		conditions.add("id", "b.id IN ("+placeholders+")", args...)
+	}
+
+	where, args := conditions.where("")
+	return queryIDs("SELECT DISTINCT b.id"+bookFilterJoins+" WHERE "+where+" ORDER BY b.id", args...)
+}
+
+// SelectReaderIDs возвращает ID читателей, выбранных списком и/или фильтром
+func SelectReaderIDs(ids []int, filter *models.ReaderFilter) ([]int, error) {
+	query := "SELECT r.id FROM readers r LEFT JOIN classes c ON r.class_id = c.id WHERE 1=1"
+	var args []interface{}
+
+	if len(ids) > 0 {
+		placeholders, idArgs := inPlaceholders(ids)
+		query += " AND r.id IN (" + placeholders + ")"
+		args = append(args, idArgs...)
+	}
+	if filter != nil {
+		query += readerStatusCondition(filter.Status)
+		if filter.Search != "" {
+			condition, searchArgs := readerSearchCondition(filter.Search)
+			query += condition
+			args = append(args, searchArgs...)
+		}
+		if filter.ClassID > 0 {
+			query += " AND r.class_id = ?"
+			args = append(args, filter.ClassID)
+		}
+		if filter.Grade > 0 {
+			query += " AND COALESCE(c.grade, r.grade) = ?"
+			args = append(args, filter.Grade)
+		}
+		if filter.UserType != "" {
+			query += " AND r.user_type = ?"
+			args = append(args, filter.UserType)
+		}
+	}
+
+	return queryIDs(query+" ORDER BY r.id", args...)
+}
+
+// queryIDs выполняет запрос, возвращающий один столбец ID
+func queryIDs(query string, args ...interface{}) ([]int, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetBulkSample возвращает названия первых записей выборки для предпросмотра
+func GetBulkSample(entityType string, ids []int) ([]string, error) {
+	if len(ids) > bulkSampleSize {
+		ids = ids[:bulkSampleSize]
+	}
+	if len(ids) == 0 {
+		return []string{}, nil
+	}
+
+	placeholders, args := inPlaceholders(ids)
+	var query string
+	switch entityType {
+	case "book":
+		query = "SELECT code || ' ' || title FROM books WHERE id IN (" + placeholders + ") ORDER BY id"
+	default:
+		query = "SELECT last_name || ' ' || first_name FROM readers WHERE id IN (" + placeholders + ") ORDER BY id"
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sample := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		sample = append(sample, name)
+	}
+	return sample, rows.Err()
+}
+
+// nullableID возвращает NULL для нулевого ID
+func nullableID(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// BulkUpdateBooks применяет изменения к книгам в одной транзакции и записывает их в журнал.
+// Место хранения меняется перемещением, чтобы сохранить историю. Возвращает число измененных книг
+func BulkUpdateBooks(ids []int, patch models.BookPatch, userID int) (int, error) {
+	var loc *models.Location
+	if patch.LocationID != nil {
+		var err error
+		if loc, err = GetLocationByID(*patch.LocationID); err != nil {
+			return 0, err
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var sets []string
+	var args []interface{}
+	if patch.ClassRange != nil {
+		minGrade, maxGrade := gradeRangeArgs(*patch.ClassRange)
+		sets = append(sets, "class_range = ?", "min_grade = ?", "max_grade = ?")
+		args = append(args, *patch.ClassRange, minGrade, maxGrade)
+	}
+	if patch.AuthorID != nil {
+		sets = append(sets, "author_id = ?")
+		args = append(args, nullableID(*patch.AuthorID))
+	}
+	if patch.PublisherID != nil {
+		sets = append(sets, "publisher_id = ?")
+		args = append(args, nullableID(*patch.PublisherID))
+	}
+	if patch.BBK != nil {
+		sets = append(sets, "bbk = ?")
+		args = append(args, *patch.BBK)
+	}
+	if patch.UDK != nil {
+		sets = append(sets, "udk = ?")
+		args = append(args, *patch.UDK)
+	}
+
+	updated, err := bulkUpdate(tx, "books", sets, args, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	if loc != nil {
+		moved, _, err := moveBooks(tx, loc, ids, userID)
+		if err != nil {
+			return 0, err
+		}
+		if moved > updated {
+			updated = moved
+		}
+	}
+
+	details := map[string]interface{}{"ids": ids, "patch": patch, "updated": updated}
+	if err := WriteAudit(tx, "book", 0, "bulk_update", details, userID); err != nil {
+		return 0, err
+	}
+
+	return updated, tx.Commit()
+}
+
+// BulkUpdateReaders применяет изменения к читателям в одной транзакции и записывает их в журнал.
+// При смене класса параллель (grade) берется из класса
+func BulkUpdateReaders(ids []int, patch models.ReaderPatch, userID int) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var sets []string
+	var args []interface{}
+	if patch.ClassID != nil {
+		if *patch.ClassID == 0 {
+			sets = append(sets, "class_id = NULL")
+		} else {
+			sets = append(sets, "class_id = ?", "grade = (SELECT grade FROM classes WHERE id = ?)")
+			args = append(args, *patch.ClassID, *patch.ClassID)
+		}
+	}
+	if patch.UserType != nil {
+		sets = append(sets, "user_type = ?")
+		args = append(args, *patch.UserType)
+	}
+	if len(sets) > 0 {
+		sets = append(sets, "updated_at = CURRENT_TIMESTAMP")
+	}
+
+	updated, err := bulkUpdate(tx, "readers", sets, args, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	details := map[string]interface{}{"ids": ids, "patch": patch, "updated": updated}
+	if err := WriteAudit(tx, "reader", 0, "bulk_update", details, userID); err != nil {
+		return 0, err
+	}
+
+	return updated, tx.Commit()
+}
+
+// bulkUpdate выполняет UPDATE таблицы для списка ID и возвращает число измененных строк
+func bulkUpdate(tx *sql.Tx, table string, sets []string, args []interface{}, ids []int) (int, error) {
+	if len(sets) == 0 || len(ids) == 0 {
+		return 0, nil
+	}
+
+	placeholders, idArgs := inPlaceholders(ids)
+	query := "UPDATE " + table + " SET " + strings.Join(sets, ", ") + " WHERE id IN (" + placeholders + ")"
+	result, err := tx.Exec(query, append(args, idArgs...)...)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
+
+// referenceTables — справочники, на которые ссылаются изменяемые группой поля
+var referenceTables = map[string]string{
+	"author":    "authors",
+	"publisher": "publishers",
+	"class":     "classes",
+}
+
+// ReferenceExists проверяет, существует ли запись справочника
+func ReferenceExists(entityType string, id int) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM "+referenceTables[entityType]+" WHERE id = ?", id).Scan(&count)
+	return count > 0, err
+}
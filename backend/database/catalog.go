@@ -0,0 +1,398 @@
+package database
+
+import (
+	"fmt"
+	"library-management/backend/classification"
+	"library-management/backend/models"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// facetLimit ограничивает число значений в фасетах с большим количеством вариантов
+const facetLimit = 50
+
+// bookFilterJoins — таблицы, по которым фильтруется каталог
+const bookFilterJoins = `
+	FROM books b
+	LEFT JOIN authors a ON b.author_id = a.id
+	LEFT JOIN publishers p ON b.publisher_id = p.id
+	LEFT JOIN loans l ON b.id = l.book_id AND l.status = 'active'
+`
+
+// Измерения фильтра каталога. Условие измерения не применяется при подсчете его фасета
+const (
+	dimSearch       = "search"
+	dimAuthor       = "author"
+	dimPublisher    = "publisher"
+	dimYear         = "year"
+	dimAvailability = "availability"
+	dimLocation     = "location"
+	dimClassRange   = "class_range"
+	dimBBK          = "bbk"
+	dimCreated      = "created"
+)
+
+// bookCondition — условие фильтра каталога
+type bookCondition struct {
+	dim  string
+	sql  string
+	args []interface{}
+}
+
+type bookConditions []bookCondition
+
+// add добавляет условие измерения
+func (c *bookConditions) add(dim, sql string, args ...interface{}) {
+	*c = append(*c, bookCondition{dim: dim, sql: sql, args: args})
+}
+
+// where собирает условия в выражение WHERE, пропуская условия измерения skip
+func (c bookConditions) where(skip string) (string, []interface{}) {
+	parts := []string{"1=1"}
+	var args []interface{}
+	for _, cond := range c {
+		if cond.dim == skip {
+			continue
+		}
+		parts = append(parts, cond.sql)
+		args = append(args, cond.args...)
+	}
+	return strings.Join(parts, " AND "), args
+}
+
+// inPlaceholders возвращает список "?, ?, ?" и аргументы для условия IN
+func inPlaceholders(ids []int) (string, []interface{}) {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", "), args
+}
+
+// bookFilterConditions преобразует фильтр каталога в условия запроса
+func bookFilterConditions(f *models.BookFilter) (bookConditions, error) {
+	var c bookConditions
+
+	if f.Search != "" {
+		pattern := "%" + f.Search + "%"
+		c.add(dimSearch, `(b.title LIKE ? OR b.barcode LIKE ? OR b.isbn LIKE ? OR a.last_name LIKE ? OR p.name LIKE ?)`,
+			pattern, pattern, pattern, pattern, pattern)
+	}
+	if len(f.AuthorIDs) > 0 {
+		placeholders, args := inPlaceholders(f.AuthorIDs)
+		c.add(dimAuthor, "b.author_id IN ("+placeholders+")", args...)
+	}
+	if len(f.PublisherIDs) > 0 {
+		placeholders, args := inPlaceholders(f.PublisherIDs)
+		c.add(dimPublisher, "b.publisher_id IN ("+placeholders+")", args...)
+	}
+	if f.YearFrom > 0 {
+		c.add(dimYear, "b.publication_year >= ?", f.YearFrom)
+	}
+	if f.YearTo > 0 {
+		c.add(dimYear, "b.publication_year <= ?", f.YearTo)
+	}
+	if f.Available != nil {
+		if *f.Available {
+			c.add(dimAvailability, "l.id IS NULL")
+		} else {
+			c.add(dimAvailability, "l.id IS NOT NULL")
+		}
+	}
+	if f.LocationID > 0 {
+		c.add(dimLocation, "b.location_id IN "+locationSubtree, f.LocationID)
+	}
+	if f.ClassRange != "" {
+		// Класс "6" находит книги для 5–7 классов, диапазон "5-7" — все пересекающиеся с ним
+		if min, max, ok := parseGradeRange(f.ClassRange); ok {
+			c.add(dimClassRange, "b.min_grade <= ? AND b.max_grade >= ?", max, min)
+		} else {
+			c.add(dimClassRange, "b.class_range = ?", f.ClassRange)
+		}
+	}
+	if f.CreatedFrom != "" {
+		c.add(dimCreated, "date(b.created_at) >= ?", f.CreatedFrom)
+	}
+	if f.CreatedTo != "" {
+		c.add(dimCreated, "date(b.created_at) <= ?", f.CreatedTo)
+	}
+
+	if f.BBK != "" {
+		indexes, err := bbkSectionIndexes(f.BBK)
+		if err != nil {
+			return nil, err
+		}
+		if len(indexes) == 0 {
+			c.add(dimBBK, "0")
+		} else {
+			args := make([]interface{}, len(indexes))
+			for i, index := range indexes {
+				args[i] = index
+			}
+			c.add(dimBBK, "b.bbk IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(indexes)), ", ")+")", args...)
+		}
+	}
+
+	return c, nil
+}
+
+// gradeRangePattern — классы книги: "6", "5-7", "5–7 кл.", "5 - 7 классы"
+var gradeRangePattern = regexp.MustCompile(`^(\d{1,2})(?:\s*[-–—]\s*(\d{1,2}))?(?:\s*(?:кл\.?|класс(?:ы|ов)?))?$`)
+
+// parseGradeRange разбирает классы книги в наименьший и наибольший класс.
+// ok=false — значение не похоже на класс или диапазон классов
+func parseGradeRange(value string) (min, max int, ok bool) {
+	m := gradeRangePattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(value)))
+	if m == nil {
+		return 0, 0, false
+	}
+	min, _ = strconv.Atoi(m[1])
+	max = min
+	if m[2] != "" {
+		max, _ = strconv.Atoi(m[2])
+	}
+	if min > max {
+		return 0, 0, false
+	}
+	return min, max, true
+}
+
+// gradeRangeArgs возвращает значения min_grade и max_grade для классов книги; NULL, если не разобраны
+func gradeRangeArgs(classRange string) (interface{}, interface{}) {
+	min, max, ok := parseGradeRange(classRange)
+	if !ok {
+		return nil, nil
+	}
+	return min, max
+}
+
+// bbkSectionIndexes возвращает индексы ББК книг, относящиеся к разделу или его подразделам.
+// Разделы-диапазоны ("6/8") не выражаются префиксом, поэтому индексы разбираются по таблице
+func bbkSectionIndexes(section string) ([]string, error) {
+	table, ok := classification.Get(classification.SchemeBBK)
+	if !ok {
+		return nil, fmt.Errorf("classification table %s is not loaded", classification.SchemeBBK)
+	}
+	target, ok := table.Lookup(section)
+	if !ok {
+		return nil, fmt.Errorf("unknown BBK section: %s", section)
+	}
+
+	rows, err := db.Query("SELECT DISTINCT bbk FROM books WHERE COALESCE(bbk, '') <> ''")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var indexes []string
+	for rows.Next() {
+		var index string
+		if err := rows.Scan(&index); err != nil {
+			return nil, err
+		}
+		for node := table.Resolve(index); node != nil; node = node.Parent {
+			if node == target {
+				indexes = append(indexes, index)
+				break
+			}
+		}
+	}
+	return indexes, rows.Err()
+}
+
+// bookOrder возвращает выражение сортировки каталога. Книги без значения
+// поля сортировки выводятся в конце при любом направлении
+func bookOrder(field string, desc bool) string {
+	dir := ""
+	if desc {
+		dir = " DESC"
+	}
+
+	switch field {
+	case "title":
+		return "b.title" + dir + ", b.id"
+	case "author":
+		return "a.last_name IS NULL, a.last_name" + dir + ", a.first_name" + dir + ", b.title, b.id"
+	case "year":
+		return "b.publication_year IS NULL, b.publication_year" + dir + ", b.title, b.id"
+	case "created":
+		return "b.created_at" + dir + ", b.id" + dir
+	default:
+		return "b.code" + dir
+	}
+}
+
+// GetBookFacets возвращает количество книг по значениям фильтров каталога
+func GetBookFacets(f models.BookFilter) (*models.BookFacets, error) {
+	conditions, err := bookFilterConditions(&f)
+	if err != nil {
+		return nil, err
+	}
+
+	facets := &models.BookFacets{}
+	queries := []struct {
+		target *[]models.FacetValue
+		dim    string
+		value  string
+		label  string
+		filter string
+		order  string
+		limit  int
+	}{
+		{&facets.Authors, dimAuthor, "a.id", "COALESCE(NULLIF(a.short_name, ''), a.last_name)", "a.id IS NOT NULL", "3 DESC, 2", facetLimit},
+		{&facets.Publishers, dimPublisher, "p.id", "p.name", "p.id IS NOT NULL", "3 DESC, 2", facetLimit},
+		{&facets.Years, dimYear, "b.publication_year", "b.publication_year", "b.publication_year IS NOT NULL", "1 DESC", 0},
+		{&facets.Availability, dimAvailability, "CASE WHEN l.id IS NULL THEN 'true' ELSE 'false' END",
+			"CASE WHEN l.id IS NULL THEN 'В наличии' ELSE 'Выдана' END", "1=1", "1 DESC", 0},
+		{&facets.ClassRanges, dimClassRange, "b.class_range", "b.class_range", "COALESCE(b.class_range, '') <> ''", "2", 0},
+		{&facets.Created, dimCreated, "strftime('%Y-%m', b.created_at)", "strftime('%Y-%m', b.created_at)", "b.created_at IS NOT NULL", "1 DESC", 0},
+	}
+
+	for _, q := range queries {
+		where, args := conditions.where(q.dim)
+		query := "SELECT " + q.value + ", " + q.label + ", COUNT(DISTINCT b.id)" + bookFilterJoins +
+			" WHERE " + where + " AND " + q.filter + " GROUP BY 1 ORDER BY " + q.order
+		if q.limit > 0 {
+			query += " LIMIT " + strconv.Itoa(q.limit)
+		}
+
+		values, err := queryFacet(query, args...)
+		if err != nil {
+			return nil, err
+		}
+		*q.target = values
+	}
+
+	if facets.Locations, err = locationFacet(conditions, f.LocationID); err != nil {
+		return nil, err
+	}
+	if facets.BBK, err = bbkFacet(conditions, f.BBK); err != nil {
+		return nil, err
+	}
+
+	return facets, nil
+}
+
+// queryFacet выполняет запрос фасета, возвращающий значение, название и количество
+func queryFacet(query string, args ...interface{}) ([]models.FacetValue, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []models.FacetValue{}
+	for rows.Next() {
+		var v models.FacetValue
+		if err := rows.Scan(&v.Value, &v.Label, &v.Count); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
+
+// locationFacet считает книги по местам хранения, вложенным в выбранное (или по корневым местам)
+func locationFacet(conditions bookConditions, selectedID int) ([]models.FacetValue, error) {
+	where, args := conditions.where("")
+	rows, err := db.Query(`
+		SELECT b.location_id, COUNT(DISTINCT b.id)`+bookFilterJoins+`
+		WHERE `+where+` AND b.location_id IS NOT NULL
+		GROUP BY b.location_id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[int]int{}
+	for rows.Next() {
+		var id, count int
+		if err := rows.Scan(&id, &count); err != nil {
+			return nil, err
+		}
+		counts[id] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tree, err := loadLocations()
+	if err != nil {
+		return nil, err
+	}
+
+	totals := map[int]int{}
+	for id, count := range counts {
+		// Поднимаемся до места, непосредственно вложенного в выбранное
+		for loc := tree.byID[id]; loc != nil; loc = tree.parent(loc) {
+			parent := tree.parent(loc)
+			if (selectedID == 0 && parent == nil) || (parent != nil && parent.ID == selectedID) {
+				totals[loc.ID] += count
+				break
+			}
+		}
+	}
+
+	values := []models.FacetValue{}
+	for _, loc := range tree.ordered(selectedID) {
+		if total, ok := totals[loc.ID]; ok {
+			values = append(values, models.FacetValue{
+				Value: strconv.Itoa(loc.ID), Label: loc.Path, Count: total,
+			})
+		}
+	}
+	return values, nil
+}
+
+// bbkFacet считает книги по подразделам выбранного раздела ББК (или по разделам верхнего уровня)
+func bbkFacet(conditions bookConditions, section string) ([]models.FacetValue, error) {
+	table, ok := classification.Get(classification.SchemeBBK)
+	if !ok {
+		return []models.FacetValue{}, nil
+	}
+
+	where, args := conditions.where("")
+	rows, err := db.Query(`
+		SELECT b.bbk, COUNT(DISTINCT b.id)`+bookFilterJoins+`
+		WHERE `+where+` AND COALESCE(b.bbk, '') <> ''
+		GROUP BY b.bbk
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]classification.Count{}
+	for rows.Next() {
+		var index string
+		var count classification.Count
+		if err := rows.Scan(&index, &count.Total); err != nil {
+			return nil, err
+		}
+		counts[index] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tally, _ := table.Tally(counts)
+
+	nodes := table.Roots
+	if section != "" {
+		if node, ok := table.Lookup(section); ok {
+			nodes = node.Children
+		}
+	}
+
+	values := []models.FacetValue{}
+	for _, node := range nodes {
+		if count := tally[node]; count.Total > 0 {
+			values = append(values, models.FacetValue{
+				Value: node.Index, Label: node.Title, Count: count.Total,
+			})
+		}
+	}
+	return values, nil
+}
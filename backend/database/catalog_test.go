@@ -0,0 +1,82 @@
+package database
+
+import (
+	"fmt"
+	"library-management/backend/models"
+	"sort"
+	"testing"
+)
+
+func TestParseGradeRange(t *testing.T) {
+	tests := []struct {
+		value    string
+		min, max int
+		ok       bool
+	}{
+		{"6", 6, 6, true},
+		{"5-7", 5, 7, true},
+		{"5–7", 5, 7, true},
+		{"5 — 7", 5, 7, true},
+		{" 10-11 кл.", 10, 11, true},
+		{"1-4 классы", 1, 4, true},
+		{"7-5", 0, 0, false},
+		{"", 0, 0, false},
+		{"старшие", 0, 0, false},
+		{"5,7", 0, 0, false},
+	}
+	for _, tt := range tests {
+		min, max, ok := parseGradeRange(tt.value)
+		if min != tt.min || max != tt.max || ok != tt.ok {
+			t.Errorf("parseGradeRange(%q) = %d, %d, %v; want %d, %d, %v", tt.value, min, max, ok, tt.min, tt.max, tt.ok)
+		}
+	}
+}
+
+func TestBookFilterClassRange(t *testing.T) {
+	openTestDB(t)
+
+	for i, classRange := range []string{"1-4", "5-7", "5–9", "6", "8-11", "старшие"} {
+		book := &models.Book{Title: classRange, Barcode: fmt.Sprintf("B%03d", i), ClassRange: classRange}
+		if _, err := CreateBook(book); err != nil {
+			t.Fatalf("CreateBook(%q): %v", classRange, err)
+		}
+	}
+
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{"6", []string{"5-7", "5–9", "6"}},
+		{"5-7", []string{"5-7", "5–9", "6"}},
+		{"7–8", []string{"5-7", "5–9", "8-11"}},
+		{"4-5", []string{"1-4", "5-7", "5–9"}},
+		{"12", nil},
+		{"старшие", []string{"старшие"}},
+	}
+	for _, tt := range tests {
+		books, total, err := GetBooks(models.BookFilter{ClassRange: tt.filter, Page: 1, PageSize: 50})
+		if err != nil {
+			t.Fatalf("GetBooks(%q): %v", tt.filter, err)
+		}
+		var got []string
+		for _, book := range books {
+			got = append(got, book.ClassRange)
+		}
+		sort.Strings(got)
+		if total != len(tt.want) || !equalStrings(got, tt.want) {
+			t.Errorf("class_range=%q: got %v (total %d), want %v", tt.filter, got, total, tt.want)
+		}
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"library-management/backend/models"
+)
+
+// ErrClassHasReaders — в классе есть действующие читатели
+var ErrClassHasReaders = errors.New("class has readers")
+
+// classColumns — столбцы класса с именем классного руководителя и числом учеников.
+// Используется вместе с classJoins и scanClass
+const classColumns = `
+	c.id, c.grade, c.letter, c.teacher_id,
+	COALESCE(TRIM(t.last_name || ' ' || t.first_name || ' ' || COALESCE(t.middle_name, '')), c.teacher_name, ''),
+	c.is_active,
+	(SELECT COUNT(*) FROM readers r WHERE r.class_id = c.id AND r.status = 'active') AS students_count
+`
+
+// classJoins — источник данных для classColumns
+const classJoins = `
+	LEFT JOIN readers t ON c.teacher_id = t.id
+`
+
+// scanClass считывает класс, выбранный через classColumns
+func scanClass(row rowScanner) (*models.Class, error) {
+	var class models.Class
+	err := row.Scan(
+		&class.ID, &class.Grade, &class.Letter, &class.TeacherID,
+		&class.TeacherName, &class.IsActive, &class.StudentsCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	class.DisplayName = fmt.Sprintf("%d \"%s\"", class.Grade, class.Letter)
+	return &class, nil
+}
+
+// GetClass возвращает класс по ID
+func GetClass(id int) (*models.Class, error) {
+	return scanClass(db.QueryRow("SELECT "+classColumns+" FROM classes c"+classJoins+" WHERE c.id = ?", id))
+}
+
+// ClassExists проверяет, есть ли другой класс с такой же параллелью и литерой
+func ClassExists(grade int, letter string, excludeID int) (bool, error) {
+	var exists bool
+	err := db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM classes WHERE grade = ? AND letter = ? AND id <> ?)",
+		grade, letter, excludeID,
+	).Scan(&exists)
+	return exists, err
+}
+
+// UpdateClass обновляет класс. При смене параллели она меняется и у всех читателей класса
+func UpdateClass(class *models.Class) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"UPDATE classes SET grade = ?, letter = ?, teacher_name = ?, teacher_id = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+		class.Grade, class.Letter, class.TeacherName, class.TeacherID, class.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	if _, err := tx.Exec("UPDATE readers SET grade = ?, updated_at = CURRENT_TIMESTAMP WHERE class_id = ?", class.Grade, class.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetClassActive включает или выключает класс. Выключить класс с действующими читателями нельзя
+func SetClassActive(id int, active bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if !active {
+		if err := checkClassEmpty(tx, id); err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.Exec("UPDATE classes SET is_active = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", active, id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return tx.Commit()
+}
+
+// DeleteClass удаляет класс без действующих читателей. Архивные читатели отвязываются
+// от класса, сохраняя параллель, — их история выдач остается
+func DeleteClass(id int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkClassEmpty(tx, id); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE readers SET class_id = NULL, updated_at = CURRENT_TIMESTAMP WHERE class_id = ?", id); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec("DELETE FROM classes WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return tx.Commit()
+}
+
+// checkClassEmpty возвращает ErrClassHasReaders, если в классе есть действующие читатели
+func checkClassEmpty(tx *sql.Tx, id int) error {
+	var readers int
+	err := tx.QueryRow("SELECT COUNT(*) FROM readers WHERE class_id = ? AND status = 'active'", id).Scan(&readers)
+	if err != nil {
+		return err
+	}
+	if readers > 0 {
+		return ErrClassHasReaders
+	}
+	return nil
+}
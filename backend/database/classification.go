@@ -0,0 +1,58 @@
+package database
+
+import (
+	"fmt"
+	"library-management/backend/classification"
+)
+
+// GetBookClassificationCounts возвращает количество книг и доступных экземпляров
+// по каждому значению индекса ББК или УДК. locationID ограничивает подсчет поддеревом мест хранения
+func GetBookClassificationCounts(scheme, classFilter string, locationID int) (map[string]classification.Count, error) {
+	var column string
+	switch scheme {
+	case classification.SchemeBBK:
+		column = "b.bbk"
+	case classification.SchemeUDK:
+		column = "b.udk"
+	default:
+		return nil, fmt.Errorf("unknown classification scheme: %s", scheme)
+	}
+
+	query := `
+		SELECT COALESCE(` + column + `, ''), COUNT(*),
+			SUM(CASE WHEN l.id IS NULL THEN 1 ELSE 0 END)
+		FROM books b
+		LEFT JOIN loans l ON b.id = l.book_id AND l.status = 'active'
+		WHERE 1=1
+	`
+
+	var args []interface{}
+	if classFilter != "" {
+		query += " AND b.class_range LIKE ?"
+		args = append(args, "%"+classFilter+"%")
+	}
+	if locationID > 0 {
+		query += " AND b.location_id IN " + locationSubtree
+		args = append(args, locationID)
+	}
+
+	query += " GROUP BY 1"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]classification.Count{}
+	for rows.Next() {
+		var index string
+		var count classification.Count
+		if err := rows.Scan(&index, &count.Total, &count.Available); err != nil {
+			return nil, err
+		}
+		counts[index] = count
+	}
+
+	return counts, rows.Err()
+}
@@ -0,0 +1,224 @@
+package database
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"library-management/backend/models"
+	"strings"
+	"time"
+)
+
+// ErrReaderNotCleared — у читателя есть невозвращенные книги или диски
+var ErrReaderNotCleared = errors.New("reader has active loans")
+
+// queryer — общий интерфейс *sql.DB и *sql.Tx для выборок
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// clearanceColumns — столбцы обходного листа с именем выдавшего сотрудника
+const clearanceColumns = `
+	c.id, c.code, c.reader_id, c.reader_name, COALESCE(c.reader_info, ''), COALESCE(c.reason, ''),
+	c.verification_code, c.file_hash, COALESCE(c.issued_by, 0), COALESCE(u.full_name, ''), c.issued_at
+	FROM clearances c
+	LEFT JOIN users u ON c.issued_by = u.id
+`
+
+// scanClearance считывает обходной лист, выбранный через clearanceColumns
+func scanClearance(row rowScanner) (*models.Clearance, error) {
+	var c models.Clearance
+	err := row.Scan(
+		&c.ID, &c.Number, &c.ReaderID, &c.ReaderName, &c.ReaderInfo, &c.Reason,
+		&c.VerificationCode, &c.FileHash, &c.IssuedBy, &c.IssuedByName, &c.IssuedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// CheckClearance проверяет, есть ли у читателя невозвращенные книги и диски, и показывает его брони.
+// Неоплаченные начисления (штрафы, возмещение утерянных книг) не проверяются: в системе их нет.
+// Когда они появятся, их нужно добавить сюда как еще один вид долга
+func CheckClearance(readerID int) (*models.ClearanceCheck, error) {
+	return checkClearance(db, readerID)
+}
+
+// checkClearance выполняет проверку в базе или внутри транзакции выдачи листа.
+// Долги — только невозвращенные книги и диски, см. CheckClearance
+func checkClearance(q queryer, readerID int) (*models.ClearanceCheck, error) {
+	check := &models.ClearanceCheck{ReaderID: readerID, Debts: []models.ClearanceDebt{}}
+	err := q.QueryRow(
+		"SELECT TRIM(last_name || ' ' || first_name || ' ' || COALESCE(middle_name, '')) FROM readers WHERE id = ?",
+		readerID,
+	).Scan(&check.ReaderName)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query(`
+		SELECT 'book', l.id, b.id, COALESCE(b.code, ''), b.title, COALESCE(b.barcode, ''), l.issue_date
+		FROM loans l
+		JOIN books b ON l.book_id = b.id
+		WHERE l.reader_id = ? AND l.status = 'active'
+		UNION ALL
+		SELECT 'disk', dl.id, d.id, COALESCE(d.code, ''), d.title, COALESCE(d.barcode, ''), dl.issue_date
+		FROM disk_loans dl
+		JOIN disks d ON dl.disk_id = d.id
+		WHERE dl.reader_id = ? AND dl.status = 'active'
+		ORDER BY 7
+	`, readerID, readerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var debt models.ClearanceDebt
+		if err := rows.Scan(
+			&debt.Type, &debt.LoanID, &debt.ItemID, &debt.Code, &debt.Title, &debt.Barcode, &debt.IssueDate,
+		); err != nil {
+			return nil, err
+		}
+		check.Debts = append(check.Debts, debt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	check.Holds, err = queryHolds(q, "h.reader_id = ? AND h.status = 'active'", readerID)
+	if err != nil {
+		return nil, err
+	}
+
+	check.Cleared = len(check.Debts) == 0
+	return check, nil
+}
+
+// CreateClearance выдает обходной лист читателю без долгов. Номер берется из последовательности
+// clearance, render формирует PDF по заполненному листу. Файл хранится вместе с SHA-256
+// и кодом проверки, по которому подлинность листа подтверждается позже
+func CreateClearance(clearance *models.Clearance, render func(*models.Clearance) ([]byte, error)) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	check, err := checkClearance(tx, clearance.ReaderID)
+	if err != nil {
+		return err
+	}
+	if !check.Cleared {
+		return ErrReaderNotCleared
+	}
+
+	clearance.Number = ""
+	if err := assignCode(tx, "clearance", &clearance.Number); err != nil {
+		return err
+	}
+	clearance.ReaderName = check.ReaderName
+	err = tx.QueryRow("SELECT COALESCE(NULLIF(full_name, ''), username) FROM users WHERE id = ?", clearance.IssuedBy).
+		Scan(&clearance.IssuedByName)
+	if err != nil {
+		return err
+	}
+	clearance.IssuedAt = time.Now().UTC().Truncate(time.Second)
+	clearance.VerificationCode, err = newVerificationCode()
+	if err != nil {
+		return err
+	}
+
+	pdf, err := render(clearance)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(pdf)
+	clearance.FileHash = hex.EncodeToString(sum[:])
+
+	result, err := tx.Exec(`
+		INSERT INTO clearances (
+			code, reader_id, reader_name, reader_info, reason,
+			verification_code, file_hash, pdf, issued_by, issued_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, clearance.Number, clearance.ReaderID, clearance.ReaderName, clearance.ReaderInfo, clearance.Reason,
+		clearance.VerificationCode, clearance.FileHash, pdf, clearance.IssuedBy, clearance.IssuedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	clearance.ID = int(id)
+
+	// Читатель уходит из библиотеки — его брони снимаются
+	if _, err := cancelReaderHolds(tx, clearance.ReaderID, clearance.IssuedBy); err != nil {
+		return err
+	}
+
+	details := map[string]string{"number": clearance.Number, "reason": clearance.Reason}
+	if err := WriteAudit(tx, "reader", clearance.ReaderID, "clearance", details, clearance.IssuedBy); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// newVerificationCode возвращает случайный код проверки из 8 байт вида 3F9A-C21B-07DE-11AA.
+// Коды прежних листов короче и по-прежнему проверяются
+func newVerificationCode() (string, error) {
+	raw := make([]byte, 8)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	code := strings.ToUpper(hex.EncodeToString(raw))
+	return code[:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:], nil
+}
+
+// GetClearances возвращает обходные листы читателя, начиная с последнего
+func GetClearances(readerID int) ([]models.Clearance, error) {
+	rows, err := db.Query("SELECT "+clearanceColumns+" WHERE c.reader_id = ? ORDER BY c.issued_at DESC, c.id DESC", readerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clearances := []models.Clearance{}
+	for rows.Next() {
+		clearance, err := scanClearance(rows)
+		if err != nil {
+			return nil, err
+		}
+		clearances = append(clearances, *clearance)
+	}
+	return clearances, rows.Err()
+}
+
+// GetClearancePDF возвращает сохраненный PDF обходного листа и его номер
+func GetClearancePDF(id int) ([]byte, string, error) {
+	var pdf []byte
+	var number string
+	err := db.QueryRow("SELECT pdf, code FROM clearances WHERE id = ?", id).Scan(&pdf, &number)
+	return pdf, number, err
+}
+
+// VerifyClearance находит обходной лист по номеру и коду проверки.
+// При неверном коде возвращается sql.ErrNoRows, как и для несуществующего номера
+func VerifyClearance(number, code string) (*models.Clearance, error) {
+	clearance, err := scanClearance(db.QueryRow("SELECT "+clearanceColumns+" WHERE c.code = ?", number))
+	if err != nil {
+		return nil, err
+	}
+
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if subtle.ConstantTimeCompare([]byte(code), []byte(clearance.VerificationCode)) != 1 {
+		return nil, sql.ErrNoRows
+	}
+	return clearance, nil
+}
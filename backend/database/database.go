@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"fmt"
 	"io/ioutil"
+	"library-management/backend/classification"
+	"library-management/backend/media"
 	"library-management/backend/models"
 	"time"
 
@@ -30,6 +32,11 @@ func Init(dataSourceName string) error {
 		return err
 	}
 
+	// Обновляем структуру существующей базы
+	if err = migrate(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -53,53 +60,99 @@ func createTables() error {
 	return err
 }
 
-// GetBooks возвращает список книг с пагинацией и поиском
-func GetBooks(search string, page, pageSize int) ([]models.Book, int, error) {
-	offset := (page - 1) * pageSize
+// bookColumns — столбцы книги вместе с автором, издательством и признаком доступности.
+// Используется вместе с bookJoins и scanBook
+const bookColumns = `
+	b.id, b.code, b.title, COALESCE(b.short_title, ''),
+	b.author_id, b.publisher_id, b.publication_year,
+	COALESCE(b.barcode, ''), COALESCE(b.isbn, ''), COALESCE(b.bbk, ''), COALESCE(b.udk, ''),
+	COALESCE(b.class_range, ''), COALESCE(b.location, ''), b.created_at, COALESCE(b.created_by, 0),
+	b.series_id, b.volume_number, COALESCE(b.part_title, ''), b.work_id,
+	b.location_id,
+	COALESCE(a.code, ''), COALESCE(a.last_name, ''), COALESCE(a.first_name, ''),
+	COALESCE(a.middle_name, ''), COALESCE(a.short_name, ''), a.created_at,
+	COALESCE(p.code, ''), COALESCE(p.name, ''), p.created_at,
+	COALESCE(cv.etag, ''),
+	CASE WHEN l.id IS NULL THEN 1 ELSE 0 END as is_available
+`
 
-	// Базовый запрос
-	baseQuery := `
-		SELECT b.*, a.*, p.*,
-			CASE WHEN l.id IS NULL THEN 1 ELSE 0 END as is_available
-		FROM books b
-		LEFT JOIN authors a ON b.author_id = a.id
-		LEFT JOIN publishers p ON b.publisher_id = p.id
-		LEFT JOIN loans l ON b.id = l.book_id AND l.status = 'active'
-		WHERE 1=1
-	`
+// bookJoins — источник данных для bookColumns
+const bookJoins = `
+	FROM books b
+	LEFT JOIN authors a ON b.author_id = a.id
+	LEFT JOIN publishers p ON b.publisher_id = p.id
+	LEFT JOIN loans l ON b.id = l.book_id AND l.status = 'active'
+	LEFT JOIN covers cv ON cv.entity_type = 'book' AND cv.entity_id = b.id
+`
 
-	// Добавляем условия поиска
-	var args []interface{}
-	if search != "" {
-		baseQuery += ` AND (
-			b.title LIKE ? OR 
-			b.barcode LIKE ? OR 
-			b.isbn LIKE ? OR
-			a.last_name LIKE ? OR
-			p.name LIKE ?
-		)`
-		searchPattern := "%" + search + "%"
-		args = append(args, searchPattern, searchPattern, searchPattern, searchPattern, searchPattern)
+// rowScanner — общий интерфейс sql.Row и sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanBook считывает книгу, выбранную через bookColumns
+func scanBook(row rowScanner) (*models.Book, error) {
+	var book models.Book
+	var author models.Author
+	var publisher models.Publisher
+	var authorCreatedAt, publisherCreatedAt sql.NullTime
+	var coverETag string
+
+	err := row.Scan(
+		&book.ID, &book.Code, &book.Title, &book.ShortTitle,
+		&book.AuthorID, &book.PublisherID, &book.PublicationYear,
+		&book.Barcode, &book.ISBN, &book.BBK, &book.UDK,
+		&book.ClassRange, &book.Location, &book.CreatedAt, &book.CreatedBy,
+		&book.SeriesID, &book.VolumeNumber, &book.PartTitle, &book.WorkID,
+		&book.LocationID,
+		&author.Code, &author.LastName, &author.FirstName,
+		&author.MiddleName, &author.ShortName, &authorCreatedAt,
+		&publisher.Code, &publisher.Name, &publisherCreatedAt,
+		&coverETag,
+		&book.IsAvailable,
+	)
+	if err != nil {
+		return nil, err
 	}
 
-	// Получаем общее количество
-	countQuery := "SELECT COUNT(DISTINCT b.id) FROM books b LEFT JOIN authors a ON b.author_id = a.id LEFT JOIN publishers p ON b.publisher_id = p.id LEFT JOIN loans l ON b.id = l.book_id AND l.status = 'active' WHERE 1=1"
-	if search != "" {
-		countQuery += ` AND (b.title LIKE ? OR b.barcode LIKE ? OR b.isbn LIKE ? OR a.last_name LIKE ? OR p.name LIKE ?)`
+	book.CoverURL = media.CoverURL(media.EntityBook, book.ID, coverETag)
+
+	if book.AuthorID != nil {
+		author.ID = *book.AuthorID
+		author.CreatedAt = authorCreatedAt.Time
+		book.Author = &author
+	}
+	if book.PublisherID != nil {
+		publisher.ID = *book.PublisherID
+		publisher.CreatedAt = publisherCreatedAt.Time
+		book.Publisher = &publisher
+	}
+
+	return &book, nil
+}
+
+// GetBooks возвращает страницу каталога книг с фильтрами и сортировкой.
+// Фильтр по месту хранения включает все вложенные места, по ББК — все вложенные разделы
+func GetBooks(f models.BookFilter) ([]models.Book, int, error) {
+	conditions, err := bookFilterConditions(&f)
+	if err != nil {
+		return nil, 0, err
 	}
+	where, args := conditions.where("")
 
+	// Получаем общее количество
 	var total int
-	row := db.QueryRow(countQuery, args...)
-	if err := row.Scan(&total); err != nil {
+	countQuery := "SELECT COUNT(DISTINCT b.id)" + bookFilterJoins + " WHERE " + where
+	if err := db.QueryRow(countQuery, args...).Scan(&total); err != nil {
 		return nil, 0, err
 	}
 
-	// Добавляем пагинацию
-	baseQuery += " ORDER BY b.code LIMIT ? OFFSET ?"
-	args = append(args, pageSize, offset)
+	// Добавляем сортировку и пагинацию
+	query := "SELECT " + bookColumns + bookJoins + " WHERE " + where +
+		" ORDER BY " + bookOrder(f.Sort, f.Desc) + " LIMIT ? OFFSET ?"
+	args = append(args, f.PageSize, (f.Page-1)*f.PageSize)
 
-	// Выполняем запрос
-	rows, err := db.Query(baseQuery, args...)
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -107,32 +160,12 @@ func GetBooks(search string, page, pageSize int) ([]models.Book, int, error) {
 
 	var books []models.Book
 	for rows.Next() {
-		var book models.Book
-		var author models.Author
-		var publisher models.Publisher
-
-		err := rows.Scan(
-			&book.ID, &book.Code, &book.Title, &book.ShortTitle,
-			&book.AuthorID, &book.PublisherID, &book.PublicationYear,
-			&book.Barcode, &book.ISBN, &book.BBK, &book.UDK,
-			&book.ClassRange, &book.Location, &book.CreatedAt, &book.CreatedBy,
-			&author.ID, &author.Code, &author.LastName, &author.FirstName,
-			&author.MiddleName, &author.ShortName, &author.CreatedAt,
-			&publisher.ID, &publisher.Code, &publisher.Name, &publisher.CreatedAt,
-			&book.IsAvailable,
-		)
+		book, err := scanBook(rows)
 		if err != nil {
 			continue
 		}
 
-		if book.AuthorID != nil {
-			book.Author = &author
-		}
-		if book.PublisherID != nil {
-			book.Publisher = &publisher
-		}
-
-		books = append(books, book)
+		books = append(books, *book)
 	}
 
 	return books, total, nil
@@ -140,103 +173,59 @@ func GetBooks(search string, page, pageSize int) ([]models.Book, int, error) {
 
 // GetBookByID возвращает книгу по ID
 func GetBookByID(id int) (*models.Book, error) {
-	query := `
-		SELECT b.*, a.*, p.*,
-			CASE WHEN l.id IS NULL THEN 1 ELSE 0 END as is_available
-		FROM books b
-		LEFT JOIN authors a ON b.author_id = a.id
-		LEFT JOIN publishers p ON b.publisher_id = p.id
-		LEFT JOIN loans l ON b.id = l.book_id AND l.status = 'active'
-		WHERE b.id = ?
-	`
-
-	var book models.Book
-	var author models.Author
-	var publisher models.Publisher
-
-	row := db.QueryRow(query, id)
-	err := row.Scan(
-		&book.ID, &book.Code, &book.Title, &book.ShortTitle,
-		&book.AuthorID, &book.PublisherID, &book.PublicationYear,
-		&book.Barcode, &book.ISBN, &book.BBK, &book.UDK,
-		&book.ClassRange, &book.Location, &book.CreatedAt, &book.CreatedBy,
-		&author.ID, &author.Code, &author.LastName, &author.FirstName,
-		&author.MiddleName, &author.ShortName, &author.CreatedAt,
-		&publisher.ID, &publisher.Code, &publisher.Name, &publisher.CreatedAt,
-		&book.IsAvailable,
-	)
-
-	if err != nil {
-		return nil, err
-	}
-
-	if book.AuthorID != nil {
-		book.Author = &author
-	}
-	if book.PublisherID != nil {
-		book.Publisher = &publisher
-	}
-
-	return &book, nil
+	query := "SELECT " + bookColumns + bookJoins + " WHERE b.id = ?"
+	return scanBook(db.QueryRow(query, id))
 }
 
 // GetBookByBarcode возвращает книгу по штрих-коду
 func GetBookByBarcode(barcode string) (*models.Book, error) {
-	query := `
-		SELECT b.*, a.*, p.*,
-			CASE WHEN l.id IS NULL THEN 1 ELSE 0 END as is_available
-		FROM books b
-		LEFT JOIN authors a ON b.author_id = a.id
-		LEFT JOIN publishers p ON b.publisher_id = p.id
-		LEFT JOIN loans l ON b.id = l.book_id AND l.status = 'active'
-		WHERE b.barcode = ?
-	`
-
-	var book models.Book
-	var author models.Author
-	var publisher models.Publisher
-
-	row := db.QueryRow(query, barcode)
-	err := row.Scan(
-		&book.ID, &book.Code, &book.Title, &book.ShortTitle,
-		&book.AuthorID, &book.PublisherID, &book.PublicationYear,
-		&book.Barcode, &book.ISBN, &book.BBK, &book.UDK,
-		&book.ClassRange, &book.Location, &book.CreatedAt, &book.CreatedBy,
-		&author.ID, &author.Code, &author.LastName, &author.FirstName,
-		&author.MiddleName, &author.ShortName, &author.CreatedAt,
-		&publisher.ID, &publisher.Code, &publisher.Name, &publisher.CreatedAt,
-		&book.IsAvailable,
-	)
+	query := "SELECT " + bookColumns + bookJoins + " WHERE b.barcode = ?"
+	return scanBook(db.QueryRow(query, barcode))
+}
 
+// GetNewArrivals возвращает последние поступившие книги
+func GetNewArrivals(limit int) ([]models.Book, error) {
+	books, err := queryBooks(" ORDER BY b.created_at DESC, b.id DESC LIMIT ?", limit)
 	if err != nil {
 		return nil, err
 	}
 
-	if book.AuthorID != nil {
-		book.Author = &author
-	}
-	if book.PublisherID != nil {
-		book.Publisher = &publisher
+	result := make([]models.Book, 0, len(books))
+	for _, book := range books {
+		result = append(result, *book)
 	}
-
-	return &book, nil
+	return result, nil
 }
 
-// CreateBook создает новую книгу
+// CreateBook создает новую книгу. Если указано место хранения,
+// начальная расстановка записывается в историю перемещений
 func CreateBook(book *models.Book) (int, error) {
 	query := `
 		INSERT INTO books (
 			code, title, short_title, author_id, publisher_id,
 			publication_year, barcode, isbn, bbk, udk,
-			class_range, location, created_by
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			class_range, min_grade, max_grade, location, created_by,
+			series_id, volume_number, part_title, work_id, location_id
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	result, err := db.Exec(query,
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := assignCode(tx, "book", &book.Code); err != nil {
+		return 0, err
+	}
+
+	minGrade, maxGrade := gradeRangeArgs(book.ClassRange)
+	result, err := tx.Exec(query,
 		book.Code, book.Title, book.ShortTitle, book.AuthorID,
 		book.PublisherID, book.PublicationYear, book.Barcode,
-		book.ISBN, book.BBK, book.UDK, book.ClassRange,
+		book.ISBN, book.BBK, book.UDK, book.ClassRange, minGrade, maxGrade,
 		book.Location, book.CreatedBy,
+		book.SeriesID, book.VolumeNumber, book.PartTitle, book.WorkID, book.LocationID,
 	)
 
 	if err != nil {
@@ -248,33 +237,57 @@ func CreateBook(book *models.Book) (int, error) {
 		return 0, err
 	}
 
-	return int(id), nil
+	if book.LocationID != nil {
+		if err := insertBookMove(tx, int(id), nil, "", book.LocationID, book.Location, book.CreatedBy); err != nil {
+			return 0, err
+		}
+	}
+
+	return int(id), tx.Commit()
 }
 
-// UpdateBook обновляет книгу
+// UpdateBook обновляет книгу. Место хранения меняется только перемещением (MoveBooks);
+// текстовое место хранения редактируется лишь у книг, не привязанных к месту хранения
 func UpdateBook(book *models.Book) error {
 	query := `
 		UPDATE books SET
 			title = ?, short_title = ?, author_id = ?,
 			publisher_id = ?, publication_year = ?, barcode = ?,
-			isbn = ?, bbk = ?, udk = ?, class_range = ?, location = ?
+			isbn = ?, bbk = ?, udk = ?, class_range = ?, min_grade = ?, max_grade = ?,
+			location = CASE WHEN location_id IS NULL THEN ? ELSE location END
 		WHERE id = ?
 	`
 
+	minGrade, maxGrade := gradeRangeArgs(book.ClassRange)
 	_, err := db.Exec(query,
 		book.Title, book.ShortTitle, book.AuthorID,
 		book.PublisherID, book.PublicationYear, book.Barcode,
-		book.ISBN, book.BBK, book.UDK, book.ClassRange,
+		book.ISBN, book.BBK, book.UDK, book.ClassRange, minGrade, maxGrade,
 		book.Location, book.ID,
 	)
 
 	return err
 }
 
-// DeleteBook удаляет книгу
+// DeleteBook удаляет книгу вместе с историей ее перемещений; активные брони на нее снимаются
 func DeleteBook(id int) error {
-	_, err := db.Exec("DELETE FROM books WHERE id = ?", id)
-	return err
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM book_moves WHERE book_id = ?", id); err != nil {
+		return err
+	}
+	// Брони на удаленную книгу не видны ни в одном списке, но занимали бы лимит читателя
+	if _, err := cancelBookHolds(tx, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM books WHERE id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // IsBookLoaned проверяет, выдана ли книга
@@ -288,22 +301,15 @@ func IsBookLoaned(bookID int) (bool, error) {
 	return count > 0, err
 }
 
-// GenerateBookCode генерирует код для книги
-func GenerateBookCode() string {
-	var maxCode int
-	db.QueryRow("SELECT COALESCE(MAX(CAST(code AS INTEGER)), 0) FROM books WHERE code GLOB '[0-9]*'").Scan(&maxCode)
-	return fmt.Sprintf("%06d", maxCode+1)
-}
-
 // GetUserByUsername возвращает пользователя по имени
 func GetUserByUsername(username string) (*models.User, error) {
-	query := `SELECT id, username, password_hash, full_name, role, created_at 
+	query := `SELECT id, username, password_hash, full_name, COALESCE(email, ''), role, is_active, last_login, created_at 
 			  FROM users WHERE username = ?`
 
 	var user models.User
 	err := db.QueryRow(query, username).Scan(
 		&user.ID, &user.Username, &user.PasswordHash,
-		&user.FullName, &user.Role, &user.CreatedAt,
+		&user.FullName, &user.Email, &user.Role, &user.IsActive, &user.LastLogin, &user.CreatedAt,
 	)
 
 	if err != nil {
@@ -334,7 +340,7 @@ func GetDashboardStats() (*models.DashboardStats, error) {
 	}
 
 	// Всего читателей
-	err = db.QueryRow("SELECT COUNT(*) FROM readers").Scan(&stats.TotalReaders)
+	err = db.QueryRow("SELECT COUNT(*) FROM readers WHERE status = 'active'").Scan(&stats.TotalReaders)
 	if err != nil {
 		return nil, err
 	}
@@ -379,98 +385,68 @@ func GetDashboardStats() (*models.DashboardStats, error) {
 
 // GetReaderByBarcode возвращает читателя по штрих-коду
 func GetReaderByBarcode(barcode string) (*models.Reader, error) {
-	query := `
-		SELECT r.*, 
-			(SELECT COUNT(*) FROM loans WHERE reader_id = r.id AND status = 'active') as active_loans_count
-		FROM readers r
-		WHERE r.barcode = ?
-	`
-
-	var reader models.Reader
-	var birthDate sql.NullTime
-
-	err := db.QueryRow(query, barcode).Scan(
-		&reader.ID, &reader.Code, &reader.Barcode,
-		&reader.LastName, &reader.FirstName, &reader.MiddleName,
-		&reader.UserType, &reader.ClassID, &reader.Grade,
-		&reader.Gender, &birthDate, &reader.Address,
-		&reader.DocumentType, &reader.DocumentNumber,
-		&reader.Phone, &reader.Email, &reader.Photo,
-		&reader.ParentMotherName, &reader.ParentMotherPhone,
-		&reader.ParentFatherName, &reader.ParentFatherPhone,
-		&reader.GuardianName, &reader.GuardianPhone,
-		&reader.CreatedAt, &reader.CreatedBy, &reader.Comments,
-		&reader.ActiveLoansCount,
-	)
-
-	if err != nil {
-		return nil, err
-	}
-
-	if birthDate.Valid {
-		reader.BirthDate = &birthDate.Time
+	reader, err := scanReader(db.QueryRow("SELECT "+readerColumns+" FROM readers r WHERE r.barcode = ?", barcode))
+	if err != sql.ErrNoRows {
+		return reader, err
 	}
 
-	return &reader, nil
+	// Прежний штрих-код объединенного дубликата приводит к сохраненной записи
+	return scanReader(db.QueryRow(
+		"SELECT "+readerColumns+" FROM readers r JOIN reader_barcode_aliases a ON a.reader_id = r.id WHERE a.barcode = ?",
+		barcode,
+	))
 }
 
 // GetActiveLoanByBookID возвращает активную выдачу по ID книги
 func GetActiveLoanByBookID(bookID int) (*models.Loan, error) {
 	query := `
-		SELECT l.*, b.*, r.*
-		FROM loans l
-		INNER JOIN books b ON l.book_id = b.id
-		INNER JOIN readers r ON l.reader_id = r.id
-		WHERE l.book_id = ? AND l.status = 'active'
+		SELECT id, book_id, reader_id, issue_date, return_date,
+			issued_by, returned_by, status
+		FROM loans
+		WHERE book_id = ? AND status = 'active'
 	`
 
 	var loan models.Loan
-	var book models.Book
-	var reader models.Reader
-
-	row := db.QueryRow(query, bookID)
-
-	// Сканируем данные выдачи
-	err := row.Scan(
+	err := db.QueryRow(query, bookID).Scan(
 		&loan.ID, &loan.BookID, &loan.ReaderID,
 		&loan.IssueDate, &loan.ReturnDate,
 		&loan.IssuedBy, &loan.ReturnedBy, &loan.Status,
-		// Данные книги
-		&book.ID, &book.Code, &book.Title, &book.ShortTitle,
-		&book.AuthorID, &book.PublisherID, &book.PublicationYear,
-		&book.Barcode, &book.ISBN, &book.BBK, &book.UDK,
-		&book.ClassRange, &book.Location, &book.CreatedAt, &book.CreatedBy,
-		// Данные читателя
-		&reader.ID, &reader.Code, &reader.Barcode,
-		&reader.LastName, &reader.FirstName, &reader.MiddleName,
-		&reader.UserType, &reader.ClassID, &reader.Grade,
-		&reader.Gender, &reader.BirthDate, &reader.Address,
-		&reader.DocumentType, &reader.DocumentNumber,
-		&reader.Phone, &reader.Email, &reader.Photo,
-		&reader.ParentMotherName, &reader.ParentMotherPhone,
-		&reader.ParentFatherName, &reader.ParentFatherPhone,
-		&reader.GuardianName, &reader.GuardianPhone,
-		&reader.CreatedAt, &reader.CreatedBy, &reader.Comments,
 	)
+	if err != nil {
+		return nil, err
+	}
 
+	book, err := GetBookByID(loan.BookID)
 	if err != nil {
 		return nil, err
 	}
 
-	loan.Book = &book
-	loan.Reader = &reader
+	reader, err := GetReaderByID(loan.ReaderID)
+	if err != nil {
+		return nil, err
+	}
+
+	loan.Book = book
+	loan.Reader = reader
 
 	return &loan, nil
 }
 
-// CreateLoan создает новую выдачу книги
-func CreateLoan(loan *models.Loan) (int, error) {
+// CreateLoan создает новую выдачу книги. overriddenHold — чужая бронь, обойденная с правом
+// loans.override: запись issue_override попадает в журнал в той же транзакции
+func CreateLoan(loan *models.Loan, overriddenHold *models.Hold) (int, error) {
 	query := `
 		INSERT INTO loans (book_id, reader_id, issue_date, issued_by, status)
 		VALUES (?, ?, ?, ?, ?)
 	`
 
-	result, err := db.Exec(query,
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(query,
 		loan.BookID, loan.ReaderID, loan.IssueDate,
 		loan.IssuedBy, loan.Status,
 	)
@@ -484,7 +460,22 @@ func CreateLoan(loan *models.Loan) (int, error) {
 		return 0, err
 	}
 
-	return int(id), nil
+	// Бронь читателя на эту книгу выполнена
+	if err := fulfillHolds(tx, loan.ReaderID, loan.BookID); err != nil {
+		return 0, err
+	}
+
+	if overriddenHold != nil {
+		details := map[string]int{
+			"loan_id": int(id), "hold_id": overriddenHold.ID,
+			"hold_reader_id": overriddenHold.ReaderID, "reader_id": loan.ReaderID,
+		}
+		if err := WriteAudit(tx, "book", loan.BookID, "issue_override", details, loan.IssuedBy); err != nil {
+			return 0, err
+		}
+	}
+
+	return int(id), tx.Commit()
 }
 
 // UpdateLoan обновляет информацию о выдаче
@@ -636,33 +627,22 @@ func GetLoanHistory(bookID, readerID int, dateFrom, dateTo string) ([]models.Loa
 	return loans, nil
 }
 
-// GetReaders возвращает список читателей с пагинацией
-func GetReaders(search string, page, pageSize int) ([]models.Reader, int, error) {
+// GetReaders возвращает список читателей с пагинацией.
+// status: пусто — только действующие, archived — архивные, all — все
+func GetReaders(search, status string, page, pageSize int) ([]models.Reader, int, error) {
 	offset := (page - 1) * pageSize
 
-	baseQuery := `
-		SELECT r.*, 
-			(SELECT COUNT(*) FROM loans WHERE reader_id = r.id AND status = 'active') as active_loans_count
-		FROM readers r
-		WHERE 1=1
-	`
-
-	var args []interface{}
-	if search != "" {
-		baseQuery += ` AND (
-			r.last_name LIKE ? OR 
-			r.first_name LIKE ? OR 
-			r.barcode LIKE ? OR
-			r.phone LIKE ?
-		)`
-		searchPattern := "%" + search + "%"
-		args = append(args, searchPattern, searchPattern, searchPattern, searchPattern)
-	}
+	baseQuery := "SELECT " + readerColumns + " FROM readers r WHERE 1=1" + readerStatusCondition(status)
 
 	// Получаем общее количество
-	countQuery := "SELECT COUNT(*) FROM readers r WHERE 1=1"
+	countQuery := "SELECT COUNT(*) FROM readers r WHERE 1=1" + readerStatusCondition(status)
+
+	var args []interface{}
 	if search != "" {
-		countQuery += ` AND (r.last_name LIKE ? OR r.first_name LIKE ? OR r.barcode LIKE ? OR r.phone LIKE ?)`
+		condition, searchArgs := readerSearchCondition(search)
+		baseQuery += condition
+		countQuery += condition
+		args = append(args, searchArgs...)
 	}
 
 	var total int
@@ -683,68 +663,71 @@ func GetReaders(search string, page, pageSize int) ([]models.Reader, int, error)
 
 	var readers []models.Reader
 	for rows.Next() {
-		var reader models.Reader
-		var birthDate sql.NullTime
-
-		err := rows.Scan(
-			&reader.ID, &reader.Code, &reader.Barcode,
-			&reader.LastName, &reader.FirstName, &reader.MiddleName,
-			&reader.UserType, &reader.ClassID, &reader.Grade,
-			&reader.Gender, &birthDate, &reader.Address,
-			&reader.DocumentType, &reader.DocumentNumber,
-			&reader.Phone, &reader.Email, &reader.Photo,
-			&reader.ParentMotherName, &reader.ParentMotherPhone,
-			&reader.ParentFatherName, &reader.ParentFatherPhone,
-			&reader.GuardianName, &reader.GuardianPhone,
-			&reader.CreatedAt, &reader.CreatedBy, &reader.Comments,
-			&reader.ActiveLoansCount,
-		)
+		// Ошибка расшифровки (например, чужой PII_KEY) не должна молча опустошать список
+		reader, err := scanReader(rows)
 		if err != nil {
-			continue
+			return nil, 0, err
 		}
-
-		if birthDate.Valid {
-			reader.BirthDate = &birthDate.Time
-		}
-
-		readers = append(readers, reader)
+		readers = append(readers, *reader)
 	}
 
-	return readers, total, nil
+	return readers, total, rows.Err()
 }
 
 // GetReaderByID возвращает читателя по ID
 func GetReaderByID(id int) (*models.Reader, error) {
-	query := `
-		SELECT r.*, 
-			(SELECT COUNT(*) FROM loans WHERE reader_id = r.id AND status = 'active') as active_loans_count
-		FROM readers r
-		WHERE r.id = ?
-	`
+	return scanReader(db.QueryRow("SELECT "+readerColumns+" FROM readers r WHERE r.id = ?", id))
+}
+
+// readerColumns — столбцы читателя вместе с количеством активных выдач.
+// Используется вместе со scanReader. Дата рождения читается как текст: она может быть зашифрована
+const readerColumns = `
+	r.id, COALESCE(r.code, ''), COALESCE(r.barcode, ''),
+	r.last_name, r.first_name, COALESCE(r.middle_name, ''),
+	COALESCE(r.user_type, ''), r.class_id, r.grade,
+	COALESCE(r.gender, ''), CAST(r.birth_date AS TEXT), COALESCE(r.address, ''),
+	COALESCE(r.document_type, ''), COALESCE(r.document_number, ''),
+	COALESCE(r.phone, ''), COALESCE(r.email, ''),
+	COALESCE((SELECT etag FROM covers WHERE entity_type = 'reader' AND entity_id = r.id), ''),
+	COALESCE(r.parent_mother_name, ''), COALESCE(r.parent_mother_phone, ''),
+	COALESCE(r.parent_father_name, ''), COALESCE(r.parent_father_phone, ''),
+	COALESCE(r.guardian_name, ''), COALESCE(r.guardian_phone, ''),
+	r.created_at, COALESCE(r.created_by, 0), COALESCE(r.comments, ''),
+	r.status, r.archived_at, COALESCE(r.archive_reason, ''),
+	(SELECT COUNT(*) FROM loans WHERE reader_id = r.id AND status = 'active') as active_loans_count
+`
 
+// scanReader считывает читателя, выбранного через readerColumns
+func scanReader(row rowScanner) (*models.Reader, error) {
 	var reader models.Reader
-	var birthDate sql.NullTime
+	var birthDate sql.NullString
+	var archivedAt sql.NullTime
+	var photoETag string
 
-	err := db.QueryRow(query, id).Scan(
+	err := row.Scan(
 		&reader.ID, &reader.Code, &reader.Barcode,
 		&reader.LastName, &reader.FirstName, &reader.MiddleName,
 		&reader.UserType, &reader.ClassID, &reader.Grade,
 		&reader.Gender, &birthDate, &reader.Address,
 		&reader.DocumentType, &reader.DocumentNumber,
-		&reader.Phone, &reader.Email, &reader.Photo,
+		&reader.Phone, &reader.Email, &photoETag,
 		&reader.ParentMotherName, &reader.ParentMotherPhone,
 		&reader.ParentFatherName, &reader.ParentFatherPhone,
 		&reader.GuardianName, &reader.GuardianPhone,
 		&reader.CreatedAt, &reader.CreatedBy, &reader.Comments,
+		&reader.Status, &archivedAt, &reader.ArchiveReason,
 		&reader.ActiveLoansCount,
 	)
-
 	if err != nil {
 		return nil, err
 	}
 
-	if birthDate.Valid {
-		reader.BirthDate = &birthDate.Time
+	reader.PhotoURL = media.PhotoURL(reader.ID, photoETag)
+	if err := openReader(&reader, birthDate); err != nil {
+		return nil, err
+	}
+	if archivedAt.Valid {
+		reader.ArchivedAt = &archivedAt.Time
 	}
 
 	return &reader, nil
@@ -757,19 +740,39 @@ func CreateReader(reader *models.Reader) (int, error) {
 			code, barcode, last_name, first_name, middle_name,
 			user_type, class_id, grade, gender, birth_date,
 			address, document_type, document_number, phone, email,
-			photo, parent_mother_name, parent_mother_phone,
+			parent_mother_name, parent_mother_phone,
 			parent_father_name, parent_father_phone,
-			guardian_name, guardian_phone, created_by, comments
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			guardian_name, guardian_phone, created_by, comments, phone_hash, email_hash
+		) VALUES (
+			?, ?, ?, ?, ?, ?, ?,
+			COALESCE((SELECT grade FROM classes WHERE id = ?), ?),
+			?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+		)
 	`
 
-	result, err := db.Exec(query,
+	// Персональные данные сохраняются зашифрованными
+	sealed, err := sealReader(reader)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := assignCode(tx, "reader", &reader.Code); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(query,
 		reader.Code, reader.Barcode, reader.LastName, reader.FirstName, reader.MiddleName,
-		reader.UserType, reader.ClassID, reader.Grade, reader.Gender, reader.BirthDate,
-		reader.Address, reader.DocumentType, reader.DocumentNumber, reader.Phone, reader.Email,
-		reader.Photo, reader.ParentMotherName, reader.ParentMotherPhone,
-		reader.ParentFatherName, reader.ParentFatherPhone,
-		reader.GuardianName, reader.GuardianPhone, reader.CreatedBy, reader.Comments,
+		reader.UserType, reader.ClassID, reader.ClassID, reader.Grade, reader.Gender, sealed.birthDate,
+		sealed.address, reader.DocumentType, sealed.documentNumber, sealed.phone, sealed.email,
+		reader.ParentMotherName, sealed.motherPhone,
+		reader.ParentFatherName, sealed.fatherPhone,
+		reader.GuardianName, sealed.guardianPhone, reader.CreatedBy, reader.Comments, sealed.phoneHash, sealed.emailHash,
 	)
 
 	if err != nil {
@@ -781,41 +784,72 @@ func CreateReader(reader *models.Reader) (int, error) {
 		return 0, err
 	}
 
-	return int(id), nil
+	return int(id), tx.Commit()
 }
 
 // UpdateReader обновляет читателя
 func UpdateReader(reader *models.Reader) error {
 	query := `
 		UPDATE readers SET
-			last_name = ?, first_name = ?, middle_name = ?,
-			user_type = ?, class_id = ?, grade = ?, gender = ?,
+			barcode = ?, last_name = ?, first_name = ?, middle_name = ?,
+			user_type = ?, class_id = ?,
+			grade = COALESCE((SELECT grade FROM classes WHERE id = ?), ?), gender = ?,
 			birth_date = ?, address = ?, document_type = ?,
-			document_number = ?, phone = ?, email = ?, photo = ?,
+			document_number = ?, phone = ?, email = ?,
 			parent_mother_name = ?, parent_mother_phone = ?,
 			parent_father_name = ?, parent_father_phone = ?,
-			guardian_name = ?, guardian_phone = ?, comments = ?
+			guardian_name = ?, guardian_phone = ?, comments = ?, phone_hash = ?, email_hash = ?,
+			updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?
 	`
 
-	_, err := db.Exec(query,
-		reader.LastName, reader.FirstName, reader.MiddleName,
-		reader.UserType, reader.ClassID, reader.Grade, reader.Gender,
-		reader.BirthDate, reader.Address, reader.DocumentType,
-		reader.DocumentNumber, reader.Phone, reader.Email, reader.Photo,
-		reader.ParentMotherName, reader.ParentMotherPhone,
-		reader.ParentFatherName, reader.ParentFatherPhone,
-		reader.GuardianName, reader.GuardianPhone, reader.Comments,
+	// Персональные данные сохраняются зашифрованными
+	sealed, err := sealReader(reader)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(query,
+		reader.Barcode, reader.LastName, reader.FirstName, reader.MiddleName,
+		reader.UserType, reader.ClassID, reader.ClassID, reader.Grade, reader.Gender,
+		sealed.birthDate, sealed.address, reader.DocumentType,
+		sealed.documentNumber, sealed.phone, sealed.email,
+		reader.ParentMotherName, sealed.motherPhone,
+		reader.ParentFatherName, sealed.fatherPhone,
+		reader.GuardianName, sealed.guardianPhone, reader.Comments, sealed.phoneHash, sealed.emailHash,
 		reader.ID,
 	)
 
 	return err
 }
 
-// DeleteReader удаляет читателя
+// DeleteReader удаляет читателя. Читателя с выданными обходными листами удалить нельзя
+// (ErrReaderHasClearances): лист остается проверяемым, а читателя переводят в архив
 func DeleteReader(id int) error {
-	_, err := db.Exec("DELETE FROM readers WHERE id = ?", id)
-	return err
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var clearances int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM clearances WHERE reader_id = ?", id).Scan(&clearances); err != nil {
+		return err
+	}
+	if clearances > 0 {
+		return ErrReaderHasClearances
+	}
+
+	for _, table := range []string{"reader_barcode_aliases", "reader_credentials", "reader_login_codes", "holds"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE reader_id = ?", id); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM readers WHERE id = ?", id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // ReaderHasActiveLoans проверяет, есть ли у читателя активные выдачи
@@ -829,13 +863,6 @@ func ReaderHasActiveLoans(readerID int) (bool, error) {
 	return count > 0, err
 }
 
-// GenerateReaderCode генерирует код для читателя
-func GenerateReaderCode() string {
-	var maxCode int
-	db.QueryRow("SELECT COALESCE(MAX(CAST(code AS INTEGER)), 0) FROM readers WHERE code GLOB '[0-9]*'").Scan(&maxCode)
-	return fmt.Sprintf("%06d", maxCode+1)
-}
-
 // GetAuthors возвращает список авторов
 func GetAuthors() ([]models.Author, error) {
 	query := `
@@ -877,7 +904,17 @@ func CreateAuthor(author *models.Author) (int, error) {
 		VALUES (?, ?, ?, ?, ?)
 	`
 
-	result, err := db.Exec(query,
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := assignCode(tx, "author", &author.Code); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(query,
 		author.Code, author.LastName, author.FirstName,
 		author.MiddleName, author.ShortName,
 	)
@@ -891,7 +928,7 @@ func CreateAuthor(author *models.Author) (int, error) {
 		return 0, err
 	}
 
-	return int(id), nil
+	return int(id), tx.Commit()
 }
 
 // UpdateAuthor обновляет автора
@@ -928,13 +965,6 @@ func AuthorHasBooks(authorID int) (bool, error) {
 	return count > 0, err
 }
 
-// GenerateAuthorCode генерирует код для автора
-func GenerateAuthorCode() string {
-	var maxCode int
-	db.QueryRow("SELECT COALESCE(MAX(CAST(code AS INTEGER)), 0) FROM authors WHERE code GLOB '[0-9]*'").Scan(&maxCode)
-	return fmt.Sprintf("%05d", maxCode+1)
-}
-
 // GetPublishers возвращает список издательств
 func GetPublishers() ([]models.Publisher, error) {
 	query := `
@@ -972,7 +1002,17 @@ func GetPublishers() ([]models.Publisher, error) {
 func CreatePublisher(publisher *models.Publisher) (int, error) {
 	query := `INSERT INTO publishers (code, name) VALUES (?, ?)`
 
-	result, err := db.Exec(query, publisher.Code, publisher.Name)
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := assignCode(tx, "publisher", &publisher.Code); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(query, publisher.Code, publisher.Name)
 	if err != nil {
 		return 0, err
 	}
@@ -982,7 +1022,7 @@ func CreatePublisher(publisher *models.Publisher) (int, error) {
 		return 0, err
 	}
 
-	return int(id), nil
+	return int(id), tx.Commit()
 }
 
 // UpdatePublisher обновляет издательство
@@ -1011,20 +1051,20 @@ func PublisherHasBooks(publisherID int) (bool, error) {
 	return count > 0, err
 }
 
-// GeneratePublisherCode генерирует код для издательства
-func GeneratePublisherCode() string {
-	var maxCode int
-	db.QueryRow("SELECT COALESCE(MAX(CAST(code AS INTEGER)), 0) FROM publishers WHERE code GLOB '[0-9]*'").Scan(&maxCode)
-	return fmt.Sprintf("%05d", maxCode+1)
-}
-
-// GetDisks возвращает список дисков
-func GetDisks(search string) ([]models.Disk, error) {
+// GetDisks возвращает список дисков с поиском и фильтрами по предмету и типу ЭОР
+func GetDisks(search string, subjectID, resourceTypeID int) ([]models.Disk, error) {
 	query := `
-		SELECT d.*, p.name,
+		SELECT d.id, d.code, d.title, d.short_title, d.publisher_id,
+			d.subject_id, COALESCE(s.name, d.subject, ''),
+			d.resource_type_id, COALESCE(rt.name, d.resource_type, ''),
+			d.barcode, d.created_at, d.created_by, d.comments, p.name,
+			COALESCE(cv.etag, ''),
 			CASE WHEN dl.id IS NULL THEN 1 ELSE 0 END as is_available
 		FROM disks d
 		LEFT JOIN publishers p ON d.publisher_id = p.id
+		LEFT JOIN covers cv ON cv.entity_type = 'disk' AND cv.entity_id = d.id
+		LEFT JOIN subjects s ON d.subject_id = s.id
+		LEFT JOIN resource_types rt ON d.resource_type_id = rt.id
 		LEFT JOIN disk_loans dl ON d.id = dl.disk_id AND dl.status = 'active'
 		WHERE 1=1
 	`
@@ -1033,12 +1073,22 @@ func GetDisks(search string) ([]models.Disk, error) {
 	if search != "" {
 		query += ` AND (
 			d.title LIKE ? OR d.barcode LIKE ? OR
-			d.subject LIKE ? OR d.resource_type LIKE ?
+			s.name LIKE ? OR rt.name LIKE ?
 		)`
 		searchPattern := "%" + search + "%"
 		args = append(args, searchPattern, searchPattern, searchPattern, searchPattern)
 	}
 
+	if subjectID > 0 {
+		query += " AND d.subject_id = ?"
+		args = append(args, subjectID)
+	}
+
+	if resourceTypeID > 0 {
+		query += " AND d.resource_type_id = ?"
+		args = append(args, resourceTypeID)
+	}
+
 	query += " ORDER BY d.title"
 
 	rows, err := db.Query(query, args...)
@@ -1050,18 +1100,28 @@ func GetDisks(search string) ([]models.Disk, error) {
 	var disks []models.Disk
 	for rows.Next() {
 		var disk models.Disk
-		var publisherName sql.NullString
+		var shortTitle, barcode, comments, publisherName sql.NullString
+		var createdBy sql.NullInt64
+		var coverETag string
 
 		err := rows.Scan(
-			&disk.ID, &disk.Code, &disk.Title, &disk.ShortTitle,
-			&disk.PublisherID, &disk.Subject, &disk.ResourceType,
-			&disk.Barcode, &disk.CreatedAt, &disk.CreatedBy,
-			&disk.Comments, &publisherName, &disk.IsAvailable,
+			&disk.ID, &disk.Code, &disk.Title, &shortTitle, &disk.PublisherID,
+			&disk.SubjectID, &disk.Subject,
+			&disk.ResourceTypeID, &disk.ResourceType,
+			&barcode, &disk.CreatedAt, &createdBy,
+			&comments, &publisherName, &coverETag, &disk.IsAvailable,
 		)
 		if err != nil {
 			continue
 		}
 
+		disk.CoverURL = media.CoverURL(media.EntityDisk, disk.ID, coverETag)
+
+		disk.ShortTitle = shortTitle.String
+		disk.Barcode = barcode.String
+		disk.Comments = comments.String
+		disk.CreatedBy = int(createdBy.Int64)
+
 		if publisherName.Valid && disk.PublisherID != nil {
 			disk.Publisher = &models.Publisher{
 				ID:   *disk.PublisherID,
@@ -1080,14 +1140,25 @@ func CreateDisk(disk *models.Disk) (int, error) {
 	query := `
 		INSERT INTO disks (
 			code, title, short_title, publisher_id,
-			subject, resource_type, barcode, created_by, comments
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			subject, subject_id, resource_type, resource_type_id,
+			barcode, created_by, comments
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	result, err := db.Exec(query,
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := assignCode(tx, "disk", &disk.Code); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(query,
 		disk.Code, disk.Title, disk.ShortTitle, disk.PublisherID,
-		disk.Subject, disk.ResourceType, disk.Barcode,
-		disk.CreatedBy, disk.Comments,
+		disk.Subject, disk.SubjectID, disk.ResourceType, disk.ResourceTypeID,
+		disk.Barcode, disk.CreatedBy, disk.Comments,
 	)
 
 	if err != nil {
@@ -1099,7 +1170,7 @@ func CreateDisk(disk *models.Disk) (int, error) {
 		return 0, err
 	}
 
-	return int(id), nil
+	return int(id), tx.Commit()
 }
 
 // UpdateDisk обновляет диск
@@ -1107,14 +1178,15 @@ func UpdateDisk(disk *models.Disk) error {
 	query := `
 		UPDATE disks SET
 			title = ?, short_title = ?, publisher_id = ?,
-			subject = ?, resource_type = ?, barcode = ?, comments = ?
+			subject = ?, subject_id = ?, resource_type = ?, resource_type_id = ?,
+			barcode = ?, comments = ?
 		WHERE id = ?
 	`
 
 	_, err := db.Exec(query,
 		disk.Title, disk.ShortTitle, disk.PublisherID,
-		disk.Subject, disk.ResourceType, disk.Barcode,
-		disk.Comments, disk.ID,
+		disk.Subject, disk.SubjectID, disk.ResourceType, disk.ResourceTypeID,
+		disk.Barcode, disk.Comments, disk.ID,
 	)
 
 	return err
@@ -1137,16 +1209,17 @@ func IsDiskLoaned(diskID int) (bool, error) {
 	return count > 0, err
 }
 
-// GenerateDiskCode генерирует код для диска
-func GenerateDiskCode() string {
-	var maxCode int
-	db.QueryRow("SELECT COALESCE(MAX(CAST(code AS INTEGER)), 0) FROM disks WHERE code GLOB '[0-9]*'").Scan(&maxCode)
-	return fmt.Sprintf("%05d", maxCode+1)
+// DiskExists проверяет, существует ли диск
+func DiskExists(diskID int) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM disks WHERE id = ?", diskID).Scan(&count)
+	return count > 0, err
 }
 
 // GetSettings возвращает настройки системы
 func GetSettings() (*models.Settings, error) {
-	query := `SELECT id, organization_name, organization_short_name, director_name, updated_at FROM settings LIMIT 1`
+	query := `SELECT id, COALESCE(organization_name, ''), COALESCE(organization_short_name, ''),
+		COALESCE(director_name, ''), updated_at FROM settings LIMIT 1`
 
 	var settings models.Settings
 	err := db.QueryRow(query).Scan(
@@ -1192,9 +1265,9 @@ func UpdateSettings(settings *models.Settings) error {
 	return err
 }
 
-// GetClasses возвращает список классов
+// GetClasses возвращает список классов с числом учеников
 func GetClasses() ([]models.Class, error) {
-	query := `SELECT id, grade, letter, teacher_name FROM classes ORDER BY grade, letter`
+	query := "SELECT " + classColumns + " FROM classes c" + classJoins + " ORDER BY c.grade, c.letter"
 
 	rows, err := db.Query(query)
 	if err != nil {
@@ -1204,13 +1277,11 @@ func GetClasses() ([]models.Class, error) {
 
 	var classes []models.Class
 	for rows.Next() {
-		var class models.Class
-		err := rows.Scan(&class.ID, &class.Grade, &class.Letter, &class.TeacherName)
+		class, err := scanClass(rows)
 		if err != nil {
 			continue
 		}
-		class.DisplayName = fmt.Sprintf("%d \"%s\"", class.Grade, class.Letter)
-		classes = append(classes, class)
+		classes = append(classes, *class)
 	}
 
 	// Возвращаем пустой массив, если классов нет
@@ -1221,9 +1292,27 @@ func GetClasses() ([]models.Class, error) {
 	return classes, nil
 }
 
+// CreateClass создает класс
+func CreateClass(class *models.Class) (int, error) {
+	result, err := db.Exec(
+		"INSERT INTO classes (grade, letter, teacher_name, teacher_id) VALUES (?, ?, ?, ?)",
+		class.Grade, class.Letter, class.TeacherName, class.TeacherID,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
 // GetLibraryUsers возвращает список пользователей библиотеки
 func GetLibraryUsers() ([]models.User, error) {
-	query := `SELECT id, username, full_name, role FROM users ORDER BY full_name`
+	query := `SELECT ` + userColumns + ` ORDER BY full_name`
 
 	rows, err := db.Query(query)
 	if err != nil {
@@ -1233,12 +1322,11 @@ func GetLibraryUsers() ([]models.User, error) {
 
 	var users []models.User
 	for rows.Next() {
-		var user models.User
-		err := rows.Scan(&user.ID, &user.Username, &user.FullName, &user.Role)
+		user, err := scanUser(rows)
 		if err != nil {
 			continue
 		}
-		users = append(users, user)
+		users = append(users, *user)
 	}
 
 	// Возвращаем пустой массив, если пользователей нет
@@ -1249,10 +1337,68 @@ func GetLibraryUsers() ([]models.User, error) {
 	return users, nil
 }
 
-// GetBookAvailabilityReport генерирует отчет о наличии книг
-func GetBookAvailabilityReport(classFilter string) (interface{}, error) {
-	// TODO: Implement book availability report
-	return nil, nil
+// GetBookAvailabilityReport генерирует отчет о наличии книг, сгруппированный по разделам классификации.
+// Если указан locationID, учитываются только книги этого места хранения и вложенных мест
+func GetBookAvailabilityReport(classFilter, scheme string, locationID, level int) (*models.BookAvailabilityReport, error) {
+	table, ok := classification.Get(scheme)
+	if !ok {
+		return nil, fmt.Errorf("unknown classification scheme: %s", scheme)
+	}
+
+	counts, err := GetBookClassificationCounts(scheme, classFilter, locationID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Относим каждый индекс к разделу заданного уровня
+	sections := map[*classification.Node]classification.Count{}
+	var unclassified classification.Count
+	for index, count := range counts {
+		node := table.Resolve(index)
+		if node == nil {
+			unclassified.Total += count.Total
+			unclassified.Available += count.Available
+			continue
+		}
+		section := node.Ancestor(level)
+		sum := sections[section]
+		sum.Total += count.Total
+		sum.Available += count.Available
+		sections[section] = sum
+	}
+
+	report := &models.BookAvailabilityReport{
+		GroupBy: "classification",
+		Scheme:  scheme,
+		Level:   level,
+		Rows:    []models.BookAvailabilityRow{},
+	}
+
+	addRow := func(index, title string, count classification.Count) {
+		row := models.BookAvailabilityRow{
+			SectionIndex: index,
+			SectionTitle: title,
+			Total:        count.Total,
+			Available:    count.Available,
+			Loaned:       count.Total - count.Available,
+		}
+		report.Rows = append(report.Rows, row)
+		report.Totals.Total += row.Total
+		report.Totals.Available += row.Available
+		report.Totals.Loaned += row.Loaned
+	}
+
+	for _, node := range table.Nodes() {
+		if count, ok := sections[node]; ok {
+			addRow(node.Index, node.Title, count)
+		}
+	}
+	if unclassified.Total > 0 {
+		addRow("", "Без классификации", unclassified)
+	}
+
+	report.Totals.SectionTitle = "Итого"
+	return report, nil
 }
 
 // GetLoanHistoryReport генерирует отчет истории выдач
@@ -1276,6 +1422,9 @@ CREATE TABLE IF NOT EXISTS users (
     password_hash TEXT NOT NULL,
     full_name TEXT NOT NULL,
     role TEXT DEFAULT 'librarian',
+    email TEXT,
+    is_active INTEGER NOT NULL DEFAULT 1,
+    last_login DATETIME,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -1293,6 +1442,9 @@ CREATE TABLE IF NOT EXISTS classes (
     grade INTEGER NOT NULL,
     letter TEXT NOT NULL,
     teacher_name TEXT,
+    teacher_id INTEGER,
+    is_active INTEGER NOT NULL DEFAULT 1,
+    updated_at DATETIME,
     UNIQUE(grade, letter)
 );
 
@@ -1309,9 +1461,233 @@ CREATE TABLE IF NOT EXISTS books (
     bbk TEXT,
     udk TEXT,
     class_range TEXT,
+    min_grade INTEGER,
+    max_grade INTEGER,
     location TEXT,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-    created_by INTEGER
+    created_by INTEGER,
+    series_id INTEGER,
+    volume_number INTEGER,
+    part_title TEXT,
+    work_id INTEGER,
+    location_id INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS locations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    parent_id INTEGER,
+    kind TEXT NOT NULL,
+    name TEXT NOT NULL,
+    barcode TEXT UNIQUE,
+    comments TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS barcode_ranges (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    entity_type TEXT NOT NULL,
+    prefix TEXT NOT NULL DEFAULT '',
+    start_number INTEGER NOT NULL,
+    end_number INTEGER NOT NULL,
+    digits INTEGER NOT NULL,
+    check_digit TEXT NOT NULL DEFAULT '',
+    next_number INTEGER NOT NULL,
+    comments TEXT,
+    created_by INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS barcode_batches (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    range_id INTEGER NOT NULL,
+    created_by INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS barcode_allocations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    range_id INTEGER NOT NULL,
+    batch_id INTEGER,
+    barcode TEXT NOT NULL UNIQUE,
+    entity_type TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'reserved',
+    entity_id INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    bound_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS class_rollovers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    academic_year TEXT NOT NULL,
+    max_grade INTEGER NOT NULL,
+    summary TEXT,
+    status TEXT NOT NULL DEFAULT 'applied',
+    created_by INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    undone_by INTEGER,
+    undone_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS class_rollover_changes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    rollover_id INTEGER NOT NULL,
+    entity_type TEXT NOT NULL,
+    entity_id INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    previous TEXT
+);
+
+CREATE TABLE IF NOT EXISTS import_templates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    entity_type TEXT NOT NULL,
+    name TEXT NOT NULL,
+    mapping TEXT NOT NULL,
+    created_by INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(entity_type, name)
+);
+
+CREATE TABLE IF NOT EXISTS sequences (
+    entity_type TEXT PRIMARY KEY,
+    prefix TEXT NOT NULL DEFAULT '',
+    padding INTEGER NOT NULL DEFAULT 6,
+    yearly_reset INTEGER NOT NULL DEFAULT 0,
+    separator TEXT NOT NULL DEFAULT '-',
+    last_number INTEGER NOT NULL DEFAULT 0,
+    last_year INTEGER NOT NULL DEFAULT 0,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS book_moves (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    book_id INTEGER NOT NULL,
+    from_location_id INTEGER,
+    to_location_id INTEGER,
+    from_location TEXT,
+    to_location TEXT,
+    moved_by INTEGER,
+    moved_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS series (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    title TEXT NOT NULL,
+    kind TEXT DEFAULT 'series',
+    comments TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS works (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    title TEXT NOT NULL,
+    author_id INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS covers (
+    entity_type TEXT NOT NULL,
+    entity_id INTEGER NOT NULL,
+    etag TEXT NOT NULL,
+    content_type TEXT,
+    width INTEGER,
+    height INTEGER,
+    file_size INTEGER,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (entity_type, entity_id)
+);
+
+CREATE TABLE IF NOT EXISTS media_blobs (
+    key TEXT PRIMARY KEY,
+    content_type TEXT,
+    data BLOB,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS reader_barcode_aliases (
+    barcode TEXT PRIMARY KEY,
+    reader_id INTEGER NOT NULL,
+    merged_reader_id INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS reader_credentials (
+    reader_id INTEGER PRIMARY KEY,
+    pin_hash TEXT NOT NULL,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    locked_until DATETIME,
+    last_login_at DATETIME,
+    updated_by INTEGER,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS reader_login_codes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reader_id INTEGER NOT NULL,
+    code_hash TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS holds (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reader_id INTEGER NOT NULL,
+    book_id INTEGER NOT NULL,
+    status TEXT NOT NULL DEFAULT 'active',
+    source TEXT NOT NULL DEFAULT 'portal',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    closed_at DATETIME,
+    closed_by INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS clearances (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    code TEXT UNIQUE NOT NULL,
+    reader_id INTEGER NOT NULL,
+    reader_name TEXT NOT NULL,
+    reader_info TEXT,
+    reason TEXT,
+    verification_code TEXT NOT NULL,
+    file_hash TEXT NOT NULL,
+    pdf BLOB,
+    issued_by INTEGER,
+    issued_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    refresh_hash TEXT NOT NULL UNIQUE,
+    previous_hash TEXT,
+    user_agent TEXT,
+    ip TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_used_at DATETIME,
+    expires_at DATETIME NOT NULL,
+    revoked_at DATETIME,
+    revoked_by INTEGER,
+    revoke_reason TEXT
+);
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL,
+    value TEXT NOT NULL,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failed_at DATETIME,
+    locked_until DATETIME,
+    UNIQUE (kind, value)
+);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    entity_type TEXT NOT NULL,
+    entity_id INTEGER,
+    action TEXT NOT NULL,
+    details TEXT,
+    user_id INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 
 CREATE TABLE IF NOT EXISTS authors (
@@ -1348,7 +1724,6 @@ CREATE TABLE IF NOT EXISTS readers (
     document_number TEXT,
     phone TEXT,
     email TEXT,
-    photo BLOB,
     parent_mother_name TEXT,
     parent_mother_phone TEXT,
     parent_father_name TEXT,
@@ -1357,7 +1732,14 @@ CREATE TABLE IF NOT EXISTS readers (
     guardian_phone TEXT,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     created_by INTEGER,
-    comments TEXT
+    comments TEXT,
+    phone_hash TEXT,
+    email_hash TEXT,
+    status TEXT NOT NULL DEFAULT 'active',
+    archived_at DATETIME,
+    archive_reason TEXT,
+    portal_token_version INTEGER NOT NULL DEFAULT 0,
+    updated_at DATETIME
 );
 
 CREATE TABLE IF NOT EXISTS loans (
@@ -1382,7 +1764,9 @@ CREATE TABLE IF NOT EXISTS disks (
     barcode TEXT UNIQUE,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     created_by INTEGER,
-    comments TEXT
+    comments TEXT,
+    subject_id INTEGER,
+    resource_type_id INTEGER
 );
 
 CREATE TABLE IF NOT EXISTS disk_loans (
@@ -1396,6 +1780,17 @@ CREATE TABLE IF NOT EXISTS disk_loans (
     status TEXT DEFAULT 'active'
 );
 
+CREATE TABLE IF NOT EXISTS subjects (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    short_name TEXT
+);
+
+CREATE TABLE IF NOT EXISTS resource_types (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL
+);
+
 -- Создаем пользователя по умолчанию (пароль: admin)
 INSERT OR IGNORE INTO users (username, password_hash, full_name, role) 
 VALUES ('admin', '$2a$12$8y8HG8bKxOqGj50zi/LdeempqTKXnVi0Xfcz/vMzFexKEXXIDnVN2', 'Администратор', 'admin');
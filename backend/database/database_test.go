@@ -0,0 +1,15 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// openTestDB открывает пустую базу во временном каталоге и закрывает ее после теста
+func openTestDB(t *testing.T) {
+	t.Helper()
+	if err := Init(filepath.Join(t.TempDir(), "library.db")); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	t.Cleanup(func() { Close() })
+}
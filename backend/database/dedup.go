@@ -0,0 +1,540 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"library-management/backend/models"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// publisherNoiseWords — организационно-правовые формы и служебные слова,
+// не различающие издательства: "АО Издательство «Просвещение»" = "Просвещение"
+var publisherNoiseWords = map[string]bool{
+	"ао": true, "оао": true, "зао": true, "пао": true, "ооо": true,
+	"гуп": true, "фгуп": true, "муп": true, "нко": true, "ано": true,
+	"издательство": true, "изд": true, "издво": true, "издательский": true,
+	"дом": true, "центр": true, "группа": true, "компания": true, "ид": true,
+}
+
+// normalizeWord приводит слово к нижнему регистру, заменяет ё на е и убирает знаки препинания
+func normalizeWord(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r == 'ё':
+			b.WriteRune('е')
+		case unicode.IsLetter(r), unicode.IsDigit(r):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// initial возвращает первую букву имени в нижнем регистре
+func initial(name string) string {
+	name = normalizeWord(name)
+	if name == "" {
+		return ""
+	}
+	return string([]rune(name)[0])
+}
+
+// authorInitials возвращает инициалы автора. Если имя и отчество не заполнены,
+// инициалы берутся из краткого имени ("Толстой Л.Н.")
+func authorInitials(author *models.Author) (string, string) {
+	first, middle := initial(author.FirstName), initial(author.MiddleName)
+	if first != "" {
+		return first, middle
+	}
+
+	rest := strings.TrimSpace(strings.TrimPrefix(author.ShortName, author.LastName))
+	parts := strings.FieldsFunc(rest, func(r rune) bool {
+		return r == '.' || unicode.IsSpace(r)
+	})
+	if len(parts) > 0 {
+		first = initial(parts[0])
+	}
+	if len(parts) > 1 {
+		middle = initial(parts[1])
+	}
+	return first, middle
+}
+
+// AuthorKey возвращает ключ сравнения автора: нормализованная фамилия и инициал имени
+func AuthorKey(author *models.Author) string {
+	first, _ := authorInitials(author)
+	return normalizeWord(author.LastName) + " " + first
+}
+
+// PublisherKey возвращает ключ сравнения издательства без кавычек, регистра и правовой формы
+func PublisherKey(name string) string {
+	var words []string
+	for _, word := range strings.Fields(strings.NewReplacer("-", "", ".", " ", ",", " ").Replace(name)) {
+		word = normalizeWord(word)
+		if word != "" && !publisherNoiseWords[word] {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		// Название целиком состоит из служебных слов — сравниваем как есть
+		return normalizeWord(name)
+	}
+	return strings.Join(words, " ")
+}
+
+// authorsCompatible проверяет, могут ли два автора с одинаковым ключом быть одним лицом:
+// отчества (инициалы) должны совпадать, если указаны у обоих
+func authorsCompatible(a, b *models.Author) bool {
+	_, middleA := authorInitials(a)
+	_, middleB := authorInitials(b)
+	return middleA == "" || middleB == "" || middleA == middleB
+}
+
+// FindAuthorDuplicates возвращает группы авторов с одинаковой фамилией и инициалами
+func FindAuthorDuplicates() ([]models.AuthorDuplicateGroup, error) {
+	authors, err := GetAuthors()
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := map[string][]models.Author{}
+	for _, author := range authors {
+		key := AuthorKey(&author)
+		byKey[key] = append(byKey[key], author)
+	}
+
+	groups := []models.AuthorDuplicateGroup{}
+	for key, candidates := range byKey {
+		// Внутри ключа разделяем авторов с разными отчествами
+		var clusters [][]models.Author
+		for _, author := range candidates {
+			placed := false
+			for i, cluster := range clusters {
+				compatible := true
+				for _, other := range cluster {
+					if !authorsCompatible(&author, &other) {
+						compatible = false
+						break
+					}
+				}
+				if compatible {
+					clusters[i] = append(clusters[i], author)
+					placed = true
+					break
+				}
+			}
+			if !placed {
+				clusters = append(clusters, []models.Author{author})
+			}
+		}
+
+		for _, cluster := range clusters {
+			if len(cluster) > 1 {
+				groups = append(groups, models.AuthorDuplicateGroup{Key: key, Authors: cluster})
+			}
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Key < groups[j].Key })
+	return groups, nil
+}
+
+// FindAuthorMatches возвращает существующих авторов, совпадающих с данным по фамилии и инициалам
+func FindAuthorMatches(author *models.Author) ([]models.Author, error) {
+	authors, err := GetAuthors()
+	if err != nil {
+		return nil, err
+	}
+
+	return MatchAuthors(author, authors), nil
+}
+
+// MatchAuthors выбирает из списка авторов с той же фамилией и инициалами, что и у author
+func MatchAuthors(author *models.Author, authors []models.Author) []models.Author {
+	key := AuthorKey(author)
+	matches := []models.Author{}
+	for _, existing := range authors {
+		if existing.ID != author.ID && AuthorKey(&existing) == key && authorsCompatible(author, &existing) {
+			matches = append(matches, existing)
+		}
+	}
+	return matches
+}
+
+// FindPublisherDuplicates возвращает группы издательств с одинаковым нормализованным названием
+func FindPublisherDuplicates() ([]models.PublisherDuplicateGroup, error) {
+	publishers, err := GetPublishers()
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := map[string][]models.Publisher{}
+	for _, publisher := range publishers {
+		key := PublisherKey(publisher.Name)
+		byKey[key] = append(byKey[key], publisher)
+	}
+
+	groups := []models.PublisherDuplicateGroup{}
+	for key, candidates := range byKey {
+		if len(candidates) > 1 {
+			groups = append(groups, models.PublisherDuplicateGroup{Key: key, Publishers: candidates})
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Key < groups[j].Key })
+	return groups, nil
+}
+
+// FindPublisherMatches возвращает существующие издательства с тем же нормализованным названием
+func FindPublisherMatches(publisher *models.Publisher) ([]models.Publisher, error) {
+	publishers, err := GetPublishers()
+	if err != nil {
+		return nil, err
+	}
+
+	key := PublisherKey(publisher.Name)
+	matches := []models.Publisher{}
+	for _, existing := range publishers {
+		if existing.ID != publisher.ID && PublisherKey(existing.Name) == key {
+			matches = append(matches, existing)
+		}
+	}
+
+	return matches, nil
+}
+
+// MergeAuthors переносит книги и произведения объединяемых авторов на сохраняемого автора,
+// удаляет дубликаты и записывает объединение в журнал
+func MergeAuthors(targetID int, sourceIDs []int, userID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRow("SELECT id FROM authors WHERE id = ?", targetID).Scan(&targetID); err != nil {
+		return err
+	}
+
+	for _, sourceID := range sourceIDs {
+		var source models.Author
+		var firstName, middleName sql.NullString
+		err := tx.QueryRow(
+			"SELECT id, code, last_name, first_name, middle_name, short_name FROM authors WHERE id = ?",
+			sourceID,
+		).Scan(&source.ID, &source.Code, &source.LastName, &firstName, &middleName, &source.ShortName)
+		if err != nil {
+			return fmt.Errorf("author %d: %w", sourceID, err)
+		}
+		source.FirstName = firstName.String
+		source.MiddleName = middleName.String
+
+		result, err := tx.Exec("UPDATE books SET author_id = ? WHERE author_id = ?", targetID, sourceID)
+		if err != nil {
+			return err
+		}
+		movedBooks, _ := result.RowsAffected()
+
+		if _, err := tx.Exec("UPDATE works SET author_id = ? WHERE author_id = ?", targetID, sourceID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM authors WHERE id = ?", sourceID); err != nil {
+			return err
+		}
+
+		details := map[string]interface{}{
+			"merged":      source,
+			"moved_books": movedBooks,
+		}
+		if err := WriteAudit(tx, "author", targetID, "merge", details, userID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// MergePublishers переносит книги и диски объединяемых издательств на сохраняемое,
+// удаляет дубликаты и записывает объединение в журнал
+func MergePublishers(targetID int, sourceIDs []int, userID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRow("SELECT id FROM publishers WHERE id = ?", targetID).Scan(&targetID); err != nil {
+		return err
+	}
+
+	for _, sourceID := range sourceIDs {
+		var source models.Publisher
+		err := tx.QueryRow(
+			"SELECT id, code, name FROM publishers WHERE id = ?",
+			sourceID,
+		).Scan(&source.ID, &source.Code, &source.Name)
+		if err != nil {
+			return fmt.Errorf("publisher %d: %w", sourceID, err)
+		}
+
+		result, err := tx.Exec("UPDATE books SET publisher_id = ? WHERE publisher_id = ?", targetID, sourceID)
+		if err != nil {
+			return err
+		}
+		movedBooks, _ := result.RowsAffected()
+
+		result, err = tx.Exec("UPDATE disks SET publisher_id = ? WHERE publisher_id = ?", targetID, sourceID)
+		if err != nil {
+			return err
+		}
+		movedDisks, _ := result.RowsAffected()
+
+		if _, err := tx.Exec("DELETE FROM publishers WHERE id = ?", sourceID); err != nil {
+			return err
+		}
+
+		details := map[string]interface{}{
+			"merged":      source,
+			"moved_books": movedBooks,
+			"moved_disks": movedDisks,
+		}
+		if err := WriteAudit(tx, "publisher", targetID, "merge", details, userID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ReaderKey возвращает ключ сравнения читателя: нормализованные фамилия и имя
+func ReaderKey(reader *models.Reader) string {
+	return normalizeWord(reader.LastName) + " " + normalizeWord(reader.FirstName)
+}
+
+// readersMatch сообщает, чем совпадают два читателя с одинаковым ключом: датой рождения
+// или классом. Разные отчества или даты рождения означают разных людей
+func readersMatch(a, b *models.Reader) string {
+	middleA, middleB := normalizeWord(a.MiddleName), normalizeWord(b.MiddleName)
+	if middleA != "" && middleB != "" && middleA != middleB {
+		return ""
+	}
+
+	if a.BirthDate != nil && b.BirthDate != nil {
+		if a.BirthDate.Format("2006-01-02") == b.BirthDate.Format("2006-01-02") {
+			return "birth_date"
+		}
+		return ""
+	}
+	if a.ClassID != nil && b.ClassID != nil && *a.ClassID == *b.ClassID {
+		return "class"
+	}
+	return ""
+}
+
+// FindReaderDuplicates возвращает группы читателей с одинаковым ФИО и датой рождения или классом.
+// Сравнение идет в приложении: дата рождения в базе может быть зашифрована
+func FindReaderDuplicates() ([]models.ReaderDuplicateGroup, error) {
+	rows, err := db.Query("SELECT " + readerColumns + " FROM readers r ORDER BY r.id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byKey := map[string][]models.Reader{}
+	for rows.Next() {
+		reader, err := scanReader(rows)
+		if err != nil {
+			return nil, err
+		}
+		key := ReaderKey(reader)
+		byKey[key] = append(byKey[key], *reader)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	groups := []models.ReaderDuplicateGroup{}
+	for key, candidates := range byKey {
+		if len(candidates) < 2 {
+			continue
+		}
+
+		// Внутри ключа в группу попадают читатели, совпадающие со всеми ее участниками
+		var clusters []models.ReaderDuplicateGroup
+		for _, reader := range candidates {
+			placed := false
+			for i := range clusters {
+				match := clusters[i].Match
+				for _, other := range clusters[i].Readers {
+					m := readersMatch(&reader, &other)
+					if m == "" {
+						match = ""
+						break
+					}
+					if m == "class" {
+						match = m
+					}
+				}
+				if match != "" {
+					clusters[i].Match = match
+					clusters[i].Readers = append(clusters[i].Readers, reader)
+					placed = true
+					break
+				}
+			}
+			if !placed {
+				clusters = append(clusters, models.ReaderDuplicateGroup{
+					Key: key, Match: "birth_date", Readers: []models.Reader{reader},
+				})
+			}
+		}
+
+		for _, cluster := range clusters {
+			if len(cluster.Readers) > 1 {
+				groups = append(groups, cluster)
+			}
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Key < groups[j].Key })
+	return groups, nil
+}
+
+// MergeReaders переносит выдачи книг и дисков, брони и комментарии объединяемых читателей
+// на сохраняемого, оставляет их штрих-коды как дополнительные, удаляет дубликаты
+// и записывает объединение в журнал. Повторные брони на одну книгу снимаются.
+// Невозвращенные книги нельзя перенести на архивного читателя
+func MergeReaders(targetID int, sourceIDs []int, userID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status, comments string
+	err = tx.QueryRow("SELECT status, COALESCE(comments, '') FROM readers WHERE id = ?", targetID).Scan(&status, &comments)
+	if err != nil {
+		return err
+	}
+
+	for _, sourceID := range sourceIDs {
+		var source struct {
+			ID         int    `json:"id"`
+			Code       string `json:"code"`
+			Barcode    string `json:"barcode"`
+			LastName   string `json:"last_name"`
+			FirstName  string `json:"first_name"`
+			MiddleName string `json:"middle_name"`
+			Comments   string `json:"comments,omitempty"`
+		}
+		err := tx.QueryRow(`
+			SELECT id, COALESCE(code, ''), COALESCE(barcode, ''), last_name, first_name,
+				COALESCE(middle_name, ''), COALESCE(comments, '')
+			FROM readers WHERE id = ?
+		`, sourceID).Scan(
+			&source.ID, &source.Code, &source.Barcode, &source.LastName, &source.FirstName,
+			&source.MiddleName, &source.Comments,
+		)
+		if err != nil {
+			return fmt.Errorf("reader %d: %w", sourceID, err)
+		}
+
+		if status == "archived" {
+			var debts int
+			err := tx.QueryRow(`
+				SELECT (SELECT COUNT(*) FROM loans WHERE reader_id = ? AND status = 'active')
+				     + (SELECT COUNT(*) FROM disk_loans WHERE reader_id = ? AND status = 'active')
+			`, sourceID, sourceID).Scan(&debts)
+			if err != nil {
+				return err
+			}
+			if debts > 0 {
+				return ErrReaderArchived
+			}
+		}
+
+		result, err := tx.Exec("UPDATE loans SET reader_id = ? WHERE reader_id = ?", targetID, sourceID)
+		if err != nil {
+			return err
+		}
+		movedLoans, _ := result.RowsAffected()
+
+		result, err = tx.Exec("UPDATE disk_loans SET reader_id = ? WHERE reader_id = ?", targetID, sourceID)
+		if err != nil {
+			return err
+		}
+		movedDiskLoans, _ := result.RowsAffected()
+
+		if _, err := tx.Exec("UPDATE classes SET teacher_id = ?, updated_at = CURRENT_TIMESTAMP WHERE teacher_id = ?", targetID, sourceID); err != nil {
+			return err
+		}
+		// Брони на книги, которые сохраняемый читатель уже забронировал или получил, снимаются:
+		// иначе у него окажутся две брони на одну книгу
+		cancelledHolds, err := cancelDuplicateHolds(tx, sourceID, targetID, userID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE holds SET reader_id = ? WHERE reader_id = ?", targetID, sourceID); err != nil {
+			return err
+		}
+		// Выданные обходные листы остаются в истории сохраняемого читателя; ФИО в листе — как при выдаче
+		result, err = tx.Exec("UPDATE clearances SET reader_id = ? WHERE reader_id = ?", targetID, sourceID)
+		if err != nil {
+			return err
+		}
+		movedClearances, _ := result.RowsAffected()
+
+		// PIN личного кабинета переходит к сохраняемому читателю, если у него своего нет
+		if _, err := tx.Exec("UPDATE OR IGNORE reader_credentials SET reader_id = ? WHERE reader_id = ?", targetID, sourceID); err != nil {
+			return err
+		}
+		for _, table := range []string{"reader_credentials", "reader_login_codes"} {
+			if _, err := tx.Exec("DELETE FROM "+table+" WHERE reader_id = ?", sourceID); err != nil {
+				return err
+			}
+		}
+
+		// Штрих-код дубликата и его прежние штрих-коды продолжают находить читателя
+		if _, err := tx.Exec("UPDATE reader_barcode_aliases SET reader_id = ? WHERE reader_id = ?", targetID, sourceID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM readers WHERE id = ?", sourceID); err != nil {
+			return err
+		}
+		if source.Barcode != "" {
+			_, err := tx.Exec(
+				"INSERT INTO reader_barcode_aliases (barcode, reader_id, merged_reader_id) VALUES (?, ?, ?)",
+				source.Barcode, targetID, sourceID,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		if source.Comments != "" && !strings.Contains(comments, source.Comments) {
+			if comments != "" {
+				comments += "\n"
+			}
+			comments += source.Comments
+		}
+
+		details := map[string]interface{}{
+			"merged":           source,
+			"moved_loans":      movedLoans,
+			"moved_disk_loans": movedDiskLoans,
+			"moved_clearances": movedClearances,
+			"cancelled_holds":  cancelledHolds,
+		}
+		if err := WriteAudit(tx, "reader", targetID, "merge", details, userID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE readers SET comments = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", comments, targetID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
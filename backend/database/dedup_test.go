@@ -0,0 +1,67 @@
+package database
+
+import (
+	"fmt"
+	"library-management/backend/models"
+	"testing"
+	"time"
+)
+
+func TestMergeReadersDuplicateHolds(t *testing.T) {
+	openTestDB(t)
+
+	var books []int
+	for i := 0; i < 3; i++ {
+		id, err := CreateBook(&models.Book{Title: fmt.Sprintf("Книга %d", i), Barcode: fmt.Sprintf("B%03d", i)})
+		if err != nil {
+			t.Fatalf("CreateBook: %v", err)
+		}
+		books = append(books, id)
+	}
+	var readers []int
+	for _, barcode := range []string{"R001", "R002"} {
+		id, err := CreateReader(&models.Reader{Barcode: barcode, LastName: "Иванов", FirstName: "Петр"})
+		if err != nil {
+			t.Fatalf("CreateReader: %v", err)
+		}
+		readers = append(readers, id)
+	}
+	target, source := readers[0], readers[1]
+
+	// Сохраняемый читатель бронирует первую книгу и держит на руках третью; дубликат бронирует все три
+	if _, err := CreateHold(target, books[0], "portal", 0); err != nil {
+		t.Fatalf("CreateHold: %v", err)
+	}
+	for _, book := range books {
+		if _, err := CreateHold(source, book, "portal", 0); err != nil {
+			t.Fatalf("CreateHold: %v", err)
+		}
+	}
+	if _, err := CreateLoan(&models.Loan{BookID: books[2], ReaderID: target, IssueDate: time.Now(), IssuedBy: 1, Status: "active"}, nil); err != nil {
+		t.Fatalf("CreateLoan: %v", err)
+	}
+
+	if err := MergeReaders(target, []int{source}, 1); err != nil {
+		t.Fatalf("MergeReaders: %v", err)
+	}
+
+	holds, err := GetReaderHolds(target, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	held := map[int]int{}
+	for _, hold := range holds {
+		held[hold.BookID]++
+	}
+	if len(holds) != 2 || held[books[0]] != 1 || held[books[1]] != 1 {
+		t.Errorf("active holds after merge = %+v, want one hold on each of the first two books", holds)
+	}
+
+	var cancelled int
+	if err := db.QueryRow("SELECT COUNT(*) FROM holds WHERE status = 'cancelled' AND closed_by = 1").Scan(&cancelled); err != nil {
+		t.Fatal(err)
+	}
+	if cancelled != 2 {
+		t.Errorf("cancelled holds = %d, want 2", cancelled)
+	}
+}
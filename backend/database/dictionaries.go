@@ -0,0 +1,305 @@
+package database
+
+import (
+	"database/sql"
+	"library-management/backend/models"
+	"strings"
+)
+
+// GetSubjects возвращает список предметов с количеством дисков
+func GetSubjects() ([]models.Subject, error) {
+	query := `
+		SELECT s.id, s.name, COALESCE(s.short_name, ''), COUNT(d.id) as disk_count
+		FROM subjects s
+		LEFT JOIN disks d ON s.id = d.subject_id
+		GROUP BY s.id
+		ORDER BY s.name
+	`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subjects := []models.Subject{}
+	for rows.Next() {
+		var subject models.Subject
+		err := rows.Scan(&subject.ID, &subject.Name, &subject.ShortName, &subject.DiskCount)
+		if err != nil {
+			continue
+		}
+		subjects = append(subjects, subject)
+	}
+
+	return subjects, nil
+}
+
+// GetSubjectByID возвращает предмет по ID
+func GetSubjectByID(id int) (*models.Subject, error) {
+	var subject models.Subject
+	err := db.QueryRow(
+		"SELECT id, name, COALESCE(short_name, '') FROM subjects WHERE id = ?",
+		id,
+	).Scan(&subject.ID, &subject.Name, &subject.ShortName)
+	if err != nil {
+		return nil, err
+	}
+	return &subject, nil
+}
+
+// CreateSubject создает новый предмет
+func CreateSubject(subject *models.Subject) (int, error) {
+	result, err := db.Exec(
+		"INSERT INTO subjects (name, short_name) VALUES (?, ?)",
+		subject.Name, subject.ShortName,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// UpdateSubject обновляет предмет и текстовое название предмета у связанных дисков
+func UpdateSubject(subject *models.Subject) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"UPDATE subjects SET name = ?, short_name = ? WHERE id = ?",
+		subject.Name, subject.ShortName, subject.ID,
+	); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(
+		"UPDATE disks SET subject = ? WHERE subject_id = ?",
+		subject.Name, subject.ID,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteSubject удаляет предмет
+func DeleteSubject(id int) error {
+	_, err := db.Exec("DELETE FROM subjects WHERE id = ?", id)
+	return err
+}
+
+// SubjectHasDisks проверяет, есть ли диски по предмету
+func SubjectHasDisks(subjectID int) (bool, error) {
+	var count int
+	err := db.QueryRow(
+		"SELECT COUNT(*) FROM disks WHERE subject_id = ?",
+		subjectID,
+	).Scan(&count)
+
+	return count > 0, err
+}
+
+// EnsureSubject возвращает ID предмета с указанным названием, создавая его при необходимости
+func EnsureSubject(name string) (int, error) {
+	name = strings.TrimSpace(name)
+
+	var id int
+	err := db.QueryRow("SELECT id FROM subjects WHERE name = ? ORDER BY id LIMIT 1", name).Scan(&id)
+	if err == sql.ErrNoRows {
+		return CreateSubject(&models.Subject{Name: name})
+	}
+	return id, err
+}
+
+// GetResourceTypes возвращает список типов ЭОР с количеством дисков
+func GetResourceTypes() ([]models.ResourceType, error) {
+	query := `
+		SELECT rt.id, rt.name, COUNT(d.id) as disk_count
+		FROM resource_types rt
+		LEFT JOIN disks d ON rt.id = d.resource_type_id
+		GROUP BY rt.id
+		ORDER BY rt.name
+	`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resourceTypes := []models.ResourceType{}
+	for rows.Next() {
+		var resourceType models.ResourceType
+		err := rows.Scan(&resourceType.ID, &resourceType.Name, &resourceType.DiskCount)
+		if err != nil {
+			continue
+		}
+		resourceTypes = append(resourceTypes, resourceType)
+	}
+
+	return resourceTypes, nil
+}
+
+// GetResourceTypeByID возвращает тип ЭОР по ID
+func GetResourceTypeByID(id int) (*models.ResourceType, error) {
+	var resourceType models.ResourceType
+	err := db.QueryRow(
+		"SELECT id, name FROM resource_types WHERE id = ?",
+		id,
+	).Scan(&resourceType.ID, &resourceType.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &resourceType, nil
+}
+
+// CreateResourceType создает новый тип ЭОР
+func CreateResourceType(resourceType *models.ResourceType) (int, error) {
+	result, err := db.Exec("INSERT INTO resource_types (name) VALUES (?)", resourceType.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// UpdateResourceType обновляет тип ЭОР и текстовое название типа у связанных дисков
+func UpdateResourceType(resourceType *models.ResourceType) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"UPDATE resource_types SET name = ? WHERE id = ?",
+		resourceType.Name, resourceType.ID,
+	); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(
+		"UPDATE disks SET resource_type = ? WHERE resource_type_id = ?",
+		resourceType.Name, resourceType.ID,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteResourceType удаляет тип ЭОР
+func DeleteResourceType(id int) error {
+	_, err := db.Exec("DELETE FROM resource_types WHERE id = ?", id)
+	return err
+}
+
+// ResourceTypeHasDisks проверяет, есть ли диски данного типа
+func ResourceTypeHasDisks(resourceTypeID int) (bool, error) {
+	var count int
+	err := db.QueryRow(
+		"SELECT COUNT(*) FROM disks WHERE resource_type_id = ?",
+		resourceTypeID,
+	).Scan(&count)
+
+	return count > 0, err
+}
+
+// EnsureResourceType возвращает ID типа ЭОР с указанным названием, создавая его при необходимости
+func EnsureResourceType(name string) (int, error) {
+	name = strings.TrimSpace(name)
+
+	var id int
+	err := db.QueryRow("SELECT id FROM resource_types WHERE name = ? ORDER BY id LIMIT 1", name).Scan(&id)
+	if err == sql.ErrNoRows {
+		return CreateResourceType(&models.ResourceType{Name: name})
+	}
+	return id, err
+}
+
+// GetEORBySubjectReport генерирует отчет о количестве ЭОР по предметам с разбивкой по типам
+func GetEORBySubjectReport() ([]models.EORSubjectReportRow, error) {
+	query := `
+		SELECT d.subject_id, COALESCE(s.name, ''),
+			d.resource_type_id, COALESCE(rt.name, ''),
+			COUNT(d.id),
+			SUM(CASE WHEN dl.id IS NULL THEN 1 ELSE 0 END)
+		FROM disks d
+		LEFT JOIN subjects s ON d.subject_id = s.id
+		LEFT JOIN resource_types rt ON d.resource_type_id = rt.id
+		LEFT JOIN disk_loans dl ON d.id = dl.disk_id AND dl.status = 'active'
+		GROUP BY d.subject_id, d.resource_type_id
+		ORDER BY s.name IS NULL, s.name, d.subject_id, rt.name
+	`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := []models.EORSubjectReportRow{}
+	for rows.Next() {
+		var subjectID, resourceTypeID *int
+		var subjectName, resourceTypeName string
+		var count, available int
+
+		if err := rows.Scan(
+			&subjectID, &subjectName,
+			&resourceTypeID, &resourceTypeName,
+			&count, &available,
+		); err != nil {
+			return nil, err
+		}
+
+		// Строки отсортированы по предмету, поэтому новая группа начинается при смене предмета
+		last := len(report) - 1
+		if last < 0 || !sameID(report[last].SubjectID, subjectID) {
+			if subjectName == "" {
+				subjectName = "Без предмета"
+			}
+			report = append(report, models.EORSubjectReportRow{
+				SubjectID: subjectID,
+				Subject:   subjectName,
+				ByType:    []models.ResourceTypeCount{},
+			})
+			last++
+		}
+
+		if resourceTypeName == "" {
+			resourceTypeName = "Тип не указан"
+		}
+
+		report[last].Total += count
+		report[last].Available += available
+		report[last].ByType = append(report[last].ByType, models.ResourceTypeCount{
+			ResourceTypeID: resourceTypeID,
+			ResourceType:   resourceTypeName,
+			Count:          count,
+		})
+	}
+
+	return report, rows.Err()
+}
+
+// sameID сравнивает два необязательных идентификатора
+func sameID(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,249 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"library-management/backend/models"
+)
+
+// MaxActiveHolds — сколько книг читатель может держать в брони одновременно
+const MaxActiveHolds = 5
+
+var (
+	// ErrHoldExists — читатель уже забронировал эту книгу
+	ErrHoldExists = errors.New("book is already on hold for the reader")
+	// ErrHoldLimit — у читателя слишком много активных броней
+	ErrHoldLimit = errors.New("too many active holds")
+	// ErrBookLoanedToReader — книга уже на руках у этого читателя
+	ErrBookLoanedToReader = errors.New("book is on loan to the reader")
+	// ErrHoldNotActive — бронь уже выполнена или снята
+	ErrHoldNotActive = errors.New("hold is not active")
+)
+
+// holdColumns — столбцы брони с книгой, читателем, местом в очереди и доступностью книги
+const holdColumns = `
+	h.id, h.reader_id, TRIM(r.last_name || ' ' || r.first_name), h.book_id, b.title, COALESCE(b.barcode, ''),
+	h.status, h.source, h.created_at, h.closed_at,
+	CASE WHEN h.status = 'active' THEN
+		(SELECT COUNT(*) FROM holds q WHERE q.book_id = h.book_id AND q.status = 'active' AND q.id <= h.id)
+	ELSE 0 END,
+	NOT EXISTS (SELECT 1 FROM loans l WHERE l.book_id = h.book_id AND l.status = 'active')
+	FROM holds h
+	JOIN books b ON h.book_id = b.id
+	JOIN readers r ON h.reader_id = r.id
+`
+
+// scanHold считывает бронь, выбранную через holdColumns
+func scanHold(row rowScanner) (*models.Hold, error) {
+	var h models.Hold
+	err := row.Scan(
+		&h.ID, &h.ReaderID, &h.ReaderName, &h.BookID, &h.BookTitle, &h.BookBarcode,
+		&h.Status, &h.Source, &h.CreatedAt, &h.ClosedAt, &h.Position, &h.Available,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// queryHolds выбирает брони по условию
+func queryHolds(q queryer, condition string, args ...interface{}) ([]models.Hold, error) {
+	rows, err := q.Query("SELECT "+holdColumns+" WHERE "+condition+" ORDER BY h.created_at DESC, h.id DESC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holds := []models.Hold{}
+	for rows.Next() {
+		hold, err := scanHold(rows)
+		if err != nil {
+			return nil, err
+		}
+		holds = append(holds, *hold)
+	}
+	return holds, rows.Err()
+}
+
+// GetHolds возвращает брони всех читателей: активные или, с all=true, все
+func GetHolds(all bool) ([]models.Hold, error) {
+	if all {
+		return queryHolds(db, "1=1")
+	}
+	return queryHolds(db, "h.status = 'active'")
+}
+
+// GetReaderHolds возвращает брони читателя: активные или, с all=true, вместе с закрытыми
+func GetReaderHolds(readerID int, all bool) ([]models.Hold, error) {
+	if all {
+		return queryHolds(db, "h.reader_id = ?", readerID)
+	}
+	return queryHolds(db, "h.reader_id = ? AND h.status = 'active'", readerID)
+}
+
+// GetHold возвращает бронь по ID
+func GetHold(id int) (*models.Hold, error) {
+	return scanHold(db.QueryRow("SELECT "+holdColumns+" WHERE h.id = ?", id))
+}
+
+// NextHold возвращает первую в очереди активную бронь на книгу; sql.ErrNoRows — броней нет
+func NextHold(bookID int) (*models.Hold, error) {
+	return scanHold(db.QueryRow(
+		"SELECT "+holdColumns+" WHERE h.book_id = ? AND h.status = 'active' ORDER BY h.id LIMIT 1",
+		bookID,
+	))
+}
+
+// CreateHold бронирует книгу для читателя. source — portal (сам читатель) или staff.
+// Книгу ставят в очередь, даже если она выдана другому читателю
+func CreateHold(readerID, bookID int, source string, userID int) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var status string
+	if err := tx.QueryRow("SELECT status FROM readers WHERE id = ?", readerID).Scan(&status); err != nil {
+		return 0, err
+	}
+	if status != "active" {
+		return 0, ErrReaderArchived
+	}
+	if err := tx.QueryRow("SELECT id FROM books WHERE id = ?", bookID).Scan(&bookID); err != nil {
+		return 0, err
+	}
+
+	var onLoan, held bool
+	var count int
+	err = tx.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM loans WHERE reader_id = ? AND book_id = ? AND status = 'active'),
+			EXISTS (SELECT 1 FROM holds WHERE reader_id = ? AND book_id = ? AND status = 'active'),
+			(SELECT COUNT(*) FROM holds h JOIN books b ON h.book_id = b.id WHERE h.reader_id = ? AND h.status = 'active')
+	`, readerID, bookID, readerID, bookID, readerID).Scan(&onLoan, &held, &count)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case onLoan:
+		return 0, ErrBookLoanedToReader
+	case held:
+		return 0, ErrHoldExists
+	case count >= MaxActiveHolds:
+		return 0, ErrHoldLimit
+	}
+
+	result, err := tx.Exec("INSERT INTO holds (reader_id, book_id, source) VALUES (?, ?, ?)", readerID, bookID, source)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	details := map[string]interface{}{"hold_id": id, "book_id": bookID, "source": source}
+	if err := WriteAudit(tx, "reader", readerID, "hold", details, userID); err != nil {
+		return 0, err
+	}
+
+	return int(id), tx.Commit()
+}
+
+// CancelHold снимает активную бронь. Если readerID задан, снять можно только свою бронь:
+// чужая считается несуществующей. userID — сотрудник, 0 — бронь снимает сам читатель
+func CancelHold(id, readerID, userID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var owner, bookID int
+	var status string
+	err = tx.QueryRow("SELECT reader_id, book_id, status FROM holds WHERE id = ?", id).Scan(&owner, &bookID, &status)
+	if err != nil {
+		return err
+	}
+	if readerID > 0 && owner != readerID {
+		return sql.ErrNoRows
+	}
+	if status != "active" {
+		return ErrHoldNotActive
+	}
+
+	var closedBy interface{}
+	if userID > 0 {
+		closedBy = userID
+	}
+	_, err = tx.Exec(
+		"UPDATE holds SET status = 'cancelled', closed_at = CURRENT_TIMESTAMP, closed_by = ? WHERE id = ?",
+		closedBy, id,
+	)
+	if err != nil {
+		return err
+	}
+
+	details := map[string]int{"hold_id": id, "book_id": bookID}
+	if err := WriteAudit(tx, "reader", owner, "cancel_hold", details, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// fulfillHolds закрывает бронь читателя на книгу, которую ему выдали
+func fulfillHolds(ex execer, readerID, bookID int) error {
+	_, err := ex.Exec(`
+		UPDATE holds SET status = 'fulfilled', closed_at = CURRENT_TIMESTAMP
+		WHERE reader_id = ? AND book_id = ? AND status = 'active'
+	`, readerID, bookID)
+	return err
+}
+
+// cancelReaderHolds снимает все активные брони читателя, например при выбытии
+func cancelReaderHolds(ex execer, readerID, userID int) (int64, error) {
+	var closedBy interface{}
+	if userID > 0 {
+		closedBy = userID
+	}
+	result, err := ex.Exec(`
+		UPDATE holds SET status = 'cancelled', closed_at = CURRENT_TIMESTAMP, closed_by = ?
+		WHERE reader_id = ? AND status = 'active'
+	`, closedBy, readerID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// cancelDuplicateHolds снимает брони читателя sourceID на книги, которые читатель targetID
+// уже забронировал или держит на руках. Используется при объединении читателей
+func cancelDuplicateHolds(ex execer, sourceID, targetID, userID int) (int64, error) {
+	var closedBy interface{}
+	if userID > 0 {
+		closedBy = userID
+	}
+	result, err := ex.Exec(`
+		UPDATE holds SET status = 'cancelled', closed_at = CURRENT_TIMESTAMP, closed_by = ?
+		WHERE reader_id = ? AND status = 'active' AND (
+			book_id IN (SELECT book_id FROM holds WHERE reader_id = ? AND status = 'active')
+			OR book_id IN (SELECT book_id FROM loans WHERE reader_id = ? AND status = 'active')
+		)
+	`, closedBy, sourceID, targetID, targetID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// cancelBookHolds снимает все активные брони на книгу, например при ее удалении
+func cancelBookHolds(ex execer, bookID int) (int64, error) {
+	result, err := ex.Exec(`
+		UPDATE holds SET status = 'cancelled', closed_at = CURRENT_TIMESTAMP
+		WHERE book_id = ? AND status = 'active'
+	`, bookID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
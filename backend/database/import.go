@@ -0,0 +1,149 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"library-management/backend/models"
+)
+
+// importTables — таблицы записей, которые можно загружать из файлов
+var importTables = map[string]string{
+	"book":   "books",
+	"reader": "readers",
+}
+
+// GetImportTemplates возвращает сохраненные шаблоны импорта для типа записей
+func GetImportTemplates(entityType string) ([]models.ImportTemplate, error) {
+	rows, err := db.Query(`
+		SELECT id, entity_type, name, mapping, COALESCE(created_by, 0), created_at
+		FROM import_templates
+		WHERE entity_type = ?
+		ORDER BY name
+	`, entityType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []models.ImportTemplate{}
+	for rows.Next() {
+		t, err := scanImportTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *t)
+	}
+	return templates, rows.Err()
+}
+
+// GetImportTemplate возвращает шаблон импорта по ID
+func GetImportTemplate(id int) (*models.ImportTemplate, error) {
+	return scanImportTemplate(db.QueryRow(`
+		SELECT id, entity_type, name, mapping, COALESCE(created_by, 0), created_at
+		FROM import_templates WHERE id = ?
+	`, id))
+}
+
+// scanImportTemplate считывает шаблон импорта
+func scanImportTemplate(row rowScanner) (*models.ImportTemplate, error) {
+	var t models.ImportTemplate
+	var mapping string
+	if err := row.Scan(&t.ID, &t.EntityType, &t.Name, &mapping, &t.CreatedBy, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(mapping), &t.Mapping); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// CreateImportTemplate сохраняет шаблон импорта
+func CreateImportTemplate(t *models.ImportTemplate) (int, error) {
+	mapping, err := json.Marshal(t.Mapping)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := db.Exec(
+		"INSERT INTO import_templates (entity_type, name, mapping, created_by) VALUES (?, ?, ?, ?)",
+		t.EntityType, t.Name, string(mapping), t.CreatedBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// UpdateImportTemplate изменяет название и сопоставление столбцов шаблона
+func UpdateImportTemplate(t *models.ImportTemplate) error {
+	mapping, err := json.Marshal(t.Mapping)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(
+		"UPDATE import_templates SET name = ?, mapping = ? WHERE id = ?",
+		t.Name, string(mapping), t.ID,
+	)
+	return err
+}
+
+// DeleteImportTemplate удаляет шаблон импорта
+func DeleteImportTemplate(id int) error {
+	_, err := db.Exec("DELETE FROM import_templates WHERE id = ?", id)
+	return err
+}
+
+// ImportTemplateNameExists проверяет, занято ли название шаблона другим шаблоном того же типа
+func ImportTemplateNameExists(entityType, name string, excludeID int) (bool, error) {
+	var count int
+	err := db.QueryRow(
+		"SELECT COUNT(*) FROM import_templates WHERE entity_type = ? AND name = ? AND id <> ?",
+		entityType, name, excludeID,
+	).Scan(&count)
+	return count > 0, err
+}
+
+// FindImportTarget ищет существующую запись для обновления при импорте: сначала по коду,
+// затем по штрих-коду. Возвращает 0, если запись не найдена. Если код и штрих-код
+// указывают на разные записи, возвращается ошибка
+func FindImportTarget(entityType, code, barcode string) (int, error) {
+	table, ok := importTables[entityType]
+	if !ok {
+		return 0, fmt.Errorf("unknown entity type: %s", entityType)
+	}
+
+	find := func(column, value string) (int, error) {
+		if value == "" {
+			return 0, nil
+		}
+		ids, err := queryIDs("SELECT id FROM "+table+" WHERE "+column+" = ?", value)
+		if err != nil || len(ids) == 0 {
+			return 0, err
+		}
+		return ids[0], nil
+	}
+
+	byCode, err := find("code", code)
+	if err != nil {
+		return 0, err
+	}
+	byBarcode, err := find("barcode", barcode)
+	if err != nil {
+		return 0, err
+	}
+
+	if byCode > 0 && byBarcode > 0 && byCode != byBarcode {
+		return 0, fmt.Errorf("code %s and barcode %s belong to different records", code, barcode)
+	}
+	if byCode > 0 {
+		return byCode, nil
+	}
+	return byBarcode, nil
+}
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"fmt"
+	"library-management/backend/models"
+	"strings"
+)
+
+// labelSources — запросы для выборки записей на этикетки по типам объектов.
+// alias — псевдоним основной таблицы, search — столбцы для поиска по фильтру
+var labelSources = map[string]struct {
+	query   string
+	alias   string
+	search  []string
+	orderBy string
+}{
+	"book": {
+		query: `SELECT b.id, b.barcode, b.title, COALESCE(a.short_name, '')
+			FROM books b
+			LEFT JOIN authors a ON b.author_id = a.id`,
+		alias:   "b",
+		search:  []string{"b.title", "b.barcode", "b.isbn", "a.last_name"},
+		orderBy: "b.code",
+	},
+	"reader": {
+		query: `SELECT r.id, r.barcode, r.last_name || ' ' || r.first_name,
+				CASE WHEN c.id IS NULL THEN '' ELSE c.grade || ' "' || c.letter || '"' END
+			FROM readers r
+			LEFT JOIN classes c ON r.class_id = c.id`,
+		alias:   "r",
+		search:  []string{"r.last_name", "r.first_name", "r.barcode"},
+		orderBy: "c.grade, c.letter, r.last_name, r.first_name",
+	},
+	"disk": {
+		query: `SELECT d.id, d.barcode, d.title, COALESCE(s.name, d.subject, '')
+			FROM disks d
+			LEFT JOIN subjects s ON d.subject_id = s.id`,
+		alias:   "d",
+		search:  []string{"d.title", "d.barcode"},
+		orderBy: "d.code",
+	},
+}
+
+// GetLabelItems возвращает записи со штрих-кодами для печати этикеток.
+// Если заданы ID, записи возвращаются в порядке списка; диапазон штрих-кодов
+// сравнивается сначала по длине, затем посимвольно, так что "B9" < "B10"
+func GetLabelItems(req models.LabelRequest) ([]models.LabelItem, error) {
+	source, ok := labelSources[req.Entity]
+	if !ok {
+		return nil, fmt.Errorf("unknown entity type: %s", req.Entity)
+	}
+
+	barcode := source.alias + ".barcode"
+	query := source.query + " WHERE COALESCE(" + barcode + ", '') <> ''"
+	orderBy := source.orderBy
+	var args []interface{}
+
+	switch {
+	case len(req.IDs) > 0:
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(req.IDs)), ", ")
+		query += " AND " + source.alias + ".id IN (" + placeholders + ")"
+		for _, id := range req.IDs {
+			args = append(args, id)
+		}
+
+	case req.BarcodeFrom != "" || req.BarcodeTo != "":
+		if req.BarcodeFrom != "" {
+			query += " AND (length(" + barcode + "), " + barcode + ") >= (length(?), ?)"
+			args = append(args, req.BarcodeFrom, req.BarcodeFrom)
+		}
+		if req.BarcodeTo != "" {
+			query += " AND (length(" + barcode + "), " + barcode + ") <= (length(?), ?)"
+			args = append(args, req.BarcodeTo, req.BarcodeTo)
+		}
+		orderBy = "length(" + barcode + "), " + barcode
+
+	default:
+		if req.Search != "" {
+			conditions := make([]string, len(source.search))
+			for i, column := range source.search {
+				conditions[i] = column + " LIKE ?"
+				args = append(args, "%"+req.Search+"%")
+			}
+			query += " AND (" + strings.Join(conditions, " OR ") + ")"
+		}
+		if req.Entity == "book" && req.LocationID > 0 {
+			query += " AND b.location_id IN " + locationSubtree
+			args = append(args, req.LocationID)
+		}
+		if req.Entity == "reader" && req.ClassID > 0 {
+			query += " AND r.class_id = ?"
+			args = append(args, req.ClassID)
+		}
+	}
+
+	rows, err := db.Query(query+" ORDER BY "+orderBy, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.LabelItem{}
+	for rows.Next() {
+		var item models.LabelItem
+		if err := rows.Scan(&item.ID, &item.Barcode, &item.Title, &item.Subtitle); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(req.IDs) > 0 {
+		byID := map[int]models.LabelItem{}
+		for _, item := range items {
+			byID[item.ID] = item
+		}
+		items = items[:0]
+		for _, id := range req.IDs {
+			if item, ok := byID[id]; ok {
+				items = append(items, item)
+			}
+		}
+	}
+
+	return items, nil
+}
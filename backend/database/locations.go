@@ -0,0 +1,569 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"library-management/backend/models"
+	"sort"
+	"strings"
+)
+
+// LocationKinds — виды мест хранения в порядке вложенности
+var LocationKinds = []string{"building", "room", "cabinet", "shelf"}
+
+// LocationKindLevel возвращает уровень вида места хранения (1 — здание) или 0 для неизвестного вида
+func LocationKindLevel(kind string) int {
+	for i, k := range LocationKinds {
+		if k == kind {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// locationSubtree — подзапрос, возвращающий ID места хранения и всех вложенных в него мест
+const locationSubtree = `(
+	WITH RECURSIVE subtree(id) AS (
+		SELECT ?
+		UNION ALL
+		SELECT loc.id FROM locations loc JOIN subtree ON loc.parent_id = subtree.id
+	)
+	SELECT id FROM subtree
+)`
+
+// locationTree — все места хранения с вычисленными путями и количеством книг
+type locationTree struct {
+	byID     map[int]*models.Location
+	children map[int][]*models.Location // 0 — корневые места
+}
+
+// loadLocations загружает дерево мест хранения
+func loadLocations() (*locationTree, error) {
+	rows, err := db.Query(`
+		SELECT id, parent_id, kind, name, COALESCE(barcode, ''), COALESCE(comments, ''), created_at
+		FROM locations
+		ORDER BY name, id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tree := &locationTree{
+		byID:     map[int]*models.Location{},
+		children: map[int][]*models.Location{},
+	}
+	for rows.Next() {
+		var loc models.Location
+		err := rows.Scan(
+			&loc.ID, &loc.ParentID, &loc.Kind, &loc.Name,
+			&loc.Barcode, &loc.Comments, &loc.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		tree.byID[loc.ID] = &loc
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, loc := range tree.byID {
+		parentID := 0
+		if loc.ParentID != nil && tree.byID[*loc.ParentID] != nil {
+			parentID = *loc.ParentID
+		}
+		tree.children[parentID] = append(tree.children[parentID], loc)
+	}
+	for _, list := range tree.children {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Name != list[j].Name {
+				return list[i].Name < list[j].Name
+			}
+			return list[i].ID < list[j].ID
+		})
+	}
+	for _, loc := range tree.byID {
+		loc.Path = tree.path(loc)
+	}
+
+	// Количество книг с учетом вложенных мест
+	countRows, err := db.Query("SELECT location_id, COUNT(*) FROM books WHERE location_id IS NOT NULL GROUP BY location_id")
+	if err != nil {
+		return nil, err
+	}
+	defer countRows.Close()
+
+	for countRows.Next() {
+		var id, count int
+		if err := countRows.Scan(&id, &count); err != nil {
+			return nil, err
+		}
+		for loc := tree.byID[id]; loc != nil; loc = tree.parent(loc) {
+			loc.BookCount += count
+		}
+	}
+
+	return tree, countRows.Err()
+}
+
+// parent возвращает родительское место хранения
+func (t *locationTree) parent(loc *models.Location) *models.Location {
+	if loc.ParentID == nil {
+		return nil
+	}
+	return t.byID[*loc.ParentID]
+}
+
+// path возвращает полное название места хранения: «Корпус 1 / Каб. 12 / Шкаф 3 / Полка 2»
+func (t *locationTree) path(loc *models.Location) string {
+	names := []string{}
+	seen := map[int]bool{}
+	for node := loc; node != nil && !seen[node.ID]; node = t.parent(node) {
+		seen[node.ID] = true
+		names = append([]string{node.Name}, names...)
+	}
+	return strings.Join(names, " / ")
+}
+
+// ordered возвращает места хранения в порядке обхода дерева, начиная с указанного (0 — все)
+func (t *locationTree) ordered(rootID int) []*models.Location {
+	var result []*models.Location
+	var walk func(parentID int)
+	walk = func(parentID int) {
+		for _, loc := range t.children[parentID] {
+			result = append(result, loc)
+			walk(loc.ID)
+		}
+	}
+
+	if rootID == 0 {
+		walk(0)
+	} else if root, ok := t.byID[rootID]; ok {
+		result = append(result, root)
+		walk(rootID)
+	}
+	return result
+}
+
+// ancestorAtLevel возвращает место хранения, в которое входит loc, уровня не глубже level
+func (t *locationTree) ancestorAtLevel(loc *models.Location, level int) *models.Location {
+	for LocationKindLevel(loc.Kind) > level && t.parent(loc) != nil {
+		loc = t.parent(loc)
+	}
+	return loc
+}
+
+// GetLocations возвращает места хранения в порядке обхода дерева
+func GetLocations() ([]models.Location, error) {
+	tree, err := loadLocations()
+	if err != nil {
+		return nil, err
+	}
+
+	locations := []models.Location{}
+	for _, loc := range tree.ordered(0) {
+		locations = append(locations, *loc)
+	}
+	return locations, nil
+}
+
+// GetLocationByID возвращает место хранения по ID
+func GetLocationByID(id int) (*models.Location, error) {
+	tree, err := loadLocations()
+	if err != nil {
+		return nil, err
+	}
+
+	loc, ok := tree.byID[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return loc, nil
+}
+
+// GetLocationByBarcode возвращает место хранения по штрих-коду
+func GetLocationByBarcode(barcode string) (*models.Location, error) {
+	var id int
+	if err := db.QueryRow("SELECT id FROM locations WHERE barcode = ?", barcode).Scan(&id); err != nil {
+		return nil, err
+	}
+	return GetLocationByID(id)
+}
+
+// CreateLocation создает место хранения. Если штрих-код не указан, он генерируется по ID
+func CreateLocation(loc *models.Location) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var barcode interface{}
+	if loc.Barcode != "" {
+		barcode = loc.Barcode
+	}
+
+	result, err := tx.Exec(
+		"INSERT INTO locations (parent_id, kind, name, barcode, comments) VALUES (?, ?, ?, ?, ?)",
+		loc.ParentID, loc.Kind, loc.Name, barcode, loc.Comments,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if loc.Barcode == "" {
+		loc.Barcode = fmt.Sprintf("LOC%06d", id)
+		if _, err := tx.Exec("UPDATE locations SET barcode = ? WHERE id = ?", loc.Barcode, id); err != nil {
+			return 0, err
+		}
+	}
+
+	return int(id), tx.Commit()
+}
+
+// UpdateLocation обновляет место хранения и текстовое место хранения книг во всем поддереве
+func UpdateLocation(loc *models.Location) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if loc.Barcode == "" {
+		loc.Barcode = fmt.Sprintf("LOC%06d", loc.ID)
+	}
+
+	_, err = tx.Exec(
+		"UPDATE locations SET parent_id = ?, kind = ?, name = ?, barcode = ?, comments = ? WHERE id = ?",
+		loc.ParentID, loc.Kind, loc.Name, loc.Barcode, loc.Comments, loc.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return refreshBookLocations(loc.ID)
+}
+
+// refreshBookLocations пересчитывает текстовое место хранения книг в поддереве
+func refreshBookLocations(rootID int) error {
+	tree, err := loadLocations()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, loc := range tree.ordered(rootID) {
+		if _, err := tx.Exec("UPDATE books SET location = ? WHERE location_id = ?", loc.Path, loc.ID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteLocation удаляет место хранения
+func DeleteLocation(id int) error {
+	_, err := db.Exec("DELETE FROM locations WHERE id = ?", id)
+	return err
+}
+
+// LocationHasChildren проверяет, есть ли вложенные места хранения
+func LocationHasChildren(id int) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM locations WHERE parent_id = ?", id).Scan(&count)
+	return count > 0, err
+}
+
+// LocationHasBooks проверяет, есть ли книги в месте хранения
+func LocationHasBooks(id int) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM books WHERE location_id = ?", id).Scan(&count)
+	return count > 0, err
+}
+
+// MoveBooks перемещает книги в место хранения и записывает историю перемещений.
+// Книги, уже находящиеся в этом месте, не перемещаются
+func MoveBooks(locationID int, bookIDs []int, userID int) (moved, unchanged int, err error) {
+	loc, err := GetLocationByID(locationID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	if moved, unchanged, err = moveBooks(tx, loc, bookIDs, userID); err != nil {
+		return 0, 0, err
+	}
+	return moved, unchanged, tx.Commit()
+}
+
+// moveBooks перемещает книги внутри транзакции и записывает историю перемещений
+func moveBooks(tx *sql.Tx, loc *models.Location, bookIDs []int, userID int) (moved, unchanged int, err error) {
+	for _, bookID := range bookIDs {
+		var fromID *int
+		var fromPath string
+		err := tx.QueryRow(
+			"SELECT location_id, COALESCE(location, '') FROM books WHERE id = ?", bookID,
+		).Scan(&fromID, &fromPath)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		if fromID != nil && *fromID == loc.ID {
+			unchanged++
+			continue
+		}
+
+		if _, err := tx.Exec(
+			"UPDATE books SET location_id = ?, location = ? WHERE id = ?",
+			loc.ID, loc.Path, bookID,
+		); err != nil {
+			return 0, 0, err
+		}
+		if err := insertBookMove(tx, bookID, fromID, fromPath, &loc.ID, loc.Path, userID); err != nil {
+			return 0, 0, err
+		}
+		moved++
+	}
+
+	return moved, unchanged, nil
+}
+
+// insertBookMove записывает перемещение книги в историю
+func insertBookMove(ex execer, bookID int, fromID *int, fromPath string, toID *int, toPath string, userID int) error {
+	var user interface{}
+	if userID > 0 {
+		user = userID
+	}
+
+	_, err := ex.Exec(`
+		INSERT INTO book_moves (book_id, from_location_id, from_location, to_location_id, to_location, moved_by)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, bookID, fromID, fromPath, toID, toPath, user)
+	return err
+}
+
+// GetBookMoves возвращает историю перемещений книги, начиная с последнего
+func GetBookMoves(bookID int) ([]models.BookMove, error) {
+	rows, err := db.Query(`
+		SELECT m.id, m.book_id, m.from_location_id, COALESCE(m.from_location, ''),
+			m.to_location_id, COALESCE(m.to_location, ''),
+			COALESCE(m.moved_by, 0), COALESCE(u.full_name, ''), m.moved_at
+		FROM book_moves m
+		LEFT JOIN users u ON m.moved_by = u.id
+		WHERE m.book_id = ?
+		ORDER BY m.moved_at DESC, m.id DESC
+	`, bookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	moves := []models.BookMove{}
+	for rows.Next() {
+		var move models.BookMove
+		err := rows.Scan(
+			&move.ID, &move.BookID, &move.FromLocationID, &move.FromLocation,
+			&move.ToLocationID, &move.ToLocation,
+			&move.MovedBy, &move.MovedByName, &move.MovedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, move)
+	}
+
+	return moves, rows.Err()
+}
+
+// GetInventoryReport формирует инвентаризационную опись: книги, которые должны находиться
+// в каждом месте хранения поддерева. Без locationID в опись попадают все книги,
+// включая книги без места хранения
+func GetInventoryReport(locationID int) (*models.InventoryReport, error) {
+	tree, err := loadLocations()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.InventoryReport{Shelves: []models.InventoryShelf{}}
+	if locationID > 0 {
+		loc, ok := tree.byID[locationID]
+		if !ok {
+			return nil, sql.ErrNoRows
+		}
+		report.Location = loc
+	}
+
+	query := `
+		SELECT b.location_id, b.id, b.code, b.title, COALESCE(a.short_name, ''),
+			COALESCE(b.barcode, ''), CASE WHEN l.id IS NULL THEN 1 ELSE 0 END
+		FROM books b
+		LEFT JOIN authors a ON b.author_id = a.id
+		LEFT JOIN loans l ON b.id = l.book_id AND l.status = 'active'
+	`
+	var args []interface{}
+	if locationID > 0 {
+		query += " WHERE b.location_id IN " + locationSubtree
+		args = append(args, locationID)
+	}
+	query += " ORDER BY b.code"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := map[int][]models.InventoryItem{} // 0 — без места хранения
+	for rows.Next() {
+		var locID *int
+		var item models.InventoryItem
+		err := rows.Scan(
+			&locID, &item.ID, &item.Code, &item.Title, &item.Author,
+			&item.Barcode, &item.IsAvailable,
+		)
+		if err != nil {
+			return nil, err
+		}
+		key := 0
+		if locID != nil && tree.byID[*locID] != nil {
+			key = *locID
+		}
+		items[key] = append(items[key], item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	addShelf := func(loc *models.Location, books []models.InventoryItem) {
+		shelf := models.InventoryShelf{Location: loc, Books: books, Total: len(books)}
+		for _, book := range books {
+			if book.IsAvailable {
+				shelf.Available++
+			}
+		}
+		report.Shelves = append(report.Shelves, shelf)
+		report.Total += shelf.Total
+		report.Available += shelf.Available
+	}
+
+	for _, loc := range tree.ordered(locationID) {
+		if books, ok := items[loc.ID]; ok {
+			addShelf(loc, books)
+		}
+	}
+	if books, ok := items[0]; ok && locationID == 0 {
+		addShelf(nil, books)
+	}
+
+	return report, nil
+}
+
+// GetBookAvailabilityByLocation генерирует отчет о наличии книг, сгруппированный по местам
+// хранения уровня level (1 — здания, 4 — полки) внутри места locationID (0 — все)
+func GetBookAvailabilityByLocation(classFilter string, locationID, level int) (*models.BookAvailabilityReport, error) {
+	tree, err := loadLocations()
+	if err != nil {
+		return nil, err
+	}
+	if locationID > 0 && tree.byID[locationID] == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	query := `
+		SELECT COALESCE(b.location_id, 0), COUNT(*),
+			SUM(CASE WHEN l.id IS NULL THEN 1 ELSE 0 END)
+		FROM books b
+		LEFT JOIN loans l ON b.id = l.book_id AND l.status = 'active'
+		WHERE 1=1
+	`
+	var args []interface{}
+	if classFilter != "" {
+		query += " AND b.class_range LIKE ?"
+		args = append(args, "%"+classFilter+"%")
+	}
+	if locationID > 0 {
+		query += " AND b.location_id IN " + locationSubtree
+		args = append(args, locationID)
+	}
+	query += " GROUP BY 1"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := map[int]models.BookAvailabilityRow{}
+	var unplaced models.BookAvailabilityRow
+	for rows.Next() {
+		var id, total, available int
+		if err := rows.Scan(&id, &total, &available); err != nil {
+			return nil, err
+		}
+
+		loc := tree.byID[id]
+		if loc == nil {
+			unplaced.Total += total
+			unplaced.Available += available
+			continue
+		}
+		group := tree.ancestorAtLevel(loc, level)
+		// Группа не может быть выше выбранного места хранения
+		if locationID > 0 && LocationKindLevel(group.Kind) < LocationKindLevel(tree.byID[locationID].Kind) {
+			group = tree.byID[locationID]
+		}
+		row := groups[group.ID]
+		row.Total += total
+		row.Available += available
+		groups[group.ID] = row
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	report := &models.BookAvailabilityReport{
+		GroupBy: "location",
+		Level:   level,
+		Rows:    []models.BookAvailabilityRow{},
+	}
+
+	addRow := func(index, title string, row models.BookAvailabilityRow) {
+		row.SectionIndex = index
+		row.SectionTitle = title
+		row.Loaned = row.Total - row.Available
+		report.Rows = append(report.Rows, row)
+		report.Totals.Total += row.Total
+		report.Totals.Available += row.Available
+		report.Totals.Loaned += row.Loaned
+	}
+
+	for _, loc := range tree.ordered(locationID) {
+		if row, ok := groups[loc.ID]; ok {
+			addRow(loc.Barcode, loc.Path, row)
+		}
+	}
+	if unplaced.Total > 0 {
+		addRow("", "Без места хранения", unplaced)
+	}
+
+	report.Totals.SectionTitle = "Итого"
+	return report, nil
+}
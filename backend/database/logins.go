@@ -0,0 +1,320 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"library-management/backend/models"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// usernameFreeAttempts — неудачные попытки по одному логину без задержки
+	usernameFreeAttempts = 3
+	// usernameLockAttempts — после стольких неудачных попыток логин блокируется на loginLockDuration
+	usernameLockAttempts = 10
+	// ipFreeAttempts и ipLockAttempts — то же для IP-адреса. Пороги выше: за одним адресом
+	// может работать вся школа
+	ipFreeAttempts = 10
+	ipLockAttempts = 50
+	// clearanceFreeAttempts и clearanceLockAttempts — пороги для неверных кодов проверки обходных листов
+	// с одного IP-адреса; считаются отдельно от входов
+	clearanceFreeAttempts = 10
+	clearanceLockAttempts = 30
+	// loginBackoffBase — первая задержка; каждая следующая неудачная попытка ее удваивает
+	loginBackoffBase = time.Second
+	// loginLockDuration — время блокировки и наибольшая задержка
+	loginLockDuration = 15 * time.Minute
+	// loginAttemptsReset — если столько времени не было неудачных попыток, счетчик начинается заново
+	loginAttemptsReset = time.Hour
+)
+
+// loginAttemptColumns — столбцы счетчика неудачных входов; используется вместе со scanLoginAttempt
+const loginAttemptColumns = `
+	id, kind, value, failures, last_failed_at, locked_until
+	FROM login_attempts
+`
+
+// scanLoginAttempt считывает счетчик, выбранный через loginAttemptColumns
+func scanLoginAttempt(row rowScanner) (*models.LoginAttempt, error) {
+	var a models.LoginAttempt
+	err := row.Scan(&a.ID, &a.Kind, &a.Value, &a.Failures, &a.LastFailedAt, &a.LockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	a.Locked = a.LockedUntil != nil && a.LockedUntil.After(time.Now())
+	return &a, nil
+}
+
+// loginKey приводит логин к виду, в котором по нему считаются попытки:
+// «Admin» и «admin » — один и тот же счетчик
+func loginKey(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// loginDelay возвращает, на сколько закрыть вход после failures неудачных попыток подряд
+func loginDelay(failures, free, lock int) time.Duration {
+	if failures >= lock {
+		return loginLockDuration
+	}
+	if failures <= free {
+		return 0
+	}
+	delay := loginBackoffBase << uint(failures-free-1)
+	if delay <= 0 || delay > loginLockDuration {
+		return loginLockDuration
+	}
+	return delay
+}
+
+// ErrLoginLocked — вход по логину или с IP-адреса временно закрыт после неудачных попыток
+var ErrLoginLocked = errors.New("login is temporarily locked")
+
+// LoginReservation — попытка входа, заранее учтенная как неудачная
+type LoginReservation struct {
+	Username string
+	IP       string
+	// userAttempt и ipAttempt — ID счетчиков логина и IP-адреса
+	userAttempt, ipAttempt int
+	failures, ipFailures   int
+	// ipKind, ipFree и ipLock — вид счетчика IP-адреса и его пороги
+	ipKind         string
+	ipFree, ipLock int
+	// LockedUntil — до какого времени закрыт вход: при ErrLoginLocked — действующая блокировка,
+	// иначе блокировка, которая останется, если попытка окажется неудачной
+	LockedUntil *time.Time
+}
+
+// ReserveLoginAttempt в одной транзакции проверяет блокировку логина и IP-адреса и сразу
+// засчитывает попытку как неудачную — до проверки пароля. Поэтому параллельные запросы
+// не проходят проверку все разом: каждый следующий видит счетчик и блокировку предыдущего.
+// После проверки пароля резерв закрывается ReleaseLoginAttempt или RecordLoginFailure.
+// С пустым username считается только IP-адрес — для входа в личный кабинет читателя.
+// Заодно удаляются счетчики, по которым давно не было попыток
+func ReserveLoginAttempt(username, ip string) (*LoginReservation, error) {
+	return reserveAttempt(username, "ip", ip, ipFreeAttempts, ipLockAttempts)
+}
+
+// ReserveClearanceCheck засчитывает проверку обходного листа с IP-адреса до сверки кода,
+// как ReserveLoginAttempt — вход. Счетчик свой (clearance_ip): перебор кодов не закрывает вход
+// сотрудникам с того же адреса. Резерв закрывается так же, ReleaseLoginAttempt или RecordLoginFailure
+func ReserveClearanceCheck(ip string) (*LoginReservation, error) {
+	return reserveAttempt("", "clearance_ip", ip, clearanceFreeAttempts, clearanceLockAttempts)
+}
+
+// reserveAttempt засчитывает попытку по логину (если задан) и по счетчику IP-адреса вида ipKind
+func reserveAttempt(username, ipKind, ip string, ipFree, ipLock int) (*LoginReservation, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Первая же запись берет блокировку базы на запись: проверка и увеличение счетчика
+	// выполняются без вклинивания других запросов
+	now := time.Now().UTC()
+	if _, err := tx.Exec(
+		"DELETE FROM login_attempts WHERE last_failed_at < ? AND (locked_until IS NULL OR locked_until < ?)",
+		now.Add(-loginAttemptsReset), now,
+	); err != nil {
+		return nil, err
+	}
+
+	r := &LoginReservation{Username: username, IP: ip, ipKind: ipKind, ipFree: ipFree, ipLock: ipLock}
+	var userUntil, ipUntil *time.Time
+	if username != "" {
+		r.userAttempt, r.failures, userUntil, err = countLoginFailure(tx, "username", loginKey(username), usernameFreeAttempts, usernameLockAttempts, now)
+	}
+	if err == nil {
+		r.ipAttempt, r.ipFailures, ipUntil, err = countLoginFailure(tx, ipKind, ip, ipFree, ipLock, now)
+	}
+	if err == ErrLoginLocked {
+		r.LockedUntil, err = lockedUntil(tx, username, ipKind, ip, now)
+		if err != nil {
+			return nil, err
+		}
+		return r, ErrLoginLocked
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.LockedUntil = userUntil
+	if r.LockedUntil == nil || (ipUntil != nil && ipUntil.After(*r.LockedUntil)) {
+		r.LockedUntil = ipUntil
+	}
+	return r, tx.Commit()
+}
+
+// countLoginFailure увеличивает счетчик и назначает задержку по порогам free и lock; возвращает
+// ID счетчика, число неудач и блокировку. Если вход уже закрыт, счетчик не меняется — ErrLoginLocked.
+// Если прошлая неудача была раньше loginAttemptsReset, счет начинается заново
+func countLoginFailure(tx *sql.Tx, kind, value string, free, lock int, now time.Time) (int, int, *time.Time, error) {
+	var id, failures int
+	err := tx.QueryRow(`
+		INSERT INTO login_attempts (kind, value, failures, last_failed_at) VALUES (?, ?, 1, ?)
+		ON CONFLICT (kind, value) DO UPDATE SET
+			failures = CASE WHEN last_failed_at < ? THEN 1 ELSE failures + 1 END,
+			last_failed_at = excluded.last_failed_at
+			WHERE locked_until IS NULL OR locked_until <= excluded.last_failed_at
+		RETURNING id, failures
+	`, kind, value, now, now.Add(-loginAttemptsReset)).Scan(&id, &failures)
+	if err == sql.ErrNoRows {
+		return 0, 0, nil, ErrLoginLocked
+	}
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	until, err := setLoginLock(tx, id, loginDelay(failures, free, lock), now)
+	return id, failures, until, err
+}
+
+// setLoginLock закрывает вход по счетчику на delay; нулевая задержка снимает блокировку
+func setLoginLock(ex execer, id int, delay time.Duration, now time.Time) (*time.Time, error) {
+	var until *time.Time
+	if delay > 0 {
+		t := now.Add(delay)
+		until = &t
+	}
+	_, err := ex.Exec("UPDATE login_attempts SET locked_until = ? WHERE id = ?", until, id)
+	return until, err
+}
+
+// lockedUntil возвращает самую позднюю действующую блокировку логина и счетчика IP-адреса вида ipKind
+func lockedUntil(tx *sql.Tx, username, ipKind, ip string, now time.Time) (*time.Time, error) {
+	var until time.Time
+	err := tx.QueryRow(`
+		SELECT locked_until FROM login_attempts
+		WHERE ((kind = 'username' AND value = ?) OR (kind = ? AND value = ?)) AND locked_until > ?
+		ORDER BY locked_until DESC LIMIT 1
+	`, loginKey(username), ipKind, ip, now).Scan(&until)
+	if err == sql.ErrNoRows {
+		// Блокировка только что истекла
+		return &now, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &until, nil
+}
+
+// RecordLoginFailure подтверждает, что зарезервированная попытка неудачна, и пишет ее в журнал:
+// login_failed или, для проверки обходного листа, clearance_check_failed
+func RecordLoginFailure(r *LoginReservation) error {
+	details := map[string]interface{}{
+		"ip":           r.IP,
+		"ip_failures":  r.ipFailures,
+		"locked_until": r.LockedUntil,
+	}
+	entityID := r.ipAttempt
+	if r.Username != "" {
+		details["username"] = r.Username
+		details["failures"] = r.failures
+		entityID = r.userAttempt
+	}
+	action := "login_failed"
+	if r.ipKind == "clearance_ip" {
+		action = "clearance_check_failed"
+	}
+	return WriteAudit(nil, "login_attempt", entityID, action, details, 0)
+}
+
+// ReleaseLoginAttempt отменяет резерв после верного пароля: счетчик логина сбрасывается,
+// у IP-адреса зарезервированная попытка вычитается. Весь счетчик IP-адреса не сбрасывается:
+// иначе подбор чужих паролей можно было бы прерывать входом в свою учетную запись
+func ReleaseLoginAttempt(r *LoginReservation) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM login_attempts WHERE id = ?", r.userAttempt); err != nil {
+		return err
+	}
+
+	var failures int
+	err = tx.QueryRow(
+		"UPDATE login_attempts SET failures = MAX(failures - 1, 0) WHERE id = ? RETURNING failures",
+		r.ipAttempt,
+	).Scan(&failures)
+	if err == sql.ErrNoRows {
+		return tx.Commit()
+	}
+	if err != nil {
+		return err
+	}
+	// Блокировку, назначенную за эту попытку, снимаем; назначенную за другие — оставляем
+	if loginDelay(failures, r.ipFree, r.ipLock) == 0 {
+		if _, err := setLoginLock(tx, r.ipAttempt, 0, time.Now().UTC()); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetLoginAttempts возвращает заблокированные логины и IP-адреса или, с all=true,
+// все счетчики неудачных входов
+func GetLoginAttempts(all bool) ([]models.LoginAttempt, error) {
+	query := "SELECT " + loginAttemptColumns
+	var args []interface{}
+	if !all {
+		query += " WHERE locked_until > ?"
+		args = append(args, time.Now().UTC())
+	}
+	query += " ORDER BY last_failed_at DESC"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []models.LoginAttempt{}
+	for rows.Next() {
+		a, err := scanLoginAttempt(rows)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, *a)
+	}
+	return attempts, rows.Err()
+}
+
+// UnlockLogin снимает блокировку и обнуляет счетчик неудачных входов
+func UnlockLogin(id, byUserID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	a, err := scanLoginAttempt(tx.QueryRow("SELECT "+loginAttemptColumns+" WHERE id = ?", id))
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM login_attempts WHERE id = ?", id); err != nil {
+		return err
+	}
+
+	details := map[string]interface{}{"kind": a.Kind, "value": a.Value, "failures": a.Failures}
+	if err := WriteAudit(tx, "login_attempt", id, "unlock", details, byUserID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DefaultAdminPassword проверяет, можно ли войти как admin с паролем admin из начальных данных
+func DefaultAdminPassword() (bool, error) {
+	user, err := GetUserByUsername("admin")
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return user.IsActive && bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("admin")) == nil, nil
+}
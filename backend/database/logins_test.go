@@ -0,0 +1,211 @@
+package database
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoginDelay(t *testing.T) {
+	tests := []struct {
+		failures, free, lock int
+		want                 time.Duration
+	}{
+		{0, 3, 10, 0},
+		{3, 3, 10, 0},
+		{4, 3, 10, time.Second},
+		{5, 3, 10, 2 * time.Second},
+		{9, 3, 10, 32 * time.Second},
+		{10, 3, 10, loginLockDuration},
+		{200, 3, 1000, loginLockDuration},
+		{11, 10, 50, time.Second},
+		{50, 10, 50, loginLockDuration},
+	}
+	for _, tt := range tests {
+		if got := loginDelay(tt.failures, tt.free, tt.lock); got != tt.want {
+			t.Errorf("loginDelay(%d, %d, %d) = %v, want %v", tt.failures, tt.free, tt.lock, got, tt.want)
+		}
+	}
+}
+
+// failLogin резервирует попытку и подтверждает ее как неудачную
+func failLogin(t *testing.T, username, ip string) (*LoginReservation, error) {
+	t.Helper()
+	r, err := ReserveLoginAttempt(username, ip)
+	if err != nil {
+		return r, err
+	}
+	if err := RecordLoginFailure(r); err != nil {
+		t.Fatalf("RecordLoginFailure: %v", err)
+	}
+	return r, nil
+}
+
+func TestLoginThrottleByUsername(t *testing.T) {
+	openTestDB(t)
+
+	for i := 1; i <= usernameFreeAttempts; i++ {
+		r, err := failLogin(t, "Admin", "10.0.0.1")
+		if err != nil || r.LockedUntil != nil {
+			t.Fatalf("attempt %d: locked = %v, error = %v; want free attempt", i, r.LockedUntil, err)
+		}
+	}
+	r, err := failLogin(t, "admin ", "10.0.0.2")
+	if err != nil || r.LockedUntil == nil {
+		t.Fatalf("attempt after the free ones must set a delay, got %v, %v", r, err)
+	}
+
+	// Пока задержка действует, вход закрыт для того же логина с любого адреса
+	r, err = ReserveLoginAttempt("ADMIN", "10.0.0.3")
+	if err != ErrLoginLocked || r == nil || r.LockedUntil == nil || !r.LockedUntil.After(time.Now()) {
+		t.Fatalf("ReserveLoginAttempt during the delay = %v, %v; want ErrLoginLocked", r, err)
+	}
+	// Другой логин с того же адреса не затронут
+	if _, err := ReserveLoginAttempt("librarian", "10.0.0.1"); err != nil {
+		t.Errorf("other username locked: %v", err)
+	}
+
+	var failures int
+	if err := db.QueryRow("SELECT failures FROM login_attempts WHERE kind = 'username' AND value = 'admin'").Scan(&failures); err != nil {
+		t.Fatal(err)
+	}
+	if failures != usernameFreeAttempts+1 {
+		t.Errorf("failures = %d, want %d: locked attempts must not be counted", failures, usernameFreeAttempts+1)
+	}
+
+	var logged int
+	if err := db.QueryRow("SELECT COUNT(*) FROM audit_log WHERE action = 'login_failed'").Scan(&logged); err != nil {
+		t.Fatal(err)
+	}
+	if logged != usernameFreeAttempts+1 {
+		t.Errorf("login_failed audit rows = %d, want %d", logged, usernameFreeAttempts+1)
+	}
+}
+
+func TestLoginThrottleLockAndUnlock(t *testing.T) {
+	openTestDB(t)
+
+	// Задержки пропускаем, сдвигая блокировку в прошлое: проверяется только счет до полной блокировки
+	for i := 1; i <= usernameLockAttempts; i++ {
+		if _, err := failLogin(t, "admin", fmt.Sprintf("10.0.1.%d", i)); err != nil {
+			t.Fatalf("attempt %d: %v", i, err)
+		}
+		if i < usernameLockAttempts {
+			if _, err := db.Exec("UPDATE login_attempts SET locked_until = NULL"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	r, err := ReserveLoginAttempt("admin", "10.0.2.1")
+	if err != ErrLoginLocked || r.LockedUntil.Sub(time.Now()) < loginLockDuration-time.Minute {
+		t.Fatalf("after %d failures: %v, %v; want a %v lock", usernameLockAttempts, r, err, loginLockDuration)
+	}
+
+	attempts, err := GetLoginAttempts(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != 1 || attempts[0].Kind != "username" || !attempts[0].Locked {
+		t.Fatalf("locked attempts = %+v, want the admin username", attempts)
+	}
+	if err := UnlockLogin(attempts[0].ID, 1); err != nil {
+		t.Fatalf("UnlockLogin: %v", err)
+	}
+	if _, err := ReserveLoginAttempt("admin", "10.0.2.1"); err != nil {
+		t.Errorf("login still locked after unlock: %v", err)
+	}
+}
+
+func TestLoginThrottleByIP(t *testing.T) {
+	openTestDB(t)
+
+	// Перебор разных логинов с одного адреса упирается в счетчик адреса
+	for i := 1; i <= ipFreeAttempts; i++ {
+		if _, err := failLogin(t, fmt.Sprintf("user%d", i), "10.0.0.9"); err != nil {
+			t.Fatalf("attempt %d: %v", i, err)
+		}
+	}
+	if r, err := failLogin(t, "user-next", "10.0.0.9"); err != nil || r.LockedUntil == nil {
+		t.Fatalf("IP must get a delay after %d failures: %v, %v", ipFreeAttempts, r, err)
+	}
+	if _, err := ReserveLoginAttempt("someone", "10.0.0.9"); err != ErrLoginLocked {
+		t.Errorf("IP not locked: %v", err)
+	}
+	if _, err := ReserveLoginAttempt("someone", "10.0.0.10"); err != nil {
+		t.Errorf("other IP locked: %v", err)
+	}
+}
+
+func TestReleaseLoginAttempt(t *testing.T) {
+	openTestDB(t)
+
+	for i := 0; i < 2; i++ {
+		if _, err := failLogin(t, "admin", "10.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r, err := ReserveLoginAttempt("admin", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ReleaseLoginAttempt(r); err != nil {
+		t.Fatalf("ReleaseLoginAttempt: %v", err)
+	}
+
+	// Верный пароль сбрасывает счетчик логина, а у адреса вычитает только свою попытку
+	var userRows, ipFailures int
+	err = db.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM login_attempts WHERE kind = 'username'),
+			(SELECT failures FROM login_attempts WHERE kind = 'ip' AND value = '10.0.0.1')
+	`).Scan(&userRows, &ipFailures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userRows != 0 || ipFailures != 2 {
+		t.Errorf("after release: username counters = %d, IP failures = %d; want 0 and 2", userRows, ipFailures)
+	}
+}
+
+func TestReserveLoginAttemptConcurrent(t *testing.T) {
+	openTestDB(t)
+
+	// Параллельные попытки не проходят проверку блокировки все разом
+	const attempts = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	reserved := 0
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				_, err := ReserveLoginAttempt("admin", "10.0.0.1")
+				if err != nil && err != ErrLoginLocked && isBusy(err) {
+					time.Sleep(time.Millisecond)
+					continue
+				}
+				if err == nil {
+					mu.Lock()
+					reserved++
+					mu.Unlock()
+				} else if err != ErrLoginLocked {
+					t.Error(err)
+				}
+				return
+			}
+		}()
+	}
+	wg.Wait()
+
+	if reserved != usernameFreeAttempts+1 {
+		t.Errorf("%d parallel attempts reserved, want %d", reserved, usernameFreeAttempts+1)
+	}
+}
+
+// isBusy проверяет, что SQLite отклонил запись из-за параллельной транзакции
+func isBusy(err error) bool {
+	msg := err.Error()
+	return msg == "database is locked" || msg == "database table is locked"
+}
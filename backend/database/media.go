@@ -0,0 +1,143 @@
+package database
+
+import (
+	"database/sql"
+	"library-management/backend/media"
+	"strings"
+)
+
+// BlobStore хранит обложки и фотографии в таблице media_blobs той же базы SQLite.
+// Удобен для установки «одним файлом», когда рядом с базой нельзя держать каталог
+type BlobStore struct{}
+
+// NewBlobStore создает хранилище в базе данных
+func NewBlobStore() *BlobStore {
+	return &BlobStore{}
+}
+
+// Put сохраняет объект
+func (s *BlobStore) Put(key string, data []byte, contentType string) error {
+	_, err := db.Exec(`
+		INSERT INTO media_blobs (key, content_type, data, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(key) DO UPDATE SET
+			content_type = excluded.content_type,
+			data = excluded.data,
+			updated_at = CURRENT_TIMESTAMP
+	`, key, contentType, data)
+	return err
+}
+
+// Get возвращает объект и тип его содержимого
+func (s *BlobStore) Get(key string) ([]byte, string, error) {
+	var data []byte
+	var contentType string
+	err := db.QueryRow(
+		"SELECT data, content_type FROM media_blobs WHERE key = ?",
+		key,
+	).Scan(&data, &contentType)
+	if err == sql.ErrNoRows {
+		return nil, "", media.ErrNotFound
+	}
+	return data, contentType, err
+}
+
+// Delete удаляет все объекты с ключом, начинающимся с prefix
+func (s *BlobStore) Delete(prefix string) error {
+	// Экранируем символы шаблона LIKE, чтобы "_" в ключе не совпадал с любым символом
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix)
+	_, err := db.Exec(
+		`DELETE FROM media_blobs WHERE key = ? OR key LIKE ? ESCAPE '\'`,
+		prefix, escaped+"/%",
+	)
+	return err
+}
+
+// SaveCoverInfo сохраняет сведения об обложке объекта
+func SaveCoverInfo(entityType string, entityID int, processed *media.Processed) error {
+	_, err := db.Exec(`
+		INSERT INTO covers (entity_type, entity_id, etag, content_type, width, height, file_size, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(entity_type, entity_id) DO UPDATE SET
+			etag = excluded.etag,
+			content_type = excluded.content_type,
+			width = excluded.width,
+			height = excluded.height,
+			file_size = excluded.file_size,
+			updated_at = CURRENT_TIMESTAMP
+	`, entityType, entityID, processed.ETag, processed.ContentType,
+		processed.Width, processed.Height, len(processed.Variants[media.SizeOriginal]))
+	return err
+}
+
+// GetCoverETag возвращает ETag обложки объекта или sql.ErrNoRows, если обложки нет
+func GetCoverETag(entityType string, entityID int) (string, error) {
+	var etag string
+	err := db.QueryRow(
+		"SELECT etag FROM covers WHERE entity_type = ? AND entity_id = ?",
+		entityType, entityID,
+	).Scan(&etag)
+	return etag, err
+}
+
+// DeleteCoverInfo удаляет сведения об обложке объекта
+func DeleteCoverInfo(entityType string, entityID int) error {
+	_, err := db.Exec(
+		"DELETE FROM covers WHERE entity_type = ? AND entity_id = ?",
+		entityType, entityID,
+	)
+	return err
+}
+
+// MoveReaderPhotos переносит фотографии, хранившиеся в столбце readers.photo старых баз,
+// в хранилище изображений с миниатюрами. Файлы, не являющиеся изображениями, остаются на месте.
+// Возвращает число перенесенных и пропущенных фотографий
+func MoveReaderPhotos() (int, int, error) {
+	exists, err := columnExists("readers", "photo")
+	if err != nil || !exists {
+		return 0, 0, err
+	}
+
+	rows, err := db.Query("SELECT id, photo FROM readers WHERE photo IS NOT NULL AND LENGTH(photo) > 0")
+	if err != nil {
+		return 0, 0, err
+	}
+	type legacyPhoto struct {
+		id   int
+		data []byte
+	}
+	var photos []legacyPhoto
+	for rows.Next() {
+		var photo legacyPhoto
+		if err := rows.Scan(&photo.id, &photo.data); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		photos = append(photos, photo)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	moved, skipped := 0, 0
+	for _, photo := range photos {
+		processed, err := media.Process(photo.data)
+		if err != nil {
+			skipped++
+			continue
+		}
+		if err := media.SavePhoto(photo.id, processed); err != nil {
+			return moved, skipped, err
+		}
+		if err := SaveCoverInfo(media.EntityReader, photo.id, processed); err != nil {
+			return moved, skipped, err
+		}
+		if _, err := db.Exec("UPDATE readers SET photo = NULL WHERE id = ?", photo.id); err != nil {
+			return moved, skipped, err
+		}
+		moved++
+	}
+
+	return moved, skipped, nil
+}
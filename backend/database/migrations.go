@@ -0,0 +1,381 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"library-management/backend/models"
+	"library-management/backend/pii"
+)
+
+// migrate приводит существующую базу данных к актуальной схеме.
+// Новые таблицы создаются из schema.sql, здесь добавляются столбцы в старые таблицы
+// и переносятся данные. Каждый шаг должен быть идемпотентным
+func migrate() error {
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"disk dictionaries", migrateDiskDictionaries},
+		{"book series and works", migrateBookSeries},
+		{"book locations", migrateBookLocations},
+		{"code sequences", migrateSequences},
+		{"reader archive", migrateReaderArchive},
+		{"reader personal data", migrateReaderPersonalData},
+		{"class settings", migrateClassSettings},
+		{"staff accounts", migrateStaffAccounts},
+		{"portal token version", migratePortalTokenVersion},
+		{"book grade range", migrateBookGradeRange},
+		{"reader and class change time", migrateUpdatedAt},
+	}
+
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			return fmt.Errorf("migration %q: %w", step.name, err)
+		}
+	}
+	return nil
+}
+
+// columnExists проверяет наличие столбца в таблице
+func columnExists(table, column string) (bool, error) {
+	rows, err := db.Query("PRAGMA table_info(" + table + ")")
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue interface{}
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// addColumn добавляет столбец в таблицу, если его еще нет
+func addColumn(table, column, definition string) error {
+	exists, err := columnExists(table, column)
+	if err != nil || exists {
+		return err
+	}
+	_, err = db.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition)
+	return err
+}
+
+// migrateDiskDictionaries связывает диски со справочниками предметов и типов ЭОР
+// и переносит в справочники ранее введенные текстовые значения
+func migrateDiskDictionaries() error {
+	if err := addColumn("disks", "subject_id", "INTEGER REFERENCES subjects(id)"); err != nil {
+		return err
+	}
+	if err := addColumn("disks", "resource_type_id", "INTEGER REFERENCES resource_types(id)"); err != nil {
+		return err
+	}
+
+	statements := []string{
+		`INSERT INTO subjects (name)
+			SELECT DISTINCT TRIM(subject) FROM disks
+			WHERE subject_id IS NULL AND TRIM(COALESCE(subject, '')) <> ''
+			AND TRIM(subject) NOT IN (SELECT name FROM subjects)`,
+		`UPDATE disks SET subject_id = (
+				SELECT id FROM subjects WHERE name = TRIM(disks.subject) ORDER BY id LIMIT 1
+			)
+			WHERE subject_id IS NULL AND TRIM(COALESCE(subject, '')) <> ''`,
+		`INSERT INTO resource_types (name)
+			SELECT DISTINCT TRIM(resource_type) FROM disks
+			WHERE resource_type_id IS NULL AND TRIM(COALESCE(resource_type, '')) <> ''
+			AND TRIM(resource_type) NOT IN (SELECT name FROM resource_types)`,
+		`UPDATE disks SET resource_type_id = (
+				SELECT id FROM resource_types WHERE name = TRIM(disks.resource_type) ORDER BY id LIMIT 1
+			)
+			WHERE resource_type_id IS NULL AND TRIM(COALESCE(resource_type, '')) <> ''`,
+		`CREATE INDEX IF NOT EXISTS idx_disks_subject ON disks(subject_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_disks_resource_type ON disks(resource_type_id)`,
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// migrateBookSeries добавляет книгам ссылки на серию, номер тома и произведение
+func migrateBookSeries() error {
+	columns := []struct{ name, definition string }{
+		{"series_id", "INTEGER REFERENCES series(id)"},
+		{"volume_number", "INTEGER"},
+		{"part_title", "TEXT"},
+		{"work_id", "INTEGER REFERENCES works(id)"},
+	}
+	for _, column := range columns {
+		if err := addColumn("books", column.name, column.definition); err != nil {
+			return err
+		}
+	}
+
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_books_series ON books(series_id)"); err != nil {
+		return err
+	}
+	_, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_books_work ON books(work_id)")
+	return err
+}
+
+// migrateBookLocations добавляет книгам ссылку на место хранения.
+// Текстовое поле location сохраняется: для книг без места хранения оно остается основным
+func migrateBookLocations() error {
+	if err := addColumn("books", "location_id", "INTEGER REFERENCES locations(id)"); err != nil {
+		return err
+	}
+	_, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_books_location ON books(location_id)")
+	return err
+}
+
+// migrateSequences создает последовательности кодов в прежнем формате.
+// Нумерация продолжается с наибольшего числового кода, уже присвоенного записям
+func migrateSequences() error {
+	defaults := []struct {
+		entity      string
+		padding     int
+		yearlyReset bool
+	}{
+		{"book", 6, false},
+		{"reader", 6, false},
+		{"author", 5, false},
+		{"publisher", 5, false},
+		{"disk", 5, false},
+		// Обходные листы нумеруются заново каждый год: 2026-0001
+		{"clearance", 4, true},
+	}
+
+	for _, d := range defaults {
+		_, err := db.Exec(`
+			INSERT OR IGNORE INTO sequences (entity_type, padding, yearly_reset, last_number)
+			SELECT ?, ?, ?, COALESCE(MAX(CAST(code AS INTEGER)), 0)
+			FROM `+sequenceTables[d.entity]+` WHERE code GLOB '[0-9]*'
+		`, d.entity, d.padding, d.yearlyReset)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateReaderArchive добавляет читателям состояние (действующий или в архиве), дату и причину архивации
+func migrateReaderArchive() error {
+	columns := []struct{ name, definition string }{
+		{"status", "TEXT NOT NULL DEFAULT 'active'"},
+		{"archived_at", "DATETIME"},
+		{"archive_reason", "TEXT"},
+	}
+	for _, column := range columns {
+		if err := addColumn("readers", column.name, column.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateClassSettings добавляет классам классного руководителя и признак активности
+func migrateClassSettings() error {
+	if err := addColumn("classes", "teacher_id", "INTEGER REFERENCES readers(id)"); err != nil {
+		return err
+	}
+	return addColumn("classes", "is_active", "INTEGER NOT NULL DEFAULT 1")
+}
+
+// migrateStaffAccounts добавляет сотрудникам e-mail, признак активности и время последнего входа
+func migrateStaffAccounts() error {
+	columns := []struct{ name, definition string }{
+		{"email", "TEXT"},
+		{"is_active", "INTEGER NOT NULL DEFAULT 1"},
+		{"last_login", "DATETIME"},
+	}
+	for _, column := range columns {
+		if err := addColumn("users", column.name, column.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateBookGradeRange добавляет книгам наименьший и наибольший класс и заполняет их из class_range
+func migrateBookGradeRange() error {
+	if err := addColumn("books", "min_grade", "INTEGER"); err != nil {
+		return err
+	}
+	if err := addColumn("books", "max_grade", "INTEGER"); err != nil {
+		return err
+	}
+
+	rows, err := db.Query("SELECT id, class_range FROM books WHERE COALESCE(class_range, '') <> '' AND min_grade IS NULL")
+	if err != nil {
+		return err
+	}
+	type gradeRange struct {
+		id       int
+		min, max int
+	}
+	var ranges []gradeRange
+	for rows.Next() {
+		var id int
+		var classRange string
+		if err := rows.Scan(&id, &classRange); err != nil {
+			rows.Close()
+			return err
+		}
+		if min, max, ok := parseGradeRange(classRange); ok {
+			ranges = append(ranges, gradeRange{id, min, max})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, r := range ranges {
+		if _, err := tx.Exec("UPDATE books SET min_grade = ?, max_grade = ? WHERE id = ?", r.min, r.max, r.id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// migrateUpdatedAt добавляет читателям и классам время последнего изменения
+func migrateUpdatedAt() error {
+	if err := addColumn("readers", "updated_at", "DATETIME"); err != nil {
+		return err
+	}
+	return addColumn("classes", "updated_at", "DATETIME")
+}
+
+// migratePortalTokenVersion добавляет читателям версию токенов личного кабинета
+func migratePortalTokenVersion() error {
+	return addColumn("readers", "portal_token_version", "INTEGER NOT NULL DEFAULT 0")
+}
+
+// migrateReaderPersonalData добавляет поисковые хэши телефона и e-mail и шифрует персональные данные
+// читателей, сохраненные открытым текстом. Проверяет, что ключ подходит к уже зашифрованным данным
+func migrateReaderPersonalData() error {
+	if err := addColumn("readers", "phone_hash", "TEXT"); err != nil {
+		return err
+	}
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_readers_phone_hash ON readers(phone_hash)"); err != nil {
+		return err
+	}
+	if err := addColumn("readers", "email_hash", "TEXT"); err != nil {
+		return err
+	}
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_readers_email_hash ON readers(email_hash)"); err != nil {
+		return err
+	}
+
+	rows, err := db.Query(`
+		SELECT id, CAST(birth_date AS TEXT), COALESCE(address, ''), COALESCE(document_number, ''),
+			COALESCE(phone, ''), COALESCE(email, ''), COALESCE(parent_mother_phone, ''),
+			COALESCE(parent_father_phone, ''), COALESCE(guardian_phone, ''), COALESCE(phone_hash, ''),
+			COALESCE(email_hash, '')
+		FROM readers
+	`)
+	if err != nil {
+		return err
+	}
+
+	type storedReader struct {
+		reader    models.Reader
+		birthDate sql.NullString
+		plain     bool
+		phoneHash string
+		emailHash string
+	}
+	var readers []storedReader
+	for rows.Next() {
+		var stored storedReader
+		r := &stored.reader
+		err := rows.Scan(
+			&r.ID, &stored.birthDate, &r.Address, &r.DocumentNumber,
+			&r.Phone, &r.Email, &r.ParentMotherPhone,
+			&r.ParentFatherPhone, &r.GuardianPhone, &stored.phoneHash,
+			&stored.emailHash,
+		)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+
+		for _, value := range []string{
+			stored.birthDate.String, r.Address, r.DocumentNumber, r.Phone, r.Email,
+			r.ParentMotherPhone, r.ParentFatherPhone, r.GuardianPhone,
+		} {
+			if value != "" && !pii.IsEncrypted(value) {
+				stored.plain = true
+			}
+		}
+		readers = append(readers, stored)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stored := range readers {
+		// Расшифровка проверяет ключ: данные, зашифрованные другим ключом, не читаются
+		if err := openReader(&stored.reader, stored.birthDate); err != nil {
+			if errors.Is(err, pii.ErrNoKey) {
+				return errors.New("reader personal data is encrypted, set PII_KEY")
+			}
+			return err
+		}
+		if !pii.Enabled() {
+			continue
+		}
+
+		r := &stored.reader
+		if !stored.plain && stored.phoneHash == pii.PhoneHash(r.Phone) && stored.emailHash == pii.EmailHash(r.Email) {
+			continue
+		}
+
+		sealed, err := sealReader(r)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`
+			UPDATE readers SET birth_date = ?, address = ?, document_number = ?, phone = ?, email = ?,
+				parent_mother_phone = ?, parent_father_phone = ?, guardian_phone = ?, phone_hash = ?,
+				email_hash = ?
+			WHERE id = ?
+		`, sealed.birthDate, sealed.address, sealed.documentNumber, sealed.phone, sealed.email,
+			sealed.motherPhone, sealed.fatherPhone, sealed.guardianPhone, sealed.phoneHash,
+			sealed.emailHash, r.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"database/sql"
+	"library-management/backend/models"
+	"library-management/backend/pii"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// sealedReader — персональные данные читателя в том виде, в котором они хранятся в базе
+type sealedReader struct {
+	birthDate      interface{}
+	address        string
+	documentNumber string
+	phone          string
+	email          string
+	motherPhone    string
+	fatherPhone    string
+	guardianPhone  string
+	phoneHash      interface{}
+	emailHash      interface{}
+}
+
+// readerSecrets возвращает пары «открытое значение — хранимое значение» персональных данных читателя
+func readerSecrets(reader *models.Reader, sealed *sealedReader) [][2]*string {
+	return [][2]*string{
+		{&reader.Address, &sealed.address},
+		{&reader.DocumentNumber, &sealed.documentNumber},
+		{&reader.Phone, &sealed.phone},
+		{&reader.Email, &sealed.email},
+		{&reader.ParentMotherPhone, &sealed.motherPhone},
+		{&reader.ParentFatherPhone, &sealed.fatherPhone},
+		{&reader.GuardianPhone, &sealed.guardianPhone},
+	}
+}
+
+// sealReader шифрует персональные данные читателя перед записью.
+// Без ключа шифрования значения сохраняются как есть
+func sealReader(reader *models.Reader) (*sealedReader, error) {
+	sealed := &sealedReader{birthDate: reader.BirthDate}
+	if pii.Enabled() && reader.BirthDate != nil {
+		value, err := pii.Encrypt(reader.BirthDate.Format("2006-01-02"))
+		if err != nil {
+			return nil, err
+		}
+		sealed.birthDate = value
+	}
+
+	for _, pair := range readerSecrets(reader, sealed) {
+		value, err := pii.Encrypt(*pair[0])
+		if err != nil {
+			return nil, err
+		}
+		*pair[1] = value
+	}
+
+	if hash := pii.PhoneHash(reader.Phone); hash != "" {
+		sealed.phoneHash = hash
+	}
+	if hash := pii.EmailHash(reader.Email); hash != "" {
+		sealed.emailHash = hash
+	}
+	return sealed, nil
+}
+
+// openReader расшифровывает персональные данные, считанные из базы
+func openReader(reader *models.Reader, birthDate sql.NullString) error {
+	for _, field := range []*string{
+		&reader.Address, &reader.DocumentNumber, &reader.Phone, &reader.Email,
+		&reader.ParentMotherPhone, &reader.ParentFatherPhone, &reader.GuardianPhone,
+	} {
+		value, err := pii.Decrypt(*field)
+		if err != nil {
+			return err
+		}
+		*field = value
+	}
+
+	if birthDate.Valid && birthDate.String != "" {
+		value, err := pii.Decrypt(birthDate.String)
+		if err != nil {
+			return err
+		}
+		reader.BirthDate = parseStoredDate(value)
+	}
+	return nil
+}
+
+// parseStoredDate разбирает дату в любом из форматов, в которых ее сохраняет драйвер SQLite
+func parseStoredDate(value string) *time.Time {
+	value = strings.TrimSuffix(value, "Z")
+	for _, format := range sqlite3.SQLiteTimestampFormats {
+		if t, err := time.ParseInLocation(format, value, time.UTC); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// readerSearchCondition возвращает условие поиска читателя по фамилии, имени, штрих-коду и телефону.
+// Зашифрованный телефон ищется только точным совпадением номера по поисковому хэшу
+func readerSearchCondition(search string) (string, []interface{}) {
+	pattern := "%" + search + "%"
+	condition := "r.last_name LIKE ? OR r.first_name LIKE ? OR r.barcode LIKE ?"
+	args := []interface{}{pattern, pattern, pattern}
+
+	if !pii.Enabled() {
+		condition += " OR r.phone LIKE ?"
+		args = append(args, pattern)
+	} else if hash := pii.PhoneHash(search); hash != "" {
+		condition += " OR r.phone_hash = ?"
+		args = append(args, hash)
+	}
+
+	return " AND (" + condition + ")", args
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"encoding/base64"
+	"fmt"
+	"library-management/backend/models"
+	"library-management/backend/pii"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testPIIKey = base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+
+// withPIIKey включает шифрование персональных данных на время теста
+func withPIIKey(t *testing.T, key string) {
+	t.Helper()
+	if err := pii.Init(key); err != nil {
+		t.Fatalf("pii.Init: %v", err)
+	}
+	t.Cleanup(func() { pii.Init("") })
+}
+
+func TestReaderPersonalDataSealed(t *testing.T) {
+	withPIIKey(t, testPIIKey)
+	openTestDB(t)
+
+	birthDate := time.Date(2012, 3, 14, 0, 0, 0, 0, time.UTC)
+	reader := &models.Reader{
+		Barcode:        "R001",
+		LastName:       "Иванов",
+		FirstName:      "Петр",
+		BirthDate:      &birthDate,
+		Address:        "г. Пермь, ул. Ленина, 1",
+		DocumentNumber: "enc:not a ciphertext",
+		Phone:          "+7 (912) 345-67-89",
+		Email:          "Ivanov@Mail.ru",
+	}
+	id, err := CreateReader(reader)
+	if err != nil {
+		t.Fatalf("CreateReader: %v", err)
+	}
+
+	var stored struct{ birthDate, address, document, phone, email, phoneHash, emailHash string }
+	err = db.QueryRow(`
+		SELECT CAST(birth_date AS TEXT), address, document_number, phone, email, phone_hash, email_hash
+		FROM readers WHERE id = ?
+	`, id).Scan(&stored.birthDate, &stored.address, &stored.document, &stored.phone, &stored.email, &stored.phoneHash, &stored.emailHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range map[string]string{
+		"birth_date": stored.birthDate, "address": stored.address, "document_number": stored.document,
+		"phone": stored.phone, "email": stored.email,
+	} {
+		if !pii.IsEncrypted(value) || strings.Contains(value, "Ленина") || strings.Contains(value, "345") {
+			t.Errorf("%s stored as %q, want ciphertext", name, value)
+		}
+	}
+	if stored.phoneHash != pii.PhoneHash("89123456789") || stored.emailHash != pii.EmailHash("ivanov@mail.ru") {
+		t.Error("search hashes are not stored")
+	}
+
+	got, err := GetReaderByID(id)
+	if err != nil {
+		t.Fatalf("GetReaderByID: %v", err)
+	}
+	if got.Address != reader.Address || got.DocumentNumber != "enc:not a ciphertext" || got.Phone != reader.Phone ||
+		got.Email != reader.Email || got.BirthDate == nil || !got.BirthDate.Equal(birthDate) {
+		t.Errorf("decrypted reader = %+v", got)
+	}
+}
+
+func TestReaderSearchByPhoneHash(t *testing.T) {
+	withPIIKey(t, testPIIKey)
+	openTestDB(t)
+
+	for i, phone := range []string{"+7 (912) 345-67-89", "8 912 000-00-00"} {
+		reader := &models.Reader{Barcode: fmt.Sprintf("R%03d", i), LastName: "Читатель", FirstName: "Тест", Phone: phone}
+		if _, err := CreateReader(reader); err != nil {
+			t.Fatalf("CreateReader: %v", err)
+		}
+	}
+
+	tests := []struct {
+		search string
+		want   int
+	}{
+		{"89123456789", 1},
+		{"912 345 67 89", 1},
+		{"345-67", 0}, // зашифрованный телефон ищется только целиком
+		{"Читатель", 2},
+	}
+	for _, tt := range tests {
+		readers, total, err := GetReaders(tt.search, "", 1, 50)
+		if err != nil {
+			t.Fatalf("GetReaders(%q): %v", tt.search, err)
+		}
+		if total != tt.want || len(readers) != tt.want {
+			t.Errorf("GetReaders(%q) found %d (total %d), want %d", tt.search, len(readers), total, tt.want)
+		}
+	}
+}
+
+func TestGetReadersWrongKey(t *testing.T) {
+	withPIIKey(t, testPIIKey)
+	openTestDB(t)
+
+	if _, err := CreateReader(&models.Reader{Barcode: "R001", LastName: "Иванов", FirstName: "Петр", Phone: "89123456789"}); err != nil {
+		t.Fatalf("CreateReader: %v", err)
+	}
+
+	// С чужим ключом список не должен молча оказаться пустым
+	pii.Init(base64.StdEncoding.EncodeToString([]byte("fedcba9876543210fedcba9876543210")))
+	if _, _, err := GetReaders("", "", 1, 50); err != pii.ErrDecrypt {
+		t.Errorf("GetReaders with a wrong key: error = %v, want pii.ErrDecrypt", err)
+	}
+}
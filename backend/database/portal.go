@@ -0,0 +1,396 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"library-management/backend/models"
+	"library-management/backend/pii"
+	"time"
+)
+
+// LoanPeriodDays — срок выдачи: после него выдача считается просроченной
+const LoanPeriodDays = 30
+
+const (
+	// maxPINAttempts — число неудачных попыток входа по PIN до временной блокировки
+	maxPINAttempts = 5
+	// pinLockDuration — время блокировки входа по PIN
+	pinLockDuration = 15 * time.Minute
+	// loginCodeAttempts — число попыток ввода одного кода из письма
+	loginCodeAttempts = 5
+	// loginCodeInterval — не чаще одного письма с кодом за этот интервал
+	loginCodeInterval = time.Minute
+)
+
+var (
+	// ErrLoginCodeTooSoon — код уже отправлен недавно
+	ErrLoginCodeTooSoon = errors.New("login code was sent recently")
+	// ErrInvalidLoginCode — код неверный, просрочен или исчерпаны попытки
+	ErrInvalidLoginCode = errors.New("invalid or expired login code")
+)
+
+// ReaderCredentials — PIN читателя для входа в личный кабинет и состояние блокировки
+type ReaderCredentials struct {
+	ReaderID       int
+	PINHash        string
+	FailedAttempts int
+	LockedUntil    *time.Time
+}
+
+// GetReaderCredentials возвращает учетные данные читателя; sql.ErrNoRows — PIN не задан
+func GetReaderCredentials(readerID int) (*ReaderCredentials, error) {
+	var creds ReaderCredentials
+	err := db.QueryRow(
+		"SELECT reader_id, pin_hash, failed_attempts, locked_until FROM reader_credentials WHERE reader_id = ?",
+		readerID,
+	).Scan(&creds.ReaderID, &creds.PINHash, &creds.FailedAttempts, &creds.LockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	return &creds, nil
+}
+
+// SetReaderPIN задает или меняет PIN читателя и снимает блокировку входа
+func SetReaderPIN(readerID int, pinHash string, userID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRow("SELECT id FROM readers WHERE id = ?", readerID).Scan(&readerID); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO reader_credentials (reader_id, pin_hash, updated_by, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT (reader_id) DO UPDATE SET
+			pin_hash = excluded.pin_hash, failed_attempts = 0, locked_until = NULL,
+			updated_by = excluded.updated_by, updated_at = CURRENT_TIMESTAMP
+	`, readerID, pinHash, userID)
+	if err != nil {
+		return err
+	}
+	if err := revokePortalTokens(tx, readerID); err != nil {
+		return err
+	}
+
+	if err := WriteAudit(tx, "reader", readerID, "set_portal_pin", nil, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteReaderPIN отключает вход читателя по PIN; выданные токены кабинета перестают действовать
+func DeleteReaderPIN(readerID int, userID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM reader_credentials WHERE reader_id = ?", readerID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+	if err := revokePortalTokens(tx, readerID); err != nil {
+		return err
+	}
+
+	if err := WriteAudit(tx, "reader", readerID, "delete_portal_pin", nil, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetPortalTokenVersion возвращает версию токенов личного кабинета действующего читателя.
+// Токен с другой версией отозван; sql.ErrNoRows — читателя нет или он выбыл
+func GetPortalTokenVersion(readerID int) (int, error) {
+	var version int
+	err := db.QueryRow(
+		"SELECT portal_token_version FROM readers WHERE id = ? AND status = 'active'", readerID,
+	).Scan(&version)
+	return version, err
+}
+
+// PortalLogout закрывает личный кабинет читателя: все выданные ему токены перестают действовать
+func PortalLogout(readerID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := revokePortalTokens(tx, readerID); err != nil {
+		return err
+	}
+	if err := WriteAudit(tx, "reader", readerID, "portal_logout", nil, 0); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// revokePortalTokens увеличивает версию токенов читателя: выданные раньше токены больше не принимаются
+func revokePortalTokens(ex execer, readerID int) error {
+	_, err := ex.Exec("UPDATE readers SET portal_token_version = portal_token_version + 1 WHERE id = ?", readerID)
+	return err
+}
+
+// RecordPINFailure учитывает неудачную попытку входа по PIN. После maxPINAttempts
+// попыток подряд вход блокируется на pinLockDuration; возвращает время окончания блокировки
+func RecordPINFailure(readerID int) (*time.Time, error) {
+	var attempts int
+	err := db.QueryRow(
+		"UPDATE reader_credentials SET failed_attempts = failed_attempts + 1 WHERE reader_id = ? RETURNING failed_attempts",
+		readerID,
+	).Scan(&attempts)
+	if err != nil || attempts < maxPINAttempts {
+		return nil, err
+	}
+
+	until := time.Now().UTC().Add(pinLockDuration)
+	_, err = db.Exec(
+		"UPDATE reader_credentials SET failed_attempts = 0, locked_until = ? WHERE reader_id = ?",
+		until, readerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := WriteAudit(nil, "reader", readerID, "portal_locked", map[string]time.Time{"locked_until": until}, 0); err != nil {
+		return nil, err
+	}
+	return &until, nil
+}
+
+// RecordReaderLogin отмечает успешный вход читателя в личный кабинет
+func RecordReaderLogin(readerID int, method string) error {
+	_, err := db.Exec(`
+		UPDATE reader_credentials SET failed_attempts = 0, locked_until = NULL, last_login_at = CURRENT_TIMESTAMP
+		WHERE reader_id = ?
+	`, readerID)
+	if err != nil {
+		return err
+	}
+	return WriteAudit(nil, "reader", readerID, "portal_login", map[string]string{"method": method}, 0)
+}
+
+// FindActiveReadersByEmail возвращает действующих читателей с указанным e-mail.
+// Зашифрованный адрес ищется по поисковому хэшу
+func FindActiveReadersByEmail(email string) ([]int, error) {
+	email = pii.NormalizeEmail(email)
+	query := "SELECT id FROM readers WHERE status = 'active' AND LOWER(TRIM(email)) = ?"
+	arg := email
+	if pii.Enabled() {
+		query = "SELECT id FROM readers WHERE status = 'active' AND email_hash = ?"
+		arg = pii.EmailHash(email)
+	}
+
+	rows, err := db.Query(query, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// CreateLoginCode сохраняет хэш нового кода входа; прежние неиспользованные коды читателя
+// перестают действовать. Код выдается не чаще раза в loginCodeInterval
+func CreateLoginCode(readerID int, codeHash string, ttl time.Duration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var recent int
+	err = tx.QueryRow(
+		"SELECT COUNT(*) FROM reader_login_codes WHERE reader_id = ? AND created_at > ?",
+		readerID, time.Now().UTC().Add(-loginCodeInterval),
+	).Scan(&recent)
+	if err != nil {
+		return err
+	}
+	if recent > 0 {
+		return ErrLoginCodeTooSoon
+	}
+
+	if _, err := tx.Exec("DELETE FROM reader_login_codes WHERE reader_id = ? AND used_at IS NULL", readerID); err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		"INSERT INTO reader_login_codes (reader_id, code_hash, expires_at, created_at) VALUES (?, ?, ?, ?)",
+		readerID, codeHash, time.Now().UTC().Add(ttl), time.Now().UTC(),
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ConsumeLoginCode проверяет код входа читателя и гасит его. Каждая неверная попытка
+// учитывается; после loginCodeAttempts код больше не принимается
+func ConsumeLoginCode(readerID int, codeHash string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id, attempts int
+	var stored string
+	err = tx.QueryRow(`
+		SELECT id, code_hash, attempts FROM reader_login_codes
+		WHERE reader_id = ? AND used_at IS NULL AND expires_at > ?
+		ORDER BY id DESC LIMIT 1
+	`, readerID, time.Now().UTC()).Scan(&id, &stored, &attempts)
+	if err == sql.ErrNoRows {
+		return ErrInvalidLoginCode
+	}
+	if err != nil {
+		return err
+	}
+	if attempts >= loginCodeAttempts {
+		return ErrInvalidLoginCode
+	}
+
+	if stored != codeHash {
+		if _, err := tx.Exec("UPDATE reader_login_codes SET attempts = attempts + 1 WHERE id = ?", id); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		return ErrInvalidLoginCode
+	}
+
+	if _, err := tx.Exec("UPDATE reader_login_codes SET used_at = CURRENT_TIMESTAMP WHERE id = ?", id); err != nil {
+		return err
+	}
+	if err := WriteAudit(tx, "reader", readerID, "portal_login", map[string]string{"method": "email"}, 0); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetPortalProfile возвращает карточку действующего читателя для личного кабинета
+func GetPortalProfile(readerID int) (*models.PortalProfile, error) {
+	var p models.PortalProfile
+	var status string
+	var grade sql.NullInt64
+	var letter sql.NullString
+	err := db.QueryRow(`
+		SELECT r.id, COALESCE(r.code, ''), COALESCE(r.barcode, ''), r.last_name, r.first_name,
+			COALESCE(r.middle_name, ''), COALESCE(r.user_type, ''), r.status, c.grade, c.letter,
+			(SELECT COUNT(*) FROM loans WHERE reader_id = r.id AND status = 'active')
+				+ (SELECT COUNT(*) FROM disk_loans WHERE reader_id = r.id AND status = 'active'),
+			(SELECT COUNT(*) FROM loans WHERE reader_id = r.id AND status = 'active'
+				AND julianday('now') - julianday(issue_date) > ?)
+				+ (SELECT COUNT(*) FROM disk_loans WHERE reader_id = r.id AND status = 'active'
+				AND julianday('now') - julianday(issue_date) > ?),
+			(SELECT COUNT(*) FROM holds WHERE reader_id = r.id AND status = 'active')
+		FROM readers r
+		LEFT JOIN classes c ON r.class_id = c.id
+		WHERE r.id = ?
+	`, LoanPeriodDays, LoanPeriodDays, readerID).Scan(
+		&p.ID, &p.Code, &p.Barcode, &p.LastName, &p.FirstName,
+		&p.MiddleName, &p.UserType, &status, &grade, &letter,
+		&p.ActiveLoans, &p.Overdue, &p.ActiveHolds,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if status != "active" {
+		return nil, ErrReaderArchived
+	}
+	if grade.Valid {
+		p.ClassName = classLabel(int(grade.Int64), letter.String)
+	}
+	return &p, nil
+}
+
+// GetPortalLoans возвращает книги и диски на руках у читателя (history=false)
+// или уже возвращенные (history=true), начиная с последних
+func GetPortalLoans(readerID int, history bool) ([]models.PortalLoan, error) {
+	condition := "= 'active'"
+	if history {
+		condition = "<> 'active'"
+	}
+
+	rows, err := db.Query(`
+		SELECT 'book', l.id, b.title, COALESCE(a.short_name, ''), COALESCE(b.barcode, ''),
+			l.issue_date, l.return_date, l.status
+		FROM loans l
+		JOIN books b ON l.book_id = b.id
+		LEFT JOIN authors a ON b.author_id = a.id
+		WHERE l.reader_id = ? AND l.status `+condition+`
+		UNION ALL
+		SELECT 'disk', dl.id, d.title, '', COALESCE(d.barcode, ''),
+			dl.issue_date, dl.return_date, dl.status
+		FROM disk_loans dl
+		JOIN disks d ON dl.disk_id = d.id
+		WHERE dl.reader_id = ? AND dl.status `+condition+`
+		ORDER BY 6 DESC
+	`, readerID, readerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	loans := []models.PortalLoan{}
+	now := time.Now()
+	for rows.Next() {
+		var loan models.PortalLoan
+		if err := rows.Scan(
+			&loan.Type, &loan.ID, &loan.Title, &loan.Author, &loan.Barcode,
+			&loan.IssueDate, &loan.ReturnDate, &loan.Status,
+		); err != nil {
+			return nil, err
+		}
+		loan.DueDate = loan.IssueDate.AddDate(0, 0, LoanPeriodDays)
+		loan.Overdue = loan.Status == "active" && now.After(loan.DueDate)
+		loans = append(loans, loan)
+	}
+	return loans, rows.Err()
+}
+
+// SearchPortalBooks ищет книги для бронирования по названию и автору
+func SearchPortalBooks(search string, limit int) ([]models.PortalBook, error) {
+	pattern := "%" + search + "%"
+	rows, err := db.Query(`
+		SELECT b.id, b.title, COALESCE(a.short_name, ''), b.publication_year,
+			NOT EXISTS (SELECT 1 FROM loans l WHERE l.book_id = b.id AND l.status = 'active'),
+			(SELECT COUNT(*) FROM holds h WHERE h.book_id = b.id AND h.status = 'active')
+		FROM books b
+		LEFT JOIN authors a ON b.author_id = a.id
+		WHERE b.title LIKE ? OR a.last_name LIKE ?
+		ORDER BY b.title
+		LIMIT ?
+	`, pattern, pattern, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	books := []models.PortalBook{}
+	for rows.Next() {
+		var book models.PortalBook
+		if err := rows.Scan(&book.ID, &book.Title, &book.Author, &book.PublicationYear, &book.Available, &book.Holds); err != nil {
+			return nil, err
+		}
+		books = append(books, book)
+	}
+	return books, rows.Err()
+}
@@ -0,0 +1,618 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"library-management/backend/models"
+	"time"
+)
+
+var (
+	// ErrRolloverDebtors — у выпускников есть активные выдачи, а должников выпускать запрещено
+	ErrRolloverDebtors = errors.New("graduates have active loans")
+	// ErrRolloverExists — перевод на этот учебный год уже выполнен
+	ErrRolloverExists = errors.New("academic year already rolled over")
+	// ErrRolloverNotLatest — отменить можно только последний действующий перевод
+	ErrRolloverNotLatest = errors.New("only the latest applied rollover can be undone")
+	// ErrRolloverClassInUse — в созданные переводом классы уже записаны читатели
+	ErrRolloverClassInUse = errors.New("new classes already have readers")
+	// ErrRolloverChanged — затронутых переводом читателей или классы изменили после перевода
+	ErrRolloverChanged = errors.New("readers or classes changed after the rollover")
+	// ErrRolloverClassExists — после перевода создан класс с параллелью и литерой восстанавливаемого класса
+	ErrRolloverClassExists = errors.New("class with the same grade and letter already exists")
+)
+
+// rolloverClassState — состояние класса до перевода
+type rolloverClassState struct {
+	Grade       int    `json:"grade"`
+	Letter      string `json:"letter"`
+	TeacherName string `json:"teacher_name"`
+	TeacherID   *int   `json:"teacher_id,omitempty"`
+	Inactive    bool   `json:"inactive,omitempty"`
+}
+
+// classState возвращает сохраняемое состояние класса
+func classState(class models.Class) rolloverClassState {
+	return rolloverClassState{
+		Grade:       class.Grade,
+		Letter:      class.Letter,
+		TeacherName: class.TeacherName,
+		TeacherID:   class.TeacherID,
+		Inactive:    !class.IsActive,
+	}
+}
+
+// rolloverReaderState — состояние читателя до перевода
+type rolloverReaderState struct {
+	ClassID       *int       `json:"class_id"`
+	Grade         *int       `json:"grade"`
+	Status        string     `json:"status"`
+	ArchivedAt    *time.Time `json:"archived_at"`
+	ArchiveReason string     `json:"archive_reason"`
+}
+
+// rolloverReader — читатель, которого затрагивает перевод
+type rolloverReader struct {
+	id          int
+	name        string
+	grade       int    // параллель класса, а у читателя без класса — его собственная
+	class       string // название класса до перевода
+	activeLoans int
+	state       rolloverReaderState
+}
+
+// rolloverPlan — план перевода вместе с затрагиваемыми записями
+type rolloverPlan struct {
+	models.ClassRolloverPlan
+	promotedClasses  []models.Class
+	graduatedClasses []models.Class
+	promoted         []rolloverReader
+	graduates        []rolloverReader
+	firstLetters     []string
+}
+
+// classLabel возвращает название класса вида "5А"
+func classLabel(grade int, letter string) string {
+	return fmt.Sprintf("%d%s", grade, letter)
+}
+
+// PlanClassRollover возвращает план перевода классов, ничего не изменяя
+func PlanClassRollover(req models.ClassRolloverRequest) (*models.ClassRolloverPlan, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	plan, err := planClassRollover(tx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &plan.ClassRolloverPlan, nil
+}
+
+// planClassRollover строит план перевода: классы ниже выпускной параллели переходят
+// на класс выше, выпускные классы расформировываются, создаются новые первые классы
+func planClassRollover(tx *sql.Tx, req models.ClassRolloverRequest) (*rolloverPlan, error) {
+	plan := &rolloverPlan{ClassRolloverPlan: models.ClassRolloverPlan{
+		AcademicYear: req.AcademicYear,
+		MaxGrade:     req.MaxGrade,
+		Promoted:     []models.ClassRolloverClass{},
+		Graduated:    []models.ClassRolloverClass{},
+		NewClasses:   []string{},
+		Debtors:      []models.ClassRolloverDebtor{},
+		Flagged:      req.OnActiveLoans == "flag",
+	}}
+
+	rows, err := tx.Query(`
+		SELECT c.id, c.grade, c.letter, COALESCE(c.teacher_name, ''), c.teacher_id, c.is_active,
+			(SELECT COUNT(*) FROM readers r WHERE r.class_id = c.id AND r.status = 'active')
+		FROM classes c
+		ORDER BY c.grade, c.letter
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	var firstLetters []string
+	for rows.Next() {
+		var class models.Class
+		var readers int
+		if err := rows.Scan(&class.ID, &class.Grade, &class.Letter, &class.TeacherName, &class.TeacherID, &class.IsActive, &readers); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		change := models.ClassRolloverClass{
+			ClassID: class.ID,
+			From:    classLabel(class.Grade, class.Letter),
+			Readers: readers,
+		}
+		if class.Grade >= req.MaxGrade {
+			plan.graduatedClasses = append(plan.graduatedClasses, class)
+			plan.Graduated = append(plan.Graduated, change)
+			continue
+		}
+
+		change.To = classLabel(class.Grade+1, class.Letter)
+		plan.promotedClasses = append(plan.promotedClasses, class)
+		plan.Promoted = append(plan.Promoted, change)
+		if class.Grade == 1 {
+			firstLetters = append(firstLetters, class.Letter)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	plan.firstLetters = req.FirstGradeLetters
+	if plan.firstLetters == nil {
+		plan.firstLetters = firstLetters
+	}
+	for _, letter := range plan.firstLetters {
+		plan.NewClasses = append(plan.NewClasses, classLabel(1, letter))
+	}
+
+	// Ученики без класса с указанной параллелью переводятся и выпускаются по параллели
+	readers, err := queryRolloverReaders(tx, `
+		SELECT r.id, r.last_name || ' ' || r.first_name, r.class_id, r.grade, r.status,
+			r.archived_at, COALESCE(r.archive_reason, ''), COALESCE(c.grade, r.grade), COALESCE(c.letter, ''),
+			(SELECT COUNT(*) FROM loans WHERE reader_id = r.id AND status = 'active') +
+			(SELECT COUNT(*) FROM disk_loans WHERE reader_id = r.id AND status = 'active')
+		FROM readers r
+		LEFT JOIN classes c ON r.class_id = c.id
+		WHERE c.id IS NOT NULL
+			OR (r.class_id IS NULL AND r.grade IS NOT NULL AND r.status = 'active' AND r.user_type = 'student')
+		ORDER BY r.last_name, r.first_name, r.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, reader := range readers {
+		if reader.grade >= req.MaxGrade {
+			plan.graduates = append(plan.graduates, reader)
+			if reader.state.Status != "active" {
+				continue
+			}
+			if reader.activeLoans > 0 {
+				plan.Debtors = append(plan.Debtors, models.ClassRolloverDebtor{
+					ReaderID:    reader.id,
+					Name:        reader.name,
+					Class:       reader.class,
+					ActiveLoans: reader.activeLoans,
+				})
+				continue
+			}
+			plan.GraduatedReaders++
+			continue
+		}
+
+		plan.promoted = append(plan.promoted, reader)
+		if reader.state.Status == "active" {
+			plan.PromotedReaders++
+		}
+	}
+
+	return plan, nil
+}
+
+// queryRolloverReaders выбирает читателей, затрагиваемых переводом
+func queryRolloverReaders(tx *sql.Tx, query string) ([]rolloverReader, error) {
+	rows, err := tx.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var readers []rolloverReader
+	for rows.Next() {
+		var reader rolloverReader
+		var archivedAt sql.NullTime
+		var letter string
+		err := rows.Scan(
+			&reader.id, &reader.name, &reader.state.ClassID, &reader.state.Grade, &reader.state.Status,
+			&archivedAt, &reader.state.ArchiveReason, &reader.grade, &letter, &reader.activeLoans,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if archivedAt.Valid {
+			reader.state.ArchivedAt = &archivedAt.Time
+		}
+		reader.class = classLabel(reader.grade, letter)
+		readers = append(readers, reader)
+	}
+	return readers, rows.Err()
+}
+
+// ApplyClassRollover переводит классы на новый учебный год в одной транзакции.
+// Каждое изменение записывается с прежним значением, чтобы перевод можно было отменить.
+// Если у выпускников есть активные выдачи и должников выпускать нельзя, возвращает план и ErrRolloverDebtors
+func ApplyClassRollover(req models.ClassRolloverRequest, userID int) (*models.ClassRollover, *models.ClassRolloverPlan, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	var count int
+	err = tx.QueryRow(
+		"SELECT COUNT(*) FROM class_rollovers WHERE academic_year = ? AND status = 'applied'",
+		req.AcademicYear,
+	).Scan(&count)
+	if err != nil {
+		return nil, nil, err
+	}
+	if count > 0 {
+		return nil, nil, ErrRolloverExists
+	}
+
+	plan, err := planClassRollover(tx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(plan.Debtors) > 0 && !plan.Flagged {
+		return nil, &plan.ClassRolloverPlan, ErrRolloverDebtors
+	}
+
+	var user interface{}
+	if userID > 0 {
+		user = userID
+	}
+	result, err := tx.Exec(
+		"INSERT INTO class_rollovers (academic_year, max_grade, created_by) VALUES (?, ?, ?)",
+		req.AcademicYear, req.MaxGrade, user,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	id64, err := result.LastInsertId()
+	if err != nil {
+		return nil, nil, err
+	}
+	rolloverID := int(id64)
+
+	record := func(entityType string, entityID int, action string, previous interface{}) error {
+		data, err := json.Marshal(previous)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(
+			"INSERT INTO class_rollover_changes (rollover_id, entity_type, entity_id, action, previous) VALUES (?, ?, ?, ?, ?)",
+			rolloverID, entityType, entityID, action, string(data),
+		)
+		return err
+	}
+
+	// Выпускники покидают класс; выпускники без долгов переводятся в архив
+	reason := "Выпуск " + req.AcademicYear[:4]
+	now := time.Now()
+	for _, reader := range plan.graduates {
+		action := "graduated"
+		query := "UPDATE readers SET class_id = NULL, status = 'archived', archived_at = ?, archive_reason = ?, portal_token_version = portal_token_version + 1 WHERE id = ?"
+		args := []interface{}{now, reason + " (" + reader.class + ")", reader.id}
+		switch {
+		case reader.state.Status != "active":
+			query, args = "UPDATE readers SET class_id = NULL WHERE id = ?", []interface{}{reader.id}
+		case reader.activeLoans > 0:
+			action = "flagged"
+			query, args = "UPDATE readers SET class_id = NULL WHERE id = ?", []interface{}{reader.id}
+		}
+		if _, err := tx.Exec(query, args...); err != nil {
+			return nil, nil, err
+		}
+		if err := record("reader", reader.id, action, reader.state); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, reader := range plan.promoted {
+		if _, err := tx.Exec("UPDATE readers SET grade = ? WHERE id = ?", reader.grade+1, reader.id); err != nil {
+			return nil, nil, err
+		}
+		if err := record("reader", reader.id, "promoted", reader.state); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Выпускные классы удаляются до повышения остальных, чтобы не нарушить уникальность параллели и литеры
+	for _, class := range plan.graduatedClasses {
+		if _, err := tx.Exec("DELETE FROM classes WHERE id = ?", class.ID); err != nil {
+			return nil, nil, err
+		}
+		if err := record("class", class.ID, "removed", classState(class)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	grades := map[int]int{}
+	for _, class := range plan.promotedClasses {
+		grades[class.ID] = class.Grade + 1
+		if err := record("class", class.ID, "promoted", classState(class)); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := setClassGrades(tx, grades); err != nil {
+		return nil, nil, err
+	}
+
+	for _, letter := range plan.firstLetters {
+		result, err := tx.Exec("INSERT INTO classes (grade, letter) VALUES (1, ?)", letter)
+		if err != nil {
+			return nil, nil, err
+		}
+		classID, err := result.LastInsertId()
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := record("class", int(classID), "created", nil); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	summary, err := json.Marshal(plan.ClassRolloverPlan)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := tx.Exec("UPDATE class_rollovers SET summary = ? WHERE id = ?", string(summary), rolloverID); err != nil {
+		return nil, nil, err
+	}
+
+	details := map[string]interface{}{
+		"academic_year":     req.AcademicYear,
+		"promoted_readers":  plan.PromotedReaders,
+		"graduated_readers": plan.GraduatedReaders,
+		"debtors":           len(plan.Debtors),
+	}
+	if err := WriteAudit(tx, "class_rollover", rolloverID, "apply", details, userID); err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+
+	rollover, err := GetClassRollover(rolloverID)
+	return rollover, nil, err
+}
+
+// setClassGrades меняет параллели классов. Сначала параллели временно делаются отрицательными:
+// иначе "5А" → "6А" нарушит уникальность, пока прежний "6А" еще не переведен
+func setClassGrades(tx *sql.Tx, grades map[int]int) error {
+	for id, grade := range grades {
+		if _, err := tx.Exec("UPDATE classes SET grade = ? WHERE id = ?", -grade, id); err != nil {
+			return err
+		}
+	}
+	_, err := tx.Exec("UPDATE classes SET grade = -grade WHERE grade < 0")
+	return err
+}
+
+// UndoClassRollover отменяет последний действующий перевод классов: удаляет созданные классы,
+// возвращает прежние параллели, восстанавливает выпускные классы и прежнее состояние читателей.
+// Если затронутых читателей или классы изменили после перевода, отмена затерла бы эти изменения:
+// возвращается ErrRolloverChanged
+func UndoClassRollover(id, userID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	if err := tx.QueryRow("SELECT status FROM class_rollovers WHERE id = ?", id).Scan(&status); err != nil {
+		return err
+	}
+	var later int
+	err = tx.QueryRow(
+		"SELECT COUNT(*) FROM class_rollovers WHERE id > ? AND status = 'applied'", id,
+	).Scan(&later)
+	if err != nil {
+		return err
+	}
+	if status != "applied" || later > 0 {
+		return ErrRolloverNotLatest
+	}
+
+	// Перевод не меняет updated_at, поэтому более позднее время означает правку вручную
+	var changed int
+	err = tx.QueryRow(`
+		SELECT COUNT(*)
+		FROM class_rollover_changes ch
+		JOIN class_rollovers cr ON ch.rollover_id = cr.id
+		LEFT JOIN readers r ON ch.entity_type = 'reader' AND r.id = ch.entity_id
+		LEFT JOIN classes c ON ch.entity_type = 'class' AND c.id = ch.entity_id
+		WHERE ch.rollover_id = ? AND COALESCE(r.updated_at, c.updated_at) >= cr.created_at
+	`, id).Scan(&changed)
+	if err != nil {
+		return err
+	}
+	if changed > 0 {
+		return ErrRolloverChanged
+	}
+
+	rows, err := tx.Query(`
+		SELECT entity_type, entity_id, action, COALESCE(previous, 'null')
+		FROM class_rollover_changes
+		WHERE rollover_id = ?
+		ORDER BY id DESC
+	`, id)
+	if err != nil {
+		return err
+	}
+	type change struct {
+		entityType, action string
+		entityID           int
+		previous           string
+	}
+	var changes []change
+	for rows.Next() {
+		var ch change
+		if err := rows.Scan(&ch.entityType, &ch.entityID, &ch.action, &ch.previous); err != nil {
+			rows.Close()
+			return err
+		}
+		changes = append(changes, ch)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Изменения отменяются по этапам: созданные классы удаляются, параллели понижаются,
+	// затем восстанавливаются выпускные классы и только после них — читатели
+	grades := map[int]int{}
+	var restored []rolloverClassState
+	for _, ch := range changes {
+		if ch.entityType != "class" {
+			continue
+		}
+		switch ch.action {
+		case "created":
+			var readers int
+			if err := tx.QueryRow("SELECT COUNT(*) FROM readers WHERE class_id = ?", ch.entityID).Scan(&readers); err != nil {
+				return err
+			}
+			if readers > 0 {
+				return ErrRolloverClassInUse
+			}
+			if _, err := tx.Exec("DELETE FROM classes WHERE id = ?", ch.entityID); err != nil {
+				return err
+			}
+		case "promoted":
+			var state rolloverClassState
+			if err := json.Unmarshal([]byte(ch.previous), &state); err != nil {
+				return err
+			}
+			grades[ch.entityID] = state.Grade
+			restored = append(restored, state)
+		case "removed":
+			var state rolloverClassState
+			if err := json.Unmarshal([]byte(ch.previous), &state); err != nil {
+				return err
+			}
+			restored = append(restored, state)
+		}
+	}
+
+	// Место восстанавливаемого класса мог занять класс, созданный после перевода
+	for _, state := range restored {
+		var classID int
+		err := tx.QueryRow("SELECT id FROM classes WHERE grade = ? AND letter = ?", state.Grade, state.Letter).Scan(&classID)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if _, moved := grades[classID]; !moved {
+			return ErrRolloverClassExists
+		}
+	}
+
+	if err := setClassGrades(tx, grades); err != nil {
+		return err
+	}
+
+	for _, ch := range changes {
+		switch {
+		case ch.entityType == "class" && ch.action == "removed":
+			var state rolloverClassState
+			if err := json.Unmarshal([]byte(ch.previous), &state); err != nil {
+				return err
+			}
+			_, err := tx.Exec(
+				"INSERT INTO classes (id, grade, letter, teacher_name, teacher_id, is_active) VALUES (?, ?, ?, ?, ?, ?)",
+				ch.entityID, state.Grade, state.Letter, state.TeacherName, state.TeacherID, !state.Inactive,
+			)
+			if err != nil {
+				return err
+			}
+		case ch.entityType == "reader":
+			var state rolloverReaderState
+			if err := json.Unmarshal([]byte(ch.previous), &state); err != nil {
+				return err
+			}
+			_, err := tx.Exec(
+				"UPDATE readers SET class_id = ?, grade = ?, status = ?, archived_at = ?, archive_reason = ? WHERE id = ?",
+				state.ClassID, state.Grade, state.Status, state.ArchivedAt, state.ArchiveReason, ch.entityID,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	var user interface{}
+	if userID > 0 {
+		user = userID
+	}
+	if _, err := tx.Exec(
+		"UPDATE class_rollovers SET status = 'undone', undone_by = ?, undone_at = CURRENT_TIMESTAMP WHERE id = ?",
+		user, id,
+	); err != nil {
+		return err
+	}
+
+	if err := WriteAudit(tx, "class_rollover", id, "undo", nil, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetClassRollovers возвращает выполненные переводы классов, последние первыми
+func GetClassRollovers() ([]models.ClassRollover, error) {
+	rows, err := db.Query(`
+		SELECT id, academic_year, status, COALESCE(summary, '{}'), COALESCE(created_by, 0), created_at, undone_by, undone_at
+		FROM class_rollovers
+		ORDER BY id DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rollovers := []models.ClassRollover{}
+	for rows.Next() {
+		rollover, err := scanClassRollover(rows)
+		if err != nil {
+			return nil, err
+		}
+		rollovers = append(rollovers, *rollover)
+	}
+	return rollovers, rows.Err()
+}
+
+// GetClassRollover возвращает перевод классов по ID
+func GetClassRollover(id int) (*models.ClassRollover, error) {
+	return scanClassRollover(db.QueryRow(`
+		SELECT id, academic_year, status, COALESCE(summary, '{}'), COALESCE(created_by, 0), created_at, undone_by, undone_at
+		FROM class_rollovers WHERE id = ?
+	`, id))
+}
+
+// scanClassRollover считывает перевод классов
+func scanClassRollover(row rowScanner) (*models.ClassRollover, error) {
+	var rollover models.ClassRollover
+	var summary string
+	var undoneAt sql.NullTime
+	err := row.Scan(
+		&rollover.ID, &rollover.AcademicYear, &rollover.Status, &summary,
+		&rollover.CreatedBy, &rollover.CreatedAt, &rollover.UndoneBy, &undoneAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if undoneAt.Valid {
+		rollover.UndoneAt = &undoneAt.Time
+	}
+	if err := json.Unmarshal([]byte(summary), &rollover.Summary); err != nil {
+		return nil, err
+	}
+	return &rollover, nil
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"library-management/backend/models"
+	"testing"
+)
+
+// setupRollover создает 10А и 11А с учениками и переводит их на новый учебный год.
+// Возвращает ID перевода и ID ученика 10А
+func setupRollover(t *testing.T) (int, int) {
+	t.Helper()
+	openTestDB(t)
+
+	var studentID int
+	for i, grade := range []int{10, 11} {
+		classID, err := CreateClass(&models.Class{Grade: grade, Letter: "А"})
+		if err != nil {
+			t.Fatalf("CreateClass: %v", err)
+		}
+		g := grade
+		id, err := CreateReader(&models.Reader{
+			Barcode: []string{"R010", "R011"}[i], LastName: "Ученик", FirstName: "Тест",
+			UserType: "student", ClassID: &classID, Grade: &g,
+		})
+		if err != nil {
+			t.Fatalf("CreateReader: %v", err)
+		}
+		if grade == 10 {
+			studentID = id
+		}
+	}
+
+	rollover, _, err := ApplyClassRollover(models.ClassRolloverRequest{
+		AcademicYear: "2026/2027", MaxGrade: 11, OnActiveLoans: "refuse",
+	}, 0)
+	if err != nil {
+		t.Fatalf("ApplyClassRollover: %v", err)
+	}
+	return rollover.ID, studentID
+}
+
+func TestUndoClassRollover(t *testing.T) {
+	rolloverID, studentID := setupRollover(t)
+
+	if err := UndoClassRollover(rolloverID, 0); err != nil {
+		t.Fatalf("UndoClassRollover: %v", err)
+	}
+	reader, err := GetReaderByID(studentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reader.Grade == nil || *reader.Grade != 10 {
+		t.Errorf("grade after undo = %v, want 10", reader.Grade)
+	}
+	if exists, err := ClassExists(11, "А", 0); err != nil || !exists {
+		t.Errorf("graduating class not restored: %v, %v", exists, err)
+	}
+}
+
+func TestUndoClassRolloverRefuses(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, studentID int)
+		want   error
+	}{
+		{"reader edited", func(t *testing.T, studentID int) {
+			reader, err := GetReaderByID(studentID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reader.Comments = "Перешел в другую школу"
+			if err := UpdateReader(reader); err != nil {
+				t.Fatal(err)
+			}
+		}, ErrRolloverChanged},
+		{"class edited", func(t *testing.T, studentID int) {
+			reader, err := GetReaderByID(studentID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			class, err := GetClass(*reader.ClassID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			class.TeacherName = "Петрова А. А."
+			if err := UpdateClass(class); err != nil {
+				t.Fatal(err)
+			}
+		}, ErrRolloverChanged},
+		{"class created in place of a restored one", func(t *testing.T, studentID int) {
+			if _, err := CreateClass(&models.Class{Grade: 10, Letter: "А"}); err != nil {
+				t.Fatal(err)
+			}
+		}, ErrRolloverClassExists},
+	}
+	for _, tt := range tests {
+		rolloverID, studentID := setupRollover(t)
+		tt.change(t, studentID)
+
+		if err := UndoClassRollover(rolloverID, 0); err != tt.want {
+			t.Errorf("%s: UndoClassRollover error = %v, want %v", tt.name, err, tt.want)
+		}
+		// Отказ ничего не меняет: перевод остается действующим
+		rollover, err := GetClassRollover(rolloverID)
+		if err != nil || rollover.Status != "applied" {
+			t.Errorf("%s: rollover after refusal = %+v, %v", tt.name, rollover, err)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"library-management/backend/models"
+	"strings"
+	"time"
+)
+
+// ErrCodeExists — код уже присвоен другой записи того же типа
+var ErrCodeExists = errors.New("code already exists")
+
+// sequenceTables — таблицы записей, коды которых выдаются из последовательностей
+var sequenceTables = map[string]string{
+	"book":      "books",
+	"reader":    "readers",
+	"author":    "authors",
+	"publisher": "publishers",
+	"disk":      "disks",
+	"clearance": "clearances",
+}
+
+// SequenceEntities — типы записей с последовательностями в порядке вывода
+var SequenceEntities = []string{"book", "reader", "author", "publisher", "disk", "clearance"}
+
+// maxCodeAttempts ограничивает пропуск кодов, уже занятых вручную введенными значениями
+const maxCodeAttempts = 1000
+
+// FormatSequenceCode формирует код: префикс, год с разделителем (при ежегодном сбросе)
+// и номер, дополненный нулями, например "2026-00042"
+func FormatSequenceCode(s *models.Sequence, year, n int) string {
+	var b strings.Builder
+	b.WriteString(s.Prefix)
+	if s.YearlyReset {
+		fmt.Fprintf(&b, "%d%s", year, s.Separator)
+	}
+	fmt.Fprintf(&b, "%0*d", s.Padding, n)
+	return b.String()
+}
+
+// fillSequence вычисляет следующий номер и пример кода на текущий год
+func fillSequence(s *models.Sequence) {
+	year := time.Now().Year()
+	s.NextNumber = s.LastNumber + 1
+	if s.YearlyReset && s.LastYear != year {
+		s.NextNumber = 1
+	}
+	s.Sample = FormatSequenceCode(s, year, s.NextNumber)
+}
+
+const sequenceColumns = `
+	entity_type, prefix, padding, yearly_reset, separator, last_number, last_year, updated_at
+`
+
+// scanSequence считывает последовательность из строки результата
+func scanSequence(row rowScanner) (*models.Sequence, error) {
+	var s models.Sequence
+	if err := row.Scan(
+		&s.EntityType, &s.Prefix, &s.Padding, &s.YearlyReset, &s.Separator,
+		&s.LastNumber, &s.LastYear, &s.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	fillSequence(&s)
+	return &s, nil
+}
+
+// GetSequences возвращает последовательности кодов всех типов записей
+func GetSequences() ([]models.Sequence, error) {
+	result := []models.Sequence{}
+	for _, entity := range SequenceEntities {
+		s, err := GetSequence(entity)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *s)
+	}
+	return result, nil
+}
+
+// GetSequence возвращает последовательность кодов для типа записей
+func GetSequence(entityType string) (*models.Sequence, error) {
+	return scanSequence(db.QueryRow(
+		"SELECT "+sequenceColumns+" FROM sequences WHERE entity_type = ?", entityType,
+	))
+}
+
+// UpdateSequence меняет формат последовательности. Номер, указанный в NextNumber,
+// будет выдан следующим; при ежегодном сбросе он относится к текущему году
+func UpdateSequence(s *models.Sequence) error {
+	_, err := db.Exec(`
+		UPDATE sequences SET
+			prefix = ?, padding = ?, yearly_reset = ?, separator = ?,
+			last_number = ?, last_year = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE entity_type = ?
+	`, s.Prefix, s.Padding, s.YearlyReset, s.Separator,
+		s.NextNumber-1, time.Now().Year(), s.EntityType)
+	return err
+}
+
+// assignCode проверяет код записи внутри транзакции вставки. Пустой код выдается
+// из последовательности, введенный вручную — проверяется на уникальность
+func assignCode(tx *sql.Tx, entityType string, code *string) error {
+	table := sequenceTables[entityType]
+
+	if *code != "" {
+		exists, err := codeExists(tx, table, *code)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrCodeExists
+		}
+		return nil
+	}
+
+	// Сначала обновляем счетчик, чтобы сразу получить блокировку записи:
+	// параллельная транзакция дождется фиксации и получит следующий номер
+	year := time.Now().Year()
+	for attempt := 0; attempt < maxCodeAttempts; attempt++ {
+		if _, err := tx.Exec(`
+			UPDATE sequences SET
+				last_number = CASE WHEN yearly_reset AND last_year <> ? THEN 1 ELSE last_number + 1 END,
+				last_year = ?
+			WHERE entity_type = ?
+		`, year, year, entityType); err != nil {
+			return err
+		}
+
+		s, err := scanSequence(tx.QueryRow(
+			"SELECT "+sequenceColumns+" FROM sequences WHERE entity_type = ?", entityType,
+		))
+		if err != nil {
+			return err
+		}
+
+		candidate := FormatSequenceCode(s, year, s.LastNumber)
+		exists, err := codeExists(tx, table, candidate)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			*code = candidate
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no free %s code after %d attempts", entityType, maxCodeAttempts)
+}
+
+// codeExists проверяет, занят ли код в таблице
+func codeExists(tx *sql.Tx, table, code string) (bool, error) {
+	var count int
+	err := tx.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE code = ?", code).Scan(&count)
+	return count > 0, err
+}
@@ -0,0 +1,329 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"library-management/backend/models"
+)
+
+// GetSeries возвращает список серий с количеством книг
+func GetSeries() ([]models.Series, error) {
+	query := `
+		SELECT s.id, s.title, COALESCE(s.kind, 'series'), COALESCE(s.comments, ''),
+			s.created_at, COUNT(b.id) as book_count
+		FROM series s
+		LEFT JOIN books b ON s.id = b.series_id
+		GROUP BY s.id
+		ORDER BY s.title
+	`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	series := []models.Series{}
+	for rows.Next() {
+		var item models.Series
+		err := rows.Scan(
+			&item.ID, &item.Title, &item.Kind, &item.Comments,
+			&item.CreatedAt, &item.BookCount,
+		)
+		if err != nil {
+			continue
+		}
+		series = append(series, item)
+	}
+
+	return series, nil
+}
+
+// GetSeriesByID возвращает серию по ID
+func GetSeriesByID(id int) (*models.Series, error) {
+	query := `
+		SELECT s.id, s.title, COALESCE(s.kind, 'series'), COALESCE(s.comments, ''),
+			s.created_at, (SELECT COUNT(*) FROM books WHERE series_id = s.id)
+		FROM series s
+		WHERE s.id = ?
+	`
+
+	var item models.Series
+	err := db.QueryRow(query, id).Scan(
+		&item.ID, &item.Title, &item.Kind, &item.Comments,
+		&item.CreatedAt, &item.BookCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+// CreateSeries создает новую серию
+func CreateSeries(series *models.Series) (int, error) {
+	result, err := db.Exec(
+		"INSERT INTO series (title, kind, comments) VALUES (?, ?, ?)",
+		series.Title, series.Kind, series.Comments,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// UpdateSeries обновляет серию
+func UpdateSeries(series *models.Series) error {
+	_, err := db.Exec(
+		"UPDATE series SET title = ?, kind = ?, comments = ? WHERE id = ?",
+		series.Title, series.Kind, series.Comments, series.ID,
+	)
+	return err
+}
+
+// DeleteSeries удаляет серию
+func DeleteSeries(id int) error {
+	_, err := db.Exec("DELETE FROM series WHERE id = ?", id)
+	return err
+}
+
+// SeriesHasBooks проверяет, есть ли книги в серии
+func SeriesHasBooks(seriesID int) (bool, error) {
+	var count int
+	err := db.QueryRow(
+		"SELECT COUNT(*) FROM books WHERE series_id = ?",
+		seriesID,
+	).Scan(&count)
+
+	return count > 0, err
+}
+
+// SetBookSeries включает книгу в серию с номером тома и названием части или исключает из нее
+func SetBookSeries(bookID int, seriesID, volumeNumber *int, partTitle string) error {
+	_, err := db.Exec(
+		"UPDATE books SET series_id = ?, volume_number = ?, part_title = ? WHERE id = ?",
+		seriesID, volumeNumber, partTitle, bookID,
+	)
+	return err
+}
+
+// GetWorkByID возвращает произведение по ID
+func GetWorkByID(id int) (*models.Work, error) {
+	var work models.Work
+	err := db.QueryRow(
+		"SELECT id, title, author_id, created_at FROM works WHERE id = ?",
+		id,
+	).Scan(&work.ID, &work.Title, &work.AuthorID, &work.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &work, nil
+}
+
+// LinkBookEditions связывает две книги как издания одного произведения.
+// Если ни одна из книг еще не относится к произведению, оно создается по первой книге;
+// если книги относятся к разным произведениям, они объединяются
+func LinkBookEditions(bookID, otherID int) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var title string
+	var authorID, workID, otherWorkID *int
+	err = tx.QueryRow(
+		"SELECT title, author_id, work_id FROM books WHERE id = ?", bookID,
+	).Scan(&title, &authorID, &workID)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.QueryRow("SELECT work_id FROM books WHERE id = ?", otherID).Scan(&otherWorkID); err != nil {
+		return 0, err
+	}
+
+	var targetID int
+	switch {
+	case workID != nil:
+		targetID = *workID
+	case otherWorkID != nil:
+		targetID = *otherWorkID
+	default:
+		result, err := tx.Exec("INSERT INTO works (title, author_id) VALUES (?, ?)", title, authorID)
+		if err != nil {
+			return 0, err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+		targetID = int(id)
+	}
+
+	// Переносим все издания второго произведения в выбранное
+	if otherWorkID != nil && *otherWorkID != targetID {
+		if _, err := tx.Exec("UPDATE books SET work_id = ? WHERE work_id = ?", targetID, *otherWorkID); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec("DELETE FROM works WHERE id = ?", *otherWorkID); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE books SET work_id = ? WHERE id IN (?, ?)", targetID, bookID, otherID); err != nil {
+		return 0, err
+	}
+
+	return targetID, tx.Commit()
+}
+
+// UnlinkBookEdition исключает книгу из произведения; произведение без изданий удаляется
+func UnlinkBookEdition(bookID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var workID *int
+	if err := tx.QueryRow("SELECT work_id FROM books WHERE id = ?", bookID).Scan(&workID); err != nil {
+		return err
+	}
+	if workID == nil {
+		return nil
+	}
+
+	if _, err := tx.Exec("UPDATE books SET work_id = NULL WHERE id = ?", bookID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		"DELETE FROM works WHERE id = ? AND NOT EXISTS (SELECT 1 FROM books WHERE work_id = ?)",
+		*workID, *workID,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetRelatedBooks возвращает другие тома серии и другие издания произведения,
+// сгруппированные с количеством доступных экземпляров
+func GetRelatedBooks(bookID int) (*models.RelatedBooks, error) {
+	book, err := GetBookByID(bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	related := &models.RelatedBooks{
+		Volumes:  []models.RelatedBookGroup{},
+		Editions: []models.RelatedBookGroup{},
+	}
+
+	if book.SeriesID != nil {
+		related.Series, err = GetSeriesByID(*book.SeriesID)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+
+		books, err := queryBooks(" WHERE b.series_id = ? ORDER BY b.volume_number IS NULL, b.volume_number, b.title, b.code", *book.SeriesID)
+		if err != nil {
+			return nil, err
+		}
+
+		related.Volumes = groupRelatedBooks(book, books, func(b *models.Book) string {
+			return fmt.Sprintf("%v|%s|%s", intValue(b.VolumeNumber), b.PartTitle, b.Title)
+		})
+	}
+
+	if book.WorkID != nil {
+		related.Work, err = GetWorkByID(*book.WorkID)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+
+		books, err := queryBooks(" WHERE b.work_id = ? ORDER BY b.publication_year DESC, b.code", *book.WorkID)
+		if err != nil {
+			return nil, err
+		}
+
+		related.Editions = groupRelatedBooks(book, books, func(b *models.Book) string {
+			return fmt.Sprintf("%v|%v|%s", intValue(b.PublicationYear), intValue(b.PublisherID), b.Title)
+		})
+	}
+
+	return related, nil
+}
+
+// queryBooks выбирает книги через bookColumns с дополнительным условием
+func queryBooks(condition string, args ...interface{}) ([]*models.Book, error) {
+	rows, err := db.Query("SELECT "+bookColumns+bookJoins+condition, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var books []*models.Book
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			return nil, err
+		}
+		books = append(books, book)
+	}
+
+	return books, rows.Err()
+}
+
+// groupRelatedBooks объединяет экземпляры в группы по ключу, исключая группу самой книги
+func groupRelatedBooks(current *models.Book, books []*models.Book, key func(*models.Book) string) []models.RelatedBookGroup {
+	currentKey := key(current)
+	groups := []models.RelatedBookGroup{}
+	index := map[string]int{}
+
+	for _, book := range books {
+		k := key(book)
+		if k == currentKey {
+			continue
+		}
+
+		i, ok := index[k]
+		if !ok {
+			i = len(groups)
+			index[k] = i
+			groups = append(groups, models.RelatedBookGroup{
+				Title:           book.Title,
+				VolumeNumber:    book.VolumeNumber,
+				PartTitle:       book.PartTitle,
+				PublicationYear: book.PublicationYear,
+				Publisher:       book.Publisher,
+				BookIDs:         []int{},
+			})
+		}
+
+		group := &groups[i]
+		group.BookIDs = append(group.BookIDs, book.ID)
+		group.TotalCopies++
+		if book.IsAvailable {
+			group.AvailableCopies++
+			if group.AvailableBarcode == "" {
+				group.AvailableBarcode = book.Barcode
+			}
+		}
+	}
+
+	return groups
+}
+
+// intValue возвращает значение необязательного числа или nil
+func intValue(value *int) interface{} {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
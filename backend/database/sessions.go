@@ -0,0 +1,309 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"library-management/backend/models"
+	"time"
+)
+
+// sessionRetention — сколько хранить истекшие и отозванные сессии для истории входов
+const sessionRetention = 30 * 24 * time.Hour
+
+// ErrSessionReuse — предъявлен уже замененный refresh-токен: им воспользовался кто-то другой,
+// поэтому сессия отозвана целиком
+var ErrSessionReuse = errors.New("refresh token has already been used")
+
+// SessionUser — сотрудник действующей сессии с текущими именем и ролью
+type SessionUser struct {
+	SessionID int
+	UserID    int
+	Username  string
+	Role      string
+}
+
+// sessionColumns — столбцы сессии с именем сотрудника
+const sessionColumns = `
+	s.id, s.user_id, u.username, COALESCE(s.user_agent, ''), COALESCE(s.ip, ''),
+	s.created_at, s.last_used_at, s.expires_at, s.revoked_at, COALESCE(s.revoke_reason, '')
+	FROM sessions s
+	JOIN users u ON s.user_id = u.id
+`
+
+// scanSession считывает сессию, выбранную через sessionColumns
+func scanSession(row rowScanner) (*models.Session, error) {
+	var s models.Session
+	err := row.Scan(
+		&s.ID, &s.UserID, &s.Username, &s.UserAgent, &s.IP,
+		&s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt, &s.RevokedAt, &s.RevokeReason,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// CreateSession открывает сессию сотрудника после входа и записывает вход в журнал.
+// Заодно удаляются сессии, закончившиеся раньше sessionRetention
+func CreateSession(userID int, refreshHash, userAgent, ip string, ttl time.Duration) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	if _, err := tx.Exec(
+		"DELETE FROM sessions WHERE expires_at < ? OR revoked_at < ?",
+		now.Add(-sessionRetention), now.Add(-sessionRetention),
+	); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO sessions (user_id, refresh_hash, user_agent, ip, created_at, last_used_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, userID, refreshHash, userAgent, ip, now, now, now.Add(ttl))
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	details := map[string]interface{}{"session_id": id, "ip": ip}
+	if err := WriteAudit(tx, "user", userID, "login", details, userID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("UPDATE users SET last_login = ? WHERE id = ?", now, userID); err != nil {
+		return 0, err
+	}
+	return int(id), tx.Commit()
+}
+
+// RotateSession заменяет refresh-токен сессии новым и продлевает ее на ttl.
+// Возвращает ID сессии и сотрудника. Повторно предъявленный старый токен отзывает сессию
+// (ErrSessionReuse); неизвестный, истекший или отозванный — sql.ErrNoRows. Сессия
+// отключенного сотрудника отзывается, тоже с sql.ErrNoRows
+func RotateSession(refreshHash, newHash string, ttl time.Duration) (int, *models.User, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	var id, userID int
+	err = tx.QueryRow(
+		"SELECT id, user_id FROM sessions WHERE refresh_hash = ? AND revoked_at IS NULL AND expires_at > ?",
+		refreshHash, now,
+	).Scan(&id, &userID)
+	if err == sql.ErrNoRows {
+		return 0, nil, revokeReusedSession(tx, refreshHash)
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	user, err := scanUser(tx.QueryRow("SELECT "+userColumns+" WHERE id = ?", userID))
+	if err != nil {
+		return 0, nil, err
+	}
+	if !user.IsActive {
+		if err := revokeSessions(tx, 0, "deactivated", "id = ?", id); err != nil {
+			return 0, nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return 0, nil, err
+		}
+		return 0, nil, sql.ErrNoRows
+	}
+
+	_, err = tx.Exec(`
+		UPDATE sessions SET refresh_hash = ?, previous_hash = refresh_hash, last_used_at = ?, expires_at = ?
+		WHERE id = ?
+	`, newHash, now, now.Add(ttl), id)
+	if err != nil {
+		return 0, nil, err
+	}
+	return id, user, tx.Commit()
+}
+
+// revokeReusedSession отзывает действующую сессию, если предъявлен ее предыдущий refresh-токен
+func revokeReusedSession(tx *sql.Tx, refreshHash string) error {
+	var id, userID int
+	err := tx.QueryRow(
+		"SELECT id, user_id FROM sessions WHERE previous_hash = ? AND revoked_at IS NULL",
+		refreshHash,
+	).Scan(&id, &userID)
+	if err != nil {
+		return err
+	}
+
+	if err := revokeSessions(tx, 0, "refresh_reuse", "id = ?", id); err != nil {
+		return err
+	}
+	if err := WriteAudit(tx, "user", userID, "session_reuse", map[string]int{"session_id": id}, 0); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return ErrSessionReuse
+}
+
+// GetSessionUser возвращает сотрудника действующей сессии; sql.ErrNoRows — сессия
+// отозвана, истекла, сотрудник отключен или удален
+func GetSessionUser(sessionID int) (*SessionUser, error) {
+	var su SessionUser
+	err := db.QueryRow(`
+		SELECT s.id, u.id, u.username, COALESCE(u.role, 'librarian')
+		FROM sessions s
+		JOIN users u ON s.user_id = u.id
+		WHERE s.id = ? AND s.revoked_at IS NULL AND s.expires_at > ? AND u.is_active = 1
+	`, sessionID, time.Now().UTC()).Scan(&su.SessionID, &su.UserID, &su.Username, &su.Role)
+	if err != nil {
+		return nil, err
+	}
+	return &su, nil
+}
+
+// GetSessions возвращает сессии сотрудника (userID > 0) или всех сотрудников:
+// действующие или, с all=true, вместе с закрытыми
+func GetSessions(userID int, all bool) ([]models.Session, error) {
+	query := "SELECT " + sessionColumns + " WHERE 1=1"
+	var args []interface{}
+	if userID > 0 {
+		query += " AND s.user_id = ?"
+		args = append(args, userID)
+	}
+	if !all {
+		query += " AND s.revoked_at IS NULL AND s.expires_at > ?"
+		args = append(args, time.Now().UTC())
+	}
+	query += " ORDER BY s.last_used_at DESC, s.id DESC"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *session)
+	}
+	return sessions, rows.Err()
+}
+
+// GetSession возвращает сессию по ID
+func GetSession(id int) (*models.Session, error) {
+	return scanSession(db.QueryRow("SELECT "+sessionColumns+" WHERE s.id = ?", id))
+}
+
+// Logout закрывает сессию по ее refresh-токену. Неизвестный токен ошибкой не считается
+func Logout(refreshHash string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id, userID int
+	err = tx.QueryRow(
+		"SELECT id, user_id FROM sessions WHERE refresh_hash = ? AND revoked_at IS NULL",
+		refreshHash,
+	).Scan(&id, &userID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := revokeSessions(tx, 0, "logout", "id = ?", id); err != nil {
+		return err
+	}
+	if err := WriteAudit(tx, "user", userID, "logout", map[string]int{"session_id": id}, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RevokeSession отзывает действующую сессию по решению сотрудника byUserID:
+// reason — user (сам сотрудник) или admin
+func RevokeSession(id, byUserID int, reason string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var userID int
+	err = tx.QueryRow("SELECT user_id FROM sessions WHERE id = ? AND revoked_at IS NULL", id).Scan(&userID)
+	if err != nil {
+		return err
+	}
+	if err := revokeSessions(tx, byUserID, reason, "id = ?", id); err != nil {
+		return err
+	}
+	details := map[string]interface{}{"session_id": id, "reason": reason}
+	if err := WriteAudit(tx, "user", userID, "revoke_session", details, byUserID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RevokeUserSessions отзывает все действующие сессии сотрудника; возвращает их число
+func RevokeUserSessions(userID, byUserID int, reason string) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	count, err := revokeUserSessions(tx, userID, 0, byUserID, reason)
+	if err != nil {
+		return 0, err
+	}
+	return count, tx.Commit()
+}
+
+// revokeUserSessions отзывает сессии сотрудника, кроме keepSessionID, внутри транзакции
+// и пишет журнал, если было что отзывать
+func revokeUserSessions(tx *sql.Tx, userID, keepSessionID, byUserID int, reason string) (int64, error) {
+	var count int64
+	err := tx.QueryRow(
+		"SELECT COUNT(*) FROM sessions WHERE user_id = ? AND id <> ? AND revoked_at IS NULL",
+		userID, keepSessionID,
+	).Scan(&count)
+	if err != nil || count == 0 {
+		return 0, err
+	}
+	if err := revokeSessions(tx, byUserID, reason, "user_id = ? AND id <> ?", userID, keepSessionID); err != nil {
+		return 0, err
+	}
+	details := map[string]interface{}{"sessions": count, "reason": reason}
+	if err := WriteAudit(tx, "user", userID, "revoke_sessions", details, byUserID); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// revokeSessions помечает действующие сессии по условию отозванными
+func revokeSessions(ex execer, byUserID int, reason, condition string, args ...interface{}) error {
+	var revokedBy interface{}
+	if byUserID > 0 {
+		revokedBy = byUserID
+	}
+	_, err := ex.Exec(
+		"UPDATE sessions SET revoked_at = ?, revoked_by = ?, revoke_reason = ? WHERE "+condition+" AND revoked_at IS NULL",
+		append([]interface{}{time.Now().UTC(), revokedBy, reason}, args...)...,
+	)
+	return err
+}
@@ -0,0 +1,250 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"library-management/backend/models"
+)
+
+var (
+	// ErrUsernameTaken — логин уже занят другим сотрудником
+	ErrUsernameTaken = errors.New("username is already taken")
+	// ErrLastAdmin — операция оставила бы систему без действующего администратора
+	ErrLastAdmin = errors.New("the last active administrator cannot be removed")
+	// ErrUserHasHistory — сотрудник уже работал в системе; его можно только отключить
+	ErrUserHasHistory = errors.New("user has recorded activity")
+)
+
+// userColumns — столбцы сотрудника без хэша пароля; используется вместе со scanUser
+const userColumns = `
+	id, username, full_name, COALESCE(email, ''), COALESCE(role, 'librarian'),
+	is_active, last_login, created_at
+	FROM users
+`
+
+// scanUser считывает сотрудника, выбранного через userColumns
+func scanUser(row rowScanner) (*models.User, error) {
+	var user models.User
+	err := row.Scan(
+		&user.ID, &user.Username, &user.FullName, &user.Email, &user.Role,
+		&user.IsActive, &user.LastLogin, &user.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUser возвращает сотрудника по ID
+func GetUser(id int) (*models.User, error) {
+	return scanUser(db.QueryRow("SELECT "+userColumns+" WHERE id = ?", id))
+}
+
+// CreateUser добавляет сотрудника с уже вычисленным хэшем пароля
+func CreateUser(user *models.User, byUserID int) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := checkUsernameFree(tx, user.Username, 0); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO users (username, password_hash, full_name, email, role, is_active)
+		VALUES (?, ?, ?, NULLIF(?, ''), ?, 1)
+	`, user.Username, user.PasswordHash, user.FullName, user.Email, user.Role)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	details := map[string]string{"username": user.Username, "role": user.Role}
+	if err := WriteAudit(tx, "user", int(id), "create", details, byUserID); err != nil {
+		return 0, err
+	}
+	return int(id), tx.Commit()
+}
+
+// UpdateUser меняет логин, имя, e-mail и роль сотрудника. Последнего действующего
+// администратора понизить нельзя (ErrLastAdmin)
+func UpdateUser(user *models.User, byUserID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := scanUser(tx.QueryRow("SELECT "+userColumns+" WHERE id = ?", user.ID))
+	if err != nil {
+		return err
+	}
+	if current.Role == "admin" && user.Role != "admin" && current.IsActive {
+		if err := checkOtherAdmins(tx, user.ID); err != nil {
+			return err
+		}
+	}
+	if err := checkUsernameFree(tx, user.Username, user.ID); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"UPDATE users SET username = ?, full_name = ?, email = NULLIF(?, ''), role = ? WHERE id = ?",
+		user.Username, user.FullName, user.Email, user.Role, user.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	details := map[string]string{"username": user.Username, "role": user.Role}
+	if current.Role != user.Role {
+		details["previous_role"] = current.Role
+	}
+	if err := WriteAudit(tx, "user", user.ID, "update", details, byUserID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetUserActive включает или отключает сотрудника. Отключенный сотрудник не может войти,
+// его сессии отзываются сразу. Последнего действующего администратора отключить нельзя
+func SetUserActive(id int, active bool, byUserID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := scanUser(tx.QueryRow("SELECT "+userColumns+" WHERE id = ?", id))
+	if err != nil {
+		return err
+	}
+	if current.IsActive == active {
+		return nil
+	}
+	if !active && current.Role == "admin" {
+		if err := checkOtherAdmins(tx, id); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE users SET is_active = ? WHERE id = ?", active, id); err != nil {
+		return err
+	}
+	if !active {
+		if _, err := revokeUserSessions(tx, id, 0, byUserID, "deactivated"); err != nil {
+			return err
+		}
+	}
+
+	action := "activate"
+	if !active {
+		action = "deactivate"
+	}
+	if err := WriteAudit(tx, "user", id, action, map[string]string{"username": current.Username}, byUserID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetUserPassword сохраняет новый хэш пароля и отзывает сессии сотрудника, кроме keepSessionID:
+// сменивший свой пароль остается в системе, на остальных устройствах нужно войти заново
+func SetUserPassword(id int, passwordHash string, keepSessionID, byUserID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE users SET password_hash = ? WHERE id = ?", passwordHash, id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	if _, err := revokeUserSessions(tx, id, keepSessionID, byUserID, "password_changed"); err != nil {
+		return err
+	}
+	if err := WriteAudit(tx, "user", id, "change_password", nil, byUserID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteUser удаляет сотрудника, заведенного по ошибке. Если от его имени уже что-то
+// записано в журнал, удаление запрещено (ErrUserHasHistory): такого сотрудника отключают
+func DeleteUser(id, byUserID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := scanUser(tx.QueryRow("SELECT "+userColumns+" WHERE id = ?", id))
+	if err != nil {
+		return err
+	}
+	if current.Role == "admin" && current.IsActive {
+		if err := checkOtherAdmins(tx, id); err != nil {
+			return err
+		}
+	}
+
+	var actions int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM audit_log WHERE user_id = ?", id).Scan(&actions); err != nil {
+		return err
+	}
+	if actions > 0 {
+		return ErrUserHasHistory
+	}
+
+	if _, err := tx.Exec("DELETE FROM sessions WHERE user_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM users WHERE id = ?", id); err != nil {
+		return err
+	}
+
+	details := map[string]string{"username": current.Username, "full_name": current.FullName}
+	if err := WriteAudit(tx, "user", id, "delete", details, byUserID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkOtherAdmins возвращает ErrLastAdmin, если кроме сотрудника id действующих администраторов нет
+func checkOtherAdmins(tx *sql.Tx, id int) error {
+	var admins int
+	err := tx.QueryRow(
+		"SELECT COUNT(*) FROM users WHERE role = 'admin' AND is_active = 1 AND id <> ?", id,
+	).Scan(&admins)
+	if err != nil {
+		return err
+	}
+	if admins == 0 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+// checkUsernameFree возвращает ErrUsernameTaken, если логин занят сотрудником, отличным от id
+func checkUsernameFree(tx *sql.Tx, username string, id int) error {
+	var exists bool
+	err := tx.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM users WHERE username = ? COLLATE NOCASE AND id <> ?)", username, id,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrUsernameTaken
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package documents
+
+import (
+	"io"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// Clearance — содержимое обходного листа
+type Clearance struct {
+	Organization     string
+	Number           string
+	Date             time.Time
+	ReaderName       string
+	ReaderInfo       string
+	Reason           string
+	Librarian        string
+	Director         string
+	VerificationCode string
+	// VerifyURL — адрес проверки листа; печатается QR-кодом, если задан
+	VerifyURL string
+}
+
+// Поля страницы A4 в миллиметрах
+const (
+	marginLeft  = 25.0
+	marginRight = 15.0
+	marginTop   = 20.0
+	pageWidth   = 210.0
+)
+
+// RenderClearance формирует PDF обходного листа: подтверждение, что у читателя
+// нет невозвращенных книг и дисков, с местами для подписей и кодом проверки.
+// Штрафы и иные начисления система не ведет, поэтому лист их не подтверждает
+func RenderClearance(w io.Writer, doc Clearance) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(marginLeft, marginTop, marginRight)
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.SetCreator("library-management", true)
+	pdf.SetTitle("Обходной лист № "+doc.Number, true)
+	pdf.SetCreationDate(doc.Date)
+	pdf.SetModificationDate(doc.Date)
+	pdf.AddUTF8FontFromBytes("Go", "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes("Go", "B", gobold.TTF)
+	pdf.AddPage()
+
+	width := pageWidth - marginLeft - marginRight
+
+	if doc.Organization != "" {
+		pdf.SetFont("Go", "", 11)
+		pdf.MultiCell(width, 5, doc.Organization, "", "C", false)
+		pdf.Ln(2)
+		pdf.Line(marginLeft, pdf.GetY(), pageWidth-marginRight, pdf.GetY())
+		pdf.Ln(10)
+	}
+
+	pdf.SetFont("Go", "B", 16)
+	pdf.CellFormat(width, 8, "ОБХОДНОЙ ЛИСТ № "+doc.Number, "", 1, "C", false, 0, "")
+	pdf.SetFont("Go", "", 11)
+	pdf.CellFormat(width, 6, "об отсутствии задолженности перед библиотекой", "", 1, "C", false, 0, "")
+	pdf.Ln(10)
+
+	field := func(name, value string) {
+		if value == "" {
+			return
+		}
+		pdf.SetFont("Go", "B", 12)
+		pdf.CellFormat(45, 7, name, "", 0, "L", false, 0, "")
+		pdf.SetFont("Go", "", 12)
+		pdf.MultiCell(width-45, 7, value, "", "L", false)
+	}
+	field("Дата выдачи:", doc.Date.Format("02.01.2006"))
+	field("Читатель:", doc.ReaderName)
+	field("Категория:", doc.ReaderInfo)
+	field("Основание:", doc.Reason)
+	pdf.Ln(6)
+
+	pdf.SetFont("Go", "", 12)
+	pdf.MultiCell(width, 7,
+		"Настоящим подтверждается, что на дату выдачи у читателя нет невозвращенных книг "+
+			"и дисков. Штрафы и иные начисления библиотечной системой не учитываются.", "", "J", false)
+	pdf.Ln(16)
+
+	signature := func(role, name string) {
+		pdf.CellFormat(45, 7, role, "", 0, "L", false, 0, "")
+		pdf.CellFormat(50, 7, "", "B", 0, "L", false, 0, "")
+		pdf.CellFormat(width-95, 7, "  / "+name, "", 1, "L", false, 0, "")
+		pdf.Ln(10)
+	}
+	signature("Библиотекарь", doc.Librarian)
+	if doc.Director != "" {
+		signature("Директор", doc.Director)
+	}
+	pdf.CellFormat(width, 7, "М. П.", "", 1, "L", false, 0, "")
+
+	// Код проверки внизу листа: по номеру и коду подлинность подтверждается в системе
+	bottom := 297.0 - 50
+	if pdf.GetY() < bottom {
+		pdf.SetY(bottom)
+	}
+	top := pdf.GetY()
+	textWidth := width
+	if doc.VerifyURL != "" {
+		qr, err := qrcode.New(doc.VerifyURL, qrcode.Medium)
+		if err != nil {
+			return err
+		}
+		drawQR(pdf, qr.Bitmap(), pageWidth-marginRight-30, top, 30)
+		textWidth = width - 35
+	}
+	pdf.SetFont("Go", "", 9)
+	pdf.SetTextColor(80, 80, 80)
+	pdf.MultiCell(textWidth, 5,
+		"Код проверки: "+doc.VerificationCode+"\n"+
+			"Подлинность листа подтверждается в библиотеке по номеру и коду проверки. "+
+			"Лист действителен без исправлений.", "", "L", false)
+
+	return pdf.Output(w)
+}
+
+// drawQR рисует QR-код в квадрате side×side, объединяя соседние модули строки
+func drawQR(pdf *fpdf.Fpdf, bitmap [][]bool, x, y, side float64) {
+	module := side / float64(len(bitmap))
+	pdf.SetFillColor(0, 0, 0)
+	for row, cells := range bitmap {
+		for col := 0; col < len(cells); {
+			if !cells[col] {
+				col++
+				continue
+			}
+			end := col
+			for end < len(cells) && cells[end] {
+				end++
+			}
+			pdf.Rect(x+float64(col)*module, y+float64(row)*module, float64(end-col)*module, module, "F")
+			col = end
+		}
+	}
+}
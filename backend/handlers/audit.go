@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"library-management/backend/database"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetAuditLog возвращает журнал операций с фильтрами по типу и ID объекта
+func GetAuditLog(c *fiber.Ctx) error {
+	entityType := c.Query("entity_type", "")
+	entityID := c.QueryInt("entity_id", 0)
+	limit := c.QueryInt("limit", 100)
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	entries, err := database.GetAuditLog(entityType, entityID, limit)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch audit log",
+		})
+	}
+
+	return c.JSON(entries)
+}
+
+// mergeSourceIDs проверяет список объединяемых записей: без повторов и без сохраняемой записи
+func mergeSourceIDs(targetID int, sourceIDs []int) ([]int, error) {
+	if len(sourceIDs) == 0 {
+		return nil, fiber.NewError(400, "No records to merge")
+	}
+
+	seen := map[int]bool{}
+	result := make([]int, 0, len(sourceIDs))
+	for _, id := range sourceIDs {
+		if id == targetID {
+			return nil, fiber.NewError(400, "Cannot merge a record into itself")
+		}
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+
+	return result, nil
+}
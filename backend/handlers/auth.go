@@ -1,9 +1,15 @@
 package handlers
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
 	"library-management/backend/database"
 	"library-management/backend/models"
+	"library-management/backend/tokens"
+	"log"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -11,7 +17,11 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-var jwtSecret = []byte("your-secret-key-change-this-in-production")
+// AccessTokenTTL — срок действия токена доступа; задается в main из конфигурации
+var AccessTokenTTL = 15 * time.Minute
+
+// RefreshTokenTTL — сколько сессия живет без обращений; каждое обновление токена ее продлевает
+var RefreshTokenTTL = 14 * 24 * time.Hour
 
 // Login обрабатывает вход пользователя
 func Login(c *fiber.Ctx) error {
@@ -23,47 +33,170 @@ func Login(c *fiber.Ctx) error {
 		})
 	}
 
+	// Попытка засчитывается заранее: пока проверяется пароль, параллельные запросы
+	// уже видят ее в счетчике. Во время блокировки пароль не проверяется
+	attempt, err := reserveLoginAttempt(c, req.Username)
+	if attempt == nil {
+		return err
+	}
+
 	// Получаем пользователя из БД
 	user, err := database.GetUserByUsername(req.Username)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return c.Status(401).JSON(fiber.Map{
-				"error": "Invalid username or password",
-			})
-		}
+	if err != nil && err != sql.ErrNoRows {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Database error",
 		})
 	}
 	// Проверяем пароль
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		return c.Status(401).JSON(fiber.Map{
-			"error": "Invalid username or password",
+	if err != nil || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil {
+		return loginFailed(c, attempt, 401, "Invalid username or password")
+	}
+	if err := database.ReleaseLoginAttempt(attempt); err != nil {
+		log.Printf("auth: failed to reset login failures for %q: %v", req.Username, err)
+	}
+	// Отключенный сотрудник войти не может; об этом говорим только знающему пароль
+	if !user.IsActive {
+		return c.Status(403).JSON(fiber.Map{
+			"error": "Account is disabled",
 		})
 	}
-	// Создаем JWT токен
-	token := jwt.New(jwt.SigningMethodHS256)
-	claims := token.Claims.(jwt.MapClaims)
-	claims["user_id"] = user.ID
-	claims["username"] = user.Username
-	claims["role"] = user.Role
-	claims["exp"] = time.Now().Add(time.Hour * 24).Unix() // Токен действителен 24 часа
-
-	tokenString, err := token.SignedString(jwtSecret)
+	// Открываем сессию и выдаем токены
+	refreshToken, refreshHash, err := newRefreshToken()
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Could not generate token",
 		})
 	}
+	sessionID, err := database.CreateSession(user.ID, refreshHash, c.Get(fiber.HeaderUserAgent), c.IP(), RefreshTokenTTL)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
 
 	// Убираем хеш пароля из ответа
 	user.PasswordHash = ""
+	return sessionTokens(c, sessionID, refreshToken, user)
+}
+
+// reserveLoginAttempt засчитывает попытку входа до проверки пароля. Если вход закрыт
+// или резерв не удался, отвечает сам и возвращает nil вместе с результатом ответа
+func reserveLoginAttempt(c *fiber.Ctx, username string) (*database.LoginReservation, error) {
+	attempt, err := database.ReserveLoginAttempt(username, c.IP())
+	if err == database.ErrLoginLocked {
+		return nil, tooManyAttempts(c, *attempt.LockedUntil)
+	}
+	if err != nil {
+		return nil, c.Status(500).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+	return attempt, nil
+}
+
+// loginFailed пишет неудачную попытку в журнал и отвечает status с message или,
+// если вход теперь закрыт, 429
+func loginFailed(c *fiber.Ctx, attempt *database.LoginReservation, status int, message string) error {
+	if err := database.RecordLoginFailure(attempt); err != nil {
+		log.Printf("auth: failed to record login failure for %q: %v", attempt.Username, err)
+	}
+	if attempt.LockedUntil != nil {
+		return tooManyAttempts(c, *attempt.LockedUntil)
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": message,
+	})
+}
+
+// RefreshToken выдает новый токен доступа по refresh-токену. Refresh-токен одноразовый:
+// вместе с токеном доступа выдается следующий, а повторное предъявление старого закрывает сессию
+func RefreshToken(c *fiber.Ctx) error {
+	var req models.RefreshRequest
+	if err := c.BodyParser(&req); err != nil || req.RefreshToken == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Refresh token is required",
+		})
+	}
+
+	refreshToken, refreshHash, err := newRefreshToken()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Could not generate token",
+		})
+	}
+
+	sessionID, user, err := database.RotateSession(hashRefreshToken(req.RefreshToken), refreshHash, RefreshTokenTTL)
+	switch err {
+	case nil:
+	case sql.ErrNoRows, database.ErrSessionReuse:
+		return c.Status(401).JSON(fiber.Map{
+			"error": "Session has expired or was revoked",
+		})
+	default:
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+
+	return sessionTokens(c, sessionID, refreshToken, user)
+}
+
+// Logout закрывает сессию: refresh-токен и выданные по нему токены доступа перестают действовать
+func Logout(c *fiber.Ctx) error {
+	var req models.RefreshRequest
+	if err := c.BodyParser(&req); err != nil || req.RefreshToken == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Refresh token is required",
+		})
+	}
+
+	if err := database.Logout(hashRefreshToken(req.RefreshToken)); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+	return c.JSON(fiber.Map{"message": "Logged out"})
+}
+
+// sessionTokens подписывает токен доступа сессии и отвечает вместе с refresh-токеном
+func sessionTokens(c *fiber.Ctx, sessionID int, refreshToken string, user *models.User) error {
+	claims := jwt.MapClaims{
+		"sid":      sessionID,
+		"user_id":  user.ID,
+		"username": user.Username,
+		"role":     user.Role,
+	}
+	tokenString, expiresAt, err := tokens.Sign(tokens.Staff, claims, AccessTokenTTL)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Could not generate token",
+		})
+	}
+
 	return c.JSON(models.LoginResponse{
-		Token: tokenString,
-		User:  *user,
+		Token:        tokenString,
+		ExpiresAt:    expiresAt,
+		RefreshToken: refreshToken,
+		User:         *user,
 	})
 }
 
+// newRefreshToken возвращает случайный refresh-токен и его хэш для хранения в базе
+func newRefreshToken() (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashRefreshToken(token), nil
+}
+
+// hashRefreshToken — в базе хранится только SHA-256 refresh-токена
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 // GetDashboardStats возвращает статистику для главной страницы
 func GetDashboardStats(c *fiber.Ctx) error {
 	stats, err := database.GetDashboardStats()
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
 	"library-management/backend/database"
 	"library-management/backend/models"
 	"strconv"
@@ -29,25 +31,33 @@ func CreateAuthor(c *fiber.Ctx) error {
 		})
 	}
 
-	// Генерируем код если не указан
-	if author.Code == "" {
-		author.Code = database.GenerateAuthorCode()
-	}
-
 	// Формируем краткое имя если не указано
-	if author.ShortName == "" && author.LastName != "" {
-		firstInitial := ""
-		if author.FirstName != "" {
-			firstInitial = string([]rune(author.FirstName)[0]) + "."
+	fillAuthorShortName(&author)
+
+	// Проверяем, нет ли уже такого автора (фамилия и инициалы).
+	// Создать автора несмотря на совпадение можно с параметром force=true
+	if c.Query("force") != "true" {
+		matches, err := database.FindAuthorMatches(&author)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to check author duplicates",
+			})
 		}
-		middleInitial := ""
-		if author.MiddleName != "" {
-			middleInitial = string([]rune(author.MiddleName)[0]) + "."
+		if len(matches) > 0 {
+			return c.Status(409).JSON(fiber.Map{
+				"error":      "Author with the same last name and initials already exists",
+				"candidates": matches,
+			})
 		}
-		author.ShortName = author.LastName + " " + firstInitial + middleInitial
 	}
 
+	// Код, если не указан, выдается из последовательности при сохранении
 	id, err := database.CreateAuthor(&author)
+	if err == database.ErrCodeExists {
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Author code already exists",
+		})
+	}
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Failed to create author",
@@ -117,3 +127,72 @@ func DeleteAuthor(c *fiber.Ctx) error {
 		"message": "Author deleted successfully",
 	})
 }
+
+// GetAuthorDuplicates возвращает группы авторов — кандидатов в дубликаты
+func GetAuthorDuplicates(c *fiber.Ctx) error {
+	groups, err := database.FindAuthorDuplicates()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to find duplicate authors",
+		})
+	}
+
+	return c.JSON(groups)
+}
+
+// MergeAuthors объединяет авторов-дубликатов с автором из URL: книги переносятся, дубликаты удаляются
+func MergeAuthors(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid author ID",
+		})
+	}
+
+	var req models.MergeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	sourceIDs, err := mergeSourceIDs(id, req.SourceIDs)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userID := c.Locals("userID").(int)
+	if err := database.MergeAuthors(id, sourceIDs, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Author not found",
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to merge authors",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Authors merged successfully",
+	})
+}
+
+// fillAuthorShortName формирует краткое имя ("Толстой Л.Н."), если оно не указано
+func fillAuthorShortName(author *models.Author) {
+	if author.ShortName != "" || author.LastName == "" {
+		return
+	}
+
+	firstInitial := ""
+	if author.FirstName != "" {
+		firstInitial = string([]rune(author.FirstName)[0]) + "."
+	}
+	middleInitial := ""
+	if author.MiddleName != "" {
+		middleInitial = string([]rune(author.MiddleName)[0]) + "."
+	}
+	author.ShortName = author.LastName + " " + firstInitial + middleInitial
+}
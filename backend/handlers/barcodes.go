@@ -0,0 +1,473 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"library-management/backend/database"
+	"library-management/backend/labels"
+	"library-management/backend/models"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxBarcodeBatch ограничивает размер одной партии штрих-кодов
+const maxBarcodeBatch = 5000
+
+// barcodeEntityNames — названия типов объектов для сообщений об ошибках
+var barcodeEntityNames = map[string]string{
+	"book":     "book",
+	"reader":   "reader",
+	"disk":     "disk",
+	"location": "location",
+}
+
+// GetBarcodeRanges возвращает диапазоны штрих-кодов
+func GetBarcodeRanges(c *fiber.Ctx) error {
+	ranges, err := database.GetBarcodeRanges()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch barcode ranges",
+		})
+	}
+
+	return c.JSON(ranges)
+}
+
+// CreateBarcodeRange создает диапазон штрих-кодов
+func CreateBarcodeRange(c *fiber.Ctx) error {
+	var r models.BarcodeRange
+	if err := c.BodyParser(&r); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	if err := validateBarcodeRange(&r, nil); err != nil {
+		return barcodeRangeError(c, err)
+	}
+
+	r.CreatedBy = c.Locals("userID").(int)
+	id, err := database.CreateBarcodeRange(&r)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to create barcode range",
+		})
+	}
+
+	created, err := database.GetBarcodeRangeByID(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch barcode range",
+		})
+	}
+
+	return c.Status(201).JSON(created)
+}
+
+// UpdateBarcodeRange обновляет диапазон штрих-кодов. После выделения штрих-кодов
+// формат диапазона менять нельзя, его можно только расширить
+func UpdateBarcodeRange(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid barcode range ID",
+		})
+	}
+
+	current, err := database.GetBarcodeRangeByID(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Barcode range not found",
+		})
+	}
+
+	var r models.BarcodeRange
+	if err := c.BodyParser(&r); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	r.ID = id
+	if err := validateBarcodeRange(&r, current); err != nil {
+		return barcodeRangeError(c, err)
+	}
+
+	if err := database.UpdateBarcodeRange(&r); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to update barcode range",
+		})
+	}
+
+	updated, err := database.GetBarcodeRangeByID(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch barcode range",
+		})
+	}
+
+	return c.JSON(updated)
+}
+
+// DeleteBarcodeRange удаляет диапазон, из которого еще не выделялись штрих-коды
+func DeleteBarcodeRange(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid barcode range ID",
+		})
+	}
+
+	hasAllocations, err := database.BarcodeRangeHasAllocations(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to check barcode range status",
+		})
+	}
+
+	if hasAllocations {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot delete barcode range with allocated barcodes",
+		})
+	}
+
+	if err := database.DeleteBarcodeRange(id); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to delete barcode range",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Barcode range deleted successfully",
+	})
+}
+
+// CreateBarcodeBatch резервирует партию штрих-кодов из диапазона для печати заранее
+func CreateBarcodeBatch(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid barcode range ID",
+		})
+	}
+
+	var req models.BarcodeBatchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	if req.Count < 1 || req.Count > maxBarcodeBatch {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("Count must be between 1 and %d", maxBarcodeBatch),
+		})
+	}
+
+	userID := c.Locals("userID").(int)
+	batchID, err := database.AllocateBarcodes(id, req.Count, userID)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Barcode range not found",
+			})
+		case database.ErrBarcodeRangeExhausted:
+			return c.Status(409).JSON(fiber.Map{
+				"error": "Not enough free barcodes left in the range",
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to allocate barcodes",
+		})
+	}
+
+	batch, err := database.GetBarcodeBatch(batchID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch barcode batch",
+		})
+	}
+
+	return c.Status(201).JSON(batch)
+}
+
+// GetBarcodeBatches возвращает партии штрих-кодов
+func GetBarcodeBatches(c *fiber.Ctx) error {
+	batches, err := database.GetBarcodeBatches(c.QueryInt("range_id", 0))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch barcode batches",
+		})
+	}
+
+	return c.JSON(batches)
+}
+
+// GetBarcodeBatch возвращает партию со списком штрих-кодов
+func GetBarcodeBatch(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid barcode batch ID",
+		})
+	}
+
+	batch, err := database.GetBarcodeBatch(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Barcode batch not found",
+		})
+	}
+
+	return c.JSON(batch)
+}
+
+// PrintBarcodeBatch печатает этикетки неиспользованных штрих-кодов партии
+func PrintBarcodeBatch(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid barcode batch ID",
+		})
+	}
+
+	var req models.LabelRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	opts, err := labelOptions(&req)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	batch, err := database.GetBarcodeBatch(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Barcode batch not found",
+		})
+	}
+
+	// На заранее напечатанной этикетке еще нет названия — указываем библиотеку
+	var title string
+	if settings, err := database.GetSettings(); err == nil {
+		title = settings.OrganizationShortName
+	}
+
+	var items []labels.Label
+	for _, allocation := range batch.Barcodes {
+		if allocation.Status == "reserved" {
+			items = append(items, labels.Label{Title: title, Code: allocation.Barcode})
+		}
+	}
+
+	if len(items) == 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Batch has no unused barcodes",
+		})
+	}
+
+	return sendLabels(c, fmt.Sprintf("barcodes-%d.pdf", id), items, opts)
+}
+
+// VoidBarcodeBatch аннулирует неиспользованные штрих-коды партии
+func VoidBarcodeBatch(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid barcode batch ID",
+		})
+	}
+
+	voided, err := database.VoidBarcodeBatch(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to void barcode batch",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"voided": voided,
+	})
+}
+
+// LookupBarcode определяет, какой записи принадлежит отсканированный штрих-код
+func LookupBarcode(c *fiber.Ctx) error {
+	lookup, err := database.LookupBarcode(c.Params("barcode"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to look up barcode",
+		})
+	}
+
+	if len(lookup.Owners) == 0 && lookup.Allocation == nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Barcode not found",
+		})
+	}
+
+	return c.JSON(lookup)
+}
+
+// GetBarcodeCollisions возвращает штрих-коды, присвоенные нескольким записям
+func GetBarcodeCollisions(c *fiber.Ctx) error {
+	collisions, err := database.GetBarcodeCollisions()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to check barcode collisions",
+		})
+	}
+
+	return c.JSON(collisions)
+}
+
+// barcodeRangeError отправляет ошибку проверки диапазона
+func barcodeRangeError(c *fiber.Ctx, err error) error {
+	if fiberErr, ok := err.(*fiber.Error); ok {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+	return c.Status(500).JSON(fiber.Map{
+		"error": "Failed to validate barcode range",
+	})
+}
+
+// validateBarcodeRange проверяет формат диапазона. current — сохраненный диапазон при обновлении
+func validateBarcodeRange(r *models.BarcodeRange, current *models.BarcodeRange) error {
+	r.Prefix = strings.TrimSpace(r.Prefix)
+
+	switch r.EntityType {
+	case "book", "reader", "disk":
+	default:
+		return fiber.NewError(400, "Entity type must be 'book', 'reader' or 'disk'")
+	}
+
+	// Префикс печатается в Code 128, поэтому допустимы только печатные символы ASCII
+	for _, ch := range r.Prefix {
+		if ch <= ' ' || ch > '~' {
+			return fiber.NewError(400, "Prefix may contain only printable ASCII characters without spaces")
+		}
+	}
+
+	if r.Digits < 1 || r.Digits > 12 {
+		return fiber.NewError(400, "Digits must be between 1 and 12")
+	}
+	if r.StartNumber < 0 || r.EndNumber < r.StartNumber {
+		return fiber.NewError(400, "Invalid number range")
+	}
+	if float64(r.EndNumber) >= math.Pow10(r.Digits) {
+		return fiber.NewError(400, fmt.Sprintf("End number does not fit into %d digits", r.Digits))
+	}
+
+	switch r.CheckDigit {
+	case "", "luhn":
+	case "ean13":
+		if len(r.Prefix)+r.Digits != 12 || strings.Trim(r.Prefix, "0123456789") != "" {
+			return fiber.NewError(400, "EAN-13 requires a numeric prefix and 12 digits in total")
+		}
+	default:
+		return fiber.NewError(400, "Check digit must be '', 'luhn' or 'ean13'")
+	}
+
+	if current != nil {
+		hasAllocations, err := database.BarcodeRangeHasAllocations(current.ID)
+		if err != nil {
+			return err
+		}
+		if hasAllocations {
+			if r.EntityType != current.EntityType || r.Prefix != current.Prefix || r.Digits != current.Digits ||
+				r.CheckDigit != current.CheckDigit || r.StartNumber != current.StartNumber {
+				return fiber.NewError(400, "Only the end number and comments can be changed after barcodes were allocated")
+			}
+			if r.EndNumber < current.NextNumber-1 {
+				return fiber.NewError(400, "End number cannot be lower than the last allocated number")
+			}
+		}
+	}
+
+	overlaps, err := database.BarcodeRangeOverlaps(r)
+	if err != nil {
+		return err
+	}
+	if overlaps {
+		return fiber.NewError(409, "Range overlaps another range with the same prefix")
+	}
+
+	return nil
+}
+
+// claimBarcode проверяет, что штрих-код можно присвоить записи: он не занят другой записью
+// любого типа, не зарезервирован под другой тип объектов и не содержит ошибки контрольной цифры
+func claimBarcode(entityType string, entityID int, barcode string) error {
+	if barcode == "" {
+		return nil
+	}
+
+	owners, err := database.FindBarcodeOwners(barcode)
+	if err != nil {
+		return err
+	}
+	for _, owner := range owners {
+		if owner.EntityType != entityType || owner.EntityID != entityID {
+			return fiber.NewError(409, fmt.Sprintf("Barcode is already assigned to %s #%d", barcodeEntityNames[owner.EntityType], owner.EntityID))
+		}
+	}
+
+	allocation, err := database.GetBarcodeAllocation(barcode)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if allocation != nil {
+		if allocation.EntityType != entityType {
+			return fiber.NewError(409, fmt.Sprintf("Barcode is reserved for %ss", barcodeEntityNames[allocation.EntityType]))
+		}
+		if allocation.Status == "void" {
+			return fiber.NewError(409, "Barcode label was voided")
+		}
+		return nil
+	}
+
+	// Штрих-код в формате диапазона, но с неверной контрольной цифрой — скорее всего, ошибка ввода
+	r, err := database.MatchBarcodeRange(barcode)
+	if err != nil {
+		return err
+	}
+	if r != nil && r.CheckDigit != "" {
+		n, _ := strconv.Atoi(barcode[len(r.Prefix) : len(r.Prefix)+r.Digits])
+		if database.FormatBarcode(r, n) != barcode {
+			return fiber.NewError(400, "Invalid barcode check digit")
+		}
+	}
+
+	return nil
+}
+
+// bindBarcode отмечает выделенный штрих-код как привязанный к записи.
+// Запись уже сохранена, поэтому ошибка только записывается в журнал
+func bindBarcode(entityType string, entityID int, barcode string) {
+	if err := database.BindBarcode(entityType, entityID, barcode); err != nil {
+		log.Printf("Failed to bind barcode %s to %s %d: %v", barcode, entityType, entityID, err)
+	}
+}
+
+// claimBarcodeError отправляет ошибку проверки штрих-кода
+func claimBarcodeError(c *fiber.Ctx, err error) error {
+	if fiberErr, ok := err.(*fiber.Error); ok {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+	return c.Status(500).JSON(fiber.Map{
+		"error": "Failed to check barcode",
+	})
+}
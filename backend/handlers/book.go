@@ -1,33 +1,50 @@
 package handlers
 
 import (
+	"errors"
+	"library-management/backend/classification"
 	"library-management/backend/database"
+	"library-management/backend/media"
 	"library-management/backend/models"
+	"log"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-// GetBooks возвращает список книг
+// GetBooks возвращает страницу каталога книг с фильтрами, сортировкой и фасетами
 func GetBooks(c *fiber.Ctx) error {
-	search := c.Query("search", "")
-	page, _ := strconv.Atoi(c.Query("page", "1"))
-	pageSize, _ := strconv.Atoi(c.Query("pageSize", "10"))
+	filter, err := parseBookFilter(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 
-	books, total, err := database.GetBooks(search, page, pageSize)
+	books, total, err := database.GetBooks(filter)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Failed to fetch books",
 		})
 	}
 
+	facets, err := database.GetBookFacets(filter)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to count book facets",
+		})
+	}
+
 	return c.JSON(fiber.Map{
 		"books": books,
 		"pagination": fiber.Map{
-			"page":     page,
-			"pageSize": pageSize,
+			"page":     filter.Page,
+			"pageSize": filter.PageSize,
 			"total":    total,
 		},
+		"facets": facets,
 	})
 }
 
@@ -73,17 +90,36 @@ func CreateBook(c *fiber.Ctx) error {
 		})
 	}
 
+	// Проверяем индексы ББК и УДК
+	if err := normalizeBookClassification(&book); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Проверяем место хранения
+	if err := resolveBookLocation(&book); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Проверяем, что штрих-код не занят другой записью
+	if err := claimBarcode("book", 0, book.Barcode); err != nil {
+		return claimBarcodeError(c, err)
+	}
+
 	// Получаем ID пользователя из контекста
 	userID := c.Locals("userID").(int)
 	book.CreatedBy = userID
 
-	// Генерируем код если не указан
-	if book.Code == "" {
-		book.Code = database.GenerateBookCode()
-	}
-
-	// Создаем книгу
+	// Создаем книгу. Код, если не указан, выдается из последовательности при сохранении
 	id, err := database.CreateBook(&book)
+	if err == database.ErrCodeExists {
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Book code already exists",
+		})
+	}
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Failed to create book",
@@ -91,6 +127,7 @@ func CreateBook(c *fiber.Ctx) error {
 	}
 
 	book.ID = id
+	bindBarcode("book", id, book.Barcode)
 	return c.Status(201).JSON(book)
 }
 
@@ -110,12 +147,25 @@ func UpdateBook(c *fiber.Ctx) error {
 		})
 	}
 
+	// Проверяем индексы ББК и УДК
+	if err := normalizeBookClassification(&book); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Проверяем, что штрих-код не занят другой записью
+	if err := claimBarcode("book", id, book.Barcode); err != nil {
+		return claimBarcodeError(c, err)
+	}
+
 	book.ID = id
 	if err := database.UpdateBook(&book); err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Failed to update book",
 		})
 	}
+	bindBarcode("book", id, book.Barcode)
 
 	return c.JSON(book)
 }
@@ -149,7 +199,105 @@ func DeleteBook(c *fiber.Ctx) error {
 		})
 	}
 
+	// Обложка удаляется вместе с записью; ошибка здесь не отменяет удаление
+	if err := removeCover(media.EntityBook, id); err != nil {
+		log.Printf("Failed to delete cover of book %d: %v", id, err)
+	}
+
 	return c.JSON(fiber.Map{
 		"message": "Book deleted successfully",
 	})
 }
+
+// parseBookFilter разбирает параметры фильтра каталога. Списки авторов и издательств
+// передаются через запятую: author_id=3,7
+func parseBookFilter(c *fiber.Ctx) (models.BookFilter, error) {
+	f := models.BookFilter{
+		Search:      c.Query("search", ""),
+		YearFrom:    c.QueryInt("year_from", 0),
+		YearTo:      c.QueryInt("year_to", 0),
+		LocationID:  c.QueryInt("location_id", 0),
+		ClassRange:  c.Query("class_range"),
+		BBK:         c.Query("bbk"),
+		CreatedFrom: c.Query("created_from"),
+		CreatedTo:   c.Query("created_to"),
+		Sort:        c.Query("sort"),
+		Desc:        c.Query("order") == "desc",
+	}
+
+	f.Page, _ = strconv.Atoi(c.Query("page", "1"))
+	f.PageSize, _ = strconv.Atoi(c.Query("pageSize", "10"))
+	if f.Page < 1 {
+		f.Page = 1
+	}
+	if f.PageSize < 1 {
+		f.PageSize = 10
+	}
+
+	var err error
+	if f.AuthorIDs, err = parseIDList(c.Query("author_id")); err != nil {
+		return f, errors.New("Invalid author_id")
+	}
+	if f.PublisherIDs, err = parseIDList(c.Query("publisher_id")); err != nil {
+		return f, errors.New("Invalid publisher_id")
+	}
+
+	if available := c.Query("available"); available != "" {
+		value, err := strconv.ParseBool(available)
+		if err != nil {
+			return f, errors.New("Invalid available value")
+		}
+		f.Available = &value
+	}
+
+	switch f.Sort {
+	case "", "title", "author", "year", "created":
+	default:
+		return f, errors.New("Sort must be 'title', 'author', 'year' or 'created'")
+	}
+
+	return f, validateBookFilter(&f)
+}
+
+// validateBookFilter проверяет даты и раздел ББК в фильтре каталога
+func validateBookFilter(f *models.BookFilter) error {
+	f.ClassRange = strings.TrimSpace(f.ClassRange)
+	f.BBK = strings.TrimSpace(f.BBK)
+
+	for _, date := range []string{f.CreatedFrom, f.CreatedTo} {
+		if date != "" {
+			if _, err := time.Parse("2006-01-02", date); err != nil {
+				return errors.New("Dates must be in YYYY-MM-DD format")
+			}
+		}
+	}
+
+	if f.BBK != "" {
+		table, ok := classification.Get(classification.SchemeBBK)
+		if !ok {
+			return errors.New("BBK table is not loaded")
+		}
+		if _, ok := table.Lookup(f.BBK); !ok {
+			return errors.New("Unknown BBK section")
+		}
+	}
+
+	return nil
+}
+
+// parseIDList разбирает список ID через запятую
+func parseIDList(value string) ([]int, error) {
+	var ids []int
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"fmt"
+	"library-management/backend/database"
+	"library-management/backend/models"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxBulkRecords ограничивает количество записей в одном групповом изменении
+const maxBulkRecords = 5000
+
+// BulkUpdateBooks применяет одно изменение к группе книг, выбранных списком ID и/или фильтром.
+// С параметром preview возвращает только количество и примеры выбранных книг
+func BulkUpdateBooks(c *fiber.Ctx) error {
+	var req models.BookBulkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	if len(req.IDs) == 0 && (req.Filter == nil || bookFilterEmpty(req.Filter)) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Select books by IDs or by a non-empty filter",
+		})
+	}
+	if req.Filter != nil {
+		if err := validateBookFilter(req.Filter); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	if err := validateBookPatch(&req.Patch, req.Preview); err != nil {
+		return bulkError(c, err)
+	}
+
+	ids, err := database.SelectBookIDs(req.IDs, req.Filter)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to select books",
+		})
+	}
+
+	return runBulk(c, "book", ids, req.Preview, func() (int, error) {
+		return database.BulkUpdateBooks(ids, req.Patch, c.Locals("userID").(int))
+	})
+}
+
+// BulkUpdateReaders применяет одно изменение к группе читателей, выбранных списком ID и/или фильтром.
+// С параметром preview возвращает только количество и примеры выбранных читателей
+func BulkUpdateReaders(c *fiber.Ctx) error {
+	var req models.ReaderBulkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	// Статус уточняет выборку, но сам по себе не считается фильтром
+	if len(req.IDs) == 0 && (req.Filter == nil || *req.Filter == (models.ReaderFilter{Status: req.Filter.Status})) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Select readers by IDs or by a non-empty filter",
+		})
+	}
+	if req.Filter != nil && !validReaderStatus(req.Filter.Status) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Reader status must be active, archived or all",
+		})
+	}
+
+	if err := validateReaderPatch(&req.Patch, req.Preview); err != nil {
+		return bulkError(c, err)
+	}
+
+	ids, err := database.SelectReaderIDs(req.IDs, req.Filter)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to select readers",
+		})
+	}
+
+	return runBulk(c, "reader", ids, req.Preview, func() (int, error) {
+		return database.BulkUpdateReaders(ids, req.Patch, c.Locals("userID").(int))
+	})
+}
+
+// runBulk проверяет размер выборки и выполняет изменение или возвращает предпросмотр
+func runBulk(c *fiber.Ctx, entityType string, ids []int, preview bool, update func() (int, error)) error {
+	if len(ids) > maxBulkRecords {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("Too many records selected (%d), the limit is %d", len(ids), maxBulkRecords),
+		})
+	}
+
+	result := models.BulkResult{Matched: len(ids), Preview: preview}
+	if preview {
+		sample, err := database.GetBulkSample(entityType, ids)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to fetch selection sample",
+			})
+		}
+		result.Sample = sample
+		return c.JSON(result)
+	}
+
+	if len(ids) == 0 {
+		return c.JSON(result)
+	}
+
+	updated, err := update()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to apply bulk update",
+		})
+	}
+
+	result.Updated = updated
+	return c.JSON(result)
+}
+
+// bulkError отправляет ошибку проверки группового изменения
+func bulkError(c *fiber.Ctx, err error) error {
+	if fiberErr, ok := err.(*fiber.Error); ok {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+	return c.Status(500).JSON(fiber.Map{
+		"error": "Failed to validate bulk update",
+	})
+}
+
+// bookFilterEmpty проверяет, что фильтр не ограничивает выборку
+func bookFilterEmpty(f *models.BookFilter) bool {
+	return f.Search == "" && len(f.AuthorIDs) == 0 && len(f.PublisherIDs) == 0 &&
+		f.YearFrom == 0 && f.YearTo == 0 && f.Available == nil && f.LocationID == 0 &&
+		f.ClassRange == "" && f.BBK == "" && f.CreatedFrom == "" && f.CreatedTo == ""
+}
+
+// validateBookPatch проверяет изменения книг: ссылки на справочники и индексы ББК/УДК
+func validateBookPatch(patch *models.BookPatch, preview bool) error {
+	if !preview && *patch == (models.BookPatch{}) {
+		return fiber.NewError(400, "Patch is empty")
+	}
+
+	if patch.LocationID != nil {
+		if _, err := database.GetLocationByID(*patch.LocationID); err != nil {
+			return fiber.NewError(400, "Location not found")
+		}
+	}
+	if err := checkReference("author", patch.AuthorID); err != nil {
+		return err
+	}
+	if err := checkReference("publisher", patch.PublisherID); err != nil {
+		return err
+	}
+
+	if patch.ClassRange != nil {
+		value := strings.TrimSpace(*patch.ClassRange)
+		patch.ClassRange = &value
+	}
+
+	// Индексы нормализуются так же, как при редактировании одной книги
+	if patch.BBK != nil || patch.UDK != nil {
+		var book models.Book
+		if patch.BBK != nil {
+			book.BBK = *patch.BBK
+		}
+		if patch.UDK != nil {
+			book.UDK = *patch.UDK
+		}
+		if err := normalizeBookClassification(&book); err != nil {
+			return err
+		}
+		if patch.BBK != nil {
+			patch.BBK = &book.BBK
+		}
+		if patch.UDK != nil {
+			patch.UDK = &book.UDK
+		}
+	}
+
+	return nil
+}
+
+// validateReaderPatch проверяет изменения читателей
+func validateReaderPatch(patch *models.ReaderPatch, preview bool) error {
+	if !preview && *patch == (models.ReaderPatch{}) {
+		return fiber.NewError(400, "Patch is empty")
+	}
+
+	if patch.ClassID != nil && *patch.ClassID != 0 {
+		if _, err := assignableClass(*patch.ClassID); err != nil {
+			return err
+		}
+	}
+	if patch.UserType != nil {
+		value := strings.TrimSpace(*patch.UserType)
+		if value == "" {
+			return fiber.NewError(400, "User type cannot be empty")
+		}
+		patch.UserType = &value
+	}
+
+	return nil
+}
+
+// referenceNames — названия справочников для сообщений об ошибках
+var referenceNames = map[string]string{
+	"author":    "Author",
+	"publisher": "Publisher",
+}
+
+// checkReference проверяет, что ненулевая ссылка указывает на существующую запись справочника
+func checkReference(entityType string, id *int) error {
+	if id == nil || *id == 0 {
+		return nil
+	}
+	exists, err := database.ReferenceExists(entityType, *id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fiber.NewError(400, fmt.Sprintf("%s %d not found", referenceNames[entityType], *id))
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"library-management/backend/database"
+	"library-management/backend/models"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetClasses возвращает классы с классными руководителями и числом учеников.
+// С параметром active=true — только активные классы
+func GetClasses(c *fiber.Ctx) error {
+	classes, err := database.GetClasses()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch classes",
+		})
+	}
+
+	if c.Query("active") == "true" {
+		active := []models.Class{}
+		for _, class := range classes {
+			if class.IsActive {
+				active = append(active, class)
+			}
+		}
+		classes = active
+	}
+
+	return c.JSON(classes)
+}
+
+// CreateClass создает класс
+func CreateClass(c *fiber.Ctx) error {
+	var class models.Class
+	if err := c.BodyParser(&class); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	if err := validateClass(&class, 0); err != nil {
+		return classError(c, err)
+	}
+
+	id, err := database.CreateClass(&class)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to create class",
+		})
+	}
+
+	created, err := database.GetClass(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch class",
+		})
+	}
+
+	return c.Status(201).JSON(created)
+}
+
+// UpdateClass обновляет класс. Параллель читателей класса меняется вместе с параллелью класса
+func UpdateClass(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid class ID",
+		})
+	}
+
+	var class models.Class
+	if err := c.BodyParser(&class); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	if err := validateClass(&class, id); err != nil {
+		return classError(c, err)
+	}
+
+	class.ID = id
+	if err := database.UpdateClass(&class); err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Class not found",
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to update class",
+		})
+	}
+
+	updated, err := database.GetClass(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch class",
+		})
+	}
+
+	return c.JSON(updated)
+}
+
+// DeleteClass удаляет класс, в котором нет действующих читателей
+func DeleteClass(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid class ID",
+		})
+	}
+
+	switch err := database.DeleteClass(id); err {
+	case nil:
+	case sql.ErrNoRows:
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Class not found",
+		})
+	case database.ErrClassHasReaders:
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Cannot delete class with readers; move them to another class first",
+		})
+	default:
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to delete class",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Class deleted successfully",
+	})
+}
+
+// ToggleClassActive включает или выключает класс. Выключенный класс не предлагается
+// для новых читателей; выключить класс с действующими читателями нельзя
+func ToggleClassActive(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid class ID",
+		})
+	}
+
+	class, err := database.GetClass(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Class not found",
+		})
+	}
+
+	switch err := database.SetClassActive(id, !class.IsActive); err {
+	case nil:
+	case sql.ErrNoRows:
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Class not found",
+		})
+	case database.ErrClassHasReaders:
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Cannot deactivate class with readers; move them to another class first",
+		})
+	default:
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to update class",
+		})
+	}
+
+	class.IsActive = !class.IsActive
+	return c.JSON(class)
+}
+
+// validateClass проверяет и нормализует класс: параллель, литеру, уникальность
+// и классного руководителя. Имя руководителя берется из карточки читателя
+func validateClass(class *models.Class, id int) error {
+	if class.Grade < 1 || class.Grade > 12 {
+		return fiber.NewError(400, "Grade must be between 1 and 12")
+	}
+
+	class.Letter = normalizeClassLetter(class.Letter)
+	if len([]rune(class.Letter)) != 1 {
+		return fiber.NewError(400, "Class letter must be a single letter")
+	}
+
+	exists, err := database.ClassExists(class.Grade, class.Letter, id)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fiber.NewError(409, fmt.Sprintf("Class %d%s already exists", class.Grade, class.Letter))
+	}
+
+	class.TeacherName = strings.TrimSpace(class.TeacherName)
+	if class.TeacherID != nil && *class.TeacherID == 0 {
+		class.TeacherID = nil
+	}
+	if class.TeacherID != nil {
+		teacher, err := database.GetReaderByID(*class.TeacherID)
+		if err == sql.ErrNoRows {
+			return fiber.NewError(400, fmt.Sprintf("Reader %d not found", *class.TeacherID))
+		}
+		if err != nil {
+			return err
+		}
+		if teacher.UserType != "teacher" || teacher.Status != "active" {
+			return fiber.NewError(400, "Class teacher must be an active reader of type teacher")
+		}
+		class.TeacherName = strings.TrimSpace(teacher.LastName + " " + teacher.FirstName + " " + teacher.MiddleName)
+	}
+
+	return nil
+}
+
+// checkReaderClass проверяет, что читателя можно записать в класс, и берет параллель из класса
+func checkReaderClass(reader *models.Reader) error {
+	if reader.ClassID != nil && *reader.ClassID == 0 {
+		reader.ClassID = nil
+	}
+	if reader.ClassID == nil {
+		return nil
+	}
+
+	class, err := assignableClass(*reader.ClassID)
+	if err != nil {
+		return err
+	}
+	reader.Grade = &class.Grade
+	return nil
+}
+
+// assignableClass возвращает класс, если в него можно записывать читателей
+func assignableClass(id int) (*models.Class, error) {
+	class, err := database.GetClass(id)
+	if err == sql.ErrNoRows {
+		return nil, fiber.NewError(400, fmt.Sprintf("Class %d not found", id))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !class.IsActive {
+		return nil, fiber.NewError(400, fmt.Sprintf("Class %d%s is inactive", class.Grade, class.Letter))
+	}
+	return class, nil
+}
+
+// classError отправляет ошибку проверки класса
+func classError(c *fiber.Ctx, err error) error {
+	if fiberErr, ok := err.(*fiber.Error); ok {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+	return c.Status(500).JSON(fiber.Map{
+		"error": "Failed to check class",
+	})
+}
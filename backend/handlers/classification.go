@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"library-management/backend/classification"
+	"library-management/backend/database"
+	"library-management/backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetClassificationTree возвращает разделы классификации одного уровня с количеством книг.
+// Без параметра parent возвращаются корневые разделы
+func GetClassificationTree(c *fiber.Ctx) error {
+	table, ok := classification.Get(c.Params("scheme"))
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Unknown classification scheme",
+		})
+	}
+
+	nodes := table.Roots
+	var path []models.ClassificationNode
+
+	counts, err := database.GetBookClassificationCounts(table.Scheme, "", 0)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to count books by classification",
+		})
+	}
+	tally, unclassified := table.Tally(counts)
+
+	if parentIndex := c.Query("parent", ""); parentIndex != "" {
+		parent, ok := table.Lookup(parentIndex)
+		if !ok {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Classification section not found",
+			})
+		}
+		nodes = parent.Children
+		for _, node := range parent.Path() {
+			path = append(path, classificationNode(table, node, tally))
+		}
+	}
+
+	result := make([]models.ClassificationNode, 0, len(nodes))
+	for _, node := range nodes {
+		result = append(result, classificationNode(table, node, tally))
+	}
+
+	if path == nil {
+		path = []models.ClassificationNode{}
+	}
+
+	return c.JSON(fiber.Map{
+		"scheme":       table.Scheme,
+		"path":         path,
+		"nodes":        result,
+		"unclassified": unclassified.Total,
+	})
+}
+
+// ValidateClassificationIndex проверяет и нормализует индекс ББК/УДК
+func ValidateClassificationIndex(c *fiber.Ctx) error {
+	table, ok := classification.Get(c.Params("scheme"))
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Unknown classification scheme",
+		})
+	}
+
+	normalized, err := table.Normalize(c.Query("index", ""))
+	if err != nil {
+		return c.JSON(fiber.Map{
+			"valid": false,
+			"error": err.Error(),
+		})
+	}
+
+	response := fiber.Map{
+		"valid":      true,
+		"normalized": normalized,
+	}
+	if node := table.Resolve(normalized); node != nil {
+		response["section"] = classificationNode(table, node, nil)
+	}
+
+	return c.JSON(response)
+}
+
+// normalizeBookClassification проверяет и нормализует индексы ББК и УДК книги перед сохранением
+func normalizeBookClassification(book *models.Book) error {
+	if table, ok := classification.Get(classification.SchemeBBK); ok {
+		normalized, err := table.Normalize(book.BBK)
+		if err != nil {
+			return fiber.NewError(400, "ББК: "+err.Error())
+		}
+		book.BBK = normalized
+	}
+
+	if table, ok := classification.Get(classification.SchemeUDK); ok {
+		normalized, err := table.Normalize(book.UDK)
+		if err != nil {
+			return fiber.NewError(400, "УДК: "+err.Error())
+		}
+		book.UDK = normalized
+	}
+
+	return nil
+}
+
+// classificationNode преобразует раздел таблицы в модель ответа
+func classificationNode(table *classification.Table, node *classification.Node, tally map[*classification.Node]classification.Count) models.ClassificationNode {
+	result := models.ClassificationNode{
+		Scheme:      table.Scheme,
+		Index:       node.Index,
+		Title:       node.Title,
+		Level:       node.Level,
+		HasChildren: len(node.Children) > 0,
+	}
+	if node.Parent != nil {
+		result.ParentIndex = node.Parent.Index
+	}
+	if count, ok := tally[node]; ok {
+		result.BookCount = count.Total
+		result.AvailableCount = count.Available
+	}
+	return result
+}
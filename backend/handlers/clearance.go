@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"library-management/backend/database"
+	"library-management/backend/documents"
+	"library-management/backend/models"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// readerTypeNames — категории читателей в обходном листе
+var readerTypeNames = map[string]string{
+	"student": "ученик",
+	"teacher": "учитель",
+	"parent":  "родитель",
+}
+
+// CheckReaderClearance проверяет, можно ли выдать читателю обходной лист:
+// возвращает невозвращенные книги и диски
+func CheckReaderClearance(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid reader ID",
+		})
+	}
+
+	check, err := database.CheckClearance(id)
+	if err == sql.ErrNoRows {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Reader not found",
+		})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to check reader debts",
+		})
+	}
+
+	return c.JSON(check)
+}
+
+// IssueClearance выдает обходной лист читателю без долгов: присваивает номер,
+// формирует PDF и сохраняет его для последующей проверки
+func IssueClearance(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid reader ID",
+		})
+	}
+
+	var req models.ClearanceRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Cannot parse request body",
+			})
+		}
+	}
+
+	reader, err := database.GetReaderByID(id)
+	if err == sql.ErrNoRows {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Reader not found",
+		})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch reader",
+		})
+	}
+
+	settings, err := database.GetSettings()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch settings",
+		})
+	}
+
+	clearance := &models.Clearance{
+		ReaderID:   id,
+		ReaderInfo: clearanceReaderInfo(reader),
+		Reason:     strings.TrimSpace(req.Reason),
+		IssuedBy:   c.Locals("userID").(int),
+	}
+	render := func(clearance *models.Clearance) ([]byte, error) {
+		var buf bytes.Buffer
+		err := documents.RenderClearance(&buf, documents.Clearance{
+			Organization:     settings.OrganizationName,
+			Number:           clearance.Number,
+			Date:             clearance.IssuedAt,
+			ReaderName:       clearance.ReaderName,
+			ReaderInfo:       clearance.ReaderInfo,
+			Reason:           clearance.Reason,
+			Librarian:        clearance.IssuedByName,
+			Director:         settings.DirectorName,
+			VerificationCode: clearance.VerificationCode,
+			VerifyURL: fmt.Sprintf("%s/api/clearances/verify/%s?code=%s",
+				c.BaseURL(), url.PathEscape(clearance.Number), clearance.VerificationCode),
+		})
+		return buf.Bytes(), err
+	}
+
+	switch err := database.CreateClearance(clearance, render); err {
+	case nil:
+	case database.ErrReaderNotCleared:
+		check, _ := database.CheckClearance(id)
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Reader has unreturned books or disks",
+			"check": check,
+		})
+	default:
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to issue clearance",
+		})
+	}
+
+	return c.Status(201).JSON(clearance)
+}
+
+// GetReaderClearances возвращает обходные листы, выданные читателю
+func GetReaderClearances(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid reader ID",
+		})
+	}
+
+	clearances, err := database.GetClearances(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch clearances",
+		})
+	}
+
+	return c.JSON(clearances)
+}
+
+// GetClearancePDF отдает сохраненный PDF обходного листа — тот же файл, что был выдан
+func GetClearancePDF(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid clearance ID",
+		})
+	}
+
+	pdf, number, err := database.GetClearancePDF(id)
+	if err == sql.ErrNoRows {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Clearance not found",
+		})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch clearance",
+		})
+	}
+
+	c.Set("Content-Type", "application/pdf")
+	c.Set("Content-Disposition", fmt.Sprintf(`inline; filename="clearance-%s.pdf"`, number))
+	return c.Send(pdf)
+}
+
+// VerifyClearance подтверждает подлинность обходного листа по номеру и коду проверки.
+// Доступен без входа в систему: лист проверяет, например, секретарь школы. Неверные коды
+// считаются по IP-адресу, как неудачные входы, чтобы коды нельзя было перебрать
+func VerifyClearance(c *fiber.Ctx) error {
+	number, err := url.PathUnescape(c.Params("number"))
+	if err != nil || c.Query("code") == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Clearance number and verification code are required",
+		})
+	}
+
+	attempt, err := database.ReserveClearanceCheck(c.IP())
+	if err == database.ErrLoginLocked {
+		return tooManyAttempts(c, *attempt.LockedUntil)
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to verify clearance",
+		})
+	}
+
+	clearance, err := database.VerifyClearance(number, c.Query("code"))
+	if err == sql.ErrNoRows {
+		return loginFailed(c, attempt, 404, "Clearance not found or verification code is wrong")
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to verify clearance",
+		})
+	}
+	if err := database.ReleaseLoginAttempt(attempt); err != nil {
+		log.Printf("clearance: failed to release verification attempt for %s: %v", attempt.IP, err)
+	}
+
+	clearance.VerificationCode = ""
+	return c.JSON(fiber.Map{
+		"valid":     true,
+		"clearance": clearance,
+	})
+}
+
+// clearanceReaderInfo описывает категорию читателя для листа: «ученик 5 "А" класса»
+func clearanceReaderInfo(reader *models.Reader) string {
+	info := readerTypeNames[reader.UserType]
+	if info == "" {
+		info = "читатель"
+	}
+	if reader.ClassID != nil {
+		if class, err := database.GetClass(*reader.ClassID); err == nil {
+			info += fmt.Sprintf(" %s класса", class.DisplayName)
+		}
+	}
+	return info
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"library-management/backend/database"
+	"library-management/backend/media"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CoverMaxSize — максимальный размер загружаемой обложки в байтах (задается из конфигурации)
+var CoverMaxSize int64 = 5 * 1024 * 1024
+
+// UploadBookCover загружает обложку книги
+func UploadBookCover(c *fiber.Ctx) error {
+	return uploadCover(c, media.EntityBook, func(id int) error {
+		_, err := database.GetBookByID(id)
+		return err
+	})
+}
+
+// UploadDiskCover загружает обложку диска
+func UploadDiskCover(c *fiber.Ctx) error {
+	return uploadCover(c, media.EntityDisk, func(id int) error {
+		exists, err := database.DiskExists(id)
+		if err == nil && !exists {
+			err = sql.ErrNoRows
+		}
+		return err
+	})
+}
+
+// DeleteBookCover удаляет обложку книги
+func DeleteBookCover(c *fiber.Ctx) error {
+	return deleteCover(c, media.EntityBook)
+}
+
+// DeleteDiskCover удаляет обложку диска
+func DeleteDiskCover(c *fiber.Ctx) error {
+	return deleteCover(c, media.EntityDisk)
+}
+
+// GetCover отдает обложку книги или диска нужного размера (small, medium, large, original).
+// Маршрут публичный, чтобы обложки можно было подключать через <img src>
+func GetCover(c *fiber.Ctx) error {
+	entityType := c.Params("type")
+	if entityType != media.EntityBook && entityType != media.EntityDisk {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Cover not found",
+		})
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid ID",
+		})
+	}
+
+	size := c.Query("size", "medium")
+	etag, err := database.GetCoverETag(entityType, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Cover not found",
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch cover",
+		})
+	}
+
+	// Ссылки с версией (?v=etag) неизменяемы и кэшируются надолго
+	tag := fmt.Sprintf(`"%s-%s"`, etag, size)
+	c.Set(fiber.HeaderETag, tag)
+	if c.Query("v") == etag {
+		c.Set(fiber.HeaderCacheControl, "public, max-age=31536000, immutable")
+	} else {
+		c.Set(fiber.HeaderCacheControl, "public, max-age=3600")
+	}
+
+	if c.Get(fiber.HeaderIfNoneMatch) == tag {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	data, contentType, err := media.GetCover(entityType, id, size)
+	if err != nil {
+		if errors.Is(err, media.ErrNotFound) {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Cover not found",
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch cover",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	return c.Send(data)
+}
+
+// uploadCover принимает файл из поля file, строит миниатюры и сохраняет обложку
+func uploadCover(c *fiber.Ctx, entityType string, exists func(id int) error) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid ID",
+		})
+	}
+
+	if err := exists(id); err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Record not found",
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to check record",
+		})
+	}
+
+	processed, err := readUploadedImage(c)
+	if err != nil {
+		return imageUploadError(c, err)
+	}
+
+	if err := media.SaveCover(entityType, id, processed); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to save cover",
+		})
+	}
+
+	if err := database.SaveCoverInfo(entityType, id, processed); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to save cover",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"cover_url": media.CoverURL(entityType, id, processed.ETag),
+		"width":     processed.Width,
+		"height":    processed.Height,
+	})
+}
+
+// readUploadedImage читает изображение из поля file, проверяет размер и тип и строит миниатюры
+func readUploadedImage(c *fiber.Ctx) (*media.Processed, error) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return nil, fiber.NewError(400, "File is required")
+	}
+
+	if fileHeader.Size > CoverMaxSize {
+		return nil, fiber.NewError(413, fmt.Sprintf("File is too large, maximum is %d KB", CoverMaxSize/1024))
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, fiber.NewError(400, "Cannot read file")
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, CoverMaxSize+1))
+	if err != nil || int64(len(data)) > CoverMaxSize {
+		return nil, fiber.NewError(400, "Cannot read file")
+	}
+
+	// Тип определяется по содержимому, а не по заголовку Content-Type клиента
+	processed, err := media.Process(data)
+	if err != nil {
+		return nil, fiber.NewError(415, err.Error())
+	}
+	return processed, nil
+}
+
+// imageUploadError отправляет ошибку загрузки изображения
+func imageUploadError(c *fiber.Ctx, err error) error {
+	if fiberErr, ok := err.(*fiber.Error); ok {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+	return c.Status(500).JSON(fiber.Map{
+		"error": "Failed to read image",
+	})
+}
+
+// deleteCover удаляет обложку и ее миниатюры
+func deleteCover(c *fiber.Ctx, entityType string) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid ID",
+		})
+	}
+
+	if err := removeCover(entityType, id); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to delete cover",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Cover deleted successfully",
+	})
+}
+
+// removeCover удаляет сведения об обложке и сами изображения
+func removeCover(entityType string, id int) error {
+	if err := database.DeleteCoverInfo(entityType, id); err != nil {
+		return err
+	}
+	return media.DeleteCover(entityType, id)
+}
+
+// GetNewArrivals возвращает последние поступления с обложками для виджета на главной странице
+func GetNewArrivals(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 12)
+	if limit <= 0 || limit > 100 {
+		limit = 12
+	}
+
+	books, err := database.GetNewArrivals(limit)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch new arrivals",
+		})
+	}
+
+	return c.JSON(books)
+}
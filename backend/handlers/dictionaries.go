@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"library-management/backend/database"
+	"library-management/backend/models"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetSubjects возвращает список предметов
+func GetSubjects(c *fiber.Ctx) error {
+	subjects, err := database.GetSubjects()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch subjects",
+		})
+	}
+
+	return c.JSON(subjects)
+}
+
+// CreateSubject создает новый предмет
+func CreateSubject(c *fiber.Ctx) error {
+	var subject models.Subject
+	if err := c.BodyParser(&subject); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	subject.Name = strings.TrimSpace(subject.Name)
+	if subject.Name == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Subject name is required",
+		})
+	}
+
+	id, err := database.CreateSubject(&subject)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to create subject",
+		})
+	}
+
+	subject.ID = id
+	return c.Status(201).JSON(subject)
+}
+
+// UpdateSubject обновляет предмет
+func UpdateSubject(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid subject ID",
+		})
+	}
+
+	var subject models.Subject
+	if err := c.BodyParser(&subject); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	subject.Name = strings.TrimSpace(subject.Name)
+	if subject.Name == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Subject name is required",
+		})
+	}
+
+	subject.ID = id
+	if err := database.UpdateSubject(&subject); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to update subject",
+		})
+	}
+
+	return c.JSON(subject)
+}
+
+// DeleteSubject удаляет предмет
+func DeleteSubject(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid subject ID",
+		})
+	}
+
+	// Проверяем, есть ли диски по предмету
+	hasDisks, err := database.SubjectHasDisks(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to check subject status",
+		})
+	}
+
+	if hasDisks {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot delete subject with existing disks",
+		})
+	}
+
+	if err := database.DeleteSubject(id); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to delete subject",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Subject deleted successfully",
+	})
+}
+
+// GetResourceTypes возвращает список типов ЭОР
+func GetResourceTypes(c *fiber.Ctx) error {
+	resourceTypes, err := database.GetResourceTypes()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch resource types",
+		})
+	}
+
+	return c.JSON(resourceTypes)
+}
+
+// CreateResourceType создает новый тип ЭОР
+func CreateResourceType(c *fiber.Ctx) error {
+	var resourceType models.ResourceType
+	if err := c.BodyParser(&resourceType); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	resourceType.Name = strings.TrimSpace(resourceType.Name)
+	if resourceType.Name == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Resource type name is required",
+		})
+	}
+
+	id, err := database.CreateResourceType(&resourceType)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to create resource type",
+		})
+	}
+
+	resourceType.ID = id
+	return c.Status(201).JSON(resourceType)
+}
+
+// UpdateResourceType обновляет тип ЭОР
+func UpdateResourceType(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid resource type ID",
+		})
+	}
+
+	var resourceType models.ResourceType
+	if err := c.BodyParser(&resourceType); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	resourceType.Name = strings.TrimSpace(resourceType.Name)
+	if resourceType.Name == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Resource type name is required",
+		})
+	}
+
+	resourceType.ID = id
+	if err := database.UpdateResourceType(&resourceType); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to update resource type",
+		})
+	}
+
+	return c.JSON(resourceType)
+}
+
+// DeleteResourceType удаляет тип ЭОР
+func DeleteResourceType(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid resource type ID",
+		})
+	}
+
+	// Проверяем, есть ли диски данного типа
+	hasDisks, err := database.ResourceTypeHasDisks(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to check resource type status",
+		})
+	}
+
+	if hasDisks {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot delete resource type with existing disks",
+		})
+	}
+
+	if err := database.DeleteResourceType(id); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to delete resource type",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Resource type deleted successfully",
+	})
+}
+
+// resolveDiskDictionaries связывает диск со справочниками предметов и типов ЭОР.
+// Если передан только текст (как из старой формы), запись справочника находится или создается по названию
+func resolveDiskDictionaries(disk *models.Disk) error {
+	if disk.SubjectID != nil {
+		subject, err := database.GetSubjectByID(*disk.SubjectID)
+		if err != nil {
+			return fiber.NewError(400, "Subject not found")
+		}
+		disk.Subject = subject.Name
+	} else if strings.TrimSpace(disk.Subject) != "" {
+		id, err := database.EnsureSubject(disk.Subject)
+		if err != nil {
+			return err
+		}
+		disk.SubjectID = &id
+		disk.Subject = strings.TrimSpace(disk.Subject)
+	}
+
+	if disk.ResourceTypeID != nil {
+		resourceType, err := database.GetResourceTypeByID(*disk.ResourceTypeID)
+		if err != nil {
+			return fiber.NewError(400, "Resource type not found")
+		}
+		disk.ResourceType = resourceType.Name
+	} else if strings.TrimSpace(disk.ResourceType) != "" {
+		id, err := database.EnsureResourceType(disk.ResourceType)
+		if err != nil {
+			return err
+		}
+		disk.ResourceTypeID = &id
+		disk.ResourceType = strings.TrimSpace(disk.ResourceType)
+	}
+
+	return nil
+}
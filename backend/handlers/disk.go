@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"library-management/backend/database"
+	"library-management/backend/media"
 	"library-management/backend/models"
+	"log"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
@@ -11,7 +13,10 @@ import (
 // GetDisks возвращает список дисков
 func GetDisks(c *fiber.Ctx) error {
 	search := c.Query("search", "")
-	disks, err := database.GetDisks(search)
+	subjectID := c.QueryInt("subject_id", 0)
+	resourceTypeID := c.QueryInt("resource_type_id", 0)
+
+	disks, err := database.GetDisks(search, subjectID, resourceTypeID)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Failed to fetch disks",
@@ -30,16 +35,34 @@ func CreateDisk(c *fiber.Ctx) error {
 		})
 	}
 
+	// Проверяем, что штрих-код не занят другой записью
+	if err := claimBarcode("disk", 0, disk.Barcode); err != nil {
+		return claimBarcodeError(c, err)
+	}
+
 	// Получаем ID пользователя из контекста
 	userID := c.Locals("userID").(int)
 	disk.CreatedBy = userID
 
-	// Генерируем код если не указан
-	if disk.Code == "" {
-		disk.Code = database.GenerateDiskCode()
+	// Связываем диск со справочниками
+	if err := resolveDiskDictionaries(&disk); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return c.Status(fiberErr.Code).JSON(fiber.Map{
+				"error": fiberErr.Message,
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to resolve disk dictionaries",
+		})
 	}
 
+	// Код, если не указан, выдается из последовательности при сохранении
 	id, err := database.CreateDisk(&disk)
+	if err == database.ErrCodeExists {
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Disk code already exists",
+		})
+	}
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Failed to create disk",
@@ -47,6 +70,7 @@ func CreateDisk(c *fiber.Ctx) error {
 	}
 
 	disk.ID = id
+	bindBarcode("disk", id, disk.Barcode)
 	return c.Status(201).JSON(disk)
 }
 
@@ -66,12 +90,30 @@ func UpdateDisk(c *fiber.Ctx) error {
 		})
 	}
 
+	// Связываем диск со справочниками
+	if err := resolveDiskDictionaries(&disk); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return c.Status(fiberErr.Code).JSON(fiber.Map{
+				"error": fiberErr.Message,
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to resolve disk dictionaries",
+		})
+	}
+
+	// Проверяем, что штрих-код не занят другой записью
+	if err := claimBarcode("disk", id, disk.Barcode); err != nil {
+		return claimBarcodeError(c, err)
+	}
+
 	disk.ID = id
 	if err := database.UpdateDisk(&disk); err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Failed to update disk",
 		})
 	}
+	bindBarcode("disk", id, disk.Barcode)
 
 	return c.JSON(disk)
 }
@@ -105,6 +147,11 @@ func DeleteDisk(c *fiber.Ctx) error {
 		})
 	}
 
+	// Обложка удаляется вместе с записью; ошибка здесь не отменяет удаление
+	if err := removeCover(media.EntityDisk, id); err != nil {
+		log.Printf("Failed to delete cover of disk %d: %v", id, err)
+	}
+
 	return c.JSON(fiber.Map{
 		"message": "Disk deleted successfully",
 	})
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"database/sql"
+	"library-management/backend/database"
+	"library-management/backend/models"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetHolds возвращает брони читателей: активные или, с status=all, все
+func GetHolds(c *fiber.Ctx) error {
+	status := c.Query("status", "active")
+	if status != "active" && status != "all" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Hold status must be active or all",
+		})
+	}
+
+	holds, err := database.GetHolds(status == "all")
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch holds",
+		})
+	}
+	return c.JSON(holds)
+}
+
+// CreateHold бронирует книгу для читателя по его просьбе у стойки
+func CreateHold(c *fiber.Ctx) error {
+	var req models.HoldRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+	if req.ReaderID <= 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Reader ID is required",
+		})
+	}
+	return placeHold(c, req.ReaderID, req.BookID, "staff", c.Locals("userID").(int))
+}
+
+// CancelHold снимает бронь
+func CancelHold(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid hold ID",
+		})
+	}
+	return cancelHold(c, id, 0, c.Locals("userID").(int))
+}
+
+// placeHold создает бронь и отвечает созданной записью; общий для сотрудников и личного кабинета
+func placeHold(c *fiber.Ctx, readerID, bookID int, source string, userID int) error {
+	if bookID <= 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Book ID is required",
+		})
+	}
+
+	id, err := database.CreateHold(readerID, bookID, source, userID)
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Reader or book not found",
+		})
+	case database.ErrReaderArchived:
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Reader is archived",
+		})
+	case database.ErrBookLoanedToReader:
+		return c.Status(409).JSON(fiber.Map{
+			"error": "The book is already on loan to the reader",
+		})
+	case database.ErrHoldExists:
+		return c.Status(409).JSON(fiber.Map{
+			"error": "The book is already on hold for the reader",
+		})
+	case database.ErrHoldLimit:
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Too many active holds",
+			"limit": database.MaxActiveHolds,
+		})
+	default:
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to create hold",
+		})
+	}
+
+	hold, err := database.GetHold(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch hold",
+		})
+	}
+	return c.Status(201).JSON(hold)
+}
+
+// cancelHold снимает бронь; readerID > 0 ограничивает снятие бронями этого читателя
+func cancelHold(c *fiber.Ctx, id, readerID, userID int) error {
+	switch err := database.CancelHold(id, readerID, userID); err {
+	case nil:
+		return c.JSON(fiber.Map{"message": "Hold cancelled"})
+	case sql.ErrNoRows:
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Hold not found",
+		})
+	case database.ErrHoldNotActive:
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Hold is not active",
+		})
+	default:
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to cancel hold",
+		})
+	}
+}
@@ -0,0 +1,1081 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"library-management/backend/database"
+	"library-management/backend/models"
+	"library-management/backend/spreadsheet"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxImportRows ограничивает количество строк данных в одном файле импорта
+const maxImportRows = 10000
+
+// maxImportHeaderRow — ниже этой строки заголовок не ищется: над ним может быть
+// только шапка документа
+const maxImportHeaderRow = 50
+
+// importFields — поля, которые можно загрузить из файла, с заголовками столбцов по умолчанию.
+// Обязательные поля должны быть заполнены у новых записей
+var importFields = map[string][]models.ImportField{
+	"book": {
+		{Field: "code", Header: "Код"},
+		{Field: "title", Header: "Наименование", Required: true},
+		{Field: "short_title", Header: "Краткое наименование"},
+		{Field: "author", Header: "Автор"},
+		{Field: "publisher", Header: "Издательство"},
+		{Field: "publication_year", Header: "Год издания"},
+		{Field: "barcode", Header: "Штрих-код"},
+		{Field: "isbn", Header: "ISBN"},
+		{Field: "bbk", Header: "ББК"},
+		{Field: "udk", Header: "УДК"},
+		{Field: "class_range", Header: "Класс"},
+		{Field: "location", Header: "Место размещения"},
+	},
+	"reader": {
+		{Field: "code", Header: "Код"},
+		{Field: "barcode", Header: "Штрих-код"},
+		{Field: "last_name", Header: "Фамилия", Required: true},
+		{Field: "first_name", Header: "Имя", Required: true},
+		{Field: "middle_name", Header: "Отчество"},
+		{Field: "user_type", Header: "Тип"},
+		{Field: "class", Header: "Класс"},
+		{Field: "gender", Header: "Пол"},
+		{Field: "birth_date", Header: "Дата рождения"},
+		{Field: "address", Header: "Адрес"},
+		{Field: "phone", Header: "Телефон"},
+		{Field: "email", Header: "Email"},
+		{Field: "parent_mother_name", Header: "ФИО матери"},
+		{Field: "parent_mother_phone", Header: "Телефон матери"},
+		{Field: "parent_father_name", Header: "ФИО отца"},
+		{Field: "parent_father_phone", Header: "Телефон отца"},
+		{Field: "guardian_name", Header: "ФИО опекуна"},
+		{Field: "guardian_phone", Header: "Телефон опекуна"},
+		{Field: "comments", Header: "Примечание"},
+	},
+}
+
+// readerTypeLabels — названия типов читателей, как их пишут в таблицах
+var readerTypeLabels = map[string]string{
+	"ученик":             "student",
+	"учитель":            "teacher",
+	"родитель":           "parent",
+	"опекун":             "guardian",
+	"социальный педагог": "social_pedagogue",
+	"воспитатель":        "educator",
+	"психолог":           "psychologist",
+	"логопед":            "speech_therapist",
+}
+
+// classLatinLetters — латинские буквы, совпадающие по написанию с русскими.
+// В таблицах литеру класса нередко набирают латиницей: "5A" вместо "5А"
+var classLatinLetters = map[rune]rune{
+	'A': 'А', 'B': 'В', 'E': 'Е', 'K': 'К', 'M': 'М', 'H': 'Н',
+	'O': 'О', 'P': 'Р', 'C': 'С', 'T': 'Т', 'X': 'Х', 'Y': 'У',
+}
+
+// GetImportFields возвращает поля импорта и заголовки столбцов по умолчанию
+func GetImportFields(c *fiber.Ctx) error {
+	fields, ok := importFields[c.Query("entity")]
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Unknown import entity, expected book or reader",
+		})
+	}
+
+	return c.JSON(fields)
+}
+
+// GetImportTemplates возвращает сохраненные шаблоны сопоставления столбцов
+func GetImportTemplates(c *fiber.Ctx) error {
+	entityType := c.Query("entity")
+	if _, ok := importFields[entityType]; !ok {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Unknown import entity, expected book or reader",
+		})
+	}
+
+	templates, err := database.GetImportTemplates(entityType)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch import templates",
+		})
+	}
+
+	return c.JSON(templates)
+}
+
+// CreateImportTemplate сохраняет шаблон сопоставления столбцов
+func CreateImportTemplate(c *fiber.Ctx) error {
+	var template models.ImportTemplate
+	if err := c.BodyParser(&template); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	template.ID = 0
+	if err := validateImportTemplate(&template); err != nil {
+		return importRequestError(c, err)
+	}
+
+	template.CreatedBy = c.Locals("userID").(int)
+	id, err := database.CreateImportTemplate(&template)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to create import template",
+		})
+	}
+
+	template.ID = id
+	return c.Status(201).JSON(template)
+}
+
+// UpdateImportTemplate изменяет название и сопоставление столбцов шаблона
+func UpdateImportTemplate(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid template ID",
+		})
+	}
+
+	current, err := database.GetImportTemplate(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Import template not found",
+		})
+	}
+
+	var template models.ImportTemplate
+	if err := c.BodyParser(&template); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	// Тип записей шаблона не меняется
+	template.ID = id
+	template.EntityType = current.EntityType
+	template.CreatedBy = current.CreatedBy
+	template.CreatedAt = current.CreatedAt
+	if err := validateImportTemplate(&template); err != nil {
+		return importRequestError(c, err)
+	}
+
+	if err := database.UpdateImportTemplate(&template); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to update import template",
+		})
+	}
+
+	return c.JSON(template)
+}
+
+// DeleteImportTemplate удаляет шаблон сопоставления столбцов
+func DeleteImportTemplate(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid template ID",
+		})
+	}
+
+	if _, err := database.GetImportTemplate(id); err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Import template not found",
+		})
+	}
+
+	if err := database.DeleteImportTemplate(id); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to delete import template",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Import template deleted successfully",
+	})
+}
+
+// ImportBooks загружает книги из файла CSV/XLSX. Книги с совпадающим кодом или штрих-кодом
+// обновляются, остальные создаются. Авторы и издательства сопоставляются с существующими
+// или создаются. С параметром dry_run файл только проверяется
+func ImportBooks(c *fiber.Ctx) error {
+	sheet, err := readImportSheet(c, "book")
+	if err != nil {
+		return importRequestError(c, err)
+	}
+
+	importer, err := newBookImporter(isDryRun(c), c.Locals("userID").(int))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to prepare import",
+		})
+	}
+
+	result := runImport(sheet, importer.dryRun, importer.importRow)
+	writeImportAudit("book", sheet, &result, importer.userID)
+	return c.JSON(result)
+}
+
+// ImportReaders загружает читателей из файла CSV/XLSX. Читатели с совпадающим кодом
+// или штрих-кодом обновляются, остальные создаются. Класс указывается строкой вида "5А";
+// отсутствующие классы создаются. С параметром dry_run файл только проверяется
+func ImportReaders(c *fiber.Ctx) error {
+	sheet, err := readImportSheet(c, "reader")
+	if err != nil {
+		return importRequestError(c, err)
+	}
+
+	importer, err := newReaderImporter(isDryRun(c), c.Locals("userID").(int))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to prepare import",
+		})
+	}
+
+	result := runImport(sheet, importer.dryRun, importer.importRow)
+	writeImportAudit("reader", sheet, &result, importer.userID)
+	return c.JSON(result)
+}
+
+// importSheet — прочитанный файл импорта с найденными столбцами полей
+type importSheet struct {
+	filename string
+	rows     [][]string
+	header   int               // индекс строки заголовков
+	columns  map[string]int    // поле → номер столбца
+	headers  map[string]string // поле → заголовок столбца
+}
+
+// value возвращает значение поля в строке файла без пробелов по краям
+func (s *importSheet) value(row []string, field string) string {
+	col, ok := s.columns[field]
+	if !ok || col >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[col])
+}
+
+// requiredError сообщает о незаполненном обязательном поле
+func (s *importSheet) requiredError(field string) error {
+	return fmt.Errorf("%q is required", s.headers[field])
+}
+
+// importRowFunc обрабатывает строку файла и сообщает, была ли создана новая запись
+type importRowFunc func(sheet *importSheet, row []string) (created bool, err error)
+
+// runImport обрабатывает строки данных по очереди. Ошибка в строке не останавливает импорт:
+// она попадает в список ошибок с номером строки, как его видит пользователь в Excel
+func runImport(sheet *importSheet, dryRun bool, process importRowFunc) models.ImportResult {
+	result := models.ImportResult{Errors: []models.ImportError{}, DryRun: dryRun}
+
+	for i := sheet.header + 1; i < len(sheet.rows); i++ {
+		row := sheet.rows[i]
+		if rowEmpty(row) {
+			continue
+		}
+
+		created, err := process(sheet, row)
+		if err != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, models.ImportError{Row: i + 1, Error: importErrorMessage(err)})
+			continue
+		}
+
+		result.Success++
+		if created {
+			result.Created++
+		} else {
+			result.Updated++
+		}
+	}
+
+	return result
+}
+
+// writeImportAudit записывает итог импорта в журнал операций
+func writeImportAudit(entityType string, sheet *importSheet, result *models.ImportResult, userID int) {
+	if result.DryRun || result.Success == 0 {
+		return
+	}
+
+	details := map[string]interface{}{
+		"file":    sheet.filename,
+		"created": result.Created,
+		"updated": result.Updated,
+		"failed":  result.Failed,
+	}
+	if err := database.WriteAudit(nil, entityType, 0, "import", details, userID); err != nil {
+		log.Printf("Failed to write import audit: %v", err)
+	}
+}
+
+// readImportSheet читает загруженный файл и находит столбцы полей по заголовкам.
+// Сопоставление берется из заголовков по умолчанию, шаблона (template_id) и параметра mapping —
+// каждый следующий источник переопределяет предыдущий
+func readImportSheet(c *fiber.Ctx, entityType string) (*importSheet, error) {
+	mapping, err := importMapping(c, entityType)
+	if err != nil {
+		return nil, err
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return nil, fiber.NewError(400, "File is required")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, fiber.NewError(400, "Cannot read file")
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fiber.NewError(400, "Cannot read file")
+	}
+
+	// Строки за пределами заголовка и maxImportRows не читаются вовсе
+	rows, err := spreadsheet.Read(fileHeader.Filename, data, maxImportHeaderRow+maxImportRows)
+	if err == spreadsheet.ErrTooManyRows {
+		return nil, fiber.NewError(400, fmt.Sprintf("Too many rows in file, the limit is %d", maxImportRows))
+	}
+	if err != nil {
+		return nil, fiber.NewError(400, "Cannot read file: "+err.Error())
+	}
+
+	sheet := &importSheet{
+		filename: fileHeader.Filename,
+		rows:     rows,
+		header:   -1,
+		columns:  map[string]int{},
+		headers:  map[string]string{},
+	}
+	for i, row := range rows {
+		if !rowEmpty(row) {
+			sheet.header = i
+			break
+		}
+	}
+	if sheet.header < 0 {
+		return nil, fiber.NewError(400, "File is empty")
+	}
+	if sheet.header >= maxImportHeaderRow {
+		return nil, fiber.NewError(400, fmt.Sprintf("Header row must be within the first %d rows", maxImportHeaderRow))
+	}
+	if len(rows)-sheet.header-1 > maxImportRows {
+		return nil, fiber.NewError(400, fmt.Sprintf("Too many rows in file, the limit is %d", maxImportRows))
+	}
+
+	byHeader := map[string]int{}
+	for col, header := range rows[sheet.header] {
+		key := normalizeHeader(header)
+		if _, ok := byHeader[key]; key != "" && !ok {
+			byHeader[key] = col
+		}
+	}
+	for field, header := range mapping {
+		if col, ok := byHeader[normalizeHeader(header)]; ok {
+			sheet.columns[field] = col
+			sheet.headers[field] = header
+		}
+	}
+	if len(sheet.columns) == 0 {
+		return nil, fiber.NewError(400, "None of the expected columns were found in the header row")
+	}
+
+	// Заголовки ненайденных обязательных полей нужны для сообщений об ошибках в строках
+	for _, field := range importFields[entityType] {
+		if _, ok := sheet.headers[field.Field]; !ok {
+			sheet.headers[field.Field] = mapping[field.Field]
+			if sheet.headers[field.Field] == "" {
+				sheet.headers[field.Field] = field.Header
+			}
+		}
+	}
+
+	return sheet, nil
+}
+
+// importMapping собирает сопоставление полей и заголовков столбцов для импорта
+func importMapping(c *fiber.Ctx, entityType string) (map[string]string, error) {
+	mapping := map[string]string{}
+	for _, field := range importFields[entityType] {
+		mapping[field.Field] = field.Header
+	}
+
+	apply := func(overrides map[string]string) error {
+		if err := validateImportMapping(entityType, overrides); err != nil {
+			return err
+		}
+		for field, header := range overrides {
+			if header == "" {
+				// Пустой заголовок — поле не загружается
+				delete(mapping, field)
+				continue
+			}
+			mapping[field] = header
+		}
+		return nil
+	}
+
+	if value := c.FormValue("template_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fiber.NewError(400, "Invalid template ID")
+		}
+		template, err := database.GetImportTemplate(id)
+		if err != nil {
+			return nil, fiber.NewError(404, "Import template not found")
+		}
+		if template.EntityType != entityType {
+			return nil, fiber.NewError(400, fmt.Sprintf("Import template is for %ss", template.EntityType))
+		}
+		if err := apply(template.Mapping); err != nil {
+			return nil, err
+		}
+	}
+
+	if value := c.FormValue("mapping"); value != "" {
+		var overrides map[string]string
+		if err := json.Unmarshal([]byte(value), &overrides); err != nil {
+			return nil, fiber.NewError(400, "Invalid mapping, expected a JSON object of field to column header")
+		}
+		if err := apply(overrides); err != nil {
+			return nil, err
+		}
+	}
+
+	return mapping, nil
+}
+
+// validateImportTemplate проверяет название, тип записей и сопоставление столбцов шаблона
+func validateImportTemplate(template *models.ImportTemplate) error {
+	template.Name = strings.TrimSpace(template.Name)
+	if template.Name == "" {
+		return fiber.NewError(400, "Template name is required")
+	}
+	if _, ok := importFields[template.EntityType]; !ok {
+		return fiber.NewError(400, "Unknown import entity, expected book or reader")
+	}
+	if template.Mapping == nil {
+		template.Mapping = map[string]string{}
+	}
+	if err := validateImportMapping(template.EntityType, template.Mapping); err != nil {
+		return err
+	}
+
+	exists, err := database.ImportTemplateNameExists(template.EntityType, template.Name, template.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fiber.NewError(409, "Import template with this name already exists")
+	}
+
+	return nil
+}
+
+// validateImportMapping проверяет, что сопоставление ссылается только на известные поля
+func validateImportMapping(entityType string, mapping map[string]string) error {
+	known := map[string]bool{}
+	for _, field := range importFields[entityType] {
+		known[field.Field] = true
+	}
+	for field, header := range mapping {
+		if !known[field] {
+			return fiber.NewError(400, fmt.Sprintf("Unknown import field %q", field))
+		}
+		mapping[field] = strings.TrimSpace(header)
+	}
+	return nil
+}
+
+// importRequestError отправляет ошибку проверки запроса импорта
+func importRequestError(c *fiber.Ctx, err error) error {
+	if fiberErr, ok := err.(*fiber.Error); ok {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+	return c.Status(500).JSON(fiber.Map{
+		"error": "Failed to process import request",
+	})
+}
+
+// importErrorMessage возвращает текст ошибки строки для пользователя
+func importErrorMessage(err error) string {
+	if fiberErr, ok := err.(*fiber.Error); ok {
+		return fiberErr.Message
+	}
+	return err.Error()
+}
+
+// isDryRun проверяет, запрошена ли только проверка файла без сохранения
+func isDryRun(c *fiber.Ctx) bool {
+	value := c.FormValue("dry_run", c.Query("dry_run"))
+	return value == "true" || value == "1"
+}
+
+// normalizeHeader приводит заголовок столбца к виду для сравнения: без регистра,
+// лишних пробелов и звездочки обязательного поля
+func normalizeHeader(header string) string {
+	header = strings.ReplaceAll(strings.ToLower(header), "ё", "е")
+	header = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(header), "*"))
+	return strings.Join(strings.Fields(header), " ")
+}
+
+// rowEmpty проверяет, что в строке нет заполненных ячеек
+func rowEmpty(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// importKeys отслеживает коды и штрих-коды, уже встретившиеся в файле
+type importKeys struct {
+	codes    map[string]bool
+	barcodes map[string]bool
+}
+
+// check сообщает о повторе кода или штрих-кода внутри файла
+func (k *importKeys) check(code, barcode string) error {
+	if k.codes == nil {
+		k.codes, k.barcodes = map[string]bool{}, map[string]bool{}
+	}
+	if code != "" && k.codes[code] {
+		return fmt.Errorf("Code %s appears in the file more than once", code)
+	}
+	if barcode != "" && k.barcodes[barcode] {
+		return fmt.Errorf("Barcode %s appears in the file more than once", barcode)
+	}
+	if code != "" {
+		k.codes[code] = true
+	}
+	if barcode != "" {
+		k.barcodes[barcode] = true
+	}
+	return nil
+}
+
+// setImportString переносит непустое значение поля в запись. Пустые ячейки
+// не затирают данные существующей записи
+func setImportString(sheet *importSheet, row []string, field string, dst *string) {
+	if value := sheet.value(row, field); value != "" {
+		*dst = value
+	}
+}
+
+// bookImporter загружает книги, сопоставляя авторов, издательства и места хранения
+type bookImporter struct {
+	dryRun     bool
+	userID     int
+	keys       importKeys
+	authors    []models.Author
+	publishers map[string]int // ключ названия → ID; отрицательный — будет создано при сохранении
+	locations  map[string]int // путь места хранения → ID
+}
+
+// newBookImporter загружает справочники для сопоставления
+func newBookImporter(dryRun bool, userID int) (*bookImporter, error) {
+	authors, err := database.GetAuthors()
+	if err != nil {
+		return nil, err
+	}
+	publishers, err := database.GetPublishers()
+	if err != nil {
+		return nil, err
+	}
+	locations, err := database.GetLocations()
+	if err != nil {
+		return nil, err
+	}
+
+	b := &bookImporter{
+		dryRun:     dryRun,
+		userID:     userID,
+		authors:    authors,
+		publishers: map[string]int{},
+		locations:  map[string]int{},
+	}
+	for _, publisher := range publishers {
+		key := database.PublisherKey(publisher.Name)
+		if _, ok := b.publishers[key]; !ok {
+			b.publishers[key] = publisher.ID
+		}
+	}
+	for _, location := range locations {
+		b.locations[normalizeHeader(location.Path)] = location.ID
+	}
+	return b, nil
+}
+
+// importRow создает или обновляет книгу из строки файла
+func (b *bookImporter) importRow(sheet *importSheet, row []string) (bool, error) {
+	code, barcode := sheet.value(row, "code"), sheet.value(row, "barcode")
+	if err := b.keys.check(code, barcode); err != nil {
+		return false, err
+	}
+
+	id, err := database.FindImportTarget("book", code, barcode)
+	if err != nil {
+		return false, err
+	}
+
+	var book models.Book
+	if id > 0 {
+		existing, err := database.GetBookByID(id)
+		if err != nil {
+			return false, err
+		}
+		book = *existing
+	} else {
+		book.Code = code
+	}
+
+	setImportString(sheet, row, "title", &book.Title)
+	setImportString(sheet, row, "short_title", &book.ShortTitle)
+	setImportString(sheet, row, "barcode", &book.Barcode)
+	setImportString(sheet, row, "isbn", &book.ISBN)
+	setImportString(sheet, row, "bbk", &book.BBK)
+	setImportString(sheet, row, "udk", &book.UDK)
+	setImportString(sheet, row, "class_range", &book.ClassRange)
+
+	if book.Title == "" {
+		return false, sheet.requiredError("title")
+	}
+
+	if value := sheet.value(row, "publication_year"); value != "" {
+		year, err := strconv.Atoi(value)
+		if err != nil || year < 1000 || year > time.Now().Year()+1 {
+			return false, fmt.Errorf("Invalid publication year %q", value)
+		}
+		book.PublicationYear = &year
+	}
+
+	if err := normalizeBookClassification(&book); err != nil {
+		return false, err
+	}
+
+	if value := sheet.value(row, "author"); value != "" {
+		authorID, err := b.resolveAuthor(value)
+		if err != nil {
+			return false, err
+		}
+		book.AuthorID = authorID
+	}
+
+	if value := sheet.value(row, "publisher"); value != "" {
+		publisherID, err := b.resolvePublisher(value)
+		if err != nil {
+			return false, err
+		}
+		book.PublisherID = publisherID
+	}
+
+	// Место размещения, совпадающее с путем места хранения, привязывает книгу к нему;
+	// иначе сохраняется как текст
+	moveTo := 0
+	if value := sheet.value(row, "location"); value != "" {
+		if locationID, ok := b.locations[normalizeHeader(value)]; ok {
+			if id == 0 {
+				book.LocationID = &locationID
+				book.Location = value
+				if err := resolveBookLocation(&book); err != nil {
+					return false, err
+				}
+			} else if book.LocationID == nil || *book.LocationID != locationID {
+				moveTo = locationID
+			}
+		} else {
+			book.Location = value
+		}
+	}
+
+	if err := claimBarcode("book", id, book.Barcode); err != nil {
+		return false, err
+	}
+
+	if b.dryRun {
+		return id == 0, nil
+	}
+
+	if id == 0 {
+		book.CreatedBy = b.userID
+		newID, err := database.CreateBook(&book)
+		if err == database.ErrCodeExists {
+			return false, fmt.Errorf("Book code already exists")
+		}
+		if err != nil {
+			return false, fmt.Errorf("Failed to create book")
+		}
+		bindBarcode("book", newID, book.Barcode)
+		return true, nil
+	}
+
+	if err := database.UpdateBook(&book); err != nil {
+		return false, fmt.Errorf("Failed to update book")
+	}
+	bindBarcode("book", id, book.Barcode)
+	if moveTo > 0 {
+		if _, _, err := database.MoveBooks(moveTo, []int{id}, b.userID); err != nil {
+			return false, fmt.Errorf("Failed to move book")
+		}
+	}
+	return false, nil
+}
+
+// resolveAuthor находит автора по фамилии и инициалам или создает нового.
+// При проверке (dry run) новый автор не сохраняется, и книга остается без ссылки на него
+func (b *bookImporter) resolveAuthor(name string) (*int, error) {
+	author := parseAuthorName(name)
+	if author.LastName == "" {
+		return nil, fmt.Errorf("Cannot recognize author name %q", name)
+	}
+
+	if matches := database.MatchAuthors(&author, b.authors); len(matches) > 0 {
+		if matches[0].ID < 0 {
+			return nil, nil
+		}
+		id := matches[0].ID
+		return &id, nil
+	}
+
+	fillAuthorShortName(&author)
+	if b.dryRun {
+		// Отрицательный ID отмечает автора, который будет создан, чтобы следующие строки нашли его
+		author.ID = -len(b.authors) - 1
+		b.authors = append(b.authors, author)
+		return nil, nil
+	}
+
+	id, err := database.CreateAuthor(&author)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create author %q", name)
+	}
+	author.ID = id
+	b.authors = append(b.authors, author)
+	return &id, nil
+}
+
+// resolvePublisher находит издательство по нормализованному названию или создает новое
+func (b *bookImporter) resolvePublisher(name string) (*int, error) {
+	key := database.PublisherKey(name)
+	if id, ok := b.publishers[key]; ok {
+		if id < 0 {
+			return nil, nil
+		}
+		return &id, nil
+	}
+
+	if b.dryRun {
+		b.publishers[key] = -1
+		return nil, nil
+	}
+
+	id, err := database.CreatePublisher(&models.Publisher{Name: name})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create publisher %q", name)
+	}
+	b.publishers[key] = id
+	return &id, nil
+}
+
+// parseAuthorName разбирает имя автора: "Толстой Лев Николаевич", "Толстой Л.Н." или "Л.Н. Толстой".
+// Полное имя записывается в порядке фамилия, имя, отчество
+func parseAuthorName(value string) models.Author {
+	var names, initials []string
+	for _, part := range strings.Fields(strings.NewReplacer(".", ". ", ",", " ").Replace(value)) {
+		letters := strings.TrimSuffix(part, ".")
+		if letters == "" {
+			continue
+		}
+		if len([]rune(letters)) == 1 {
+			initials = append(initials, strings.ToUpper(letters))
+		} else {
+			names = append(names, letters)
+		}
+	}
+
+	var author models.Author
+	if len(names) == 0 {
+		return author
+	}
+
+	author.LastName = names[0]
+	if len(initials) > 0 {
+		author.ShortName = author.LastName + " " + strings.Join(initials, ".") + "."
+		return author
+	}
+	if len(names) > 1 {
+		author.FirstName = names[1]
+	}
+	if len(names) > 2 {
+		author.MiddleName = strings.Join(names[2:], " ")
+	}
+	return author
+}
+
+// readerImporter загружает читателей, сопоставляя классы
+type readerImporter struct {
+	dryRun  bool
+	userID  int
+	keys    importKeys
+	classes map[string]int // "5А" → ID; отрицательный — будет создан при сохранении
+	// inactive — выключенные классы, в которые читателей не записывают
+	inactive map[string]bool
+}
+
+// newReaderImporter загружает классы для сопоставления
+func newReaderImporter(dryRun bool, userID int) (*readerImporter, error) {
+	classes, err := database.GetClasses()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &readerImporter{dryRun: dryRun, userID: userID, classes: map[string]int{}, inactive: map[string]bool{}}
+	for _, class := range classes {
+		key := fmt.Sprintf("%d%s", class.Grade, normalizeClassLetter(class.Letter))
+		if _, ok := r.classes[key]; !ok {
+			r.classes[key] = class.ID
+			r.inactive[key] = !class.IsActive
+		}
+	}
+	return r, nil
+}
+
+// importRow создает или обновляет читателя из строки файла
+func (r *readerImporter) importRow(sheet *importSheet, row []string) (bool, error) {
+	code, barcode := sheet.value(row, "code"), sheet.value(row, "barcode")
+	if err := r.keys.check(code, barcode); err != nil {
+		return false, err
+	}
+
+	id, err := database.FindImportTarget("reader", code, barcode)
+	if err != nil {
+		return false, err
+	}
+
+	var reader models.Reader
+	if id > 0 {
+		existing, err := database.GetReaderByID(id)
+		if err != nil {
+			return false, err
+		}
+		reader = *existing
+	} else {
+		reader.Code = code
+	}
+
+	setImportString(sheet, row, "barcode", &reader.Barcode)
+	setImportString(sheet, row, "last_name", &reader.LastName)
+	setImportString(sheet, row, "first_name", &reader.FirstName)
+	setImportString(sheet, row, "middle_name", &reader.MiddleName)
+	setImportString(sheet, row, "address", &reader.Address)
+	setImportString(sheet, row, "phone", &reader.Phone)
+	setImportString(sheet, row, "email", &reader.Email)
+	setImportString(sheet, row, "parent_mother_name", &reader.ParentMotherName)
+	setImportString(sheet, row, "parent_mother_phone", &reader.ParentMotherPhone)
+	setImportString(sheet, row, "parent_father_name", &reader.ParentFatherName)
+	setImportString(sheet, row, "parent_father_phone", &reader.ParentFatherPhone)
+	setImportString(sheet, row, "guardian_name", &reader.GuardianName)
+	setImportString(sheet, row, "guardian_phone", &reader.GuardianPhone)
+	setImportString(sheet, row, "comments", &reader.Comments)
+
+	if reader.LastName == "" {
+		return false, sheet.requiredError("last_name")
+	}
+	if reader.FirstName == "" {
+		return false, sheet.requiredError("first_name")
+	}
+
+	if value := sheet.value(row, "user_type"); value != "" {
+		userType, ok := parseReaderType(value)
+		if !ok {
+			return false, fmt.Errorf("Unknown reader type %q", value)
+		}
+		reader.UserType = userType
+	}
+
+	if value := sheet.value(row, "class"); value != "" {
+		grade, letter, err := parseClassName(value)
+		if err != nil {
+			return false, err
+		}
+		classID, err := r.resolveClass(grade, letter)
+		if err != nil {
+			return false, err
+		}
+		reader.ClassID = classID
+		reader.Grade = &grade
+	}
+	if reader.UserType == "" {
+		reader.UserType = "student"
+	}
+
+	if value := sheet.value(row, "gender"); value != "" {
+		gender, ok := parseGender(value)
+		if !ok {
+			return false, fmt.Errorf("Unknown gender %q, expected М or Ж", value)
+		}
+		reader.Gender = gender
+	}
+
+	if value := sheet.value(row, "birth_date"); value != "" {
+		birthDate, err := parseImportDate(value)
+		if err != nil || birthDate.After(time.Now()) {
+			return false, fmt.Errorf("Invalid birth date %q, expected DD.MM.YYYY", value)
+		}
+		reader.BirthDate = &birthDate
+	}
+
+	if err := checkPersonalData(&reader); err != nil {
+		return false, err
+	}
+	if err := claimBarcode("reader", id, reader.Barcode); err != nil {
+		return false, err
+	}
+
+	if r.dryRun {
+		return id == 0, nil
+	}
+
+	if id == 0 {
+		reader.CreatedBy = r.userID
+		newID, err := database.CreateReader(&reader)
+		if err == database.ErrCodeExists {
+			return false, fmt.Errorf("Reader code already exists")
+		}
+		if err != nil {
+			return false, fmt.Errorf("Failed to create reader")
+		}
+		bindBarcode("reader", newID, reader.Barcode)
+		return true, nil
+	}
+
+	if err := database.UpdateReader(&reader); err != nil {
+		return false, fmt.Errorf("Failed to update reader")
+	}
+	bindBarcode("reader", id, reader.Barcode)
+	return false, nil
+}
+
+// resolveClass находит класс по параллели и литере или создает новый.
+// При проверке (dry run) класс не создается, и читатель остается без ссылки на него
+func (r *readerImporter) resolveClass(grade int, letter string) (*int, error) {
+	key := fmt.Sprintf("%d%s", grade, letter)
+	if r.inactive[key] {
+		return nil, fmt.Errorf("Class %s is inactive", key)
+	}
+	if id, ok := r.classes[key]; ok {
+		if id < 0 {
+			return nil, nil
+		}
+		return &id, nil
+	}
+
+	if r.dryRun {
+		r.classes[key] = -1
+		return nil, nil
+	}
+
+	id, err := database.CreateClass(&models.Class{Grade: grade, Letter: letter})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create class %d%s", grade, letter)
+	}
+	r.classes[key] = id
+	return &id, nil
+}
+
+// parseClassName разбирает класс вида "5А", "5 А", "5-а" или "5 \"А\"" на параллель и литеру
+func parseClassName(value string) (int, string, error) {
+	runes := []rune(strings.TrimSpace(value))
+	i := 0
+	for i < len(runes) && unicode.IsDigit(runes[i]) {
+		i++
+	}
+
+	grade, err := strconv.Atoi(string(runes[:i]))
+	if err != nil || grade < 1 || grade > 11 {
+		return 0, "", fmt.Errorf("Invalid class %q, expected a grade and a letter like 5А", value)
+	}
+
+	var letter []rune
+	for _, r := range runes[i:] {
+		switch {
+		case unicode.IsLetter(r):
+			letter = append(letter, r)
+		case unicode.IsSpace(r) || strings.ContainsRune("-\"'«»", r):
+		default:
+			return 0, "", fmt.Errorf("Invalid class %q, expected a grade and a letter like 5А", value)
+		}
+	}
+	if len(letter) != 1 {
+		return 0, "", fmt.Errorf("Invalid class %q, expected a grade and a letter like 5А", value)
+	}
+
+	return grade, normalizeClassLetter(string(letter)), nil
+}
+
+// normalizeClassLetter приводит литеру класса к заглавной кириллической букве
+func normalizeClassLetter(letter string) string {
+	runes := []rune(strings.ToUpper(strings.TrimSpace(letter)))
+	for i, r := range runes {
+		if cyrillic, ok := classLatinLetters[r]; ok {
+			runes[i] = cyrillic
+		} else if r == 'Ё' {
+			runes[i] = 'Е'
+		}
+	}
+	return string(runes)
+}
+
+// parseReaderType распознает тип читателя по коду ("student") или названию ("Ученик")
+func parseReaderType(value string) (string, bool) {
+	value = strings.Join(strings.Fields(strings.ToLower(value)), " ")
+	if userType, ok := readerTypeLabels[value]; ok {
+		return userType, true
+	}
+	for _, userType := range readerTypeLabels {
+		if userType == value {
+			return userType, true
+		}
+	}
+	return "", false
+}
+
+// parseGender приводит пол к значениям "М" или "Ж"
+func parseGender(value string) (string, bool) {
+	switch strings.ToLower(strings.TrimSuffix(strings.TrimSpace(value), ".")) {
+	case "м", "муж", "мужской", "m", "male":
+		return "М", true
+	case "ж", "жен", "женский", "f", "female":
+		return "Ж", true
+	}
+	return "", false
+}
+
+// parseImportDate разбирает дату в форматах DD.MM.YYYY и YYYY-MM-DD
+func parseImportDate(value string) (time.Time, error) {
+	var err error
+	for _, layout := range []string{"02.01.2006", "2.1.2006", "2006-01-02"} {
+		var date time.Time
+		if date, err = time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, err
+}
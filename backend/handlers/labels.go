@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"library-management/backend/database"
+	"library-management/backend/labels"
+	"library-management/backend/models"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxLabels ограничивает размер одного PDF с этикетками
+const maxLabels = 5000
+
+// GetLabelTemplates возвращает стандартные шаблоны листов этикеток
+func GetLabelTemplates(c *fiber.Ctx) error {
+	return c.JSON(labels.Templates())
+}
+
+// PrintLabels формирует PDF с этикетками штрих-кодов или QR-кодов для книг, читателей или дисков
+func PrintLabels(c *fiber.Ctx) error {
+	var req models.LabelRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	switch req.Entity {
+	case "book", "reader", "disk":
+	default:
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Entity must be 'book', 'reader' or 'disk'",
+		})
+	}
+
+	opts, err := labelOptions(&req)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	req.BarcodeFrom = strings.TrimSpace(req.BarcodeFrom)
+	req.BarcodeTo = strings.TrimSpace(req.BarcodeTo)
+	items, err := database.GetLabelItems(req)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch items",
+		})
+	}
+
+	if len(items) == 0 {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "No items with barcodes match the selection",
+		})
+	}
+	if len(items) > maxLabels {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("Too many labels (%d), the limit is %d", len(items), maxLabels),
+		})
+	}
+
+	batch := make([]labels.Label, len(items))
+	for i, item := range items {
+		batch[i] = labels.Label{Title: item.Title, Subtitle: item.Subtitle, Code: item.Barcode}
+	}
+
+	return sendLabels(c, "labels-"+req.Entity+".pdf", batch, opts)
+}
+
+// labelOptions проверяет параметры листа и символики из запроса на печать
+func labelOptions(req *models.LabelRequest) (labels.Options, error) {
+	switch req.Symbology {
+	case "":
+		req.Symbology = labels.SymbologyCode128
+	case labels.SymbologyCode128, labels.SymbologyEAN13, labels.SymbologyQR:
+	default:
+		return labels.Options{}, errors.New("Symbology must be 'code128', 'ean13' or 'qr'")
+	}
+
+	if req.Template == "" {
+		req.Template = "a4-40"
+	}
+	tmpl, ok := labels.GetTemplate(req.Template)
+	if !ok {
+		return labels.Options{}, errors.New("Unknown label template")
+	}
+	// Поля и промежутки можно подогнать под конкретную партию листов
+	if req.MarginTop != nil {
+		tmpl.MarginTop = *req.MarginTop
+	}
+	if req.MarginLeft != nil {
+		tmpl.MarginLeft = *req.MarginLeft
+	}
+	if req.GapX != nil {
+		tmpl.GapX = *req.GapX
+	}
+	if req.GapY != nil {
+		tmpl.GapY = *req.GapY
+	}
+	if err := tmpl.Validate(); err != nil {
+		return labels.Options{}, err
+	}
+
+	if req.StartPosition == 0 {
+		req.StartPosition = 1
+	}
+	if req.StartPosition < 1 || req.StartPosition > tmpl.PerPage() {
+		return labels.Options{}, fmt.Errorf("Start position must be between 1 and %d", tmpl.PerPage())
+	}
+
+	return labels.Options{
+		Template:  tmpl,
+		Symbology: req.Symbology,
+		Skip:      req.StartPosition - 1,
+		Outline:   req.Outline,
+	}, nil
+}
+
+// sendLabels формирует PDF с этикетками и отправляет его клиенту
+func sendLabels(c *fiber.Ctx, filename string, batch []labels.Label, opts labels.Options) error {
+	var buf bytes.Buffer
+	if err := labels.Render(&buf, batch, opts); err != nil {
+		var codeErr *labels.CodeError
+		if errors.As(err, &codeErr) {
+			return c.Status(400).JSON(fiber.Map{
+				"error":   "Barcode cannot be encoded: " + codeErr.Err.Error(),
+				"barcode": codeErr.Code,
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to render labels",
+		})
+	}
+
+	c.Set("Content-Type", "application/pdf")
+	c.Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, filename))
+	return c.Send(buf.Bytes())
+}
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"database/sql"
 	"library-management/backend/database"
 	"library-management/backend/models"
+	"library-management/backend/rbac"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -52,6 +54,35 @@ func IssueBook(c *fiber.Ctx) error {
 			"error": "Абонент не найден",
 		})
 	}
+	if reader.Status == "archived" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Абонент в архиве",
+		})
+	}
+
+	// Забронированную книгу выдают первому в очереди; другому читателю — только с правом loans.override
+	hold, err := database.NextHold(book.ID)
+	if err != nil && err != sql.ErrNoRows {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Не удалось проверить брони",
+		})
+	}
+	// Обойденная бронь пишется в журнал вместе с выдачей
+	var overridden *models.Hold
+	if hold != nil && hold.ReaderID != reader.ID {
+		role, _ := c.Locals("role").(string)
+		if !req.Override || !rbac.Has(role, rbac.LoansOverride) {
+			return c.Status(409).JSON(fiber.Map{
+				"error": "Книга забронирована другим абонентом",
+				"details": fiber.Map{
+					"reader":    hold.ReaderName,
+					"hold_date": hold.CreatedAt.Format("02.01.2006"),
+				},
+				"can_override": rbac.Has(role, rbac.LoansOverride),
+			})
+		}
+		overridden = hold
+	}
 
 	// Создаем запись о выдаче
 	loan := &models.Loan{
@@ -62,7 +93,7 @@ func IssueBook(c *fiber.Ctx) error {
 		Status:    "active",
 	}
 
-	loanID, err := database.CreateLoan(loan)
+	loanID, err := database.CreateLoan(loan, overridden)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Не удалось выдать книгу",
@@ -72,6 +103,7 @@ func IssueBook(c *fiber.Ctx) error {
 	loan.ID = loanID
 	loan.Book = book
 	loan.Reader = reader
+	maskPersonalData(reader)
 
 	return c.Status(201).JSON(fiber.Map{
 		"message": "Книга успешно выдана",
@@ -121,6 +153,7 @@ func ReturnBook(c *fiber.Ctx) error {
 
 	// Вычисляем количество дней
 	daysOnLoan := int(now.Sub(loan.IssueDate).Hours() / 24)
+	maskPersonalData(loan.Reader)
 
 	return c.JSON(fiber.Map{
 		"message": "Книга успешно возвращена",
@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"fmt"
+	"library-management/backend/database"
+	"library-management/backend/models"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetLocations возвращает места хранения в порядке обхода дерева
+func GetLocations(c *fiber.Ctx) error {
+	locations, err := database.GetLocations()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch locations",
+		})
+	}
+
+	return c.JSON(locations)
+}
+
+// GetLocationByBarcode возвращает место хранения по штрих-коду полки
+func GetLocationByBarcode(c *fiber.Ctx) error {
+	location, err := database.GetLocationByBarcode(c.Params("barcode"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Location not found",
+		})
+	}
+
+	return c.JSON(location)
+}
+
+// CreateLocation создает место хранения
+func CreateLocation(c *fiber.Ctx) error {
+	var location models.Location
+	if err := c.BodyParser(&location); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	if err := validateLocation(&location); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return c.Status(fiberErr.Code).JSON(fiber.Map{
+				"error": fiberErr.Message,
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to validate location",
+		})
+	}
+
+	id, err := database.CreateLocation(&location)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to create location",
+		})
+	}
+
+	created, err := database.GetLocationByID(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch location",
+		})
+	}
+
+	return c.Status(201).JSON(created)
+}
+
+// UpdateLocation обновляет место хранения
+func UpdateLocation(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid location ID",
+		})
+	}
+
+	if _, err := database.GetLocationByID(id); err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Location not found",
+		})
+	}
+
+	var location models.Location
+	if err := c.BodyParser(&location); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	location.ID = id
+	if err := validateLocation(&location); err != nil {
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			return c.Status(fiberErr.Code).JSON(fiber.Map{
+				"error": fiberErr.Message,
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to validate location",
+		})
+	}
+
+	if err := database.UpdateLocation(&location); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to update location",
+		})
+	}
+
+	updated, err := database.GetLocationByID(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch location",
+		})
+	}
+
+	return c.JSON(updated)
+}
+
+// DeleteLocation удаляет место хранения
+func DeleteLocation(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid location ID",
+		})
+	}
+
+	// Проверяем, нет ли вложенных мест и книг
+	hasChildren, err := database.LocationHasChildren(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to check location status",
+		})
+	}
+
+	if hasChildren {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot delete location with nested locations",
+		})
+	}
+
+	hasBooks, err := database.LocationHasBooks(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to check location status",
+		})
+	}
+
+	if hasBooks {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot delete location with books; move them first",
+		})
+	}
+
+	if err := database.DeleteLocation(id); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to delete location",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Location deleted successfully",
+	})
+}
+
+// MoveBooks перемещает одну или несколько книг в место хранения.
+// Книги и полку можно указать отсканированными штрих-кодами
+func MoveBooks(c *fiber.Ctx) error {
+	var req models.MoveBooksRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	var location *models.Location
+	var err error
+	switch {
+	case req.LocationID != nil:
+		location, err = database.GetLocationByID(*req.LocationID)
+	case strings.TrimSpace(req.LocationBarcode) != "":
+		location, err = database.GetLocationByBarcode(strings.TrimSpace(req.LocationBarcode))
+	default:
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Location ID or barcode is required",
+		})
+	}
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Location not found",
+		})
+	}
+
+	result := models.MoveBooksResult{Location: location, NotFound: []string{}}
+
+	// Собираем книги без повторов: один экземпляр могли отсканировать дважды
+	var bookIDs []int
+	seen := map[int]bool{}
+	addBook := func(id int) {
+		if !seen[id] {
+			seen[id] = true
+			bookIDs = append(bookIDs, id)
+		}
+	}
+	for _, id := range req.BookIDs {
+		if _, err := database.GetBookByID(id); err != nil {
+			result.NotFound = append(result.NotFound, strconv.Itoa(id))
+			continue
+		}
+		addBook(id)
+	}
+	for _, barcode := range req.BookBarcodes {
+		barcode = strings.TrimSpace(barcode)
+		if barcode == "" {
+			continue
+		}
+		book, err := database.GetBookByBarcode(barcode)
+		if err != nil {
+			result.NotFound = append(result.NotFound, barcode)
+			continue
+		}
+		addBook(book.ID)
+	}
+
+	if len(bookIDs) == 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error":     "No books to move",
+			"not_found": result.NotFound,
+		})
+	}
+
+	userID := c.Locals("userID").(int)
+	result.Moved, result.Unchanged, err = database.MoveBooks(location.ID, bookIDs, userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to move books",
+		})
+	}
+
+	return c.JSON(result)
+}
+
+// GetBookMoves возвращает историю перемещений книги
+func GetBookMoves(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid book ID",
+		})
+	}
+
+	moves, err := database.GetBookMoves(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch book moves",
+		})
+	}
+
+	return c.JSON(moves)
+}
+
+// resolveBookLocation проверяет место хранения новой книги и заполняет текстовое место хранения
+func resolveBookLocation(book *models.Book) error {
+	if book.LocationID == nil {
+		return nil
+	}
+
+	location, err := database.GetLocationByID(*book.LocationID)
+	if err != nil {
+		return fiber.NewError(400, "Location not found")
+	}
+
+	book.Location = location.Path
+	return nil
+}
+
+// validateLocation проверяет название, вид и вложенность места хранения, а также уникальность штрих-кода.
+// Вид вложенного места должен быть «глубже» вида родителя: здание → помещение → шкаф → полка
+func validateLocation(location *models.Location) error {
+	location.Name = strings.TrimSpace(location.Name)
+	location.Barcode = strings.TrimSpace(location.Barcode)
+	if location.Name == "" {
+		return fiber.NewError(400, "Location name is required")
+	}
+
+	level := database.LocationKindLevel(location.Kind)
+	if level == 0 {
+		return fiber.NewError(400, fmt.Sprintf("Location kind must be one of: %s", strings.Join(database.LocationKinds, ", ")))
+	}
+
+	if location.ParentID != nil {
+		parent, err := database.GetLocationByID(*location.ParentID)
+		if err != nil {
+			return fiber.NewError(400, "Parent location not found")
+		}
+		if database.LocationKindLevel(parent.Kind) >= level {
+			return fiber.NewError(400, fmt.Sprintf("A %s cannot be placed inside a %s", location.Kind, parent.Kind))
+		}
+	}
+
+	if location.ID > 0 {
+		locations, err := database.GetLocations()
+		if err != nil {
+			return fiber.NewError(500, "Failed to check nested locations")
+		}
+		for _, child := range locations {
+			if child.ParentID != nil && *child.ParentID == location.ID && database.LocationKindLevel(child.Kind) <= level {
+				return fiber.NewError(400, fmt.Sprintf("Location contains a %s and cannot become a %s", child.Kind, location.Kind))
+			}
+		}
+	}
+
+	// Штрих-код полки не должен совпадать со штрих-кодами книг, читателей, дисков и других мест
+	if err := claimBarcode("location", location.ID, location.Barcode); err != nil {
+		if _, ok := err.(*fiber.Error); ok {
+			return err
+		}
+		return fiber.NewError(500, "Failed to check barcode")
+	}
+
+	return nil
+}
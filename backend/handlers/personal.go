@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"fmt"
+	"library-management/backend/database"
+	"library-management/backend/models"
+	"library-management/backend/pii"
+	"library-management/backend/rbac"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// canViewPersonalData проверяет, разрешено ли роли пользователя видеть персональные данные
+func canViewPersonalData(c *fiber.Ctx) bool {
+	role, _ := c.Locals("role").(string)
+	return rbac.Has(role, rbac.ReadersPIIRead)
+}
+
+// maskPersonalData скрывает персональные данные читателя: у телефонов, e-mail и документа
+// остаются последние символы, адрес и дата рождения не передаются
+func maskPersonalData(reader *models.Reader) {
+	reader.BirthDate = nil
+	reader.Address = ""
+	reader.DocumentNumber = pii.MaskDocument(reader.DocumentNumber)
+	reader.Phone = pii.MaskPhone(reader.Phone)
+	reader.Email = pii.MaskEmail(reader.Email)
+	reader.ParentMotherPhone = pii.MaskPhone(reader.ParentMotherPhone)
+	reader.ParentFatherPhone = pii.MaskPhone(reader.ParentFatherPhone)
+	reader.GuardianPhone = pii.MaskPhone(reader.GuardianPhone)
+	reader.PersonalDataMasked = true
+}
+
+// showReader готовит карточку читателя к выдаче: полные данные получают только роли
+// с доступом, и каждый такой просмотр записывается в журнал
+func showReader(c *fiber.Ctx, reader *models.Reader) {
+	if !canViewPersonalData(c) {
+		maskPersonalData(reader)
+		return
+	}
+
+	details := map[string]string{"path": c.Path()}
+	if err := database.WriteAudit(nil, "reader", reader.ID, "view_personal_data", details, c.Locals("userID").(int)); err != nil {
+		log.Printf("Failed to log personal data access to reader %d: %v", reader.ID, err)
+	}
+}
+
+// checkPersonalData отклоняет персональные данные, начинающиеся с pii.Prefix: без ключа шифрования
+// такое значение сохранилось бы открытым текстом и при чтении было бы принято за шифротекст
+func checkPersonalData(reader *models.Reader) error {
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"address", reader.Address},
+		{"document_number", reader.DocumentNumber},
+		{"phone", reader.Phone},
+		{"email", reader.Email},
+		{"parent_mother_phone", reader.ParentMotherPhone},
+		{"parent_father_phone", reader.ParentFatherPhone},
+		{"guardian_phone", reader.GuardianPhone},
+	}
+	for _, field := range fields {
+		if pii.IsEncrypted(field.value) {
+			return fiber.NewError(400, fmt.Sprintf("%s must not start with %q", field.name, pii.Prefix))
+		}
+	}
+	return nil
+}
+
+// keepMaskedPersonalData сохраняет прежние значения полей, которые пришли обратно в скрытом виде.
+// Так пользователь без доступа может изменить карточку, не затерев персональные данные масками
+func keepMaskedPersonalData(reader, existing *models.Reader) {
+	masked := *existing
+	maskPersonalData(&masked)
+
+	if reader.BirthDate == nil {
+		reader.BirthDate = existing.BirthDate
+	}
+	if reader.Address == "" {
+		reader.Address = existing.Address
+	}
+
+	fields := []struct{ value, masked, existing *string }{
+		{&reader.DocumentNumber, &masked.DocumentNumber, &existing.DocumentNumber},
+		{&reader.Phone, &masked.Phone, &existing.Phone},
+		{&reader.Email, &masked.Email, &existing.Email},
+		{&reader.ParentMotherPhone, &masked.ParentMotherPhone, &existing.ParentMotherPhone},
+		{&reader.ParentFatherPhone, &masked.ParentFatherPhone, &existing.ParentFatherPhone},
+		{&reader.GuardianPhone, &masked.GuardianPhone, &existing.GuardianPhone},
+	}
+	for _, field := range fields {
+		if *field.value != "" && *field.value == *field.masked {
+			*field.value = *field.existing
+		}
+	}
+}
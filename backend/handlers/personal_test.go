@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"library-management/backend/database"
+	"library-management/backend/models"
+	"library-management/backend/rbac"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestShowReaderByRole(t *testing.T) {
+	if err := database.Init(filepath.Join(t.TempDir(), "library.db")); err != nil {
+		t.Fatalf("database.Init: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	rbac.SetPersonalDataRoles("admin")
+	t.Cleanup(func() { rbac.SetPersonalDataRoles("admin") })
+
+	birthDate := time.Date(2012, 3, 14, 0, 0, 0, 0, time.UTC)
+	id, err := database.CreateReader(&models.Reader{
+		Barcode: "R001", LastName: "Иванов", FirstName: "Петр", BirthDate: &birthDate,
+		Address: "г. Пермь, ул. Ленина, 1", DocumentNumber: "4510 123456",
+		Phone: "+7 (912) 345-67-89", Email: "ivanov@mail.ru",
+	})
+	if err != nil {
+		t.Fatalf("CreateReader: %v", err)
+	}
+
+	// GetReader показывает карточку с ролью из заголовка вместо AuthMiddleware
+	app := fiber.New()
+	app.Get("/readers/:id", func(c *fiber.Ctx) error {
+		c.Locals("role", c.Get("X-Role"))
+		c.Locals("userID", 1)
+		return c.Next()
+	}, GetReader)
+
+	tests := []struct {
+		role   string
+		masked bool
+	}{
+		{"admin", false},
+		{"librarian", true},
+		{"teacher", true},
+		{"", true},
+	}
+	for _, tt := range tests {
+		before, err := database.GetAuditLog("reader", id, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req := httptest.NewRequest("GET", "/readers/"+strconv.Itoa(id), nil)
+		req.Header.Set("X-Role", tt.role)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var reader models.Reader
+		if err := json.NewDecoder(resp.Body).Decode(&reader); err != nil {
+			t.Fatal(err)
+		}
+
+		if tt.masked {
+			if !reader.PersonalDataMasked || reader.Address != "" || reader.BirthDate != nil ||
+				reader.Phone != "***89" || reader.Email != "i***@mail.ru" || reader.DocumentNumber != "***56" {
+				t.Errorf("role %q: personal data not masked: %+v", tt.role, reader)
+			}
+		} else if reader.PersonalDataMasked || reader.Phone != "+7 (912) 345-67-89" || reader.Address == "" || reader.BirthDate == nil {
+			t.Errorf("role %q: personal data masked: %+v", tt.role, reader)
+		}
+
+		// Каждый просмотр полных данных пишется в журнал, просмотр скрытых — нет
+		after, err := database.GetAuditLog("reader", id, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if logged := len(after) > len(before); logged == tt.masked {
+			t.Errorf("role %q: personal data view logged = %v", tt.role, logged)
+		}
+	}
+}
+
+func TestKeepMaskedPersonalData(t *testing.T) {
+	existing := &models.Reader{
+		Address: "г. Пермь, ул. Ленина, 1", DocumentNumber: "4510 123456",
+		Phone: "+7 (912) 345-67-89", Email: "ivanov@mail.ru", GuardianPhone: "89120000000",
+	}
+
+	// Пользователь без доступа вернул скрытую карточку, изменив только e-mail
+	reader := *existing
+	maskPersonalData(&reader)
+	reader.Email = "petrov@mail.ru"
+	keepMaskedPersonalData(&reader, existing)
+
+	if reader.Address != existing.Address || reader.DocumentNumber != existing.DocumentNumber ||
+		reader.Phone != existing.Phone || reader.GuardianPhone != existing.GuardianPhone {
+		t.Errorf("masked values overwrote personal data: %+v", reader)
+	}
+	if reader.Email != "petrov@mail.ru" {
+		t.Errorf("changed e-mail lost: %q", reader.Email)
+	}
+}
+
+func TestCheckPersonalData(t *testing.T) {
+	tests := []struct {
+		name    string
+		reader  models.Reader
+		wantErr bool
+	}{
+		{"plain values", models.Reader{Phone: "89123456789", Email: "a@b.ru"}, false},
+		{"prefix inside", models.Reader{Address: "ул. enc:1"}, false},
+		{"phone", models.Reader{Phone: "enc:abc"}, true},
+		{"address", models.Reader{Address: "enc:"}, true},
+		{"guardian phone", models.Reader{GuardianPhone: "enc:abc"}, true},
+	}
+	for _, tt := range tests {
+		err := checkPersonalData(&tt.reader)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: checkPersonalData error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"library-management/backend/database"
+	"library-management/backend/media"
+	"library-management/backend/models"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// UploadReaderPhoto загружает фотографию читателя и строит миниатюры
+func UploadReaderPhoto(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid reader ID",
+		})
+	}
+
+	if _, err := database.GetReaderByID(id); err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Reader not found",
+		})
+	}
+
+	processed, err := readUploadedImage(c)
+	if err != nil {
+		return imageUploadError(c, err)
+	}
+
+	if err := saveReaderPhoto(id, processed); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to save photo",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"photo_url": media.PhotoURL(id, processed.ETag),
+		"width":     processed.Width,
+		"height":    processed.Height,
+	})
+}
+
+// GetReaderPhoto отдает фотографию читателя нужного размера (small, medium, large, original).
+// Фотографии — персональные данные, поэтому маршрут доступен только после входа
+func GetReaderPhoto(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid reader ID",
+		})
+	}
+
+	size := c.Query("size", "medium")
+	etag, err := database.GetCoverETag(media.EntityReader, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Photo not found",
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch photo",
+		})
+	}
+
+	// Ссылки с версией (?v=etag) неизменяемы, но кэшируются только в браузере пользователя
+	tag := fmt.Sprintf(`"%s-%s"`, etag, size)
+	c.Set(fiber.HeaderETag, tag)
+	if c.Query("v") == etag {
+		c.Set(fiber.HeaderCacheControl, "private, max-age=31536000, immutable")
+	} else {
+		c.Set(fiber.HeaderCacheControl, "private, no-cache")
+	}
+
+	if c.Get(fiber.HeaderIfNoneMatch) == tag {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	data, contentType, err := media.GetPhoto(id, size)
+	if err != nil {
+		if errors.Is(err, media.ErrNotFound) {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Photo not found",
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch photo",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	return c.Send(data)
+}
+
+// DeleteReaderPhoto удаляет фотографию читателя
+func DeleteReaderPhoto(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid reader ID",
+		})
+	}
+
+	if err := removeReaderPhoto(id); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to delete photo",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Photo deleted successfully",
+	})
+}
+
+// ImportReaderPhotos загружает фотографии читателей из ZIP-архива (день фотографирования).
+// Имя файла без расширения — код или штрих-код читателя: 000123.jpg. С параметром dry_run
+// архив только проверяется
+func ImportReaderPhotos(c *fiber.Ctx) error {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "File is required",
+		})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot read file",
+		})
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot read file",
+		})
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "File is not a ZIP archive",
+		})
+	}
+
+	result := models.PhotoImportResult{DryRun: isDryRun(c), Errors: []models.PhotoImportError{}}
+	readerFiles := map[int]string{}
+	for _, entry := range archive.File {
+		if entry.FileInfo().IsDir() || skipArchiveEntry(entry.Name) {
+			continue
+		}
+
+		if err := importReaderPhoto(entry, readerFiles, result.DryRun); err != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, models.PhotoImportError{File: entry.Name, Error: err.Error()})
+			continue
+		}
+		result.Success++
+	}
+
+	if !result.DryRun && result.Success > 0 {
+		details := map[string]interface{}{
+			"file":    fileHeader.Filename,
+			"success": result.Success,
+			"failed":  result.Failed,
+		}
+		if err := database.WriteAudit(nil, "reader", 0, "import_photos", details, c.Locals("userID").(int)); err != nil {
+			log.Printf("Failed to write photo import audit: %v", err)
+		}
+	}
+
+	return c.JSON(result)
+}
+
+// importReaderPhoto находит читателя по имени файла из архива и сохраняет его фотографию.
+// readerFiles запоминает, из какого файла уже взята фотография читателя
+func importReaderPhoto(entry *zip.File, readerFiles map[int]string, dryRun bool) error {
+	name := path.Base(entry.Name)
+	key := strings.TrimSpace(strings.TrimSuffix(name, path.Ext(name)))
+	if key == "" {
+		return errors.New("File name must be a reader code or barcode")
+	}
+
+	id, err := database.FindImportTarget("reader", key, key)
+	if err != nil {
+		return err
+	}
+	if id == 0 {
+		return fmt.Errorf("No reader with code or barcode %s", key)
+	}
+	if other, ok := readerFiles[id]; ok {
+		return fmt.Errorf("Reader already has a photo in this archive: %s", other)
+	}
+	readerFiles[id] = entry.Name
+
+	if entry.UncompressedSize64 > uint64(CoverMaxSize) {
+		return fmt.Errorf("File is too large, maximum is %d KB", CoverMaxSize/1024)
+	}
+
+	file, err := entry.Open()
+	if err != nil {
+		return errors.New("Cannot read file")
+	}
+	defer file.Close()
+
+	// Размер в заголовке архива может не совпадать с содержимым — ограничиваем чтение
+	data, err := io.ReadAll(io.LimitReader(file, CoverMaxSize+1))
+	if err != nil || int64(len(data)) > CoverMaxSize {
+		return errors.New("Cannot read file")
+	}
+
+	processed, err := media.Process(data)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		return nil
+	}
+
+	if err := saveReaderPhoto(id, processed); err != nil {
+		log.Printf("Failed to save photo of reader %d: %v", id, err)
+		return errors.New("Failed to save photo")
+	}
+	return nil
+}
+
+// skipArchiveEntry отбрасывает служебные файлы, которые добавляют в архивы macOS и Windows
+func skipArchiveEntry(name string) bool {
+	base := path.Base(name)
+	return strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(base, ".") ||
+		strings.EqualFold(base, "Thumbs.db") || strings.EqualFold(base, "desktop.ini")
+}
+
+// saveReaderPhoto сохраняет изображения и сведения о фотографии читателя
+func saveReaderPhoto(id int, processed *media.Processed) error {
+	if err := media.SavePhoto(id, processed); err != nil {
+		return err
+	}
+	return database.SaveCoverInfo(media.EntityReader, id, processed)
+}
+
+// removeReaderPhoto удаляет сведения о фотографии читателя и сами изображения
+func removeReaderPhoto(id int) error {
+	if err := database.DeleteCoverInfo(media.EntityReader, id); err != nil {
+		return err
+	}
+	return media.DeletePhoto(id)
+}
@@ -0,0 +1,483 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"library-management/backend/database"
+	"library-management/backend/mail"
+	"library-management/backend/middleware"
+	"library-management/backend/models"
+	"log"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// loginCodeTTL — срок действия кода входа из письма
+const loginCodeTTL = 15 * time.Minute
+
+// dummyPINHash сверяется вместо PIN, когда у читателя его нет: ответ не должен быть быстрее
+var dummyPINHash, _ = bcrypt.GenerateFromPassword([]byte("no-pin"), bcrypt.DefaultCost)
+
+// portalCodeSent — ответ на запрос кода: одинаковый, есть ли такой адрес или нет
+const portalCodeSent = "If the address is registered, a login code has been sent"
+
+// PortalLogin выполняет вход читателя в личный кабинет по штрих-коду и PIN
+func PortalLogin(c *fiber.Ctx) error {
+	var req models.PortalLoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	barcode := strings.TrimSpace(req.Barcode)
+	if barcode == "" || req.PIN == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Barcode and PIN are required",
+		})
+	}
+
+	// Штрих-коды идут подряд, а PIN короткий: попытки с одного адреса считаются так же,
+	// как входы сотрудников, иначе можно перебрать по паре PIN на каждый штрих-код
+	attempt, err := reserveLoginAttempt(c, "")
+	if attempt == nil {
+		return err
+	}
+
+	// Неизвестный штрих-код, выбывший читатель, отсутствие PIN и блокировка читателя
+	// неотличимы от неверного PIN ни по ответу, ни по времени: PIN сверяется всегда
+	var creds *database.ReaderCredentials
+	reader, err := database.GetReaderByBarcode(barcode)
+	if err == nil && reader.Status == "active" {
+		creds, err = database.GetReaderCredentials(reader.ID)
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+
+	pinHash := dummyPINHash
+	locked := false
+	if creds != nil {
+		pinHash = []byte(creds.PINHash)
+		locked = creds.LockedUntil != nil && time.Now().Before(*creds.LockedUntil)
+	}
+	if bcrypt.CompareHashAndPassword(pinHash, []byte(req.PIN)) != nil || creds == nil || locked {
+		if creds != nil && !locked {
+			if _, err := database.RecordPINFailure(reader.ID); err != nil {
+				log.Printf("portal: failed to record PIN failure for reader %d: %v", reader.ID, err)
+			}
+		}
+		return loginFailed(c, attempt, 401, "Invalid barcode or PIN")
+	}
+	if err := database.ReleaseLoginAttempt(attempt); err != nil {
+		log.Printf("portal: failed to reset login failures for %s: %v", attempt.IP, err)
+	}
+
+	if err := database.RecordReaderLogin(reader.ID, "pin"); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+	return portalSession(c, reader.ID)
+}
+
+// PortalRequestCode отправляет код входа на e-mail читателя. Ответ не раскрывает,
+// зарегистрирован ли адрес; код уходит, только если адрес однозначно указывает на читателя
+func PortalRequestCode(c *fiber.Ctx) error {
+	if !mail.Enabled() {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "Login by email is not configured",
+		})
+	}
+
+	var req models.PortalCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+	if !strings.Contains(req.Email, "@") {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Valid email is required",
+		})
+	}
+
+	ids, err := database.FindActiveReadersByEmail(req.Email)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+	// Один адрес у нескольких читателей (например, у родителя и ребенка) — входить нужно по PIN
+	if len(ids) != 1 {
+		return c.JSON(fiber.Map{"message": portalCodeSent})
+	}
+
+	code, err := newLoginCode()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Could not generate login code",
+		})
+	}
+	switch err := database.CreateLoginCode(ids[0], hashLoginCode(code), loginCodeTTL); err {
+	case nil:
+	case database.ErrLoginCodeTooSoon:
+		return c.JSON(fiber.Map{"message": portalCodeSent})
+	default:
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+
+	body := fmt.Sprintf(
+		"Код для входа в личный кабинет читателя: %s\n\nКод действует %d минут. "+
+			"Если вы не запрашивали код, просто удалите это письмо.\n",
+		code, int(loginCodeTTL.Minutes()),
+	)
+	if err := mail.Send(strings.TrimSpace(req.Email), "Код входа в библиотеку", body); err != nil {
+		log.Printf("portal: failed to send login code to reader %d: %v", ids[0], err)
+		return c.Status(502).JSON(fiber.Map{
+			"error": "Failed to send login code",
+		})
+	}
+
+	return c.JSON(fiber.Map{"message": portalCodeSent})
+}
+
+// PortalVerifyCode выполняет вход читателя по коду из письма
+func PortalVerifyCode(c *fiber.Ctx) error {
+	var req models.PortalCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+	code := strings.TrimSpace(req.Code)
+	if req.Email == "" || code == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Email and code are required",
+		})
+	}
+
+	invalid := func() error {
+		return c.Status(401).JSON(fiber.Map{
+			"error": "Invalid or expired code",
+		})
+	}
+
+	ids, err := database.FindActiveReadersByEmail(req.Email)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+	if len(ids) != 1 {
+		return invalid()
+	}
+
+	switch err := database.ConsumeLoginCode(ids[0], hashLoginCode(code)); err {
+	case nil:
+	case database.ErrInvalidLoginCode:
+		return invalid()
+	default:
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+	return portalSession(c, ids[0])
+}
+
+// portalSession выдает токен читателя вместе с его карточкой
+func portalSession(c *fiber.Ctx, readerID int) error {
+	profile, err := database.GetPortalProfile(readerID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch reader",
+		})
+	}
+
+	version, err := database.GetPortalTokenVersion(readerID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch reader",
+		})
+	}
+	token, expiresAt, err := middleware.NewReaderToken(readerID, version)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Could not generate token",
+		})
+	}
+
+	return c.JSON(models.PortalLoginResponse{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		Reader:    *profile,
+	})
+}
+
+// PortalLogout закрывает личный кабинет: токены читателя на всех устройствах перестают действовать
+func PortalLogout(c *fiber.Ctx) error {
+	if err := database.PortalLogout(c.Locals("readerID").(int)); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+	return c.JSON(fiber.Map{"message": "Logged out"})
+}
+
+// tooManyAttempts отвечает на вход при временной блокировке после неудачных попыток
+func tooManyAttempts(c *fiber.Ctx, until time.Time) error {
+	retry := int(time.Until(until).Seconds()) + 1
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retry))
+	return c.Status(429).JSON(fiber.Map{
+		"error":        "Too many failed attempts, try again later",
+		"locked_until": until,
+	})
+}
+
+// newLoginCode возвращает случайный шестизначный код
+func newLoginCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// hashLoginCode — в базе хранится только хэш кода входа
+func hashLoginCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// portalReader проверяет, что читатель из токена по-прежнему действующий.
+// Выбывший читатель теряет доступ сразу, не дожидаясь истечения токена
+func portalReader(c *fiber.Ctx) (*models.PortalProfile, error) {
+	profile, err := database.GetPortalProfile(c.Locals("readerID").(int))
+	if err == sql.ErrNoRows || err == database.ErrReaderArchived {
+		return nil, c.Status(401).JSON(fiber.Map{
+			"error": "Reader account is not active",
+		})
+	}
+	if err != nil {
+		return nil, c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch reader",
+		})
+	}
+	return profile, nil
+}
+
+// GetPortalProfile возвращает карточку читателя
+func GetPortalProfile(c *fiber.Ctx) error {
+	profile, err := portalReader(c)
+	if profile == nil {
+		return err
+	}
+	return c.JSON(profile)
+}
+
+// GetPortalLoans возвращает книги и диски на руках у читателя со сроками возврата
+func GetPortalLoans(c *fiber.Ctx) error {
+	return portalLoans(c, false)
+}
+
+// GetPortalHistory возвращает возвращенные книги и диски читателя
+func GetPortalHistory(c *fiber.Ctx) error {
+	return portalLoans(c, true)
+}
+
+func portalLoans(c *fiber.Ctx, history bool) error {
+	profile, err := portalReader(c)
+	if profile == nil {
+		return err
+	}
+
+	loans, err := database.GetPortalLoans(profile.ID, history)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch loans",
+		})
+	}
+	return c.JSON(loans)
+}
+
+// GetPortalHolds возвращает брони читателя; с all=true — вместе с закрытыми
+func GetPortalHolds(c *fiber.Ctx) error {
+	profile, err := portalReader(c)
+	if profile == nil {
+		return err
+	}
+
+	holds, err := database.GetReaderHolds(profile.ID, c.QueryBool("all"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch holds",
+		})
+	}
+	// Имя читателя в его собственном кабинете не нужно
+	for i := range holds {
+		holds[i].ReaderName = ""
+	}
+	return c.JSON(holds)
+}
+
+// CreatePortalHold бронирует книгу от имени читателя
+func CreatePortalHold(c *fiber.Ctx) error {
+	profile, err := portalReader(c)
+	if profile == nil {
+		return err
+	}
+
+	var req models.HoldRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+	return placeHold(c, profile.ID, req.BookID, "portal", 0)
+}
+
+// CancelPortalHold снимает бронь читателя. Чужие брони для него не существуют
+func CancelPortalHold(c *fiber.Ctx) error {
+	profile, err := portalReader(c)
+	if profile == nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid hold ID",
+		})
+	}
+	return cancelHold(c, id, profile.ID, 0)
+}
+
+// SearchPortalBooks ищет книги для бронирования
+func SearchPortalBooks(c *fiber.Ctx) error {
+	profile, err := portalReader(c)
+	if profile == nil {
+		return err
+	}
+
+	search := strings.TrimSpace(c.Query("search"))
+	if len([]rune(search)) < 2 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Search must contain at least 2 characters",
+		})
+	}
+
+	books, err := database.SearchPortalBooks(search, 50)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to search books",
+		})
+	}
+	return c.JSON(books)
+}
+
+// SetReaderPIN задает PIN личного кабинета читателя. Без PIN в запросе генерируется
+// случайный шестизначный и возвращается один раз — для выдачи читателю
+func SetReaderPIN(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid reader ID",
+		})
+	}
+
+	var req models.ReaderPINRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Cannot parse request body",
+			})
+		}
+	}
+
+	pin := strings.TrimSpace(req.PIN)
+	generated := pin == ""
+	if generated {
+		if pin, err = newLoginCode(); err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Could not generate PIN",
+			})
+		}
+	}
+	if !validPIN(pin) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "PIN must contain 4 to 8 digits",
+		})
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.DefaultCost)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Could not hash PIN",
+		})
+	}
+
+	err = database.SetReaderPIN(id, string(hash), c.Locals("userID").(int))
+	if err == sql.ErrNoRows {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Reader not found",
+		})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to set PIN",
+		})
+	}
+
+	if generated {
+		return c.JSON(fiber.Map{"pin": pin})
+	}
+	return c.JSON(fiber.Map{"message": "PIN updated"})
+}
+
+// DeleteReaderPIN отключает вход читателя по PIN
+func DeleteReaderPIN(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid reader ID",
+		})
+	}
+
+	err = database.DeleteReaderPIN(id, c.Locals("userID").(int))
+	if err == sql.ErrNoRows {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Reader has no PIN",
+		})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to delete PIN",
+		})
+	}
+	return c.JSON(fiber.Map{"message": "PIN deleted"})
+}
+
+// validPIN проверяет, что PIN состоит из 4–8 цифр
+func validPIN(pin string) bool {
+	if len(pin) < 4 || len(pin) > 8 {
+		return false
+	}
+	for _, r := range pin {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
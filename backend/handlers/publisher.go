@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
 	"library-management/backend/database"
 	"library-management/backend/models"
 	"strconv"
@@ -29,12 +31,30 @@ func CreatePublisher(c *fiber.Ctx) error {
 		})
 	}
 
-	// Генерируем код если не указан
-	if publisher.Code == "" {
-		publisher.Code = database.GeneratePublisherCode()
+	// Проверяем, нет ли уже такого издательства под другим написанием.
+	// Создать издательство несмотря на совпадение можно с параметром force=true
+	if c.Query("force") != "true" {
+		matches, err := database.FindPublisherMatches(&publisher)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to check publisher duplicates",
+			})
+		}
+		if len(matches) > 0 {
+			return c.Status(409).JSON(fiber.Map{
+				"error":      "Publisher with the same name already exists",
+				"candidates": matches,
+			})
+		}
 	}
 
+	// Код, если не указан, выдается из последовательности при сохранении
 	id, err := database.CreatePublisher(&publisher)
+	if err == database.ErrCodeExists {
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Publisher code already exists",
+		})
+	}
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Failed to create publisher",
@@ -104,3 +124,55 @@ func DeletePublisher(c *fiber.Ctx) error {
 		"message": "Publisher deleted successfully",
 	})
 }
+
+// GetPublisherDuplicates возвращает группы издательств — кандидатов в дубликаты
+func GetPublisherDuplicates(c *fiber.Ctx) error {
+	groups, err := database.FindPublisherDuplicates()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to find duplicate publishers",
+		})
+	}
+
+	return c.JSON(groups)
+}
+
+// MergePublishers объединяет издательства-дубликаты с издательством из URL
+func MergePublishers(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid publisher ID",
+		})
+	}
+
+	var req models.MergeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	sourceIDs, err := mergeSourceIDs(id, req.SourceIDs)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userID := c.Locals("userID").(int)
+	if err := database.MergePublishers(id, sourceIDs, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Publisher not found",
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to merge publishers",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Publishers merged successfully",
+	})
+}
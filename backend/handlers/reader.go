@@ -1,26 +1,46 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
 	"library-management/backend/database"
 	"library-management/backend/models"
+	"library-management/backend/rbac"
+	"log"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-// GetReaders возвращает список читателей
+// GetReaders возвращает список читателей. Архивные читатели показываются только
+// с параметром status=archived или status=all
 func GetReaders(c *fiber.Ctx) error {
 	search := c.Query("search", "")
+	status := c.Query("status", "")
 	page, _ := strconv.Atoi(c.Query("page", "1"))
 	pageSize, _ := strconv.Atoi(c.Query("pageSize", "10"))
 
-	readers, total, err := database.GetReaders(search, page, pageSize)
+	if !validReaderStatus(status) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Reader status must be active, archived or all",
+		})
+	}
+
+	readers, total, err := database.GetReaders(search, status, page, pageSize)
 	if err != nil {
+		log.Printf("Failed to fetch readers: %v", err)
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Failed to fetch readers",
 		})
 	}
 
+	// В списке персональные данные не нужны никому — полная карточка открывается отдельно
+	for i := range readers {
+		maskPersonalData(&readers[i])
+	}
+
 	return c.JSON(fiber.Map{
 		"readers": readers,
 		"pagination": fiber.Map{
@@ -47,10 +67,12 @@ func GetReader(c *fiber.Ctx) error {
 		})
 	}
 
+	showReader(c, reader)
 	return c.JSON(reader)
 }
 
-// GetReaderByBarcode возвращает читателя по штрих-коду
+// GetReaderByBarcode возвращает читателя по штрих-коду. Архивный читатель
+// находится только с параметром include_archived=true
 func GetReaderByBarcode(c *fiber.Ctx) error {
 	barcode := c.Params("barcode")
 
@@ -61,6 +83,13 @@ func GetReaderByBarcode(c *fiber.Ctx) error {
 		})
 	}
 
+	if reader.Status == "archived" && c.Query("include_archived") != "true" {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Reader is archived",
+		})
+	}
+
+	showReader(c, reader)
 	return c.JSON(reader)
 }
 
@@ -73,17 +102,31 @@ func CreateReader(c *fiber.Ctx) error {
 		})
 	}
 
+	if err := checkPersonalData(&reader); err != nil {
+		return classError(c, err)
+	}
+
+	// Класс должен быть активным; параллель читателя берется из класса
+	if err := checkReaderClass(&reader); err != nil {
+		return classError(c, err)
+	}
+
+	// Проверяем, что штрих-код не занят другой записью
+	if err := claimBarcode("reader", 0, reader.Barcode); err != nil {
+		return claimBarcodeError(c, err)
+	}
+
 	// Получаем ID пользователя из контекста
 	userID := c.Locals("userID").(int)
 	reader.CreatedBy = userID
 
-	// Генерируем код если не указан
-	if reader.Code == "" {
-		reader.Code = database.GenerateReaderCode()
-	}
-
-	// Создаем читателя
+	// Создаем читателя. Код, если не указан, выдается из последовательности при сохранении
 	id, err := database.CreateReader(&reader)
+	if err == database.ErrCodeExists {
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Reader code already exists",
+		})
+	}
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Failed to create reader",
@@ -91,6 +134,10 @@ func CreateReader(c *fiber.Ctx) error {
 	}
 
 	reader.ID = id
+	bindBarcode("reader", id, reader.Barcode)
+	if !canViewPersonalData(c) {
+		maskPersonalData(&reader)
+	}
 	return c.Status(201).JSON(reader)
 }
 
@@ -110,17 +157,47 @@ func UpdateReader(c *fiber.Ctx) error {
 		})
 	}
 
+	if err := checkPersonalData(&reader); err != nil {
+		return classError(c, err)
+	}
+
+	// Класс должен быть активным; параллель читателя берется из класса
+	if err := checkReaderClass(&reader); err != nil {
+		return classError(c, err)
+	}
+
+	// Проверяем, что штрих-код не занят другой записью
+	if err := claimBarcode("reader", id, reader.Barcode); err != nil {
+		return claimBarcodeError(c, err)
+	}
+
+	// Без доступа к персональным данным карточка приходит со скрытыми полями — их не затираем
+	canView := canViewPersonalData(c)
+	if !canView {
+		existing, err := database.GetReaderByID(id)
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Reader not found",
+			})
+		}
+		keepMaskedPersonalData(&reader, existing)
+	}
+
 	reader.ID = id
 	if err := database.UpdateReader(&reader); err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Failed to update reader",
 		})
 	}
+	bindBarcode("reader", id, reader.Barcode)
 
+	if !canView {
+		maskPersonalData(&reader)
+	}
 	return c.JSON(reader)
 }
 
-// DeleteReader удаляет читателя
+// DeleteReader удаляет читателя. Читателя с историей выдач удалить нельзя — его переводят в архив
 func DeleteReader(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
@@ -143,13 +220,241 @@ func DeleteReader(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := database.DeleteReader(id); err != nil {
+	// Удаление оборвало бы историю выдач и отчеты
+	hasHistory, err := database.ReaderHasLoanHistory(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to check reader status",
+		})
+	}
+
+	if hasHistory {
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Reader has loan history; archive the reader instead",
+		})
+	}
+
+	switch err := database.DeleteReader(id); err {
+	case nil:
+	case database.ErrReaderHasClearances:
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Reader has clearance certificates; archive the reader instead",
+		})
+	default:
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Failed to delete reader",
 		})
 	}
 
+	// Фотография удаляется вместе с записью; ошибка здесь не отменяет удаление
+	if err := removeReaderPhoto(id); err != nil {
+		log.Printf("Failed to delete photo of reader %d: %v", id, err)
+	}
+
 	return c.JSON(fiber.Map{
 		"message": "Reader deleted successfully",
 	})
 }
+
+// ArchiveReader переводит выбывшего читателя в архив с датой и причиной.
+// Читателя с невозвращенными книгами можно архивировать только со списанием долгов (write_off_debts),
+// для которого нужно право loans.override
+func ArchiveReader(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid reader ID",
+		})
+	}
+
+	var req models.ReaderArchiveRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Archive reason is required",
+		})
+	}
+
+	archivedAt := time.Now()
+	if req.Date != "" {
+		archivedAt, err = time.ParseInLocation("2006-01-02", req.Date, time.Local)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid date format, expected YYYY-MM-DD",
+			})
+		}
+	}
+
+	// Списание долгов закрывает выдачи без возврата — это отдельное право
+	if role, _ := c.Locals("role").(string); req.WriteOffDebts && !rbac.Has(role, rbac.LoansOverride) {
+		return c.Status(403).JSON(fiber.Map{
+			"error":      "Permission denied",
+			"permission": rbac.LoansOverride,
+		})
+	}
+
+	writtenOff, err := database.ArchiveReader(id, archivedAt, req.Reason, req.WriteOffDebts, c.Locals("userID").(int))
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Reader not found",
+		})
+	case database.ErrReaderArchived:
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Reader is already archived",
+		})
+	case database.ErrReaderHasDebts:
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Reader has unreturned items; collect them or archive with write_off_debts",
+		})
+	default:
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to archive reader",
+		})
+	}
+
+	reader, err := database.GetReaderByID(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch reader",
+		})
+	}
+
+	maskPersonalData(reader)
+	return c.JSON(fiber.Map{
+		"reader":      reader,
+		"written_off": writtenOff,
+	})
+}
+
+// RestoreReader возвращает читателя из архива
+func RestoreReader(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid reader ID",
+		})
+	}
+
+	err = database.RestoreReader(id, c.Locals("userID").(int))
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Reader not found",
+		})
+	case database.ErrReaderNotArchived:
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Reader is not archived",
+		})
+	default:
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to restore reader",
+		})
+	}
+
+	reader, err := database.GetReaderByID(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch reader",
+		})
+	}
+
+	maskPersonalData(reader)
+	return c.JSON(reader)
+}
+
+// GetReaderDuplicates возвращает группы читателей — кандидатов в дубликаты:
+// одинаковое ФИО и дата рождения или класс
+func GetReaderDuplicates(c *fiber.Ctx) error {
+	groups, err := database.FindReaderDuplicates()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to find duplicate readers",
+		})
+	}
+
+	// Как и в списке читателей, персональные данные скрыты; чем совпали записи, видно из match
+	for i := range groups {
+		for j := range groups[i].Readers {
+			maskPersonalData(&groups[i].Readers[j])
+		}
+	}
+
+	return c.JSON(groups)
+}
+
+// MergeReaders объединяет читателей-дубликатов с читателем из URL: выдачи и комментарии
+// переносятся, штрих-коды дубликатов продолжают находить читателя, дубликаты удаляются
+func MergeReaders(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid reader ID",
+		})
+	}
+
+	var req models.MergeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	sourceIDs, err := mergeSourceIDs(id, req.SourceIDs)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userID := c.Locals("userID").(int)
+	if err := database.MergeReaders(id, sourceIDs, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Reader not found",
+			})
+		}
+		if err == database.ErrReaderArchived {
+			return c.Status(409).JSON(fiber.Map{
+				"error": "Cannot move active loans to an archived reader",
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to merge readers",
+		})
+	}
+
+	// Фотографии дубликатов больше не нужны; ошибка здесь не отменяет объединение
+	for _, sourceID := range sourceIDs {
+		if err := removeReaderPhoto(sourceID); err != nil {
+			log.Printf("Failed to delete photo of reader %d: %v", sourceID, err)
+		}
+	}
+
+	reader, err := database.GetReaderByID(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch reader",
+		})
+	}
+
+	maskPersonalData(reader)
+	return c.JSON(reader)
+}
+
+// validReaderStatus проверяет значение фильтра по статусу читателя
+func validReaderStatus(status string) bool {
+	switch status {
+	case "", "active", "archived", "all":
+		return true
+	}
+	return false
+}
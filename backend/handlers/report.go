@@ -1,17 +1,52 @@
 package handlers
 
 import (
+	"database/sql"
+	"library-management/backend/classification"
 	"library-management/backend/database"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-// BookAvailabilityReport генерирует отчет о наличии книг
+// BookAvailabilityReport генерирует отчет о наличии книг по разделам классификации
+// или по местам хранения (group_by=location)
 func BookAvailabilityReport(c *fiber.Ctx) error {
 	classFilter := c.Query("class", "")
+	scheme := c.Query("scheme", classification.SchemeBBK)
+	level := c.QueryInt("level", 2)
+	locationID := c.QueryInt("location_id", 0)
 
-	report, err := database.GetBookAvailabilityReport(classFilter)
+	if c.Query("group_by") == "location" {
+		if level < 1 || level > len(database.LocationKinds) {
+			level = len(database.LocationKinds)
+		}
+
+		report, err := database.GetBookAvailabilityByLocation(classFilter, locationID, level)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return c.Status(404).JSON(fiber.Map{
+					"error": "Location not found",
+				})
+			}
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to generate report",
+			})
+		}
+
+		return c.JSON(report)
+	}
+
+	if _, ok := classification.Get(scheme); !ok {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Unknown classification scheme",
+		})
+	}
+	if level < 1 {
+		level = 1
+	}
+
+	report, err := database.GetBookAvailabilityReport(classFilter, scheme, locationID, level)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Failed to generate report",
@@ -57,3 +92,34 @@ func ClassLoansReport(c *fiber.Ctx) error {
 
 	return c.JSON(report)
 }
+
+// EORBySubjectReport генерирует отчет об ЭОР по предметам для управления образования
+func EORBySubjectReport(c *fiber.Ctx) error {
+	report, err := database.GetEORBySubjectReport()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to generate report",
+		})
+	}
+
+	return c.JSON(report)
+}
+
+// InventoryReport формирует инвентаризационную опись по местам хранения
+func InventoryReport(c *fiber.Ctx) error {
+	locationID := c.QueryInt("location_id", 0)
+
+	report, err := database.GetInventoryReport(locationID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Location not found",
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to generate report",
+		})
+	}
+
+	return c.JSON(report)
+}
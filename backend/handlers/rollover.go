@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"library-management/backend/database"
+	"library-management/backend/models"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// academicYearPattern — учебный год вида 2026/2027
+var academicYearPattern = regexp.MustCompile(`^(\d{4})/(\d{4})$`)
+
+// PreviewClassRollover показывает, как изменятся классы и читатели при переводе на новый учебный год
+func PreviewClassRollover(c *fiber.Ctx) error {
+	req, err := parseRolloverRequest(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	plan, err := database.PlanClassRollover(req)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to plan class rollover",
+		})
+	}
+
+	return c.JSON(plan)
+}
+
+// ApplyClassRollover переводит все классы на новый учебный год: классы переходят на параллель выше,
+// выпускники покидают школу и переводятся в архив, создаются новые первые классы
+func ApplyClassRollover(c *fiber.Ctx) error {
+	req, err := parseRolloverRequest(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	rollover, plan, err := database.ApplyClassRollover(req, c.Locals("userID").(int))
+	switch err {
+	case nil:
+		return c.Status(201).JSON(rollover)
+	case database.ErrRolloverExists:
+		return c.Status(409).JSON(fiber.Map{
+			"error": fmt.Sprintf("Classes have already been moved to the %s academic year", req.AcademicYear),
+		})
+	case database.ErrRolloverDebtors:
+		return c.Status(409).JSON(fiber.Map{
+			"error":   "Some graduates have not returned books; collect them or run with on_active_loans=flag",
+			"debtors": plan.Debtors,
+		})
+	default:
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to apply class rollover",
+		})
+	}
+}
+
+// GetClassRollovers возвращает историю переводов классов
+func GetClassRollovers(c *fiber.Ctx) error {
+	rollovers, err := database.GetClassRollovers()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch class rollovers",
+		})
+	}
+
+	return c.JSON(rollovers)
+}
+
+// GetClassRollover возвращает перевод классов с его итогом
+func GetClassRollover(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid rollover ID",
+		})
+	}
+
+	rollover, err := database.GetClassRollover(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Class rollover not found",
+		})
+	}
+
+	return c.JSON(rollover)
+}
+
+// UndoClassRollover отменяет последний перевод классов и возвращает классы и читателей в прежнее состояние
+func UndoClassRollover(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid rollover ID",
+		})
+	}
+
+	err = database.UndoClassRollover(id, c.Locals("userID").(int))
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Class rollover not found",
+		})
+	case database.ErrRolloverNotLatest:
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Only the latest applied rollover can be undone",
+		})
+	case database.ErrRolloverClassInUse:
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Readers have already been added to the new first grades; move them out before undoing",
+		})
+	case database.ErrRolloverChanged:
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Readers or classes have been edited since the rollover; undoing it would overwrite those changes",
+		})
+	case database.ErrRolloverClassExists:
+		return c.Status(409).JSON(fiber.Map{
+			"error": "A class with the same grade and letter as a restored class has been created since the rollover; rename or delete it before undoing",
+		})
+	default:
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to undo class rollover",
+		})
+	}
+
+	rollover, err := database.GetClassRollover(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch class rollover",
+		})
+	}
+
+	return c.JSON(rollover)
+}
+
+// parseRolloverRequest разбирает параметры перевода и подставляет значения по умолчанию
+func parseRolloverRequest(c *fiber.Ctx) (models.ClassRolloverRequest, error) {
+	var req models.ClassRolloverRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return req, fiber.NewError(400, "Cannot parse request body")
+		}
+	}
+
+	if req.MaxGrade == 0 {
+		req.MaxGrade = 11
+	}
+	if req.MaxGrade < 2 || req.MaxGrade > 12 {
+		return req, fiber.NewError(400, "Graduating grade must be between 2 and 12")
+	}
+
+	// Перевод выполняется летом: с июня учебный год считается новым
+	if req.AcademicYear == "" {
+		now := time.Now()
+		year := now.Year()
+		if now.Month() < time.June {
+			year--
+		}
+		req.AcademicYear = fmt.Sprintf("%d/%d", year, year+1)
+	}
+	match := academicYearPattern.FindStringSubmatch(req.AcademicYear)
+	if match == nil {
+		return req, fiber.NewError(400, "Academic year must look like 2026/2027")
+	}
+	if from, _ := strconv.Atoi(match[1]); fmt.Sprint(from+1) != match[2] {
+		return req, fiber.NewError(400, "Academic year must span two consecutive years")
+	}
+
+	switch req.OnActiveLoans {
+	case "":
+		req.OnActiveLoans = "refuse"
+	case "refuse", "flag":
+	default:
+		return req, fiber.NewError(400, "on_active_loans must be refuse or flag")
+	}
+
+	if req.FirstGradeLetters != nil {
+		letters := []string{}
+		seen := map[string]bool{}
+		for _, value := range req.FirstGradeLetters {
+			letter := normalizeClassLetter(value)
+			if len([]rune(letter)) != 1 {
+				return req, fiber.NewError(400, fmt.Sprintf("Invalid class letter %q", value))
+			}
+			if !seen[letter] {
+				seen[letter] = true
+				letters = append(letters, letter)
+			}
+		}
+		req.FirstGradeLetters = letters
+	}
+
+	return req, nil
+}
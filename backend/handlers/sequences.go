@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"database/sql"
+	"library-management/backend/database"
+	"library-management/backend/models"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetSequences возвращает форматы автоматических кодов записей
+func GetSequences(c *fiber.Ctx) error {
+	sequences, err := database.GetSequences()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch sequences",
+		})
+	}
+
+	return c.JSON(sequences)
+}
+
+// UpdateSequence меняет формат кодов и следующий номер для типа записей
+func UpdateSequence(c *fiber.Ctx) error {
+	current, err := database.GetSequence(c.Params("entity"))
+	if err == sql.ErrNoRows {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Sequence not found",
+		})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch sequence",
+		})
+	}
+
+	var s models.Sequence
+	if err := c.BodyParser(&s); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	s.EntityType = current.EntityType
+	s.Prefix = strings.TrimSpace(s.Prefix)
+	if s.NextNumber == 0 {
+		s.NextNumber = current.NextNumber
+	}
+	if s.YearlyReset && s.Separator == "" {
+		s.Separator = "-"
+	}
+
+	if s.Padding < 1 || s.Padding > 12 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Padding must be between 1 and 12",
+		})
+	}
+	if len(s.Prefix) > 20 || len(s.Separator) > 3 || strings.ContainsAny(s.Prefix+s.Separator, " \t") {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Prefix must be up to 20 and separator up to 3 characters without spaces",
+		})
+	}
+	if s.NextNumber < 1 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Next number must be positive",
+		})
+	}
+
+	if err := database.UpdateSequence(&s); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to update sequence",
+		})
+	}
+
+	updated, err := database.GetSequence(s.EntityType)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch sequence",
+		})
+	}
+
+	return c.JSON(updated)
+}
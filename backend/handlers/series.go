@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"database/sql"
+	"library-management/backend/database"
+	"library-management/backend/models"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetSeries возвращает список серий и многотомных изданий
+func GetSeries(c *fiber.Ctx) error {
+	series, err := database.GetSeries()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch series",
+		})
+	}
+
+	return c.JSON(series)
+}
+
+// CreateSeries создает новую серию
+func CreateSeries(c *fiber.Ctx) error {
+	var series models.Series
+	if err := c.BodyParser(&series); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	if err := validateSeries(&series); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	id, err := database.CreateSeries(&series)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to create series",
+		})
+	}
+
+	series.ID = id
+	return c.Status(201).JSON(series)
+}
+
+// UpdateSeries обновляет серию
+func UpdateSeries(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid series ID",
+		})
+	}
+
+	var series models.Series
+	if err := c.BodyParser(&series); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	if err := validateSeries(&series); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	series.ID = id
+	if err := database.UpdateSeries(&series); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to update series",
+		})
+	}
+
+	return c.JSON(series)
+}
+
+// DeleteSeries удаляет серию
+func DeleteSeries(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid series ID",
+		})
+	}
+
+	// Проверяем, есть ли книги в серии
+	hasBooks, err := database.SeriesHasBooks(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to check series status",
+		})
+	}
+
+	if hasBooks {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot delete series with existing books",
+		})
+	}
+
+	if err := database.DeleteSeries(id); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to delete series",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Series deleted successfully",
+	})
+}
+
+// SetBookSeries включает книгу в серию (с номером тома и названием части) или исключает из нее
+func SetBookSeries(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid book ID",
+		})
+	}
+
+	var req models.BookSeriesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	if req.SeriesID == nil {
+		// Исключение из серии сбрасывает и номер тома
+		req.VolumeNumber = nil
+		req.PartTitle = ""
+	} else if _, err := database.GetSeriesByID(*req.SeriesID); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Series not found",
+		})
+	}
+
+	if req.VolumeNumber != nil && *req.VolumeNumber < 1 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Volume number must be positive",
+		})
+	}
+
+	if _, err := database.GetBookByID(id); err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Book not found",
+		})
+	}
+
+	if err := database.SetBookSeries(id, req.SeriesID, req.VolumeNumber, strings.TrimSpace(req.PartTitle)); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to update book series",
+		})
+	}
+
+	book, err := database.GetBookByID(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch book",
+		})
+	}
+
+	return c.JSON(book)
+}
+
+// LinkBookEdition связывает книгу с другим изданием того же произведения
+func LinkBookEdition(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid book ID",
+		})
+	}
+
+	var req models.LinkEditionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	if req.BookID == id {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot link a book to itself",
+		})
+	}
+
+	workID, err := database.LinkBookEditions(id, req.BookID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Book not found",
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to link editions",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"work_id": workID,
+	})
+}
+
+// UnlinkBookEdition исключает книгу из списка изданий произведения
+func UnlinkBookEdition(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid book ID",
+		})
+	}
+
+	if err := database.UnlinkBookEdition(id); err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Book not found",
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to unlink edition",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Edition unlinked successfully",
+	})
+}
+
+// GetRelatedBooks возвращает другие тома серии и другие издания произведения с их наличием
+func GetRelatedBooks(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid book ID",
+		})
+	}
+
+	related, err := database.GetRelatedBooks(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Book not found",
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch related books",
+		})
+	}
+
+	return c.JSON(related)
+}
+
+// validateSeries проверяет название и вид серии
+func validateSeries(series *models.Series) error {
+	series.Title = strings.TrimSpace(series.Title)
+	if series.Title == "" {
+		return fiber.NewError(400, "Series title is required")
+	}
+
+	switch series.Kind {
+	case "":
+		series.Kind = "series"
+	case "series", "multivolume":
+	default:
+		return fiber.NewError(400, "Series kind must be 'series' or 'multivolume'")
+	}
+
+	return nil
+}
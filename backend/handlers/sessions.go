@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"database/sql"
+	"library-management/backend/database"
+	"library-management/backend/rbac"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetMyPermissions возвращает роль и права текущего сотрудника — интерфейс по ним
+// скрывает недоступные разделы и кнопки
+func GetMyPermissions(c *fiber.Ctx) error {
+	role, _ := c.Locals("role").(string)
+	return c.JSON(fiber.Map{
+		"role":        role,
+		"permissions": rbac.Permissions(role),
+	})
+}
+
+// GetMySessions возвращает действующие сессии текущего сотрудника: где еще выполнен вход
+func GetMySessions(c *fiber.Ctx) error {
+	sessions, err := database.GetSessions(c.Locals("userID").(int), false)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch sessions",
+		})
+	}
+
+	current, _ := c.Locals("sessionID").(int)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+	return c.JSON(sessions)
+}
+
+// RevokeMySession завершает свою сессию на другом устройстве
+func RevokeMySession(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid session ID",
+		})
+	}
+
+	// Чужая сессия для сотрудника не существует
+	session, err := database.GetSession(id)
+	if err == sql.ErrNoRows || (err == nil && session.UserID != c.Locals("userID").(int)) {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Session not found",
+		})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch session",
+		})
+	}
+	return revokeSession(c, id, "user")
+}
+
+// GetSessions возвращает сессии сотрудников для администратора.
+// Параметры: user_id — только этого сотрудника, all=true — вместе с закрытыми
+func GetSessions(c *fiber.Ctx) error {
+	userID, _ := strconv.Atoi(c.Query("user_id"))
+	sessions, err := database.GetSessions(userID, c.QueryBool("all"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch sessions",
+		})
+	}
+
+	current, _ := c.Locals("sessionID").(int)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+	return c.JSON(sessions)
+}
+
+// RevokeSession отзывает сессию сотрудника: его токены перестают действовать сразу
+func RevokeSession(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid session ID",
+		})
+	}
+	return revokeSession(c, id, "admin")
+}
+
+// RevokeUserSessions отзывает все сессии сотрудника (user_id), например при утере пароля
+func RevokeUserSessions(c *fiber.Ctx) error {
+	userID, err := strconv.Atoi(c.Query("user_id"))
+	if err != nil || userID <= 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "user_id is required",
+		})
+	}
+
+	count, err := database.RevokeUserSessions(userID, c.Locals("userID").(int), "admin")
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to revoke sessions",
+		})
+	}
+	return c.JSON(fiber.Map{"revoked": count})
+}
+
+func revokeSession(c *fiber.Ctx, id int, reason string) error {
+	err := database.RevokeSession(id, c.Locals("userID").(int), reason)
+	if err == sql.ErrNoRows {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Session not found or already closed",
+		})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to revoke session",
+		})
+	}
+	return c.JSON(fiber.Map{"message": "Session revoked"})
+}
+
+// GetLoginAttempts возвращает заблокированные после неудачных входов логины и IP-адреса.
+// С all=true — все счетчики, включая те, где задержки еще нет
+func GetLoginAttempts(c *fiber.Ctx) error {
+	attempts, err := database.GetLoginAttempts(c.QueryBool("all"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch login attempts",
+		})
+	}
+	return c.JSON(attempts)
+}
+
+// UnlockLogin снимает блокировку входа с логина или IP-адреса до истечения ее срока
+func UnlockLogin(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid login attempt ID",
+		})
+	}
+
+	err = database.UnlockLogin(id, c.Locals("userID").(int))
+	if err == sql.ErrNoRows {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Login attempt record not found",
+		})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to unlock login",
+		})
+	}
+	return c.JSON(fiber.Map{"message": "Login unlocked"})
+}
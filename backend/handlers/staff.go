@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"database/sql"
+	"library-management/backend/database"
+	"library-management/backend/models"
+	"library-management/backend/rbac"
+	"log"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// minPasswordLength — минимальная длина пароля сотрудника
+const minPasswordLength = 6
+
+// GetStaff возвращает сотрудников библиотеки
+func GetStaff(c *fiber.Ctx) error {
+	users, err := database.GetLibraryUsers()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch staff",
+		})
+	}
+	return c.JSON(users)
+}
+
+// CreateStaff заводит сотрудника с паролем
+func CreateStaff(c *fiber.Ctx) error {
+	var req models.StaffRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	user, err := validateStaff(&req)
+	if err != nil {
+		return staffError(c, err)
+	}
+	if err := validatePassword(req.Password); err != nil {
+		return staffError(c, err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to hash password",
+		})
+	}
+	user.PasswordHash = string(hash)
+
+	id, err := database.CreateUser(user, c.Locals("userID").(int))
+	if err != nil {
+		return staffError(c, err)
+	}
+
+	created, err := database.GetUser(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch staff member",
+		})
+	}
+	return c.Status(201).JSON(created)
+}
+
+// UpdateStaff меняет логин, имя, e-mail и роль сотрудника. Новая роль действует
+// со следующего запроса: права проверяются по базе
+func UpdateStaff(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid staff ID",
+		})
+	}
+
+	var req models.StaffRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+
+	user, err := validateStaff(&req)
+	if err != nil {
+		return staffError(c, err)
+	}
+	user.ID = id
+	if err := database.UpdateUser(user, c.Locals("userID").(int)); err != nil {
+		return staffError(c, err)
+	}
+
+	updated, err := database.GetUser(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to fetch staff member",
+		})
+	}
+	return c.JSON(updated)
+}
+
+// DeleteStaff удаляет сотрудника, который еще ничего не делал в системе.
+// Остальных сотрудников отключают, чтобы в журнале осталось, кто что делал
+func DeleteStaff(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid staff ID",
+		})
+	}
+	if id == c.Locals("userID").(int) {
+		return c.Status(409).JSON(fiber.Map{
+			"error": "You cannot delete your own account",
+		})
+	}
+
+	if err := database.DeleteUser(id, c.Locals("userID").(int)); err != nil {
+		return staffError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "Staff member deleted"})
+}
+
+// ToggleStaffActive отключает или снова включает сотрудника. Отключенный сотрудник
+// не может войти, его сессии закрываются сразу
+func ToggleStaffActive(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid staff ID",
+		})
+	}
+	if id == c.Locals("userID").(int) {
+		return c.Status(409).JSON(fiber.Map{
+			"error": "You cannot deactivate your own account",
+		})
+	}
+
+	user, err := database.GetUser(id)
+	if err != nil {
+		return staffError(c, err)
+	}
+	if err := database.SetUserActive(id, !user.IsActive, c.Locals("userID").(int)); err != nil {
+		return staffError(c, err)
+	}
+
+	user.IsActive = !user.IsActive
+	return c.JSON(user)
+}
+
+// SetStaffPassword задает сотруднику новый пароль, например забытый. Все его сессии
+// закрываются. Свой пароль так сменить нельзя без текущего — см. ChangeMyPassword
+func SetStaffPassword(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid staff ID",
+		})
+	}
+	if id == c.Locals("userID").(int) {
+		return ChangeMyPassword(c)
+	}
+
+	var req models.PasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+	return setPassword(c, id, req.Password, 0)
+}
+
+// ChangeMyPassword меняет пароль текущего сотрудника по текущему паролю.
+// Сессии на других устройствах закрываются, текущая остается
+func ChangeMyPassword(c *fiber.Ctx) error {
+	var req models.PasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cannot parse request body",
+		})
+	}
+	if req.CurrentPassword == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Current password is required",
+		})
+	}
+
+	// Текущий пароль подбирается так же, как при входе: попытки идут в те же счетчики и журнал,
+	// иначе украденный токен доступа позволял бы перебирать пароль без ограничений
+	username := c.Locals("username").(string)
+	attempt, err := reserveLoginAttempt(c, username)
+	if attempt == nil {
+		return err
+	}
+
+	user, err := database.GetUserByUsername(username)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+		return loginFailed(c, attempt, 403, "Current password is incorrect")
+	}
+	if err := database.ReleaseLoginAttempt(attempt); err != nil {
+		log.Printf("auth: failed to reset login failures for %q: %v", username, err)
+	}
+
+	sessionID, _ := c.Locals("sessionID").(int)
+	return setPassword(c, user.ID, req.Password, sessionID)
+}
+
+// setPassword проверяет и сохраняет пароль; keepSessionID — сессия, которую не закрывать
+func setPassword(c *fiber.Ctx, id int, password string, keepSessionID int) error {
+	if err := validatePassword(password); err != nil {
+		return staffError(c, err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to hash password",
+		})
+	}
+
+	if err := database.SetUserPassword(id, string(hash), keepSessionID, c.Locals("userID").(int)); err != nil {
+		return staffError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "Password changed"})
+}
+
+// validateStaff проверяет и нормализует карточку сотрудника. Роль по умолчанию — librarian
+func validateStaff(req *models.StaffRequest) (*models.User, error) {
+	user := &models.User{
+		Username: strings.TrimSpace(req.Username),
+		FullName: strings.TrimSpace(req.FullName),
+		Email:    strings.TrimSpace(req.Email),
+		Role:     strings.TrimSpace(req.Role),
+	}
+	if user.Username == "" || strings.ContainsAny(user.Username, " \t") {
+		return nil, fiber.NewError(400, "Username is required and must not contain spaces")
+	}
+	if user.FullName == "" {
+		return nil, fiber.NewError(400, "Full name is required")
+	}
+	if user.Email != "" && !strings.Contains(user.Email, "@") {
+		return nil, fiber.NewError(400, "Invalid email")
+	}
+
+	if user.Role == "" {
+		user.Role = "librarian"
+	}
+	for _, role := range rbac.Roles() {
+		if role == user.Role {
+			return user, nil
+		}
+	}
+	return nil, fiber.NewError(400, "Role must be one of: "+strings.Join(rbac.Roles(), ", "))
+}
+
+// validatePassword проверяет длину пароля. bcrypt учитывает только первые 72 байта
+func validatePassword(password string) error {
+	if utf8.RuneCountInString(password) < minPasswordLength {
+		return fiber.NewError(400, "Password must be at least "+strconv.Itoa(minPasswordLength)+" characters")
+	}
+	if len(password) > 72 {
+		return fiber.NewError(400, "Password must be at most 72 bytes")
+	}
+	return nil
+}
+
+// staffError переводит ошибки проверки и базы данных в ответ API
+func staffError(c *fiber.Ctx, err error) error {
+	if fiberErr, ok := err.(*fiber.Error); ok {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+
+	switch err {
+	case sql.ErrNoRows:
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Staff member not found",
+		})
+	case database.ErrUsernameTaken:
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Username is already taken",
+		})
+	case database.ErrLastAdmin:
+		return c.Status(409).JSON(fiber.Map{
+			"error": "At least one active administrator must remain",
+		})
+	case database.ErrUserHasHistory:
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Staff member has recorded activity; deactivate the account instead",
+		})
+	default:
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to update staff",
+		})
+	}
+}
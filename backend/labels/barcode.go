@@ -0,0 +1,213 @@
+package labels
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Поддерживаемые символики
+const (
+	SymbologyCode128 = "code128"
+	SymbologyEAN13   = "ean13"
+	SymbologyQR      = "qr"
+)
+
+// Barcode — линейный штрих-код: модули (true — штрих) и подпись под ним
+type Barcode struct {
+	Modules []bool
+	Text    string
+	// QuietLeft и QuietRight — минимальные свободные зоны в модулях
+	QuietLeft  int
+	QuietRight int
+}
+
+// code128Patterns — ширины штрихов и пробелов символов Code 128 (значения 0–105) и стоп-символа
+var code128Patterns = [...]string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+	"221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+	"221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+	"212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+	"231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+	"314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+	"112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+	"214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+	"114131", "311141", "411131", "211412", "211214", "211232", "2331112",
+}
+
+const (
+	code128CodeC  = 99
+	code128CodeB  = 100
+	code128StartB = 104
+	code128StartC = 105
+	code128Stop   = 106
+)
+
+// Code128 кодирует строку из печатных символов ASCII. Для серий цифр используется
+// набор C (две цифры на символ), для остального — набор B
+func Code128(data string) (*Barcode, error) {
+	if data == "" {
+		return nil, errors.New("пустой штрих-код")
+	}
+	for _, r := range data {
+		if r < 32 || r > 126 {
+			return nil, fmt.Errorf("символ %q нельзя закодировать в Code 128", r)
+		}
+	}
+
+	digitRun := func(i int) int {
+		n := 0
+		for i+n < len(data) && data[i+n] >= '0' && data[i+n] <= '9' {
+			n++
+		}
+		return n
+	}
+
+	var values []int
+	setC := digitRun(0) >= 4
+	if setC {
+		values = append(values, code128StartC)
+	} else {
+		values = append(values, code128StartB)
+	}
+
+	for i := 0; i < len(data); {
+		run := digitRun(i)
+		switch {
+		case setC && run >= 2:
+			values = append(values, int(data[i]-'0')*10+int(data[i+1]-'0'))
+			i += 2
+		case setC:
+			values = append(values, code128CodeB)
+			setC = false
+		case run >= 6 || run >= 4 && i+run == len(data):
+			// Нечетную серию начинаем одной цифрой в наборе B
+			if run%2 == 1 {
+				values = append(values, int(data[i])-32)
+				i++
+			}
+			values = append(values, code128CodeC)
+			setC = true
+		default:
+			values = append(values, int(data[i])-32)
+			i++
+		}
+	}
+
+	checksum := values[0]
+	for i, value := range values[1:] {
+		checksum += (i + 1) * value
+	}
+	values = append(values, checksum%103, code128Stop)
+
+	barcode := &Barcode{Text: data, QuietLeft: 10, QuietRight: 10}
+	for _, value := range values {
+		barcode.Modules = appendWidths(barcode.Modules, code128Patterns[value])
+	}
+	return barcode, nil
+}
+
+// appendWidths разворачивает ширины «штрих, пробел, штрих…» в модули
+func appendWidths(modules []bool, widths string) []bool {
+	bar := true
+	for _, w := range widths {
+		for i := 0; i < int(w-'0'); i++ {
+			modules = append(modules, bar)
+		}
+		bar = !bar
+	}
+	return modules
+}
+
+var (
+	eanL = [10]string{"0001101", "0011001", "0010011", "0111101", "0100011", "0110001", "0101111", "0111011", "0110111", "0001011"}
+	eanG = [10]string{"0100111", "0110011", "0011011", "0100001", "0011101", "0111001", "0000101", "0010001", "0001001", "0010111"}
+	eanR = [10]string{"1110010", "1100110", "1101100", "1000010", "1011100", "1001110", "1010000", "1000100", "1001000", "1110100"}
+
+	// eanParity — наборы L/G для левой половины в зависимости от первой цифры
+	eanParity = [10]string{"LLLLLL", "LLGLGG", "LLGGLG", "LLGGGL", "LGLLGG", "LGGLLG", "LGGGLL", "LGLGLG", "LGLGGL", "LGGLGL"}
+)
+
+// EAN13CheckDigit вычисляет контрольную цифру для первых 12 цифр кода EAN-13
+func EAN13CheckDigit(digits string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		d := int(digits[i] - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// LuhnCheckDigit вычисляет контрольную цифру по алгоритму Луна для строки цифр
+func LuhnCheckDigit(digits string) byte {
+	sum := 0
+	double := true
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// EAN13 кодирует 12 цифр (контрольная цифра добавляется) или 13 цифр (контрольная цифра проверяется)
+func EAN13(data string) (*Barcode, error) {
+	if len(data) != 12 && len(data) != 13 {
+		return nil, fmt.Errorf("код EAN-13 должен содержать 12 или 13 цифр: %s", data)
+	}
+	for _, r := range data {
+		if r < '0' || r > '9' {
+			return nil, fmt.Errorf("код EAN-13 должен состоять из цифр: %s", data)
+		}
+	}
+
+	check := EAN13CheckDigit(data)
+	if len(data) == 13 && data[12] != check {
+		return nil, fmt.Errorf("неверная контрольная цифра EAN-13: %s", data)
+	}
+	digits := data[:12] + string(check)
+
+	pattern := "101"
+	parity := eanParity[digits[0]-'0']
+	for i := 1; i <= 6; i++ {
+		d := digits[i] - '0'
+		if parity[i-1] == 'L' {
+			pattern += eanL[d]
+		} else {
+			pattern += eanG[d]
+		}
+	}
+	pattern += "01010"
+	for i := 7; i <= 12; i++ {
+		pattern += eanR[digits[i]-'0']
+	}
+	pattern += "101"
+
+	barcode := &Barcode{Text: digits, QuietLeft: 11, QuietRight: 7}
+	for _, m := range pattern {
+		barcode.Modules = append(barcode.Modules, m == '1')
+	}
+	return barcode, nil
+}
+
+// Encode кодирует данные в линейный штрих-код указанной символики
+func Encode(symbology, data string) (*Barcode, error) {
+	switch symbology {
+	case SymbologyCode128:
+		return Code128(data)
+	case SymbologyEAN13:
+		return EAN13(data)
+	default:
+		return nil, fmt.Errorf("неизвестная символика: %s", symbology)
+	}
+}
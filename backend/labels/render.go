@@ -0,0 +1,232 @@
+package labels
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// Label — содержимое одной этикетки
+type Label struct {
+	Title    string
+	Subtitle string
+	Code     string
+}
+
+// Options задает шаблон листа и вид этикеток
+type Options struct {
+	Template  Template
+	Symbology string
+	// Skip — количество уже использованных этикеток в начале первого листа
+	Skip int
+	// Outline рисует контуры этикеток для пробной печати на обычной бумаге
+	Outline bool
+}
+
+// CodeError — значение, которое нельзя закодировать выбранной символикой
+type CodeError struct {
+	Code string
+	Err  error
+}
+
+func (e *CodeError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Code, e.Err)
+}
+
+func (e *CodeError) Unwrap() error {
+	return e.Err
+}
+
+// ptPerMM — пунктов в миллиметре
+const ptPerMM = 72 / 25.4
+
+// Render формирует PDF с этикетками. Размеры задаются в миллиметрах и не зависят
+// от масштабирования при печати, если печатать в масштабе 100%
+func Render(w io.Writer, items []Label, opts Options) error {
+	tmpl := opts.Template
+	if err := tmpl.Validate(); err != nil {
+		return err
+	}
+	if opts.Skip < 0 || opts.Skip >= tmpl.PerPage() {
+		return fmt.Errorf("номер первой этикетки должен быть от 1 до %d", tmpl.PerPage())
+	}
+	if len(items) == 0 {
+		return errors.New("нет этикеток для печати")
+	}
+
+	// Кодируем все значения заранее, чтобы не формировать PDF с ошибкой посередине
+	draw := make([]func(pdf *fpdf.Fpdf, label Label, x, y float64), len(items))
+	for i, item := range items {
+		switch opts.Symbology {
+		case SymbologyQR:
+			qr, err := qrcode.New(item.Code, qrcode.Medium)
+			if err != nil {
+				return &CodeError{Code: item.Code, Err: err}
+			}
+			bitmap := qr.Bitmap()
+			draw[i] = func(pdf *fpdf.Fpdf, label Label, x, y float64) {
+				drawQRLabel(pdf, tmpl, label, bitmap, x, y)
+			}
+		default:
+			barcode, err := Encode(opts.Symbology, item.Code)
+			if err != nil {
+				return &CodeError{Code: item.Code, Err: err}
+			}
+			draw[i] = func(pdf *fpdf.Fpdf, label Label, x, y float64) {
+				drawBarcodeLabel(pdf, tmpl, label, barcode, x, y)
+			}
+		}
+	}
+
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		UnitStr: "mm",
+		Size:    fpdf.SizeType{Wd: tmpl.PageWidth, Ht: tmpl.PageHeight},
+	})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetDisplayMode("real", "default")
+	pdf.SetCatalogSort(true)
+	pdf.SetCreator("library-management", true)
+	pdf.AddUTF8FontFromBytes("Go", "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes("Go", "B", gobold.TTF)
+	pdf.SetFillColor(0, 0, 0)
+	pdf.SetDrawColor(200, 200, 200)
+	pdf.SetLineWidth(0.1)
+
+	slot := opts.Skip
+	for i, item := range items {
+		if i == 0 || slot == tmpl.PerPage() {
+			pdf.AddPage()
+			if i > 0 {
+				slot = 0
+			}
+		}
+
+		x, y := tmpl.Position(slot)
+		if opts.Outline {
+			pdf.Rect(x, y, tmpl.LabelWidth, tmpl.LabelHeight, "D")
+		}
+		draw[i](pdf, item, x, y)
+		slot++
+	}
+
+	return pdf.Output(w)
+}
+
+// labelPadding возвращает внутренний отступ этикетки
+func labelPadding(tmpl Template) float64 {
+	return math.Min(2, tmpl.LabelHeight*0.08)
+}
+
+// drawBarcodeLabel рисует этикетку с линейным штрих-кодом: название, подзаголовок, штрихи и код
+func drawBarcodeLabel(pdf *fpdf.Fpdf, tmpl Template, label Label, barcode *Barcode, x, y float64) {
+	pad := labelPadding(tmpl)
+	width := tmpl.LabelWidth - 2*pad
+	line := tmpl.LabelHeight * 0.16
+	top := y + pad
+	bottom := y + tmpl.LabelHeight - pad
+
+	if label.Title != "" {
+		top = drawText(pdf, "B", label.Title, x+pad, top, width, line, false)
+	}
+	// Подзаголовок выводится, только если остается достаточно места для штрихов
+	if label.Subtitle != "" && tmpl.LabelHeight >= 25 {
+		top = drawText(pdf, "", label.Subtitle, x+pad, top, width, line, false)
+	}
+
+	textLine := line * 0.85
+	barsBottom := bottom - textLine
+	drawText(pdf, "", barcode.Text, x+pad, barsBottom, width, textLine, true)
+
+	modules := len(barcode.Modules) + barcode.QuietLeft + barcode.QuietRight
+	module := width / float64(modules)
+	barsLeft := x + pad + float64(barcode.QuietLeft)*module
+	barsTop := top + pad/2
+
+	// Соседние модули-штрихи объединяются в один прямоугольник
+	for i := 0; i < len(barcode.Modules); {
+		if !barcode.Modules[i] {
+			i++
+			continue
+		}
+		j := i
+		for j < len(barcode.Modules) && barcode.Modules[j] {
+			j++
+		}
+		pdf.Rect(barsLeft+float64(i)*module, barsTop, float64(j-i)*module, barsBottom-barsTop, "F")
+		i = j
+	}
+}
+
+// drawQRLabel рисует этикетку с QR-кодом слева и текстом справа
+func drawQRLabel(pdf *fpdf.Fpdf, tmpl Template, label Label, bitmap [][]bool, x, y float64) {
+	pad := labelPadding(tmpl)
+	side := tmpl.LabelHeight - 2*pad
+	if side > tmpl.LabelWidth/2 {
+		side = tmpl.LabelWidth / 2
+	}
+	module := side / float64(len(bitmap))
+
+	for row, cells := range bitmap {
+		for col := 0; col < len(cells); {
+			if !cells[col] {
+				col++
+				continue
+			}
+			end := col
+			for end < len(cells) && cells[end] {
+				end++
+			}
+			pdf.Rect(x+pad+float64(col)*module, y+pad+float64(row)*module, float64(end-col)*module, module, "F")
+			col = end
+		}
+	}
+
+	textX := x + pad + side + pad
+	width := x + tmpl.LabelWidth - pad - textX
+	line := tmpl.LabelHeight * 0.16
+	top := y + pad
+	if label.Title != "" {
+		top = drawText(pdf, "B", label.Title, textX, top, width, line, false)
+	}
+	if label.Subtitle != "" {
+		top = drawText(pdf, "", label.Subtitle, textX, top, width, line, false)
+	}
+	drawText(pdf, "", label.Code, textX, top, width, line, false)
+}
+
+// drawText выводит одну строку высотой line, усекая ее по ширине.
+// Возвращает координату под строкой
+func drawText(pdf *fpdf.Fpdf, style, text string, x, top, width, line float64, center bool) float64 {
+	pdf.SetFont("Go", style, line*0.8*ptPerMM)
+	text = fitText(pdf, text, width)
+
+	textX := x
+	if center {
+		textX = x + (width-pdf.GetStringWidth(text))/2
+	}
+	pdf.Text(textX, top+line*0.8, text)
+	return top + line
+}
+
+// fitText укорачивает строку с многоточием, чтобы она поместилась в ширину
+func fitText(pdf *fpdf.Fpdf, text string, width float64) string {
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		candidate := string(runes) + "…"
+		if pdf.GetStringWidth(candidate) <= width {
+			return candidate
+		}
+	}
+	return ""
+}
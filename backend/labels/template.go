@@ -0,0 +1,95 @@
+package labels
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Template описывает лист самоклеящихся этикеток. Все размеры — в миллиметрах
+type Template struct {
+	Name        string  `json:"name"`
+	Title       string  `json:"title"`
+	PageWidth   float64 `json:"page_width"`
+	PageHeight  float64 `json:"page_height"`
+	Columns     int     `json:"columns"`
+	Rows        int     `json:"rows"`
+	LabelWidth  float64 `json:"label_width"`
+	LabelHeight float64 `json:"label_height"`
+	MarginTop   float64 `json:"margin_top"`
+	MarginLeft  float64 `json:"margin_left"`
+	GapX        float64 `json:"gap_x"`
+	GapY        float64 `json:"gap_y"`
+}
+
+// Стандартные листы A4
+var templates = []Template{
+	{
+		Name: "a4-24", Title: "A4, 24 этикетки 70×37 мм",
+		PageWidth: 210, PageHeight: 297, Columns: 3, Rows: 8,
+		LabelWidth: 70, LabelHeight: 37, MarginTop: 0.5,
+	},
+	{
+		Name: "a4-40", Title: "A4, 40 этикеток 52,5×29,7 мм",
+		PageWidth: 210, PageHeight: 297, Columns: 4, Rows: 10,
+		LabelWidth: 52.5, LabelHeight: 29.7,
+	},
+	{
+		Name: "a4-65", Title: "A4, 65 этикеток 38,1×21,2 мм",
+		PageWidth: 210, PageHeight: 297, Columns: 5, Rows: 13,
+		LabelWidth: 38.1, LabelHeight: 21.2, MarginTop: 10.7, MarginLeft: 4.75, GapX: 2.5,
+	},
+}
+
+// Templates возвращает стандартные шаблоны листов
+func Templates() []Template {
+	return append([]Template(nil), templates...)
+}
+
+// GetTemplate возвращает стандартный шаблон по названию
+func GetTemplate(name string) (Template, bool) {
+	for _, t := range templates {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Template{}, false
+}
+
+// PerPage возвращает количество этикеток на листе
+func (t Template) PerPage() int {
+	return t.Columns * t.Rows
+}
+
+// Position возвращает левый верхний угол этикетки с порядковым номером n на листе
+func (t Template) Position(n int) (x, y float64) {
+	col := n % t.Columns
+	row := n / t.Columns
+	x = t.MarginLeft + float64(col)*(t.LabelWidth+t.GapX)
+	y = t.MarginTop + float64(row)*(t.LabelHeight+t.GapY)
+	return x, y
+}
+
+// Validate проверяет, что этикетки заданного размера помещаются на листе
+func (t Template) Validate() error {
+	if t.PageWidth <= 0 || t.PageHeight <= 0 {
+		return errors.New("размер листа должен быть положительным")
+	}
+	if t.Columns < 1 || t.Rows < 1 {
+		return errors.New("на листе должна быть хотя бы одна этикетка")
+	}
+	if t.LabelWidth <= 0 || t.LabelHeight <= 0 {
+		return errors.New("размер этикетки должен быть положительным")
+	}
+	if t.MarginTop < 0 || t.MarginLeft < 0 || t.GapX < 0 || t.GapY < 0 {
+		return errors.New("поля и промежутки не могут быть отрицательными")
+	}
+
+	// Допуск 0,01 мм на округление размеров в паспорте листа
+	width := t.MarginLeft + float64(t.Columns)*t.LabelWidth + float64(t.Columns-1)*t.GapX
+	height := t.MarginTop + float64(t.Rows)*t.LabelHeight + float64(t.Rows-1)*t.GapY
+	if width > t.PageWidth+0.01 || height > t.PageHeight+0.01 {
+		return fmt.Errorf("этикетки занимают %.1f×%.1f мм и не помещаются на лист %.1f×%.1f мм",
+			width, height, t.PageWidth, t.PageHeight)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Config — параметры SMTP-сервера для отправки писем
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+var server *Config
+
+// Init задает SMTP-сервер. Без адреса сервера или отправителя отправка писем отключена
+func Init(cfg Config) {
+	server = nil
+	if cfg.Host != "" && cfg.From != "" {
+		server = &cfg
+	}
+}
+
+// Enabled сообщает, настроена ли отправка писем
+func Enabled() bool {
+	return server != nil
+}
+
+// Send отправляет текстовое письмо в UTF-8
+func Send(to, subject, body string) error {
+	if server == nil {
+		return errors.New("mail: SMTP server is not configured")
+	}
+	// Переводы строк в адресе позволили бы дописать в письмо свои заголовки
+	if strings.ContainsAny(to, "\r\n") {
+		return errors.New("mail: invalid recipient address")
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", server.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if server.Username != "" {
+		auth = smtp.PlainAuth("", server.Username, server.Password, server.Host)
+	}
+	addr := fmt.Sprintf("%s:%d", server.Host, server.Port)
+	return smtp.SendMail(addr, auth, server.From, []string{to}, []byte(msg.String()))
+}
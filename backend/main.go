@@ -3,12 +3,19 @@ package main
 import (
 	"embed"
 	"github.com/gofiber/fiber/v2"
+	"library-management/backend/classification"
 	"library-management/backend/config"
 	"library-management/backend/database"
 	"library-management/backend/handlers"
+	"library-management/backend/mail"
+	"library-management/backend/media"
 	"library-management/backend/middleware"
+	"library-management/backend/pii"
+	"library-management/backend/rbac"
+	"library-management/backend/tokens"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/filesystem"
@@ -22,15 +29,94 @@ func main() {
 	// Загрузка конфигурации
 	cfg := config.Load()
 
+	// Ключ шифрования персональных данных нужен до открытия базы: при запуске шифруются старые записи
+	if err := pii.Init(cfg.PIIKey); err != nil {
+		log.Fatal("Invalid PII_KEY:", err)
+	}
+	// Без ключа персональные данные лежали бы открытым текстом: это допускается только явно
+	if !pii.Enabled() {
+		if !cfg.PIIAllowPlaintext {
+			log.Fatal("PII_KEY is not set; set PII_ALLOW_PLAINTEXT=true to store reader personal data unencrypted")
+		}
+		log.Println("PII_KEY is not set: reader personal data is stored unencrypted (PII_ALLOW_PLAINTEXT)")
+	}
+	rbac.SetPersonalDataRoles(cfg.PIIRoles)
+
+	// Ключи подписи токенов сотрудников и читателей
+	err := tokens.Init(tokens.Config{
+		Secret:         cfg.JWTSecret,
+		Keys:           cfg.JWTKeys,
+		SigningKey:     cfg.JWTSigningKey,
+		Issuer:         cfg.JWTIssuer,
+		Audience:       cfg.JWTAudience,
+		PortalAudience: cfg.JWTPortalAudience,
+	})
+	if err != nil {
+		log.Fatal("Invalid JWT configuration:", err)
+	}
+	for _, warning := range tokens.Warnings {
+		log.Println(warning)
+	}
+	log.Printf("JWT signing key: %s", tokens.SigningKeyID())
+	handlers.AccessTokenTTL = time.Duration(cfg.AccessTTLMinutes) * time.Minute
+	handlers.RefreshTokenTTL = time.Duration(cfg.RefreshTTLDays) * 24 * time.Hour
+
 	// Инициализация базы данных
 	if err := database.Init(cfg.DatabasePath); err != nil {
 		log.Fatal("Failed to initialize database:", err)
 	}
 	defer database.Close()
 
+	// Пароль admin/admin из начальных данных подбирается первым
+	if weak, err := database.DefaultAdminPassword(); err != nil {
+		log.Printf("Failed to check the default admin password: %v", err)
+	} else if weak {
+		log.Println("WARNING: user admin still has the default password admin, change it in the staff settings")
+	}
+
+	// Загрузка таблиц классификации ББК и УДК
+	if err := classification.Load(cfg.ClassificationDir); err != nil {
+		log.Fatal("Failed to load classification tables:", err)
+	}
+
+	// Хранилище обложек
+	switch cfg.CoverStorage {
+	case "sqlite":
+		media.Init(database.NewBlobStore())
+	default:
+		store, err := media.NewFSStore(cfg.CoverDir)
+		if err != nil {
+			log.Fatal("Failed to initialize cover storage:", err)
+		}
+		media.Init(store)
+	}
+	handlers.CoverMaxSize = int64(cfg.CoverMaxSizeMB) * 1024 * 1024
+
+	// Фотографии читателей из старых баз переносятся в хранилище изображений
+	moved, skipped, err := database.MoveReaderPhotos()
+	if err != nil {
+		log.Fatal("Failed to move reader photos:", err)
+	}
+	if moved > 0 || skipped > 0 {
+		log.Printf("Reader photos moved to image storage: %d, skipped (not an image): %d", moved, skipped)
+	}
+
+	// Письма читателям с кодами входа в личный кабинет
+	mail.Init(mail.Config{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.SMTPFrom,
+	})
+
 	// Создание Fiber приложения
 	app := fiber.New(fiber.Config{
-		AppName: "Библиотека v1.0",
+		AppName:   "Библиотека v1.0",
+		BodyLimit: cfg.MaxUploadSizeMB * 1024 * 1024,
+		// За обратным прокси адрес клиента берется из заголовка, иначе блокировка по IP
+		// закрыла бы вход всем сразу
+		ProxyHeader: cfg.ProxyHeader,
 	})
 
 	// Middleware
@@ -38,7 +124,7 @@ func main() {
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
 		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
-		AllowMethods: "GET, POST, PUT, DELETE, OPTIONS",
+		AllowMethods: "GET, POST, PUT, PATCH, DELETE, OPTIONS",
 	}))
 
 	// API Routes
@@ -46,61 +132,207 @@ func main() {
 
 	// Публичные маршруты
 	api.Post("/auth/login", handlers.Login)
+	api.Post("/auth/refresh", handlers.RefreshToken)
+	api.Post("/auth/logout", handlers.Logout)
+	api.Get("/covers/:type/:id", handlers.GetCover)
+	api.Get("/clearances/verify/:number", handlers.VerifyClearance)
 
-	// Защищенные маршруты
+	// Личный кабинет читателя: свои токены, маршруты сотрудников для них закрыты.
+	// Группа регистрируется до защищенных маршрутов, чтобы AuthMiddleware ее не перехватывал
+	api.Post("/portal/login", handlers.PortalLogin)
+	api.Post("/portal/login/code", handlers.PortalRequestCode)
+	api.Post("/portal/login/code/verify", handlers.PortalVerifyCode)
+	portal := api.Group("/portal/me", middleware.ReaderAuth)
+	portal.Get("/", handlers.GetPortalProfile)
+	portal.Get("/loans", handlers.GetPortalLoans)
+	portal.Get("/history", handlers.GetPortalHistory)
+	portal.Get("/holds", handlers.GetPortalHolds)
+	portal.Post("/holds", handlers.CreatePortalHold)
+	portal.Delete("/holds/:id", handlers.CancelPortalHold)
+	portal.Get("/books", handlers.SearchPortalBooks)
+	portal.Post("/logout", handlers.PortalLogout)
+
+	// Защищенные маршруты: каждый объявляет право, которое проверяет middleware.Require
 	protected := api.Group("/", middleware.AuthMiddleware)
 
+	// Свои права, сессии и пароль — любому вошедшему сотруднику
+	protected.Get("/auth/permissions", handlers.GetMyPermissions)
+	protected.Get("/auth/sessions", handlers.GetMySessions)
+	protected.Delete("/auth/sessions/:id", handlers.RevokeMySession)
+	protected.Put("/auth/password", handlers.ChangeMyPassword)
+
+	// Сессии сотрудников и блокировки входа после неудачных попыток
+	protected.Get("/sessions", middleware.Require(rbac.StaffManage), handlers.GetSessions)
+	protected.Delete("/sessions", middleware.Require(rbac.StaffManage), handlers.RevokeUserSessions)
+	protected.Delete("/sessions/:id", middleware.Require(rbac.StaffManage), handlers.RevokeSession)
+	protected.Get("/login-attempts", middleware.Require(rbac.StaffManage), handlers.GetLoginAttempts)
+	protected.Delete("/login-attempts/:id", middleware.Require(rbac.StaffManage), handlers.UnlockLogin)
+
 	// Книги
-	protected.Get("/books", handlers.GetBooks)
-	protected.Get("/books/:id", handlers.GetBook)
-	protected.Post("/books", handlers.CreateBook)
-	protected.Put("/books/:id", handlers.UpdateBook)
-	protected.Delete("/books/:id", handlers.DeleteBook)
-	protected.Get("/books/barcode/:barcode", handlers.GetBookByBarcode)
+	protected.Get("/books", middleware.Require(rbac.BooksRead), handlers.GetBooks)
+	protected.Get("/books/:id", middleware.Require(rbac.BooksRead), handlers.GetBook)
+	protected.Post("/books", middleware.Require(rbac.BooksWrite), handlers.CreateBook)
+	protected.Put("/books/:id", middleware.Require(rbac.BooksWrite), handlers.UpdateBook)
+	protected.Delete("/books/:id", middleware.Require(rbac.BooksWrite), handlers.DeleteBook)
+	protected.Get("/books/barcode/:barcode", middleware.Require(rbac.BooksRead), handlers.GetBookByBarcode)
+	protected.Post("/books/move", middleware.Require(rbac.BooksWrite), handlers.MoveBooks)
+	protected.Post("/books/bulk", middleware.Require(rbac.BooksWrite), handlers.BulkUpdateBooks)
+	protected.Get("/books/:id/moves", middleware.Require(rbac.BooksRead), handlers.GetBookMoves)
+	protected.Get("/books/:id/related", middleware.Require(rbac.BooksRead), handlers.GetRelatedBooks)
+	protected.Put("/books/:id/series", middleware.Require(rbac.BooksWrite), handlers.SetBookSeries)
+	protected.Post("/books/:id/editions", middleware.Require(rbac.BooksWrite), handlers.LinkBookEdition)
+	protected.Delete("/books/:id/editions", middleware.Require(rbac.BooksWrite), handlers.UnlinkBookEdition)
+	protected.Post("/books/:id/cover", middleware.Require(rbac.BooksWrite), handlers.UploadBookCover)
+	protected.Delete("/books/:id/cover", middleware.Require(rbac.BooksWrite), handlers.DeleteBookCover)
+
+	// Места хранения
+	protected.Get("/locations", middleware.Require(rbac.BooksRead), handlers.GetLocations)
+	protected.Get("/locations/barcode/:barcode", middleware.Require(rbac.BooksRead), handlers.GetLocationByBarcode)
+	protected.Post("/locations", middleware.Require(rbac.BooksWrite), handlers.CreateLocation)
+	protected.Put("/locations/:id", middleware.Require(rbac.BooksWrite), handlers.UpdateLocation)
+	protected.Delete("/locations/:id", middleware.Require(rbac.BooksWrite), handlers.DeleteLocation)
+
+	// Серии и многотомные издания
+	protected.Get("/series", middleware.Require(rbac.BooksRead), handlers.GetSeries)
+	protected.Post("/series", middleware.Require(rbac.BooksWrite), handlers.CreateSeries)
+	protected.Put("/series/:id", middleware.Require(rbac.BooksWrite), handlers.UpdateSeries)
+	protected.Delete("/series/:id", middleware.Require(rbac.BooksWrite), handlers.DeleteSeries)
 
 	// Читатели (абоненты)
-	protected.Get("/readers", handlers.GetReaders)
-	protected.Get("/readers/:id", handlers.GetReader)
-	protected.Post("/readers", handlers.CreateReader)
-	protected.Put("/readers/:id", handlers.UpdateReader)
-	protected.Delete("/readers/:id", handlers.DeleteReader)
-	protected.Get("/readers/barcode/:barcode", handlers.GetReaderByBarcode)
+	protected.Get("/readers", middleware.Require(rbac.ReadersRead), handlers.GetReaders)
+	protected.Get("/readers/duplicates", middleware.Require(rbac.ReadersRead), handlers.GetReaderDuplicates)
+	protected.Get("/readers/:id", middleware.Require(rbac.ReadersRead), handlers.GetReader)
+	protected.Post("/readers", middleware.Require(rbac.ReadersWrite), handlers.CreateReader)
+	protected.Put("/readers/:id", middleware.Require(rbac.ReadersWrite), handlers.UpdateReader)
+	protected.Delete("/readers/:id", middleware.Require(rbac.ReadersDelete), handlers.DeleteReader)
+	protected.Post("/readers/:id/archive", middleware.Require(rbac.ReadersWrite), handlers.ArchiveReader)
+	protected.Post("/readers/:id/restore", middleware.Require(rbac.ReadersWrite), handlers.RestoreReader)
+	protected.Post("/readers/:id/merge", middleware.Require(rbac.ReadersDelete), handlers.MergeReaders)
+	protected.Get("/readers/:id/clearance", middleware.Require(rbac.ReadersRead), handlers.CheckReaderClearance)
+	protected.Post("/readers/:id/clearance", middleware.Require(rbac.ReadersWrite), handlers.IssueClearance)
+	protected.Get("/readers/:id/clearances", middleware.Require(rbac.ReadersRead), handlers.GetReaderClearances)
+	protected.Post("/readers/:id/portal-pin", middleware.Require(rbac.ReadersWrite), handlers.SetReaderPIN)
+	protected.Delete("/readers/:id/portal-pin", middleware.Require(rbac.ReadersWrite), handlers.DeleteReaderPIN)
+	protected.Get("/clearances/:id/pdf", middleware.Require(rbac.ReadersRead), handlers.GetClearancePDF)
+	protected.Post("/readers/photos/import", middleware.Require(rbac.ReadersWrite), handlers.ImportReaderPhotos)
+	protected.Get("/readers/:id/photo", middleware.Require(rbac.ReadersRead), handlers.GetReaderPhoto)
+	protected.Post("/readers/:id/photo", middleware.Require(rbac.ReadersWrite), handlers.UploadReaderPhoto)
+	protected.Delete("/readers/:id/photo", middleware.Require(rbac.ReadersWrite), handlers.DeleteReaderPhoto)
+	protected.Get("/readers/barcode/:barcode", middleware.Require(rbac.ReadersRead), handlers.GetReaderByBarcode)
+	protected.Post("/readers/bulk", middleware.Require(rbac.ReadersWrite), handlers.BulkUpdateReaders)
+
+	// Перевод классов на новый учебный год
+	protected.Get("/class-rollovers", middleware.Require(rbac.SettingsRead), handlers.GetClassRollovers)
+	protected.Post("/class-rollovers/preview", middleware.Require(rbac.SettingsRead), handlers.PreviewClassRollover)
+	protected.Post("/class-rollovers", middleware.Require(rbac.SettingsManage), handlers.ApplyClassRollover)
+	protected.Get("/class-rollovers/:id", middleware.Require(rbac.SettingsRead), handlers.GetClassRollover)
+	protected.Post("/class-rollovers/:id/undo", middleware.Require(rbac.SettingsManage), handlers.UndoClassRollover)
 
 	// Авторы
-	protected.Get("/authors", handlers.GetAuthors)
-	protected.Post("/authors", handlers.CreateAuthor)
-	protected.Put("/authors/:id", handlers.UpdateAuthor)
-	protected.Delete("/authors/:id", handlers.DeleteAuthor)
+	protected.Get("/authors", middleware.Require(rbac.BooksRead), handlers.GetAuthors)
+	protected.Get("/authors/duplicates", middleware.Require(rbac.BooksRead), handlers.GetAuthorDuplicates)
+	protected.Post("/authors/:id/merge", middleware.Require(rbac.BooksWrite), handlers.MergeAuthors)
+	protected.Post("/authors", middleware.Require(rbac.BooksWrite), handlers.CreateAuthor)
+	protected.Put("/authors/:id", middleware.Require(rbac.BooksWrite), handlers.UpdateAuthor)
+	protected.Delete("/authors/:id", middleware.Require(rbac.BooksWrite), handlers.DeleteAuthor)
 
 	// Издательства
-	protected.Get("/publishers", handlers.GetPublishers)
-	protected.Post("/publishers", handlers.CreatePublisher)
-	protected.Put("/publishers/:id", handlers.UpdatePublisher)
-	protected.Delete("/publishers/:id", handlers.DeletePublisher)
+	protected.Get("/publishers", middleware.Require(rbac.BooksRead), handlers.GetPublishers)
+	protected.Get("/publishers/duplicates", middleware.Require(rbac.BooksRead), handlers.GetPublisherDuplicates)
+	protected.Post("/publishers/:id/merge", middleware.Require(rbac.BooksWrite), handlers.MergePublishers)
+	protected.Post("/publishers", middleware.Require(rbac.BooksWrite), handlers.CreatePublisher)
+	protected.Put("/publishers/:id", middleware.Require(rbac.BooksWrite), handlers.UpdatePublisher)
+	protected.Delete("/publishers/:id", middleware.Require(rbac.BooksWrite), handlers.DeletePublisher)
+
+	// Классификация ББК/УДК
+	protected.Get("/classification/:scheme", middleware.Require(rbac.BooksRead), handlers.GetClassificationTree)
+	protected.Get("/classification/:scheme/validate", middleware.Require(rbac.BooksRead), handlers.ValidateClassificationIndex)
 
 	// Диски
-	protected.Get("/disks", handlers.GetDisks)
-	protected.Post("/disks", handlers.CreateDisk)
-	protected.Put("/disks/:id", handlers.UpdateDisk)
-	protected.Delete("/disks/:id", handlers.DeleteDisk)
+	protected.Get("/disks", middleware.Require(rbac.BooksRead), handlers.GetDisks)
+	protected.Post("/disks", middleware.Require(rbac.BooksWrite), handlers.CreateDisk)
+	protected.Put("/disks/:id", middleware.Require(rbac.BooksWrite), handlers.UpdateDisk)
+	protected.Delete("/disks/:id", middleware.Require(rbac.BooksWrite), handlers.DeleteDisk)
+	protected.Post("/disks/:id/cover", middleware.Require(rbac.BooksWrite), handlers.UploadDiskCover)
+	protected.Delete("/disks/:id/cover", middleware.Require(rbac.BooksWrite), handlers.DeleteDiskCover)
+
+	// Справочники предметов и типов ЭОР
+	protected.Get("/subjects", middleware.Require(rbac.BooksRead), handlers.GetSubjects)
+	protected.Post("/subjects", middleware.Require(rbac.BooksWrite), handlers.CreateSubject)
+	protected.Put("/subjects/:id", middleware.Require(rbac.BooksWrite), handlers.UpdateSubject)
+	protected.Delete("/subjects/:id", middleware.Require(rbac.BooksWrite), handlers.DeleteSubject)
+	protected.Get("/resource-types", middleware.Require(rbac.BooksRead), handlers.GetResourceTypes)
+	protected.Post("/resource-types", middleware.Require(rbac.BooksWrite), handlers.CreateResourceType)
+	protected.Put("/resource-types/:id", middleware.Require(rbac.BooksWrite), handlers.UpdateResourceType)
+	protected.Delete("/resource-types/:id", middleware.Require(rbac.BooksWrite), handlers.DeleteResourceType)
+
+	// Печать этикеток со штрих-кодами
+	protected.Get("/labels/templates", middleware.Require(rbac.BooksRead), handlers.GetLabelTemplates)
+	protected.Post("/labels", middleware.Require(rbac.BooksWrite), handlers.PrintLabels)
+
+	// Диапазоны штрих-кодов и заранее напечатанные партии этикеток
+	protected.Get("/barcode-ranges", middleware.Require(rbac.BooksRead), handlers.GetBarcodeRanges)
+	protected.Post("/barcode-ranges", middleware.Require(rbac.BooksWrite), handlers.CreateBarcodeRange)
+	protected.Put("/barcode-ranges/:id", middleware.Require(rbac.BooksWrite), handlers.UpdateBarcodeRange)
+	protected.Delete("/barcode-ranges/:id", middleware.Require(rbac.BooksWrite), handlers.DeleteBarcodeRange)
+	protected.Post("/barcode-ranges/:id/batches", middleware.Require(rbac.BooksWrite), handlers.CreateBarcodeBatch)
+	protected.Get("/barcode-batches", middleware.Require(rbac.BooksRead), handlers.GetBarcodeBatches)
+	protected.Get("/barcode-batches/:id", middleware.Require(rbac.BooksRead), handlers.GetBarcodeBatch)
+	protected.Post("/barcode-batches/:id/labels", middleware.Require(rbac.BooksWrite), handlers.PrintBarcodeBatch)
+	protected.Post("/barcode-batches/:id/void", middleware.Require(rbac.BooksWrite), handlers.VoidBarcodeBatch)
+	protected.Get("/barcodes/collisions", middleware.Require(rbac.BooksRead), handlers.GetBarcodeCollisions)
+	protected.Get("/barcodes/:barcode", middleware.Require(rbac.BooksRead), handlers.LookupBarcode)
+
+	// Импорт книг и читателей из CSV/XLSX
+	protected.Get("/import/fields", middleware.Require(rbac.ImportRun), handlers.GetImportFields)
+	protected.Get("/import/templates", middleware.Require(rbac.ImportRun), handlers.GetImportTemplates)
+	protected.Post("/import/templates", middleware.Require(rbac.ImportRun), handlers.CreateImportTemplate)
+	protected.Put("/import/templates/:id", middleware.Require(rbac.ImportRun), handlers.UpdateImportTemplate)
+	protected.Delete("/import/templates/:id", middleware.Require(rbac.ImportRun), handlers.DeleteImportTemplate)
+	protected.Post("/import/books", middleware.Require(rbac.ImportRun), handlers.ImportBooks)
+	protected.Post("/import/readers", middleware.Require(rbac.ImportRun), handlers.ImportReaders)
 
 	// Выдача/возврат книг
-	protected.Post("/loans/issue", handlers.IssueBook)
-	protected.Post("/loans/return", handlers.ReturnBook)
-	protected.Get("/loans/active", handlers.GetActiveLoans)
-	protected.Get("/loans/history", handlers.GetLoanHistory)
+	protected.Post("/loans/issue", middleware.Require(rbac.LoansIssue), handlers.IssueBook)
+	protected.Post("/loans/return", middleware.Require(rbac.LoansIssue), handlers.ReturnBook)
+	protected.Get("/loans/active", middleware.Require(rbac.LoansRead), handlers.GetActiveLoans)
+	protected.Get("/loans/history", middleware.Require(rbac.LoansRead), handlers.GetLoanHistory)
+
+	// Брони книг
+	protected.Get("/holds", middleware.Require(rbac.LoansRead), handlers.GetHolds)
+	protected.Post("/holds", middleware.Require(rbac.LoansIssue), handlers.CreateHold)
+	protected.Delete("/holds/:id", middleware.Require(rbac.LoansIssue), handlers.CancelHold)
 
 	// Отчеты
-	protected.Get("/reports/book-availability", handlers.BookAvailabilityReport)
-	protected.Get("/reports/loan-history", handlers.LoanHistoryReport)
-	protected.Get("/reports/class-loans", handlers.ClassLoansReport)
+	protected.Get("/reports/book-availability", middleware.Require(rbac.ReportsRead), handlers.BookAvailabilityReport)
+	protected.Get("/reports/loan-history", middleware.Require(rbac.ReportsRead), handlers.LoanHistoryReport)
+	protected.Get("/reports/class-loans", middleware.Require(rbac.ReportsRead), handlers.ClassLoansReport)
+	protected.Get("/reports/eor-by-subject", middleware.Require(rbac.ReportsRead), handlers.EORBySubjectReport)
+	protected.Get("/reports/inventory", middleware.Require(rbac.ReportsRead), handlers.InventoryReport)
 
 	// Настройки
-	protected.Get("/settings", handlers.GetSettings)
-	protected.Post("/settings", handlers.UpdateSettings)
+	protected.Get("/settings", middleware.Require(rbac.SettingsRead), handlers.GetSettings)
+	protected.Post("/settings", middleware.Require(rbac.SettingsManage), handlers.UpdateSettings)
+	protected.Get("/settings/sequences", middleware.Require(rbac.SettingsRead), handlers.GetSequences)
+	protected.Get("/settings/classes", middleware.Require(rbac.SettingsRead), handlers.GetClasses)
+	protected.Post("/settings/classes", middleware.Require(rbac.SettingsManage), handlers.CreateClass)
+	protected.Put("/settings/classes/:id", middleware.Require(rbac.SettingsManage), handlers.UpdateClass)
+	protected.Delete("/settings/classes/:id", middleware.Require(rbac.SettingsManage), handlers.DeleteClass)
+	protected.Patch("/settings/classes/:id/toggle-active", middleware.Require(rbac.SettingsManage), handlers.ToggleClassActive)
+	protected.Put("/settings/sequences/:entity", middleware.Require(rbac.SettingsManage), handlers.UpdateSequence)
+	protected.Get("/settings/staff", middleware.Require(rbac.StaffManage), handlers.GetStaff)
+	protected.Post("/settings/staff", middleware.Require(rbac.StaffManage), handlers.CreateStaff)
+	protected.Put("/settings/staff/:id", middleware.Require(rbac.StaffManage), handlers.UpdateStaff)
+	protected.Delete("/settings/staff/:id", middleware.Require(rbac.StaffManage), handlers.DeleteStaff)
+	protected.Put("/settings/staff/:id/password", middleware.Require(rbac.StaffManage), handlers.SetStaffPassword)
+	protected.Patch("/settings/staff/:id/toggle-active", middleware.Require(rbac.StaffManage), handlers.ToggleStaffActive)
+
+	// Журнал операций
+	protected.Get("/audit-log", middleware.Require(rbac.AuditRead), handlers.GetAuditLog)
 
 	// Статистика для дашборда
-	protected.Get("/dashboard/stats", handlers.GetDashboardStats)
+	protected.Get("/dashboard/stats", middleware.Require(rbac.BooksRead), handlers.GetDashboardStats)
+	protected.Get("/dashboard/new-arrivals", middleware.Require(rbac.BooksRead), handlers.GetNewArrivals)
 
 	// Обслуживание frontend как встроенных файлов
 	app.Use("/", filesystem.New(filesystem.Config{
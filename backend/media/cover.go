@@ -0,0 +1,74 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Типы объектов, у которых могут быть обложки
+const (
+	EntityBook = "book"
+	EntityDisk = "disk"
+)
+
+// coverKey возвращает ключ хранилища для варианта обложки
+func coverKey(entityType string, id int, size string) string {
+	return fmt.Sprintf("covers/%s/%d/%s", entityType, id, size)
+}
+
+// CoverURL возвращает адрес обложки; etag добавляется как версия для сброса кэша браузера
+func CoverURL(entityType string, id int, etag string) string {
+	if etag == "" {
+		return ""
+	}
+	return fmt.Sprintf("/api/covers/%s/%d?v=%s", entityType, id, etag)
+}
+
+// SaveCover сохраняет все варианты обложки в хранилище
+func SaveCover(entityType string, id int, processed *Processed) error {
+	return saveVariants(processed, func(size string) string {
+		return coverKey(entityType, id, size)
+	})
+}
+
+// GetCover возвращает вариант обложки указанного размера
+func GetCover(entityType string, id int, size string) ([]byte, string, error) {
+	return getVariant(coverKey(entityType, id, size), size)
+}
+
+// saveVariants сохраняет исходное изображение и миниатюры под ключами, построенными по размеру
+func saveVariants(processed *Processed, key func(size string) string) error {
+	if store == nil {
+		return errors.New("media: store is not initialized")
+	}
+
+	for size, data := range processed.Variants {
+		contentType := "image/jpeg"
+		if size == SizeOriginal {
+			contentType = processed.ContentType
+		}
+		if err := store.Put(key(size), data, contentType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getVariant возвращает вариант изображения, проверяя название размера
+func getVariant(key, size string) ([]byte, string, error) {
+	if store == nil {
+		return nil, "", errors.New("media: store is not initialized")
+	}
+	if _, ok := ThumbnailSizes[size]; !ok && size != SizeOriginal {
+		return nil, "", ErrNotFound
+	}
+	return store.Get(key)
+}
+
+// DeleteCover удаляет все варианты обложки
+func DeleteCover(entityType string, id int) error {
+	if store == nil {
+		return errors.New("media: store is not initialized")
+	}
+	return store.Delete(fmt.Sprintf("covers/%s/%d", entityType, id))
+}
@@ -0,0 +1,140 @@
+package media
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+)
+
+// Размеры миниатюр: наибольшая сторона в пикселях
+var ThumbnailSizes = map[string]int{
+	"small":  120,
+	"medium": 300,
+	"large":  600,
+}
+
+// SizeOriginal — имя варианта с исходным изображением
+const SizeOriginal = "original"
+
+// maxPixels ограничивает размер декодируемого изображения (защита от «бомб» распаковки)
+const maxPixels = 40_000_000
+
+// allowedTypes — допустимые типы загружаемых изображений
+var allowedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// ErrUnsupportedType возвращается для файлов, не являющихся изображением поддерживаемого формата
+var ErrUnsupportedType = errors.New("unsupported image type, expected JPEG, PNG or GIF")
+
+// Processed — загруженное изображение и его миниатюры
+type Processed struct {
+	ContentType string
+	Width       int
+	Height      int
+	ETag        string
+	Variants    map[string][]byte
+}
+
+// Process проверяет изображение по содержимому и строит миниатюры всех размеров
+func Process(data []byte) (*Processed, error) {
+	contentType := http.DetectContentType(data)
+	if !allowedTypes[contentType] {
+		return nil, ErrUnsupportedType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, fmt.Errorf("image is too large: %dx%d", config.Width, config.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+
+	sum := sha1.Sum(data)
+	processed := &Processed{
+		ContentType: contentType,
+		Width:       config.Width,
+		Height:      config.Height,
+		ETag:        hex.EncodeToString(sum[:8]),
+		Variants:    map[string][]byte{SizeOriginal: data},
+	}
+
+	for name, size := range ThumbnailSizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, Thumbnail(img, size), &jpeg.Options{Quality: 85}); err != nil {
+			return nil, err
+		}
+		processed.Variants[name] = buf.Bytes()
+	}
+
+	return processed, nil
+}
+
+// Thumbnail уменьшает изображение так, чтобы наибольшая сторона не превышала size.
+// Используется усреднение по площади, поэтому мелкий текст на обложке не «рассыпается»
+func Thumbnail(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	// Прозрачные области (PNG, GIF) заливаем белым, так как JPEG не поддерживает прозрачность
+	rgba := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(rgba, rgba.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Over)
+
+	if width <= size && height <= size {
+		return rgba
+	}
+
+	dstWidth, dstHeight := size, size
+	if width > height {
+		dstHeight = max(1, height*size/width)
+	} else {
+		dstWidth = max(1, width*size/height)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		y0, y1 := y*height/dstHeight, max((y+1)*height/dstHeight, y*height/dstHeight+1)
+		for x := 0; x < dstWidth; x++ {
+			x0, x1 := x*width/dstWidth, max((x+1)*width/dstWidth, x*width/dstWidth+1)
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				offset := rgba.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += int(rgba.Pix[offset])
+					g += int(rgba.Pix[offset+1])
+					b += int(rgba.Pix[offset+2])
+					a += int(rgba.Pix[offset+3])
+					offset += 4
+					n++
+				}
+			}
+
+			offset := dst.PixOffset(x, y)
+			dst.Pix[offset] = uint8(r / n)
+			dst.Pix[offset+1] = uint8(g / n)
+			dst.Pix[offset+2] = uint8(b / n)
+			dst.Pix[offset+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
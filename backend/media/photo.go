@@ -0,0 +1,43 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+)
+
+// EntityReader — тип объекта для фотографий читателей
+const EntityReader = "reader"
+
+// photoKey возвращает ключ хранилища для варианта фотографии читателя
+func photoKey(id int, size string) string {
+	return fmt.Sprintf("photos/reader/%d/%s", id, size)
+}
+
+// PhotoURL возвращает адрес фотографии читателя; etag добавляется как версия для сброса кэша браузера.
+// В отличие от обложек адрес требует авторизации
+func PhotoURL(id int, etag string) string {
+	if etag == "" {
+		return ""
+	}
+	return fmt.Sprintf("/api/readers/%d/photo?v=%s", id, etag)
+}
+
+// SavePhoto сохраняет фотографию читателя и ее миниатюры
+func SavePhoto(id int, processed *Processed) error {
+	return saveVariants(processed, func(size string) string {
+		return photoKey(id, size)
+	})
+}
+
+// GetPhoto возвращает вариант фотографии читателя указанного размера
+func GetPhoto(id int, size string) ([]byte, string, error) {
+	return getVariant(photoKey(id, size), size)
+}
+
+// DeletePhoto удаляет фотографию читателя со всеми миниатюрами
+func DeletePhoto(id int) error {
+	if store == nil {
+		return errors.New("media: store is not initialized")
+	}
+	return store.Delete(fmt.Sprintf("photos/reader/%d", id))
+}
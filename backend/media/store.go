@@ -0,0 +1,100 @@
+package media
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotFound возвращается хранилищем, если объекта с таким ключом нет
+var ErrNotFound = errors.New("media: object not found")
+
+// Store — хранилище бинарных объектов (обложек, фотографий) по ключу вида "covers/book/12/small"
+type Store interface {
+	Put(key string, data []byte, contentType string) error
+	Get(key string) ([]byte, string, error)
+	Delete(prefix string) error
+}
+
+var store Store
+
+// Init задает хранилище, используемое пакетом
+func Init(s Store) {
+	store = s
+}
+
+// FSStore хранит объекты в каталоге файловой системы
+type FSStore struct {
+	dir string
+}
+
+// NewFSStore создает файловое хранилище в указанном каталоге
+func NewFSStore(dir string) (*FSStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FSStore{dir: dir}, nil
+}
+
+// path возвращает путь к файлу объекта; ключ не может выйти за пределы каталога
+func (s *FSStore) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" || strings.Contains(key, "..") {
+		return "", errors.New("media: invalid key")
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(clean)), nil
+}
+
+// Put сохраняет объект. Тип содержимого хранится в соседнем файле .type
+func (s *FSStore) Put(key string, data []byte, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path+".type", []byte(contentType), 0o644); err != nil {
+		return err
+	}
+
+	// Пишем во временный файл и переименовываем, чтобы не отдать недописанный объект
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Get возвращает объект и тип его содержимого
+func (s *FSStore) Get(key string) ([]byte, string, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, "", ErrNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	contentType, err := os.ReadFile(path + ".type")
+	if err != nil {
+		contentType = []byte("application/octet-stream")
+	}
+
+	return data, string(contentType), nil
+}
+
+// Delete удаляет все объекты, ключ которых начинается с prefix (каталог объекта)
+func (s *FSStore) Delete(prefix string) error {
+	path, err := s.path(prefix)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(path)
+}
@@ -1,65 +1,90 @@
 package middleware
 
 import (
+	"database/sql"
+	"errors"
+	"library-management/backend/database"
+	"library-management/backend/rbac"
+	"library-management/backend/tokens"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret = []byte("your-secret-key-change-this-in-production")
-
-// AuthMiddleware проверяет JWT токен
-func AuthMiddleware(c *fiber.Ctx) error {
-	// Получаем токен из заголовка
+// bearerToken извлекает токен из заголовка Authorization
+func bearerToken(c *fiber.Ctx) (string, error) {
 	authHeader := c.Get("Authorization")
 	if authHeader == "" {
-		return c.Status(401).JSON(fiber.Map{
-			"error": "Missing authorization header",
-		})
+		return "", errors.New("Missing authorization header")
 	}
 
-	// Проверяем формат заголовка
 	tokenParts := strings.Split(authHeader, " ")
 	if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+		return "", errors.New("Invalid authorization header format")
+	}
+	return tokenParts[1], nil
+}
+
+// AuthMiddleware проверяет JWT токен
+func AuthMiddleware(c *fiber.Ctx) error {
+	// Получаем токен из заголовка
+	tokenString, err := bearerToken(c)
+	if err != nil {
 		return c.Status(401).JSON(fiber.Map{
-			"error": "Invalid authorization header format",
+			"error": err.Error(),
 		})
 	}
 
-	tokenString := tokenParts[1]
-
-	// Парсим и валидируем токен
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Проверяем метод подписи
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fiber.ErrUnauthorized
-		}
-		return jwtSecret, nil
-	})
-
-	if err != nil || !token.Valid {
+	// Парсим и валидируем токен: подпись, издатель, получатель и срок действия
+	claims, err := tokens.Parse(tokens.Staff, tokenString)
+	if err != nil {
 		return c.Status(401).JSON(fiber.Map{
 			"error": "Invalid or expired token",
 		})
 	}
 
-	// Извлекаем claims
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
+	// Токены с другой областью действия (личный кабинет читателя) сюда не допускаются
+	scope, _ := claims["scope"].(string)
+	sessionID, okSession := claims["sid"].(float64)
+	userID, okID := claims["user_id"].(float64)
+	if scope != "" || !okSession || !okID {
 		return c.Status(401).JSON(fiber.Map{
 			"error": "Invalid token claims",
 		})
 	}
 
-	// Сохраняем информацию о пользователе в контексте
-	userID := int(claims["user_id"].(float64))
-	username := claims["username"].(string)
-	role := claims["role"].(string)
+	// Сессия проверяется при каждом запросе: отозванная сессия закрывает доступ сразу,
+	// а имя и роль берутся из базы, а не из токена
+	user, err := database.GetSessionUser(int(sessionID))
+	if err == sql.ErrNoRows || (err == nil && user.UserID != int(userID)) {
+		return c.Status(401).JSON(fiber.Map{
+			"error": "Session has expired or was revoked",
+		})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
 
-	c.Locals("userID", userID)
-	c.Locals("username", username)
-	c.Locals("role", role)
+	// Сохраняем информацию о пользователе в контексте
+	c.Locals("userID", user.UserID)
+	c.Locals("username", user.Username)
+	c.Locals("role", user.Role)
+	c.Locals("sessionID", user.SessionID)
 
 	return c.Next()
 }
+
+// Require пропускает только сотрудников, чьей роли выдано право permission
+func Require(permission string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if role, _ := c.Locals("role").(string); !rbac.Has(role, permission) {
+			return c.Status(403).JSON(fiber.Map{
+				"error":      "Permission denied",
+				"permission": permission,
+			})
+		}
+		return c.Next()
+	}
+}
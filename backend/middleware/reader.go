@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"database/sql"
+	"library-management/backend/database"
+	"library-management/backend/tokens"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ReaderScope — значение claim scope в токенах личного кабинета читателя
+const ReaderScope = "reader"
+
+// ReaderTokenTTL — срок действия токена читателя
+const ReaderTokenTTL = 12 * time.Hour
+
+// NewReaderToken выдает токен личного кабинета читателя. У него свой получатель (aud):
+// токен читателя не проходит AuthMiddleware, а токен сотрудника — ReaderAuth.
+// version — текущая версия токенов читателя (database.GetPortalTokenVersion)
+func NewReaderToken(readerID, version int) (string, time.Time, error) {
+	return tokens.Sign(tokens.Portal, jwt.MapClaims{
+		"reader_id": readerID,
+		"ver":       version,
+		"scope":     ReaderScope,
+	}, ReaderTokenTTL)
+}
+
+// ReaderAuth проверяет токен читателя и сохраняет readerID в контексте.
+// Маршруты личного кабинета не видят userID и role и не принимают токены сотрудников.
+// Версия токена сверяется с базой при каждом запросе: смена или удаление PIN, выход
+// и выбытие читателя закрывают доступ сразу
+func ReaderAuth(c *fiber.Ctx) error {
+	tokenString, err := bearerToken(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	claims, err := tokens.Parse(tokens.Portal, tokenString)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{
+			"error": "Invalid or expired token",
+		})
+	}
+
+	scope, _ := claims["scope"].(string)
+	readerID, ok := claims["reader_id"].(float64)
+	version, okVersion := claims["ver"].(float64)
+	if scope != ReaderScope || !ok || !okVersion {
+		return c.Status(401).JSON(fiber.Map{
+			"error": "Invalid token claims",
+		})
+	}
+
+	current, err := database.GetPortalTokenVersion(int(readerID))
+	if err == sql.ErrNoRows || (err == nil && current != int(version)) {
+		return c.Status(401).JSON(fiber.Map{
+			"error": "Session has expired or was revoked",
+		})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+
+	c.Locals("readerID", int(readerID))
+	return c.Next()
+}
@@ -2,17 +2,37 @@ package models
 
 import (
 	"database/sql/driver"
+	"encoding/json"
 	"time"
 )
 
 // User представляет пользователя системы (библиотекаря)
 type User struct {
-	ID           int       `json:"id"`
-	Username     string    `json:"username"`
-	PasswordHash string    `json:"-"`
-	FullName     string    `json:"full_name"`
-	Role         string    `json:"role"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID           int        `json:"id"`
+	Username     string     `json:"username"`
+	PasswordHash string     `json:"-"`
+	FullName     string     `json:"full_name"`
+	Email        string     `json:"email"`
+	Role         string     `json:"role"`
+	IsActive     bool       `json:"is_active"`
+	LastLogin    *time.Time `json:"last_login"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// StaffRequest представляет карточку сотрудника при создании и изменении.
+// Пароль учитывается только при создании, для смены есть отдельный запрос
+type StaffRequest struct {
+	Username string `json:"username"`
+	FullName string `json:"full_name"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	Password string `json:"password"`
+}
+
+// PasswordRequest представляет смену пароля. Текущий пароль нужен, когда сотрудник меняет свой
+type PasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	Password        string `json:"password"`
 }
 
 // Book представляет книгу
@@ -32,11 +52,100 @@ type Book struct {
 	UDK             string     `json:"udk"`
 	ClassRange      string     `json:"class_range"`
 	Location        string     `json:"location"`
+	LocationID      *int       `json:"location_id"`
+	SeriesID        *int       `json:"series_id"`
+	VolumeNumber    *int       `json:"volume_number"`
+	PartTitle       string     `json:"part_title"`
+	WorkID          *int       `json:"work_id"`
+	CoverURL        string     `json:"cover_url,omitempty"`
 	CreatedAt       time.Time  `json:"created_at"`
 	CreatedBy       int        `json:"created_by"`
 	IsAvailable     bool       `json:"is_available"`
 }
 
+// Location представляет место хранения: здание, помещение, шкаф или полку
+type Location struct {
+	ID        int       `json:"id"`
+	ParentID  *int      `json:"parent_id"`
+	Kind      string    `json:"kind"` // building, room, cabinet, shelf
+	Name      string    `json:"name"`
+	Barcode   string    `json:"barcode"`
+	Comments  string    `json:"comments"`
+	Path      string    `json:"path"`
+	BookCount int       `json:"book_count"` // с учетом вложенных мест хранения
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BookMove представляет запись истории перемещений книги
+type BookMove struct {
+	ID             int       `json:"id"`
+	BookID         int       `json:"book_id"`
+	FromLocationID *int      `json:"from_location_id"`
+	FromLocation   string    `json:"from_location"`
+	ToLocationID   *int      `json:"to_location_id"`
+	ToLocation     string    `json:"to_location"`
+	MovedBy        int       `json:"moved_by"`
+	MovedByName    string    `json:"moved_by_name"`
+	MovedAt        time.Time `json:"moved_at"`
+}
+
+// MoveBooksRequest представляет запрос на перемещение книг.
+// Место назначения задается ID или отсканированным штрих-кодом полки,
+// книги — списком ID и/или отсканированных штрих-кодов
+type MoveBooksRequest struct {
+	LocationID      *int     `json:"location_id"`
+	LocationBarcode string   `json:"location_barcode"`
+	BookIDs         []int    `json:"book_ids"`
+	BookBarcodes    []string `json:"book_barcodes"`
+}
+
+// MoveBooksResult представляет результат перемещения книг
+type MoveBooksResult struct {
+	Location  *Location `json:"location"`
+	Moved     int       `json:"moved"`
+	Unchanged int       `json:"unchanged"`
+	NotFound  []string  `json:"not_found"`
+}
+
+// Series представляет серию или многотомное издание
+type Series struct {
+	ID        int       `json:"id"`
+	Title     string    `json:"title"`
+	Kind      string    `json:"kind"` // series, multivolume
+	Comments  string    `json:"comments"`
+	CreatedAt time.Time `json:"created_at"`
+	BookCount int       `json:"book_count"`
+}
+
+// Work представляет произведение, объединяющее разные издания и годы выпуска
+type Work struct {
+	ID        int       `json:"id"`
+	Title     string    `json:"title"`
+	AuthorID  *int      `json:"author_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RelatedBookGroup представляет связанный том или издание с количеством экземпляров
+type RelatedBookGroup struct {
+	Title            string     `json:"title"`
+	VolumeNumber     *int       `json:"volume_number"`
+	PartTitle        string     `json:"part_title"`
+	PublicationYear  *int       `json:"publication_year"`
+	Publisher        *Publisher `json:"publisher,omitempty"`
+	BookIDs          []int      `json:"book_ids"`
+	TotalCopies      int        `json:"total_copies"`
+	AvailableCopies  int        `json:"available_copies"`
+	AvailableBarcode string     `json:"available_barcode"`
+}
+
+// RelatedBooks представляет другие тома серии и другие издания того же произведения
+type RelatedBooks struct {
+	Series   *Series            `json:"series"`
+	Volumes  []RelatedBookGroup `json:"volumes"`
+	Work     *Work              `json:"work"`
+	Editions []RelatedBookGroup `json:"editions"`
+}
+
 // Reader представляет читателя (абонента)
 type Reader struct {
 	ID                int        `json:"id"`
@@ -56,7 +165,7 @@ type Reader struct {
 	DocumentNumber    string     `json:"document_number"`
 	Phone             string     `json:"phone"`
 	Email             string     `json:"email"`
-	Photo             []byte     `json:"photo,omitempty"`
+	PhotoURL          string     `json:"photo_url,omitempty"`
 	ParentMotherName  string     `json:"parent_mother_name"`
 	ParentMotherPhone string     `json:"parent_mother_phone"`
 	ParentFatherName  string     `json:"parent_father_name"`
@@ -66,7 +175,12 @@ type Reader struct {
 	CreatedAt         time.Time  `json:"created_at"`
 	CreatedBy         int        `json:"created_by"`
 	Comments          string     `json:"comments"`
+	Status            string     `json:"status"` // active, archived
+	ArchivedAt        *time.Time `json:"archived_at"`
+	ArchiveReason     string     `json:"archive_reason"`
 	ActiveLoansCount  int        `json:"active_loans_count"`
+	// PersonalDataMasked — персональные данные скрыты по роли пользователя
+	PersonalDataMasked bool `json:"personal_data_masked,omitempty"`
 }
 
 // Author представляет автора
@@ -92,11 +206,70 @@ type Publisher struct {
 
 // Class представляет класс
 type Class struct {
-	ID          int    `json:"id"`
-	Grade       int    `json:"grade"`
-	Letter      string `json:"letter"`
-	TeacherName string `json:"teacher_name"`
-	DisplayName string `json:"display_name"`
+	ID            int    `json:"id"`
+	Grade         int    `json:"grade"`
+	Letter        string `json:"letter"`
+	TeacherID     *int   `json:"teacher_id"` // классный руководитель — читатель-учитель
+	TeacherName   string `json:"teacher_name"`
+	DisplayName   string `json:"display_name"`
+	IsActive      bool   `json:"is_active"`
+	StudentsCount int    `json:"students_count"`
+}
+
+// ReaderArchiveRequest — параметры перевода читателя в архив
+type ReaderArchiveRequest struct {
+	Reason        string `json:"reason"`
+	Date          string `json:"date"`            // дата выбытия YYYY-MM-DD, по умолчанию сегодня
+	WriteOffDebts bool   `json:"write_off_debts"` // списать невозвращенные книги и диски
+}
+
+// ClassRolloverRequest — параметры перевода классов на новый учебный год
+type ClassRolloverRequest struct {
+	AcademicYear      string   `json:"academic_year"`       // новый учебный год, например 2026/2027
+	MaxGrade          int      `json:"max_grade"`           // выпускная параллель, по умолчанию 11
+	FirstGradeLetters []string `json:"first_grade_letters"` // литеры новых первых классов, по умолчанию как у нынешних
+	OnActiveLoans     string   `json:"on_active_loans"`     // refuse — отказать, flag — выпустить должников без архивации
+}
+
+// ClassRolloverClass — изменение класса при переводе
+type ClassRolloverClass struct {
+	ClassID int    `json:"class_id"`
+	From    string `json:"from"`
+	To      string `json:"to"` // пусто у выпускных классов
+	Readers int    `json:"readers"`
+}
+
+// ClassRolloverDebtor — выпускник, не вернувший книги или диски
+type ClassRolloverDebtor struct {
+	ReaderID    int    `json:"reader_id"`
+	Name        string `json:"name"`
+	Class       string `json:"class"`
+	ActiveLoans int    `json:"active_loans"`
+}
+
+// ClassRolloverPlan — что изменит перевод классов (при предпросмотре) или что изменил
+type ClassRolloverPlan struct {
+	AcademicYear     string                `json:"academic_year"`
+	MaxGrade         int                   `json:"max_grade"`
+	Promoted         []ClassRolloverClass  `json:"promoted"`
+	Graduated        []ClassRolloverClass  `json:"graduated"`
+	NewClasses       []string              `json:"new_classes"`
+	PromotedReaders  int                   `json:"promoted_readers"`
+	GraduatedReaders int                   `json:"graduated_readers"` // выпускники, переведенные в архив
+	Debtors          []ClassRolloverDebtor `json:"debtors"`           // выпускники с активными выдачами
+	Flagged          bool                  `json:"flagged"`           // должники выпущены без архивации
+}
+
+// ClassRollover — выполненный перевод классов
+type ClassRollover struct {
+	ID           int               `json:"id"`
+	AcademicYear string            `json:"academic_year"`
+	Status       string            `json:"status"` // applied, undone
+	Summary      ClassRolloverPlan `json:"summary"`
+	CreatedBy    int               `json:"created_by"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UndoneBy     *int              `json:"undone_by"`
+	UndoneAt     *time.Time        `json:"undone_at"`
 }
 
 // Loan представляет выдачу книги
@@ -117,19 +290,214 @@ type Loan struct {
 
 // Disk представляет диск
 type Disk struct {
-	ID           int        `json:"id"`
-	Code         string     `json:"code"`
-	Title        string     `json:"title"`
-	ShortTitle   string     `json:"short_title"`
-	PublisherID  *int       `json:"publisher_id"`
-	Publisher    *Publisher `json:"publisher,omitempty"`
-	Subject      string     `json:"subject"`
-	ResourceType string     `json:"resource_type"`
-	Barcode      string     `json:"barcode"`
-	CreatedAt    time.Time  `json:"created_at"`
-	CreatedBy    int        `json:"created_by"`
-	Comments     string     `json:"comments"`
-	IsAvailable  bool       `json:"is_available"`
+	ID             int        `json:"id"`
+	Code           string     `json:"code"`
+	Title          string     `json:"title"`
+	ShortTitle     string     `json:"short_title"`
+	PublisherID    *int       `json:"publisher_id"`
+	Publisher      *Publisher `json:"publisher,omitempty"`
+	SubjectID      *int       `json:"subject_id"`
+	Subject        string     `json:"subject"`
+	ResourceTypeID *int       `json:"resource_type_id"`
+	ResourceType   string     `json:"resource_type"`
+	Barcode        string     `json:"barcode"`
+	CreatedAt      time.Time  `json:"created_at"`
+	CreatedBy      int        `json:"created_by"`
+	Comments       string     `json:"comments"`
+	CoverURL       string     `json:"cover_url,omitempty"`
+	IsAvailable    bool       `json:"is_available"`
+}
+
+// Subject представляет учебный предмет
+type Subject struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	ShortName string `json:"short_name"`
+	DiskCount int    `json:"disk_count"`
+}
+
+// ResourceType представляет тип электронного образовательного ресурса (ЭОР)
+type ResourceType struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	DiskCount int    `json:"disk_count"`
+}
+
+// ResourceTypeCount представляет количество ЭОР одного типа
+type ResourceTypeCount struct {
+	ResourceTypeID *int   `json:"resource_type_id"`
+	ResourceType   string `json:"resource_type"`
+	Count          int    `json:"count"`
+}
+
+// EORSubjectReportRow представляет строку отчета об ЭОР по предмету
+type EORSubjectReportRow struct {
+	SubjectID *int                `json:"subject_id"`
+	Subject   string              `json:"subject"`
+	Total     int                 `json:"total"`
+	Available int                 `json:"available"`
+	ByType    []ResourceTypeCount `json:"by_type"`
+}
+
+// AuthorDuplicateGroup представляет группу авторов — кандидатов в дубликаты
+type AuthorDuplicateGroup struct {
+	Key     string   `json:"key"`
+	Authors []Author `json:"authors"`
+}
+
+// PublisherDuplicateGroup представляет группу издательств — кандидатов в дубликаты
+type PublisherDuplicateGroup struct {
+	Key        string      `json:"key"`
+	Publishers []Publisher `json:"publishers"`
+}
+
+// ReaderDuplicateGroup представляет группу читателей — кандидатов в дубликаты.
+// Match — чем совпадают записи помимо ФИО: birth_date или class
+type ReaderDuplicateGroup struct {
+	Key     string   `json:"key"`
+	Match   string   `json:"match"`
+	Readers []Reader `json:"readers"`
+}
+
+// ClearanceDebt представляет невозвращенную книгу или диск, мешающие выдать обходной лист
+type ClearanceDebt struct {
+	Type      string    `json:"type"` // book, disk
+	LoanID    int       `json:"loan_id"`
+	ItemID    int       `json:"item_id"`
+	Code      string    `json:"code"`
+	Title     string    `json:"title"`
+	Barcode   string    `json:"barcode"`
+	IssueDate time.Time `json:"issue_date"`
+}
+
+// ClearanceCheck представляет проверку задолженностей читателя перед выдачей обходного листа.
+// Проверяются только невозвращенные книги и диски: начисления в системе не ведутся
+type ClearanceCheck struct {
+	ReaderID   int             `json:"reader_id"`
+	ReaderName string          `json:"reader_name"`
+	Cleared    bool            `json:"cleared"`
+	Debts      []ClearanceDebt `json:"debts"`
+	// Holds — активные брони; долгом не считаются и снимаются при выдаче листа
+	Holds []Hold `json:"holds"`
+}
+
+// Clearance представляет выданный обходной лист
+type Clearance struct {
+	ID               int       `json:"id"`
+	Number           string    `json:"number"`
+	ReaderID         int       `json:"reader_id"`
+	ReaderName       string    `json:"reader_name"`
+	ReaderInfo       string    `json:"reader_info"`
+	Reason           string    `json:"reason"`
+	VerificationCode string    `json:"verification_code,omitempty"`
+	FileHash         string    `json:"file_hash"`
+	IssuedBy         int       `json:"issued_by"`
+	IssuedByName     string    `json:"issued_by_name"`
+	IssuedAt         time.Time `json:"issued_at"`
+}
+
+// ClearanceRequest представляет запрос на выдачу обходного листа
+type ClearanceRequest struct {
+	Reason string `json:"reason"`
+}
+
+// Hold представляет бронь книги читателем
+type Hold struct {
+	ID          int        `json:"id"`
+	ReaderID    int        `json:"reader_id"`
+	ReaderName  string     `json:"reader_name,omitempty"`
+	BookID      int        `json:"book_id"`
+	BookTitle   string     `json:"book_title"`
+	BookBarcode string     `json:"book_barcode"`
+	Status      string     `json:"status"` // active, fulfilled, cancelled
+	Source      string     `json:"source"` // portal, staff
+	CreatedAt   time.Time  `json:"created_at"`
+	ClosedAt    *time.Time `json:"closed_at"`
+	// Position — место в очереди на книгу (для активной брони), 1 — первый
+	Position int `json:"position,omitempty"`
+	// Available — книга сейчас не выдана и ждет читателя в библиотеке
+	Available bool `json:"available"`
+}
+
+// HoldRequest представляет запрос на бронирование книги
+type HoldRequest struct {
+	BookID   int `json:"book_id"`
+	ReaderID int `json:"reader_id"` // только для сотрудников
+}
+
+// PortalLoan представляет книгу или диск на руках у читателя или в его истории
+type PortalLoan struct {
+	Type       string     `json:"type"` // book, disk
+	ID         int        `json:"id"`
+	Title      string     `json:"title"`
+	Author     string     `json:"author"`
+	Barcode    string     `json:"barcode"`
+	IssueDate  time.Time  `json:"issue_date"`
+	DueDate    time.Time  `json:"due_date"`
+	ReturnDate *time.Time `json:"return_date"`
+	Status     string     `json:"status"`
+	Overdue    bool       `json:"overdue"`
+}
+
+// PortalProfile представляет карточку читателя в личном кабинете — без персональных данных
+type PortalProfile struct {
+	ID          int    `json:"id"`
+	Code        string `json:"code"`
+	Barcode     string `json:"barcode"`
+	LastName    string `json:"last_name"`
+	FirstName   string `json:"first_name"`
+	MiddleName  string `json:"middle_name"`
+	UserType    string `json:"user_type"`
+	ClassName   string `json:"class_name"`
+	ActiveLoans int    `json:"active_loans"`
+	Overdue     int    `json:"overdue"`
+	ActiveHolds int    `json:"active_holds"`
+}
+
+// PortalBook представляет книгу в каталоге личного кабинета
+type PortalBook struct {
+	ID              int    `json:"id"`
+	Title           string `json:"title"`
+	Author          string `json:"author"`
+	PublicationYear *int   `json:"publication_year"`
+	Available       bool   `json:"available"`
+	Holds           int    `json:"holds"`
+}
+
+// PortalLoginRequest представляет вход читателя по штрих-коду и PIN
+type PortalLoginRequest struct {
+	Barcode string `json:"barcode"`
+	PIN     string `json:"pin"`
+}
+
+// PortalCodeRequest представляет запрос кода входа на e-mail или его проверку
+type PortalCodeRequest struct {
+	Email string `json:"email"`
+	Code  string `json:"code"`
+}
+
+// PortalLoginResponse представляет ответ на вход в личный кабинет
+type PortalLoginResponse struct {
+	Token     string        `json:"token"`
+	ExpiresAt time.Time     `json:"expires_at"`
+	Reader    PortalProfile `json:"reader"`
+}
+
+// ReaderPINRequest представляет установку PIN читателя сотрудником; пустой PIN генерируется
+type ReaderPINRequest struct {
+	PIN string `json:"pin"`
+}
+
+// AuditEntry представляет запись журнала операций
+type AuditEntry struct {
+	ID         int             `json:"id"`
+	EntityType string          `json:"entity_type"`
+	EntityID   int             `json:"entity_id"`
+	Action     string          `json:"action"`
+	Details    json.RawMessage `json:"details"`
+	UserID     *int            `json:"user_id"`
+	Username   string          `json:"username"`
+	CreatedAt  time.Time       `json:"created_at"`
 }
 
 // Settings представляет настройки системы
@@ -148,16 +516,54 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
-// LoginResponse представляет ответ на вход
+// LoginResponse представляет ответ на вход и на обновление токена
 type LoginResponse struct {
 	Token string `json:"token"`
-	User  User   `json:"user"`
+	// ExpiresAt — срок действия токена доступа; после него нужен новый через refresh-токен
+	ExpiresAt    time.Time `json:"expires_at"`
+	RefreshToken string    `json:"refresh_token"`
+	User         User      `json:"user"`
+}
+
+// RefreshRequest представляет обновление токена доступа или выход
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Session представляет сессию сотрудника (вход на одном устройстве)
+type Session struct {
+	ID           int        `json:"id"`
+	UserID       int        `json:"user_id"`
+	Username     string     `json:"username"`
+	UserAgent    string     `json:"user_agent"`
+	IP           string     `json:"ip"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	RevokeReason string     `json:"revoke_reason,omitempty"`
+	// Current — сессия, из которой сделан запрос
+	Current bool `json:"current"`
+}
+
+// LoginAttempt представляет счетчик неудачных входов сотрудников по логину или IP-адресу
+type LoginAttempt struct {
+	ID           int        `json:"id"`
+	Kind         string     `json:"kind"` // username, ip
+	Value        string     `json:"value"`
+	Failures     int        `json:"failures"`
+	LastFailedAt *time.Time `json:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until"`
+	// Locked — вход сейчас закрыт
+	Locked bool `json:"locked"`
 }
 
 // IssueBookRequest представляет запрос на выдачу книги
 type IssueBookRequest struct {
 	BookBarcode   string `json:"book_barcode"`
 	ReaderBarcode string `json:"reader_barcode"`
+	// Override — выдать книгу, забронированную другим читателем (нужно право loans.override)
+	Override bool `json:"override"`
 }
 
 // ReturnBookRequest представляет запрос на возврат книги
@@ -165,6 +571,23 @@ type ReturnBookRequest struct {
 	BookBarcode string `json:"book_barcode"`
 }
 
+// BookSeriesRequest представляет запрос на включение книги в серию
+type BookSeriesRequest struct {
+	SeriesID     *int   `json:"series_id"`
+	VolumeNumber *int   `json:"volume_number"`
+	PartTitle    string `json:"part_title"`
+}
+
+// LinkEditionRequest представляет запрос на связывание изданий одного произведения
+type LinkEditionRequest struct {
+	BookID int `json:"book_id"`
+}
+
+// MergeRequest представляет запрос на объединение дубликатов с сохраняемой записью
+type MergeRequest struct {
+	SourceIDs []int `json:"source_ids"`
+}
+
 // DashboardStats представляет статистику для главной страницы
 type DashboardStats struct {
 	TotalBooks     int `json:"total_books"`
@@ -176,6 +599,310 @@ type DashboardStats struct {
 	OverdueLoans   int `json:"overdue_loans"`
 }
 
+// ClassificationNode представляет раздел классификации (ББК/УДК) с количеством книг
+type ClassificationNode struct {
+	Scheme         string `json:"scheme"`
+	Index          string `json:"index"`
+	Title          string `json:"title"`
+	ParentIndex    string `json:"parent_index"`
+	Level          int    `json:"level"`
+	HasChildren    bool   `json:"has_children"`
+	BookCount      int    `json:"book_count"`
+	AvailableCount int    `json:"available_count"`
+}
+
+// BookAvailabilityRow представляет строку отчета о наличии книг по разделу классификации
+type BookAvailabilityRow struct {
+	SectionIndex string `json:"section_index"`
+	SectionTitle string `json:"section_title"`
+	Total        int    `json:"total"`
+	Available    int    `json:"available"`
+	Loaned       int    `json:"loaned"`
+}
+
+// BookFilter — параметры выборки каталога книг
+type BookFilter struct {
+	Search       string `json:"search"`
+	AuthorIDs    []int  `json:"author_ids"`
+	PublisherIDs []int  `json:"publisher_ids"`
+	YearFrom     int    `json:"year_from"`
+	YearTo       int    `json:"year_to"`
+	Available    *bool  `json:"available"`
+	LocationID   int    `json:"location_id"`
+	ClassRange   string `json:"class_range"`
+	BBK          string `json:"bbk"`          // раздел ББК, включая вложенные разделы
+	CreatedFrom  string `json:"created_from"` // YYYY-MM-DD
+	CreatedTo    string `json:"created_to"`
+	Sort         string `json:"-"` // title, author, year, created; по умолчанию — код
+	Desc         bool   `json:"-"`
+	Page         int    `json:"-"`
+	PageSize     int    `json:"-"`
+}
+
+// FacetValue — значение фильтра каталога с количеством книг
+type FacetValue struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// BookFacets — количество книг по значениям каждого фильтра каталога.
+// Для многозначных фильтров подсчет ведется без учета собственного фильтра,
+// для иерархических (места хранения, ББК) — по разделам следующего уровня
+type BookFacets struct {
+	Authors      []FacetValue `json:"authors"`
+	Publishers   []FacetValue `json:"publishers"`
+	Years        []FacetValue `json:"years"`
+	Availability []FacetValue `json:"availability"`
+	Locations    []FacetValue `json:"locations"`
+	ClassRanges  []FacetValue `json:"class_ranges"`
+	BBK          []FacetValue `json:"bbk"`
+	Created      []FacetValue `json:"created"`
+}
+
+// BookPatch — изменения для группы книг. Заданы только изменяемые поля;
+// нулевой author_id или publisher_id очищает ссылку
+type BookPatch struct {
+	LocationID  *int    `json:"location_id"`
+	ClassRange  *string `json:"class_range"`
+	AuthorID    *int    `json:"author_id"`
+	PublisherID *int    `json:"publisher_id"`
+	BBK         *string `json:"bbk"`
+	UDK         *string `json:"udk"`
+}
+
+// BookBulkRequest — групповое изменение книг, выбранных по списку ID и/или фильтру
+type BookBulkRequest struct {
+	IDs     []int       `json:"ids"`
+	Filter  *BookFilter `json:"filter"`
+	Patch   BookPatch   `json:"patch"`
+	Preview bool        `json:"preview"`
+}
+
+// ReaderFilter — параметры выборки читателей
+type ReaderFilter struct {
+	Search   string `json:"search"`
+	ClassID  int    `json:"class_id"`
+	Grade    int    `json:"grade"`
+	UserType string `json:"user_type"`
+	Status   string `json:"status"` // active (по умолчанию), archived, all
+}
+
+// ReaderPatch — изменения для группы читателей. Нулевой class_id очищает класс
+type ReaderPatch struct {
+	ClassID  *int    `json:"class_id"`
+	UserType *string `json:"user_type"`
+}
+
+// ReaderBulkRequest — групповое изменение читателей, выбранных по списку ID и/или фильтру
+type ReaderBulkRequest struct {
+	IDs     []int         `json:"ids"`
+	Filter  *ReaderFilter `json:"filter"`
+	Patch   ReaderPatch   `json:"patch"`
+	Preview bool          `json:"preview"`
+}
+
+// BulkResult — результат группового изменения или его предпросмотра
+type BulkResult struct {
+	Matched int      `json:"matched"`
+	Updated int      `json:"updated"`
+	Preview bool     `json:"preview"`
+	Sample  []string `json:"sample,omitempty"`
+}
+
+// ImportTemplate — сохраненное сопоставление полей записи и столбцов файла импорта
+type ImportTemplate struct {
+	ID         int               `json:"id"`
+	EntityType string            `json:"entity_type"`
+	Name       string            `json:"name"`
+	Mapping    map[string]string `json:"mapping"` // поле → заголовок столбца
+	CreatedBy  int               `json:"created_by"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// ImportField — поле, которое можно загрузить из файла
+type ImportField struct {
+	Field    string `json:"field"`
+	Header   string `json:"header"` // заголовок столбца по умолчанию
+	Required bool   `json:"required"`
+}
+
+// ImportError — ошибка в строке файла импорта
+type ImportError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// ImportResult — итог импорта. При проверке (dry_run) данные не сохраняются
+type ImportResult struct {
+	Success int           `json:"success"`
+	Failed  int           `json:"failed"`
+	Errors  []ImportError `json:"errors"`
+	Created int           `json:"created"`
+	Updated int           `json:"updated"`
+	DryRun  bool          `json:"dry_run"`
+}
+
+// PhotoImportError — ошибка импорта одного файла из архива фотографий
+type PhotoImportError struct {
+	File  string `json:"file"`
+	Error string `json:"error"`
+}
+
+// PhotoImportResult — итог импорта фотографий читателей. При проверке (dry_run) фотографии не сохраняются
+type PhotoImportResult struct {
+	Success int                `json:"success"`
+	Failed  int                `json:"failed"`
+	Errors  []PhotoImportError `json:"errors"`
+	DryRun  bool               `json:"dry_run"`
+}
+
+// BookAvailabilityReport представляет отчет о наличии книг
+type BookAvailabilityReport struct {
+	GroupBy string                `json:"group_by"` // classification, location
+	Scheme  string                `json:"scheme"`
+	Level   int                   `json:"level"`
+	Rows    []BookAvailabilityRow `json:"rows"`
+	Totals  BookAvailabilityRow   `json:"totals"`
+}
+
+// InventoryItem представляет книгу в инвентаризационной описи
+type InventoryItem struct {
+	ID          int    `json:"id"`
+	Code        string `json:"code"`
+	Title       string `json:"title"`
+	Author      string `json:"author"`
+	Barcode     string `json:"barcode"`
+	IsAvailable bool   `json:"is_available"`
+}
+
+// InventoryShelf представляет опись одного места хранения
+type InventoryShelf struct {
+	Location  *Location       `json:"location"` // nil — книги без места хранения
+	Books     []InventoryItem `json:"books"`
+	Total     int             `json:"total"`
+	Available int             `json:"available"`
+}
+
+// InventoryReport представляет инвентаризационную опись по местам хранения
+type InventoryReport struct {
+	Location  *Location        `json:"location"`
+	Shelves   []InventoryShelf `json:"shelves"`
+	Total     int              `json:"total"`
+	Available int              `json:"available"`
+}
+
+// BarcodeRange представляет диапазон штрих-кодов для одного типа объектов.
+// Штрих-код — префикс, номер с ведущими нулями до Digits знаков и контрольная цифра
+type BarcodeRange struct {
+	ID          int       `json:"id"`
+	EntityType  string    `json:"entity_type"` // book, reader, disk
+	Prefix      string    `json:"prefix"`
+	StartNumber int       `json:"start_number"`
+	EndNumber   int       `json:"end_number"`
+	Digits      int       `json:"digits"`
+	CheckDigit  string    `json:"check_digit"` // "", luhn, ean13
+	NextNumber  int       `json:"next_number"`
+	Comments    string    `json:"comments"`
+	CreatedBy   int       `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	Remaining   int       `json:"remaining"`
+	Reserved    int       `json:"reserved"`
+	Bound       int       `json:"bound"`
+	Sample      string    `json:"sample"`
+}
+
+// BarcodeBatch представляет партию заранее напечатанных штрих-кодов
+type BarcodeBatch struct {
+	ID           int                 `json:"id"`
+	RangeID      int                 `json:"range_id"`
+	EntityType   string              `json:"entity_type"`
+	FirstBarcode string              `json:"first_barcode"`
+	LastBarcode  string              `json:"last_barcode"`
+	Count        int                 `json:"count"`
+	Reserved     int                 `json:"reserved"`
+	Bound        int                 `json:"bound"`
+	Void         int                 `json:"void"`
+	CreatedBy    int                 `json:"created_by"`
+	CreatedAt    time.Time           `json:"created_at"`
+	Barcodes     []BarcodeAllocation `json:"barcodes,omitempty"`
+}
+
+// BarcodeAllocation представляет выделенный штрих-код
+type BarcodeAllocation struct {
+	ID         int        `json:"id"`
+	RangeID    int        `json:"range_id"`
+	BatchID    *int       `json:"batch_id"`
+	Barcode    string     `json:"barcode"`
+	EntityType string     `json:"entity_type"`
+	Status     string     `json:"status"` // reserved, bound, void
+	EntityID   *int       `json:"entity_id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	BoundAt    *time.Time `json:"bound_at"`
+}
+
+// BarcodeBatchRequest представляет запрос на выделение партии штрих-кодов
+type BarcodeBatchRequest struct {
+	Count int `json:"count"`
+}
+
+// BarcodeOwner представляет запись, которой принадлежит штрих-код
+type BarcodeOwner struct {
+	EntityType string `json:"entity_type"` // book, reader, disk, location
+	EntityID   int    `json:"entity_id"`
+	Title      string `json:"title"`
+}
+
+// BarcodeLookup представляет результат поиска по отсканированному штрих-коду
+type BarcodeLookup struct {
+	Barcode    string             `json:"barcode"`
+	Owners     []BarcodeOwner     `json:"owners"`
+	Allocation *BarcodeAllocation `json:"allocation,omitempty"`
+}
+
+// Sequence — формат автоматически присваиваемых кодов для одного типа записей
+type Sequence struct {
+	EntityType  string    `json:"entity_type"`
+	Prefix      string    `json:"prefix"`
+	Padding     int       `json:"padding"`
+	YearlyReset bool      `json:"yearly_reset"`
+	Separator   string    `json:"separator"`
+	LastNumber  int       `json:"last_number"`
+	LastYear    int       `json:"last_year"`
+	NextNumber  int       `json:"next_number"`
+	Sample      string    `json:"sample"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// LabelRequest представляет запрос на печать этикеток.
+// Записи выбираются списком ID, диапазоном штрих-кодов или фильтром (в этом порядке приоритета)
+type LabelRequest struct {
+	Entity      string `json:"entity"` // book, reader, disk
+	IDs         []int  `json:"ids"`
+	BarcodeFrom string `json:"barcode_from"`
+	BarcodeTo   string `json:"barcode_to"`
+	Search      string `json:"search"`
+	LocationID  int    `json:"location_id"` // только для книг
+	ClassID     int    `json:"class_id"`    // только для читателей
+
+	Template      string   `json:"template"` // a4-24, a4-40, a4-65
+	MarginTop     *float64 `json:"margin_top"`
+	MarginLeft    *float64 `json:"margin_left"`
+	GapX          *float64 `json:"gap_x"`
+	GapY          *float64 `json:"gap_y"`
+	Symbology     string   `json:"symbology"`      // code128, ean13, qr
+	StartPosition int      `json:"start_position"` // первая свободная этикетка на листе, с 1
+	Outline       bool     `json:"outline"`
+}
+
+// LabelItem представляет запись, для которой печатается этикетка
+type LabelItem struct {
+	ID       int    `json:"id"`
+	Barcode  string `json:"barcode"`
+	Title    string `json:"title"`
+	Subtitle string `json:"subtitle"`
+}
+
 // Pagination представляет параметры пагинации
 type Pagination struct {
 	Page     int `json:"page"`
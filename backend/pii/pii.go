@@ -0,0 +1,199 @@
+package pii
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// Prefix отличает зашифрованные значения от открытых, оставшихся в старых базах.
+// Открытое значение с таким началом принять нельзя: при чтении его примут за шифротекст
+const Prefix = "enc:"
+
+var (
+	aead    cipher.AEAD
+	hashKey []byte
+)
+
+var (
+	// ErrNoKey возвращается при чтении зашифрованного значения без настроенного ключа
+	ErrNoKey = errors.New("pii: encryption key is not configured")
+	// ErrDecrypt возвращается, если значение повреждено или зашифровано другим ключом
+	ErrDecrypt = errors.New("pii: cannot decrypt value, wrong key or corrupted data")
+)
+
+// Init задает ключ шифрования персональных данных: 32 байта в base64 или hex.
+// Пустой ключ отключает шифрование
+func Init(key string) error {
+	aead, hashKey = nil, nil
+	if key == "" {
+		return nil
+	}
+
+	raw, err := decodeKey(key)
+	if err != nil {
+		return err
+	}
+
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return err
+	}
+	aead, err = cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	// Ключ поискового хэша выводится из основного, чтобы не хранить второй ключ
+	mac := hmac.New(sha256.New, raw)
+	mac.Write([]byte("reader search"))
+	hashKey = mac.Sum(nil)
+	return nil
+}
+
+// decodeKey разбирает ключ из base64 или hex
+func decodeKey(key string) ([]byte, error) {
+	key = strings.TrimSpace(key)
+	if raw, err := hex.DecodeString(key); err == nil && len(raw) == 32 {
+		return raw, nil
+	}
+	if raw, err := base64.StdEncoding.DecodeString(key); err == nil && len(raw) == 32 {
+		return raw, nil
+	}
+	return nil, errors.New("pii: key must be 32 bytes encoded in base64 or hex")
+}
+
+// Enabled сообщает, настроено ли шифрование
+func Enabled() bool {
+	return aead != nil
+}
+
+// IsEncrypted проверяет, что значение зашифровано
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// Encrypt шифрует значение. Пустые значения, значения, уже зашифрованные текущим ключом,
+// а также все значения при отключенном шифровании возвращаются как есть. Открытый текст,
+// лишь начинающийся с Prefix, шифруется как обычный
+func Encrypt(value string) (string, error) {
+	if value == "" || aead == nil {
+		return value, nil
+	}
+	if IsEncrypted(value) {
+		if _, err := Decrypt(value); err == nil {
+			return value, nil
+		}
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), nil)
+	return Prefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt расшифровывает значение; открытые значения возвращаются как есть
+func Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if aead == nil {
+		return "", ErrNoKey
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(value, Prefix))
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrDecrypt
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	return string(plain), nil
+}
+
+// PhoneDigits приводит номер телефона к 10 цифрам без кода страны: +7 (912) 345-67-89 → 9123456789
+func PhoneDigits(phone string) string {
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, phone)
+	if len(digits) == 11 && (digits[0] == '7' || digits[0] == '8') {
+		digits = digits[1:]
+	}
+	return digits
+}
+
+// PhoneHash возвращает поисковый хэш телефона, по которому зашифрованный номер
+// находится точным совпадением. Без ключа и для коротких номеров хэш пустой
+func PhoneHash(phone string) string {
+	digits := PhoneDigits(phone)
+	if hashKey == nil || len(digits) < 5 {
+		return ""
+	}
+	mac := hmac.New(sha256.New, hashKey)
+	mac.Write([]byte(digits))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NormalizeEmail приводит адрес к виду, в котором он сравнивается: без пробелов и в нижнем регистре
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// EmailHash возвращает поисковый хэш e-mail для входа читателя по коду из письма.
+// Без ключа и для пустого адреса хэш пустой
+func EmailHash(email string) string {
+	email = NormalizeEmail(email)
+	if hashKey == nil || email == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, hashKey)
+	mac.Write([]byte("email:" + email))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// MaskPhone оставляет от телефона две последние цифры: ***67
+func MaskPhone(phone string) string {
+	if phone == "" {
+		return ""
+	}
+	digits := PhoneDigits(phone)
+	if len(digits) <= 2 {
+		return "***"
+	}
+	return "***" + digits[len(digits)-2:]
+}
+
+// MaskEmail оставляет первую букву имени и домен: i***@mail.ru
+func MaskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return MaskDocument(email)
+	}
+	return string([]rune(email)[0]) + "***" + email[at:]
+}
+
+// MaskDocument оставляет два последних символа номера документа: ***45
+func MaskDocument(number string) string {
+	runes := []rune(number)
+	if len(runes) == 0 {
+		return ""
+	}
+	if len(runes) <= 4 {
+		return "***"
+	}
+	return "***" + string(runes[len(runes)-2:])
+}
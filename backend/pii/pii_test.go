@@ -0,0 +1,201 @@
+package pii
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+)
+
+var (
+	testKey  = base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	otherKey = hex.EncodeToString([]byte("fedcba9876543210fedcba9876543210"))
+)
+
+// withKey включает шифрование ключом key и отключает его после теста
+func withKey(t *testing.T, key string) {
+	t.Helper()
+	if err := Init(key); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	t.Cleanup(func() { Init("") })
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		enabled bool
+		wantErr bool
+	}{
+		{"empty", "", false, false},
+		{"base64", testKey, true, false},
+		{"hex", otherKey, true, false},
+		{"spaces around", " " + testKey + "\n", true, false},
+		{"short", base64.StdEncoding.EncodeToString([]byte("short")), false, true},
+		{"not encoded", "not a key", false, true},
+	}
+	for _, tt := range tests {
+		err := Init(tt.key)
+		if (err != nil) != tt.wantErr || (err == nil && Enabled() != tt.enabled) {
+			t.Errorf("%s: Init error = %v, enabled = %v; want error %v, enabled %v", tt.name, err, Enabled(), tt.wantErr, tt.enabled)
+		}
+	}
+	Init("")
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	withKey(t, testKey)
+
+	for _, value := range []string{"г. Москва, ул. Ленина, 1", "+7 912 345-67-89", "enc:", "enc:looks like ciphertext", "x"} {
+		sealed, err := Encrypt(value)
+		if err != nil {
+			t.Fatalf("Encrypt(%q): %v", value, err)
+		}
+		if sealed == value || !IsEncrypted(sealed) {
+			t.Errorf("Encrypt(%q) = %q, want ciphertext", value, sealed)
+		}
+		plain, err := Decrypt(sealed)
+		if err != nil || plain != value {
+			t.Errorf("Decrypt(Encrypt(%q)) = %q, %v", value, plain, err)
+		}
+	}
+}
+
+func TestEncryptPrefix(t *testing.T) {
+	withKey(t, testKey)
+
+	sealed, err := Encrypt("12 34 567890")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Значение, уже зашифрованное текущим ключом, повторно не шифруется
+	if again, err := Encrypt(sealed); err != nil || again != sealed {
+		t.Errorf("Encrypt of own ciphertext = %q, %v; want unchanged", again, err)
+	}
+
+	// Шифротекст другого ключа для текущего — просто текст с префиксом: он шифруется
+	Init(otherKey)
+	foreign, err := Encrypt("12 34 567890")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Init(testKey)
+	resealed, err := Encrypt(foreign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resealed == foreign {
+		t.Error("ciphertext of another key stored unchanged")
+	}
+	if plain, err := Decrypt(resealed); err != nil || plain != foreign {
+		t.Errorf("Decrypt = %q, %v; want %q", plain, err, foreign)
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	withKey(t, testKey)
+	sealed, err := Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		key   string
+		value string
+		want  error
+	}{
+		{"plain value", testKey, "plain", nil},
+		{"wrong key", otherKey, sealed, ErrDecrypt},
+		{"no key", "", sealed, ErrNoKey},
+		{"not base64", testKey, Prefix + "***", ErrDecrypt},
+		{"too short", testKey, Prefix + "AAAA", ErrDecrypt},
+		{"tampered", testKey, sealed[:len(sealed)-2] + "AA", ErrDecrypt},
+	}
+	for _, tt := range tests {
+		Init(tt.key)
+		if _, err := Decrypt(tt.value); err != tt.want {
+			t.Errorf("%s: Decrypt error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestEncryptDisabled(t *testing.T) {
+	Init("")
+	for _, value := range []string{"", "plain", "enc:abc"} {
+		if got, err := Encrypt(value); err != nil || got != value {
+			t.Errorf("Encrypt(%q) without key = %q, %v; want unchanged", value, got, err)
+		}
+	}
+	if PhoneHash("+7 912 345-67-89") != "" || EmailHash("a@b.ru") != "" {
+		t.Error("search hashes must be empty without a key")
+	}
+}
+
+func TestPhoneHash(t *testing.T) {
+	withKey(t, testKey)
+
+	hash := PhoneHash("+7 (912) 345-67-89")
+	if hash == "" {
+		t.Fatal("empty hash")
+	}
+	for _, phone := range []string{"89123456789", "9123456789", "7-912-345-67-89"} {
+		if PhoneHash(phone) != hash {
+			t.Errorf("PhoneHash(%q) differs from the same number in another format", phone)
+		}
+	}
+	if PhoneHash("+7 912 345-67-88") == hash {
+		t.Error("different numbers have the same hash")
+	}
+	if PhoneHash("1234") != "" {
+		t.Error("short numbers must not be hashed")
+	}
+
+	Init(otherKey)
+	if PhoneHash("+7 (912) 345-67-89") == hash {
+		t.Error("hash does not depend on the key")
+	}
+}
+
+func TestEmailHash(t *testing.T) {
+	withKey(t, testKey)
+
+	hash := EmailHash("Reader@Mail.ru")
+	if hash == "" || EmailHash("  reader@mail.ru ") != hash {
+		t.Error("e-mail hash must ignore case and spaces")
+	}
+	if EmailHash("other@mail.ru") == hash {
+		t.Error("different addresses have the same hash")
+	}
+	if EmailHash(" ") != "" {
+		t.Error("empty address must not be hashed")
+	}
+	// Телефон и e-mail хэшируются в разных пространствах
+	if EmailHash("9123456789") == PhoneHash("9123456789") {
+		t.Error("e-mail and phone hashes collide")
+	}
+}
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		name string
+		mask func(string) string
+		in   string
+		want string
+	}{
+		{"phone", MaskPhone, "+7 (912) 345-67-89", "***89"},
+		{"empty phone", MaskPhone, "", ""},
+		{"short phone", MaskPhone, "12", "***"},
+		{"email", MaskEmail, "ivanov@mail.ru", "i***@mail.ru"},
+		{"cyrillic email", MaskEmail, "иванов@почта.рф", "и***@почта.рф"},
+		{"email without at", MaskEmail, "ivanov", "***ov"},
+		{"document", MaskDocument, "4510 123456", "***56"},
+		{"short document", MaskDocument, "1234", "***"},
+		{"empty document", MaskDocument, "", ""},
+	}
+	for _, tt := range tests {
+		if got := tt.mask(tt.in); got != tt.want {
+			t.Errorf("%s: mask(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package rbac
+
+import (
+	"sort"
+	"strings"
+)
+
+// Права сотрудников. Маршрут в main.go объявляет нужное право, роль дает набор прав
+const (
+	// BooksRead — просмотр каталога: книги, диски, авторы, места хранения, справочники
+	BooksRead = "books.read"
+	// BooksWrite — изменение каталога, обложки, этикетки и штрих-коды
+	BooksWrite = "books.write"
+	// ReadersRead — просмотр читателей (персональные данные скрыты)
+	ReadersRead = "readers.read"
+	// ReadersWrite — запись и изменение читателей, архив, фотографии, PIN, обходные листы
+	ReadersWrite = "readers.write"
+	// ReadersDelete — удаление и объединение читателей
+	ReadersDelete = "readers.delete"
+	// ReadersPIIRead — полные персональные данные читателей; роли задаются PII_ROLES
+	ReadersPIIRead = "readers.pii.read"
+	// LoansRead — просмотр выдач и броней
+	LoansRead = "loans.read"
+	// LoansIssue — выдача и возврат, бронирование
+	LoansIssue = "loans.issue"
+	// LoansOverride — выдача книги в обход очереди броней, списание долгов при выбытии
+	LoansOverride = "loans.override"
+	// ReportsRead — отчеты
+	ReportsRead = "reports.read"
+	// ImportRun — импорт книг и читателей и шаблоны импорта
+	ImportRun = "import.run"
+	// SettingsRead — просмотр настроек, нумерации и классов
+	SettingsRead = "settings.read"
+	// SettingsManage — изменение настроек, нумерации, классов, перевод на новый учебный год
+	SettingsManage = "settings.manage"
+	// StaffManage — сотрудники и их сессии
+	StaffManage = "staff.manage"
+	// AuditRead — журнал операций
+	AuditRead = "audit.read"
+)
+
+// rolePermissions — права ролей. Право на персональные данные сюда не входит:
+// оно выдается ролям из PII_ROLES
+var rolePermissions = map[string][]string{
+	"admin": {
+		BooksRead, BooksWrite,
+		ReadersRead, ReadersWrite, ReadersDelete,
+		LoansRead, LoansIssue, LoansOverride,
+		ReportsRead, ImportRun,
+		SettingsRead, SettingsManage,
+		StaffManage, AuditRead,
+	},
+	"librarian": {
+		BooksRead, BooksWrite,
+		ReadersRead, ReadersWrite,
+		LoansRead, LoansIssue,
+		ReportsRead, ImportRun,
+		SettingsRead,
+	},
+	"teacher": {
+		BooksRead,
+		ReadersRead,
+		LoansRead,
+		ReportsRead,
+	},
+}
+
+// grants — права по ролям для быстрой проверки
+var grants = build(rolePermissions)
+
+func build(roles map[string][]string) map[string]map[string]bool {
+	result := map[string]map[string]bool{}
+	for role, permissions := range roles {
+		result[role] = map[string]bool{}
+		for _, permission := range permissions {
+			result[role][permission] = true
+		}
+	}
+	return result
+}
+
+// SetPersonalDataRoles выдает право на персональные данные ролям из списка через запятую
+// и отбирает его у остальных
+func SetPersonalDataRoles(roles string) {
+	for _, permissions := range grants {
+		delete(permissions, ReadersPIIRead)
+	}
+	for _, role := range strings.Split(roles, ",") {
+		if role = strings.TrimSpace(role); role == "" {
+			continue
+		}
+		if grants[role] == nil {
+			grants[role] = map[string]bool{}
+		}
+		grants[role][ReadersPIIRead] = true
+	}
+}
+
+// Has проверяет, есть ли у роли право. У неизвестной роли прав нет
+func Has(role, permission string) bool {
+	return grants[role][permission]
+}
+
+// Permissions возвращает права роли по алфавиту
+func Permissions(role string) []string {
+	permissions := []string{}
+	for permission := range grants[role] {
+		permissions = append(permissions, permission)
+	}
+	sort.Strings(permissions)
+	return permissions
+}
+
+// Roles возвращает известные роли по алфавиту
+func Roles() []string {
+	roles := make([]string, 0, len(rolePermissions))
+	for role := range rolePermissions {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
@@ -0,0 +1,83 @@
+// Package spreadsheet читает таблицы CSV и XLSX для импорта данных.
+// Результат — строки листа в том же порядке, что и в файле: индекс строки + 1
+// равен номеру строки, который видит пользователь в Excel
+package spreadsheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+var (
+	// ErrUnsupportedFormat — формат файла не поддерживается
+	ErrUnsupportedFormat = errors.New("only .xlsx and .csv files are supported; save .xls files as .xlsx")
+	// ErrTooManyRows — в файле больше строк, чем разрешено
+	ErrTooManyRows = errors.New("too many rows in file")
+)
+
+// Read читает первый лист файла. Формат определяется по расширению имени файла.
+// Строки с номером больше maxRows не читаются — ErrTooManyRows
+func Read(filename string, data []byte, maxRows int) ([][]string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv", ".txt":
+		return readCSV(data, maxRows)
+	case ".xlsx":
+		return readXLSX(data, maxRows)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+// readCSV читает CSV в UTF-8 (с BOM или без) или Windows-1251.
+// Разделитель — точка с запятой, запятая или табуляция, определяется по первой строке
+func readCSV(data []byte, maxRows int) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+	if !utf8.Valid(data) {
+		// Excel в русской локали сохраняет CSV в Windows-1251
+		decoded, err := charmap.Windows1251.NewDecoder().Bytes(data)
+		if err != nil {
+			return nil, err
+		}
+		data = decoded
+	}
+
+	firstLine := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		firstLine = data[:i]
+	}
+	delimiter := ';'
+	best := bytes.Count(firstLine, []byte(";"))
+	for _, d := range []rune{',', '\t'} {
+		if n := bytes.Count(firstLine, []byte(string(d))); n > best {
+			delimiter, best = d, n
+		}
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = delimiter
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	var rows [][]string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) >= maxRows {
+			return nil, ErrTooManyRows
+		}
+		rows = append(rows, record)
+	}
+	return rows, nil
+}
@@ -0,0 +1,308 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxXMLSize — наибольший размер распакованного файла внутри книги. Сжатие XML
+	// бывает стократным, поэтому размер загруженного файла ничего не говорит о распакованном
+	maxXMLSize = 64 << 20
+	// maxColumns — число столбцов листа Excel
+	maxColumns = 16384
+)
+
+// xlsxWorkbook — список листов книги
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+// xlsxRelationships — связи книги с файлами листов
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText — текст строки: простой (<t>) или форматированный (<r><t>)
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.T)
+	}
+	return b.String()
+}
+
+// xlsxSharedStrings — общая таблица строк
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+// xlsxStyles — форматы ячеек; нужны, чтобы отличить даты от чисел
+type xlsxStyles struct {
+	NumFmts []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfs []struct {
+		NumFmtID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+// xlsxSheet — данные листа
+type xlsxSheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R         string   `xml:"r,attr"`
+			T         string   `xml:"t,attr"`
+			S         int      `xml:"s,attr"`
+			V         string   `xml:"v"`
+			InlineStr xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX читает первый лист книги Excel
+func readXLSX(data []byte, maxRows int) ([][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, errors.New("file is not an Excel workbook (.xlsx)")
+	}
+	files := map[string]*zip.File{}
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+
+	var workbook xlsxWorkbook
+	if err := decodeXML(files, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	if len(workbook.Sheets) == 0 {
+		return nil, errors.New("workbook has no sheets")
+	}
+
+	var rels xlsxRelationships
+	if err := decodeXML(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	sheetPath := ""
+	for _, rel := range rels.Relationships {
+		if rel.ID == workbook.Sheets[0].RID {
+			sheetPath = rel.Target
+		}
+	}
+	if sheetPath == "" {
+		return nil, errors.New("first sheet of the workbook not found")
+	}
+	if strings.HasPrefix(sheetPath, "/") {
+		sheetPath = strings.TrimPrefix(sheetPath, "/")
+	} else {
+		sheetPath = path.Join("xl", sheetPath)
+	}
+
+	var shared xlsxSharedStrings
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeXML(files, "xl/sharedStrings.xml", &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	var styles xlsxStyles
+	if _, ok := files["xl/styles.xml"]; ok {
+		if err := decodeXML(files, "xl/styles.xml", &styles); err != nil {
+			return nil, err
+		}
+	}
+	dateStyles := dateStyleIndexes(&styles)
+
+	var sheet xlsxSheet
+	if err := decodeXML(files, sheetPath, &sheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for i, row := range sheet.Rows {
+		rowNumber := row.R
+		if rowNumber == 0 {
+			rowNumber = i + 1
+		}
+		// Номер строки берется из файла: без проверки одна строка с r="2000000000"
+		// заставила бы выделить память под миллиарды пустых строк
+		if rowNumber < 0 {
+			return nil, fmt.Errorf("invalid row number %d", rowNumber)
+		}
+		if rowNumber > maxRows {
+			return nil, ErrTooManyRows
+		}
+		for len(rows) < rowNumber {
+			rows = append(rows, nil)
+		}
+
+		var values []string
+		for j, cell := range row.Cells {
+			col := j
+			if cell.R != "" {
+				if col, err = columnIndex(cell.R); err != nil {
+					return nil, err
+				}
+			}
+			if col >= maxColumns {
+				return nil, fmt.Errorf("row %d: too many columns", rowNumber)
+			}
+			for len(values) <= col {
+				values = append(values, "")
+			}
+
+			switch cell.T {
+			case "s":
+				n, err := strconv.Atoi(cell.V)
+				if err != nil || n < 0 || n >= len(shared.Items) {
+					return nil, fmt.Errorf("cell %s: invalid shared string reference", cell.R)
+				}
+				values[col] = shared.Items[n].String()
+			case "inlineStr":
+				values[col] = cell.InlineStr.String()
+			case "b":
+				values[col] = map[string]string{"1": "true", "0": "false"}[cell.V]
+			case "", "n":
+				values[col] = formatNumber(cell.V, dateStyles[cell.S])
+			default:
+				values[col] = cell.V
+			}
+		}
+		rows[rowNumber-1] = values
+	}
+	return rows, nil
+}
+
+// decodeXML разбирает XML-файл из архива не больше maxXMLSize в распакованном виде
+func decodeXML(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("workbook has no file %s", name)
+	}
+	tooLarge := fmt.Errorf("file %s in the workbook is larger than %d MB", name, maxXMLSize>>20)
+	if f.UncompressedSize64 > maxXMLSize {
+		return tooLarge
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	// Размер в заголовке архива может не совпадать с содержимым — ограничиваем чтение
+	data, err := io.ReadAll(io.LimitReader(rc, maxXMLSize+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > maxXMLSize {
+		return tooLarge
+	}
+	return xml.Unmarshal(data, v)
+}
+
+// columnIndex возвращает номер столбца (с нуля) по адресу ячейки вида "AB12"
+func columnIndex(ref string) (int, error) {
+	col := 0
+	for i, r := range ref {
+		if r >= 'A' && r <= 'Z' {
+			col = col*26 + int(r-'A') + 1
+			if col > maxColumns {
+				return 0, fmt.Errorf("cell %s: column is out of range", ref)
+			}
+			continue
+		}
+		if i == 0 {
+			break
+		}
+		return col - 1, nil
+	}
+	return 0, fmt.Errorf("invalid cell reference %q", ref)
+}
+
+// dateStyleIndexes возвращает номера стилей ячеек с форматом даты
+func dateStyleIndexes(styles *xlsxStyles) map[int]bool {
+	custom := map[int]string{}
+	for _, f := range styles.NumFmts {
+		custom[f.ID] = f.Code
+	}
+
+	result := map[int]bool{}
+	for i, xf := range styles.CellXfs {
+		id := xf.NumFmtID
+		// Встроенные форматы дат: 14–22 и 45–47
+		if (id >= 14 && id <= 22) || (id >= 45 && id <= 47) {
+			result[i] = true
+			continue
+		}
+		if code, ok := custom[id]; ok && isDateFormat(code) {
+			result[i] = true
+		}
+	}
+	return result
+}
+
+// isDateFormat проверяет, содержит ли пользовательский формат дни, месяцы или годы
+func isDateFormat(code string) bool {
+	inQuotes := false
+	for i := 0; i < len(code); i++ {
+		switch c := code[i]; {
+		case c == '"':
+			inQuotes = !inQuotes
+		case c == '\\' || c == '_':
+			i++
+		case c == '[':
+			// Цвета и условия: [Red], [<100]
+			for i < len(code) && code[i] != ']' {
+				i++
+			}
+		case !inQuotes && strings.ContainsRune("dDyY", rune(c)):
+			return true
+		}
+	}
+	return false
+}
+
+// formatNumber выводит число без экспоненты, а дату — в формате YYYY-MM-DD
+func formatNumber(v string, isDate bool) string {
+	if v == "" {
+		return ""
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return v
+	}
+	if isDate {
+		// Отсчет дат Excel — 30.12.1899 (с учетом ошибки 1900 года)
+		days := math.Floor(f)
+		date := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).AddDate(0, 0, int(days))
+		return date.Format("2006-01-02")
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
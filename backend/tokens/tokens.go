@@ -0,0 +1,249 @@
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Audience — для кого выпущен токен: API сотрудников или личный кабинет читателя
+type Audience int
+
+const (
+	Staff Audience = iota
+	Portal
+)
+
+// Config — ключи подписи и обязательные claims токенов
+type Config struct {
+	// Secret — общий ключ HS256 (JWT_SECRET), если Keys не заданы
+	Secret string
+	// Keys — ключи через запятую в виде kid:алгоритм:источник. Для HS256 источник —
+	// сам ключ, для EdDSA и RS256 — путь к PEM-файлу. Файл с открытым ключом
+	// годится только для проверки: так оставляют старый ключ на время смены
+	Keys string
+	// SigningKey — kid ключа, которым подписываются новые токены; пусто — первый ключ с закрытой частью
+	SigningKey     string
+	Issuer         string
+	Audience       string
+	PortalAudience string
+}
+
+// key — ключ подписи или проверки
+type key struct {
+	id     string
+	method jwt.SigningMethod
+	// private — ключ подписи; nil у ключей, оставленных только для проверки
+	private interface{}
+	public  interface{}
+}
+
+// insecureSecret — ключ, который раньше был зашит в код: с ним токен подделает кто угодно
+const insecureSecret = "your-secret-key-change-this-in-production"
+
+// minSecretLength — рекомендуемая длина ключа HS256 в байтах
+const minSecretLength = 32
+
+// leeway — допустимое расхождение часов при проверке exp, nbf и iat
+const leeway = 30 * time.Second
+
+var (
+	keys      map[string]*key
+	signing   *key
+	issuer    string
+	audiences [2]string
+	// Warnings — замечания по настройке ключей для журнала при запуске
+	Warnings []string
+)
+
+// ErrInvalidToken — токен не прошел проверку подписи или claims
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// Init загружает ключи. Без JWT_KEYS и JWT_SECRET создается случайный ключ:
+// подделать токен нельзя, но выданные токены перестают действовать после перезапуска
+func Init(cfg Config) error {
+	keys, signing, Warnings = map[string]*key{}, nil, nil
+	issuer = cfg.Issuer
+	audiences = [2]string{cfg.Audience, cfg.PortalAudience}
+	if issuer == "" || cfg.Audience == "" || cfg.PortalAudience == "" {
+		return errors.New("tokens: issuer and audiences are required")
+	}
+	if cfg.Audience == cfg.PortalAudience {
+		return errors.New("tokens: staff and portal audiences must differ")
+	}
+
+	var loaded []*key
+	switch {
+	case strings.TrimSpace(cfg.Keys) != "":
+		for _, entry := range strings.Split(cfg.Keys, ",") {
+			k, err := parseKey(strings.TrimSpace(entry))
+			if err != nil {
+				return err
+			}
+			if keys[k.id] != nil {
+				return fmt.Errorf("tokens: duplicate key id %q", k.id)
+			}
+			keys[k.id] = k
+			loaded = append(loaded, k)
+		}
+	case cfg.Secret != "" && cfg.Secret != insecureSecret:
+		k := hmacKey("default", cfg.Secret)
+		keys[k.id] = k
+		loaded = append(loaded, k)
+	default:
+		if cfg.Secret == insecureSecret {
+			Warnings = append(Warnings, "JWT_SECRET has the well-known default value and is ignored")
+		}
+		Warnings = append(Warnings, "JWT_SECRET is not set: using a random signing key, access tokens expire on restart")
+		secret := make([]byte, minSecretLength)
+		if _, err := rand.Read(secret); err != nil {
+			return err
+		}
+		k := &key{id: "ephemeral", method: jwt.SigningMethodHS256, private: secret, public: secret}
+		keys[k.id] = k
+		loaded = append(loaded, k)
+	}
+
+	if cfg.SigningKey != "" {
+		signing = keys[cfg.SigningKey]
+		if signing == nil {
+			return fmt.Errorf("tokens: signing key %q is not configured", cfg.SigningKey)
+		}
+		if signing.private == nil {
+			return fmt.Errorf("tokens: signing key %q has no private key", cfg.SigningKey)
+		}
+		return nil
+	}
+	for _, k := range loaded {
+		if k.private != nil {
+			signing = k
+			return nil
+		}
+	}
+	return errors.New("tokens: no key can sign tokens")
+}
+
+// parseKey разбирает запись kid:алгоритм:источник. Источник может содержать двоеточия (пути Windows)
+func parseKey(entry string) (*key, error) {
+	parts := strings.SplitN(entry, ":", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return nil, fmt.Errorf("tokens: key %q must look like kid:algorithm:source", entry)
+	}
+	id, alg, source := parts[0], strings.ToUpper(parts[1]), parts[2]
+
+	switch alg {
+	case "HS256":
+		return hmacKey(id, source), nil
+	case "EDDSA", "RS256":
+		pem, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("tokens: key %q: %w", id, err)
+		}
+		k, err := pemKey(id, alg, pem)
+		if err != nil {
+			return nil, fmt.Errorf("tokens: key %q: %w", id, err)
+		}
+		return k, nil
+	default:
+		return nil, fmt.Errorf("tokens: key %q: unsupported algorithm %s (HS256, EdDSA or RS256)", id, parts[1])
+	}
+}
+
+// hmacKey создает ключ HS256; слишком короткий ключ допускается с предупреждением
+func hmacKey(id, secret string) *key {
+	if len(secret) < minSecretLength {
+		Warnings = append(Warnings, fmt.Sprintf("JWT key %q is shorter than %d bytes", id, minSecretLength))
+	}
+	return &key{id: id, method: jwt.SigningMethodHS256, private: []byte(secret), public: []byte(secret)}
+}
+
+// pemKey загружает закрытый ключ или, если это не он, открытый ключ только для проверки
+func pemKey(id, alg string, pem []byte) (*key, error) {
+	if alg == "EDDSA" {
+		k := &key{id: id, method: jwt.SigningMethodEdDSA}
+		if private, err := jwt.ParseEdPrivateKeyFromPEM(pem); err == nil {
+			k.private = private
+			k.public = private.(ed25519.PrivateKey).Public()
+			return k, nil
+		}
+		public, err := jwt.ParseEdPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, errors.New("not an Ed25519 key in PEM format")
+		}
+		k.public = public
+		return k, nil
+	}
+
+	k := &key{id: id, method: jwt.SigningMethodRS256}
+	var public *rsa.PublicKey
+	if private, err := jwt.ParseRSAPrivateKeyFromPEM(pem); err == nil {
+		k.private = private
+		public = &private.PublicKey
+	} else if public, err = jwt.ParseRSAPublicKeyFromPEM(pem); err != nil {
+		return nil, errors.New("not an RSA key in PEM format")
+	}
+	if public.N.BitLen() < 2048 {
+		return nil, errors.New("RSA key must be at least 2048 bits")
+	}
+	k.public = public
+	return k, nil
+}
+
+// Sign подписывает claims текущим ключом, добавляя kid, iss, aud, iat и exp
+func Sign(aud Audience, claims jwt.MapClaims, ttl time.Duration) (string, time.Time, error) {
+	if signing == nil {
+		return "", time.Time{}, errors.New("tokens: keys are not initialized")
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	claims["iss"] = issuer
+	claims["aud"] = audiences[aud]
+	claims["iat"] = now.Unix()
+	claims["exp"] = expiresAt.Unix()
+
+	token := jwt.NewWithClaims(signing.method, claims)
+	token.Header["kid"] = signing.id
+	signed, err := token.SignedString(signing.private)
+	return signed, expiresAt, err
+}
+
+// Parse проверяет подпись ключом из заголовка kid, алгоритм этого ключа,
+// издателя, получателя и срок действия. Токены без kid не принимаются
+func Parse(aud Audience, tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		id, _ := token.Header["kid"].(string)
+		k := keys[id]
+		if k == nil || token.Method.Alg() != k.method.Alg() {
+			return nil, ErrInvalidToken
+		}
+		return k.public, nil
+	},
+		jwt.WithValidMethods([]string{"HS256", "EdDSA", "RS256"}),
+		jwt.WithIssuer(issuer),
+		jwt.WithAudience(audiences[aud]),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(leeway),
+	)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// SigningKeyID возвращает kid ключа, которым подписываются новые токены
+func SigningKeyID() string {
+	if signing == nil {
+		return ""
+	}
+	return signing.id
+}
@@ -0,0 +1,244 @@
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testSecret    = "0123456789abcdef0123456789abcdef"
+	testNewSecret = "fedcba9876543210fedcba9876543210"
+)
+
+// testConfig возвращает настройки с обязательными издателем и получателями
+func testConfig(keys, signingKey string) Config {
+	return Config{
+		Keys:           keys,
+		SigningKey:     signingKey,
+		Issuer:         "library-test",
+		Audience:       "staff-test",
+		PortalAudience: "portal-test",
+	}
+}
+
+// writeEdKeys сохраняет закрытый и открытый ключ Ed25519 в PEM-файлы и возвращает их пути
+func writeEdKeys(t *testing.T) (string, string) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, "ed.pem")
+	publicPath := filepath.Join(dir, "ed.pub.pem")
+	if err := os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return privatePath, publicPath
+}
+
+// mustInit загружает ключи или прерывает тест
+func mustInit(t *testing.T, cfg Config) {
+	t.Helper()
+	if err := Init(cfg); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+}
+
+// signRaw подписывает произвольный токен в обход Sign: так собираются поддельные токены
+func signRaw(t *testing.T, method jwt.SigningMethod, kid string, claims jwt.MapClaims, key interface{}) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// validClaims возвращает claims, которые Parse принял бы для сотрудника
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss": "library-test",
+		"aud": "staff-test",
+		"iat": now.Unix(),
+		"exp": now.Add(time.Minute).Unix(),
+	}
+}
+
+func TestInit(t *testing.T) {
+	privatePath, publicPath := writeEdKeys(t)
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		signing string
+		wantErr bool
+	}{
+		{"secret", Config{Secret: testSecret, Issuer: "i", Audience: "a", PortalAudience: "p"}, "default", false},
+		{"random key without secret", Config{Issuer: "i", Audience: "a", PortalAudience: "p"}, "ephemeral", false},
+		{"well-known secret is ignored", Config{Secret: insecureSecret, Issuer: "i", Audience: "a", PortalAudience: "p"}, "ephemeral", false},
+		{"first key signs", testConfig("old:HS256:"+testSecret+",new:HS256:"+testNewSecret, ""), "old", false},
+		{"signing key selected", testConfig("old:HS256:"+testSecret+",new:HS256:"+testNewSecret, "new"), "new", false},
+		{"public key is skipped", testConfig("old:EdDSA:"+publicPath+",new:EdDSA:"+privatePath, ""), "new", false},
+		{"public key cannot sign", testConfig("old:EdDSA:"+publicPath, "old"), "", true},
+		{"only public keys", testConfig("old:EdDSA:"+publicPath, ""), "", true},
+		{"unknown signing key", testConfig("old:HS256:"+testSecret, "new"), "", true},
+		{"duplicate kid", testConfig("k:HS256:"+testSecret+",k:HS256:"+testNewSecret, ""), "", true},
+		{"unsupported algorithm", testConfig("k:HS512:"+testSecret, ""), "", true},
+		{"malformed entry", testConfig("k:"+testSecret, ""), "", true},
+		{"missing PEM file", testConfig("k:RS256:"+filepath.Join(t.TempDir(), "missing.pem"), ""), "", true},
+		{"wrong key type in PEM", testConfig("k:RS256:"+privatePath, ""), "", true},
+		{"same audiences", Config{Secret: testSecret, Issuer: "i", Audience: "a", PortalAudience: "a"}, "", true},
+		{"missing issuer", Config{Secret: testSecret, Audience: "a", PortalAudience: "p"}, "", true},
+	}
+	for _, tt := range tests {
+		err := Init(tt.cfg)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Init error = %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && SigningKeyID() != tt.signing {
+			t.Errorf("%s: signing key = %q, want %q", tt.name, SigningKeyID(), tt.signing)
+		}
+	}
+}
+
+func TestSignAndParse(t *testing.T) {
+	mustInit(t, testConfig("old:HS256:"+testSecret+",new:HS256:"+testNewSecret, "new"))
+
+	signed, _, err := Sign(Staff, jwt.MapClaims{"user_id": 7}, time.Minute)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	token, _, err := jwt.NewParser().ParseUnverified(signed, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid := token.Header["kid"]; kid != "new" {
+		t.Errorf("kid = %v, want new", kid)
+	}
+
+	claims, err := Parse(Staff, signed)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if claims["user_id"] != float64(7) || claims["iss"] != "library-test" || claims["aud"] != "staff-test" {
+		t.Errorf("unexpected claims: %v", claims)
+	}
+	if _, err := Parse(Portal, signed); err == nil {
+		t.Error("staff token accepted by the portal")
+	}
+
+	portal, _, err := Sign(Portal, jwt.MapClaims{"reader_id": 3}, time.Minute)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if _, err := Parse(Portal, portal); err != nil {
+		t.Errorf("portal token rejected: %v", err)
+	}
+	if _, err := Parse(Staff, portal); err == nil {
+		t.Error("portal token accepted by the staff API")
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	privatePath, publicPath := writeEdKeys(t)
+
+	mustInit(t, testConfig("old:EdDSA:"+privatePath, ""))
+	oldToken, _, err := Sign(Staff, jwt.MapClaims{}, time.Minute)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	// Старый ключ оставлен открытым ключом: выданные им токены действуют, новые подписываются новым
+	mustInit(t, testConfig("new:HS256:"+testNewSecret+",old:EdDSA:"+publicPath, ""))
+	if _, err := Parse(Staff, oldToken); err != nil {
+		t.Errorf("token of the retired key rejected: %v", err)
+	}
+	if SigningKeyID() != "new" {
+		t.Errorf("signing key = %q, want new", SigningKeyID())
+	}
+
+	// Старый ключ убран совсем: его токены больше не принимаются
+	mustInit(t, testConfig("new:HS256:"+testNewSecret, ""))
+	if _, err := Parse(Staff, oldToken); err == nil {
+		t.Error("token of a removed key accepted")
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	privatePath, publicPath := writeEdKeys(t)
+	mustInit(t, testConfig("hs:HS256:"+testSecret+",ed:EdDSA:"+privatePath, ""))
+	publicPEM, err := os.ReadFile(publicPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	with := func(change func(jwt.MapClaims)) jwt.MapClaims {
+		claims := validClaims()
+		change(claims)
+		return claims
+	}
+	none := func() string {
+		token := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims())
+		token.Header["kid"] = "hs"
+		signed, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"alg none", none()},
+		{"missing kid", signRaw(t, jwt.SigningMethodHS256, "", validClaims(), []byte(testSecret))},
+		{"unknown kid", signRaw(t, jwt.SigningMethodHS256, "other", validClaims(), []byte(testSecret))},
+		{"wrong secret", signRaw(t, jwt.SigningMethodHS256, "hs", validClaims(), []byte(testNewSecret))},
+		{"HS256 with the public key of an EdDSA kid", signRaw(t, jwt.SigningMethodHS256, "ed", validClaims(), publicPEM)},
+		{"HS512 for an HS256 kid", signRaw(t, jwt.SigningMethodHS512, "hs", validClaims(), []byte(testSecret))},
+		{"wrong issuer", signRaw(t, jwt.SigningMethodHS256, "hs", with(func(c jwt.MapClaims) { c["iss"] = "other" }), []byte(testSecret))},
+		{"wrong audience", signRaw(t, jwt.SigningMethodHS256, "hs", with(func(c jwt.MapClaims) { c["aud"] = "portal-test" }), []byte(testSecret))},
+		{"expired", signRaw(t, jwt.SigningMethodHS256, "hs", with(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }), []byte(testSecret))},
+		{"no expiry", signRaw(t, jwt.SigningMethodHS256, "hs", with(func(c jwt.MapClaims) { delete(c, "exp") }), []byte(testSecret))},
+		{"issued in the future", signRaw(t, jwt.SigningMethodHS256, "hs", with(func(c jwt.MapClaims) { c["iat"] = time.Now().Add(time.Hour).Unix() }), []byte(testSecret))},
+		{"garbage", "not.a.token"},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(Staff, tt.token); err != ErrInvalidToken {
+			t.Errorf("%s: Parse error = %v, want ErrInvalidToken", tt.name, err)
+		}
+	}
+
+	// Те же claims с верной подписью проходят: отказы выше вызваны именно изменениями
+	if _, err := Parse(Staff, signRaw(t, jwt.SigningMethodHS256, "hs", validClaims(), []byte(testSecret))); err != nil {
+		t.Errorf("valid token rejected: %v", err)
+	}
+}
@@ -5,6 +5,9 @@ CREATE TABLE IF NOT EXISTS users (
                                      password_hash TEXT NOT NULL,
                                      full_name TEXT NOT NULL,
                                      role TEXT DEFAULT 'librarian',
+                                     email TEXT,
+                                     is_active INTEGER NOT NULL DEFAULT 1, -- отключенный сотрудник не может войти
+                                     last_login DATETIME,
                                      created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -24,7 +27,11 @@ CREATE TABLE IF NOT EXISTS classes (
                                        grade INTEGER NOT NULL,
                                        letter TEXT NOT NULL,
                                        teacher_name TEXT,
-                                       UNIQUE(grade, letter)
+                                       teacher_id INTEGER, -- классный руководитель (читатель-учитель)
+                                       is_active INTEGER NOT NULL DEFAULT 1,
+                                       updated_at DATETIME, -- последнее изменение вручную; перевод классов его не меняет
+                                       UNIQUE(grade, letter),
+    FOREIGN KEY (teacher_id) REFERENCES readers(id)
     );
 
 -- Авторы
@@ -60,15 +67,161 @@ CREATE TABLE IF NOT EXISTS books (
                                      bbk TEXT,
                                      udk TEXT,
                                      class_range TEXT,
+                                     min_grade INTEGER, -- классы из class_range для фильтра каталога
+                                     max_grade INTEGER,
                                      location TEXT,
                                      created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
                                      created_by INTEGER,
+                                     series_id INTEGER,
+                                     volume_number INTEGER,
+                                     part_title TEXT,
+                                     work_id INTEGER,
+                                     location_id INTEGER,
                                      FOREIGN KEY (author_id) REFERENCES authors(id),
     FOREIGN KEY (publisher_id) REFERENCES publishers(id),
-    FOREIGN KEY (created_by) REFERENCES users(id)
+    FOREIGN KEY (created_by) REFERENCES users(id),
+    FOREIGN KEY (series_id) REFERENCES series(id),
+    FOREIGN KEY (work_id) REFERENCES works(id),
+    FOREIGN KEY (location_id) REFERENCES locations(id)
     );
 
--- Читатели (абоненты)
+-- Места хранения: здание → помещение → шкаф → полка
+CREATE TABLE IF NOT EXISTS locations (
+                                         id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                         parent_id INTEGER,
+                                         kind TEXT NOT NULL, -- building, room, cabinet, shelf
+                                         name TEXT NOT NULL,
+                                         barcode TEXT UNIQUE,
+                                         comments TEXT,
+                                         created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                                         FOREIGN KEY (parent_id) REFERENCES locations(id)
+);
+
+-- Диапазоны штрих-кодов по типам объектов
+CREATE TABLE IF NOT EXISTS barcode_ranges (
+                                              id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                              entity_type TEXT NOT NULL, -- book, reader, disk
+                                              prefix TEXT NOT NULL DEFAULT '',
+                                              start_number INTEGER NOT NULL,
+                                              end_number INTEGER NOT NULL,
+                                              digits INTEGER NOT NULL, -- ширина номера с ведущими нулями
+                                              check_digit TEXT NOT NULL DEFAULT '', -- '', luhn, ean13
+                                              next_number INTEGER NOT NULL,
+                                              comments TEXT,
+                                              created_by INTEGER,
+                                              created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                                              FOREIGN KEY (created_by) REFERENCES users(id)
+);
+
+-- Партии заранее напечатанных этикеток
+CREATE TABLE IF NOT EXISTS barcode_batches (
+                                               id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                               range_id INTEGER NOT NULL,
+                                               created_by INTEGER,
+                                               created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                                               FOREIGN KEY (range_id) REFERENCES barcode_ranges(id),
+                                               FOREIGN KEY (created_by) REFERENCES users(id)
+);
+
+-- Выделенные штрих-коды: зарезервированные, привязанные к записи и аннулированные
+CREATE TABLE IF NOT EXISTS barcode_allocations (
+                                                   id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                                   range_id INTEGER NOT NULL,
+                                                   batch_id INTEGER,
+                                                   barcode TEXT NOT NULL UNIQUE,
+                                                   entity_type TEXT NOT NULL,
+                                                   status TEXT NOT NULL DEFAULT 'reserved', -- reserved, bound, void
+                                                   entity_id INTEGER,
+                                                   created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                                                   bound_at DATETIME,
+                                                   FOREIGN KEY (range_id) REFERENCES barcode_ranges(id),
+                                                   FOREIGN KEY (batch_id) REFERENCES barcode_batches(id)
+);
+
+-- Переводы классов на новый учебный год
+CREATE TABLE IF NOT EXISTS class_rollovers (
+                                               id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                               academic_year TEXT NOT NULL, -- новый учебный год, например 2026/2027
+                                               max_grade INTEGER NOT NULL, -- выпускная параллель
+                                               summary TEXT, -- JSON: итог перевода
+                                               status TEXT NOT NULL DEFAULT 'applied', -- applied, undone
+                                               created_by INTEGER,
+                                               created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                                               undone_by INTEGER,
+                                               undone_at DATETIME,
+                                               FOREIGN KEY (created_by) REFERENCES users(id),
+                                               FOREIGN KEY (undone_by) REFERENCES users(id)
+);
+
+-- Изменения классов и читателей при переводе с прежними значениями для отмены
+CREATE TABLE IF NOT EXISTS class_rollover_changes (
+                                                      id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                                      rollover_id INTEGER NOT NULL,
+                                                      entity_type TEXT NOT NULL, -- class, reader
+                                                      entity_id INTEGER NOT NULL,
+                                                      action TEXT NOT NULL, -- created, promoted, removed, graduated, flagged
+                                                      previous TEXT, -- JSON: прежнее состояние записи
+                                                      FOREIGN KEY (rollover_id) REFERENCES class_rollovers(id)
+);
+
+-- Шаблоны сопоставления столбцов для импорта
+CREATE TABLE IF NOT EXISTS import_templates (
+                                                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                                entity_type TEXT NOT NULL, -- book, reader
+                                                name TEXT NOT NULL,
+                                                mapping TEXT NOT NULL, -- JSON: поле → заголовок столбца
+                                                created_by INTEGER,
+                                                created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                                                UNIQUE(entity_type, name),
+                                                FOREIGN KEY (created_by) REFERENCES users(id)
+);
+
+-- Последовательности автоматически присваиваемых кодов
+CREATE TABLE IF NOT EXISTS sequences (
+                                         entity_type TEXT PRIMARY KEY, -- book, reader, author, publisher, disk, clearance
+                                         prefix TEXT NOT NULL DEFAULT '',
+                                         padding INTEGER NOT NULL DEFAULT 6, -- ширина номера с ведущими нулями
+                                         yearly_reset INTEGER NOT NULL DEFAULT 0, -- нумерация с 1 каждый год, код вида 2026-00042
+                                         separator TEXT NOT NULL DEFAULT '-', -- разделитель года и номера
+                                         last_number INTEGER NOT NULL DEFAULT 0,
+                                         last_year INTEGER NOT NULL DEFAULT 0,
+                                         updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- История перемещений книг
+CREATE TABLE IF NOT EXISTS book_moves (
+                                          id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                          book_id INTEGER NOT NULL,
+                                          from_location_id INTEGER,
+                                          to_location_id INTEGER,
+                                          from_location TEXT,
+                                          to_location TEXT,
+                                          moved_by INTEGER,
+                                          moved_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                                          FOREIGN KEY (book_id) REFERENCES books(id),
+                                          FOREIGN KEY (moved_by) REFERENCES users(id)
+);
+
+-- Серии и многотомные издания
+CREATE TABLE IF NOT EXISTS series (
+                                      id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                      title TEXT NOT NULL,
+                                      kind TEXT DEFAULT 'series', -- series, multivolume
+                                      comments TEXT,
+                                      created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Произведения, объединяющие разные издания одной книги
+CREATE TABLE IF NOT EXISTS works (
+                                     id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                     title TEXT NOT NULL,
+                                     author_id INTEGER,
+                                     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                                     FOREIGN KEY (author_id) REFERENCES authors(id)
+);
+
+-- Читатели (абоненты). Адрес, номер документа, телефоны, e-mail и дата рождения
+-- хранятся зашифрованными, если задан ключ PII_KEY
 CREATE TABLE IF NOT EXISTS readers (
                                        id INTEGER PRIMARY KEY AUTOINCREMENT,
                                        code TEXT UNIQUE,
@@ -86,7 +239,6 @@ CREATE TABLE IF NOT EXISTS readers (
                                        document_number TEXT,
                                        phone TEXT,
                                        email TEXT,
-                                       photo BLOB,
                                        parent_mother_name TEXT,
                                        parent_mother_phone TEXT,
                                        parent_father_name TEXT,
@@ -96,10 +248,26 @@ CREATE TABLE IF NOT EXISTS readers (
                                        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
                                        created_by INTEGER,
                                        comments TEXT,
+                                       phone_hash TEXT, -- поисковый хэш телефона (при шифровании персональных данных)
+                                       email_hash TEXT, -- поисковый хэш e-mail для входа в кабинет читателя
+                                       status TEXT NOT NULL DEFAULT 'active', -- active, archived
+                                       archived_at DATETIME,
+                                       archive_reason TEXT,
+                                       portal_token_version INTEGER NOT NULL DEFAULT 0, -- растет при смене PIN, выходе и выбытии: прежние токены кабинета не действуют
+                                       updated_at DATETIME, -- последнее изменение карточки вручную; перевод классов его не меняет
                                        FOREIGN KEY (class_id) REFERENCES classes(id),
     FOREIGN KEY (created_by) REFERENCES users(id)
     );
 
+-- Прежние штрих-коды объединенных читателей: по ним находится сохраненная запись
+CREATE TABLE IF NOT EXISTS reader_barcode_aliases (
+                                                      barcode TEXT PRIMARY KEY,
+                                                      reader_id INTEGER NOT NULL,
+                                                      merged_reader_id INTEGER, -- удаленная запись-дубликат
+                                                      created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                                                      FOREIGN KEY (reader_id) REFERENCES readers(id)
+);
+
 -- Выдача книг
 CREATE TABLE IF NOT EXISTS loans (
                                      id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -109,7 +277,7 @@ CREATE TABLE IF NOT EXISTS loans (
                                      return_date DATETIME,
                                      issued_by INTEGER NOT NULL,
                                      returned_by INTEGER,
-                                     status TEXT DEFAULT 'active', -- active, returned
+                                     status TEXT DEFAULT 'active', -- active, returned, written_off
                                      FOREIGN KEY (book_id) REFERENCES books(id),
     FOREIGN KEY (reader_id) REFERENCES readers(id),
     FOREIGN KEY (issued_by) REFERENCES users(id),
@@ -129,8 +297,12 @@ CREATE TABLE IF NOT EXISTS disks (
                                      created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
                                      created_by INTEGER,
                                      comments TEXT,
+                                     subject_id INTEGER,
+                                     resource_type_id INTEGER,
                                      FOREIGN KEY (publisher_id) REFERENCES publishers(id),
-    FOREIGN KEY (created_by) REFERENCES users(id)
+    FOREIGN KEY (created_by) REFERENCES users(id),
+    FOREIGN KEY (subject_id) REFERENCES subjects(id),
+    FOREIGN KEY (resource_type_id) REFERENCES resource_types(id)
     );
 
 -- Выдача дисков
@@ -142,7 +314,7 @@ CREATE TABLE IF NOT EXISTS disk_loans (
                                           return_date DATETIME,
                                           issued_by INTEGER NOT NULL,
                                           returned_by INTEGER,
-                                          status TEXT DEFAULT 'active',
+                                          status TEXT DEFAULT 'active', -- active, returned, written_off
                                           FOREIGN KEY (disk_id) REFERENCES disks(id),
     FOREIGN KEY (reader_id) REFERENCES readers(id),
     FOREIGN KEY (issued_by) REFERENCES users(id),
@@ -162,16 +334,148 @@ CREATE TABLE IF NOT EXISTS resource_types (
                                               name TEXT NOT NULL
 );
 
+-- Обложки книг и дисков и фотографии читателей (сами изображения — в хранилище media)
+CREATE TABLE IF NOT EXISTS covers (
+                                      entity_type TEXT NOT NULL, -- book, disk, reader (фотография читателя)
+                                      entity_id INTEGER NOT NULL,
+                                      etag TEXT NOT NULL,
+                                      content_type TEXT,
+                                      width INTEGER,
+                                      height INTEGER,
+                                      file_size INTEGER,
+                                      updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                                      PRIMARY KEY (entity_type, entity_id)
+);
+
+-- Хранилище изображений в базе (COVER_STORAGE=sqlite)
+CREATE TABLE IF NOT EXISTS media_blobs (
+                                           key TEXT PRIMARY KEY,
+                                           content_type TEXT,
+                                           data BLOB,
+                                           updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Учетные данные читателей для входа в личный кабинет (отдельно от сотрудников users)
+CREATE TABLE IF NOT EXISTS reader_credentials (
+                                                  reader_id INTEGER PRIMARY KEY,
+                                                  pin_hash TEXT NOT NULL, -- bcrypt
+                                                  failed_attempts INTEGER NOT NULL DEFAULT 0,
+                                                  locked_until DATETIME, -- вход по PIN заблокирован после неудачных попыток
+                                                  last_login_at DATETIME,
+                                                  updated_by INTEGER,
+                                                  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                                                  FOREIGN KEY (reader_id) REFERENCES readers(id),
+                                                  FOREIGN KEY (updated_by) REFERENCES users(id)
+);
+
+-- Одноразовые коды входа в личный кабинет, отправленные на e-mail читателя
+CREATE TABLE IF NOT EXISTS reader_login_codes (
+                                                  id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                                  reader_id INTEGER NOT NULL,
+                                                  code_hash TEXT NOT NULL, -- SHA-256 кода
+                                                  attempts INTEGER NOT NULL DEFAULT 0,
+                                                  expires_at DATETIME NOT NULL,
+                                                  used_at DATETIME,
+                                                  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                                                  FOREIGN KEY (reader_id) REFERENCES readers(id)
+);
+
+-- Бронирование книг читателями
+CREATE TABLE IF NOT EXISTS holds (
+                                     id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                     reader_id INTEGER NOT NULL,
+                                     book_id INTEGER NOT NULL,
+                                     status TEXT NOT NULL DEFAULT 'active', -- active, fulfilled, cancelled
+                                     source TEXT NOT NULL DEFAULT 'portal', -- portal (читатель), staff
+                                     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                                     closed_at DATETIME,
+                                     closed_by INTEGER, -- сотрудник; пусто, если бронь снял сам читатель
+                                     FOREIGN KEY (reader_id) REFERENCES readers(id),
+                                     FOREIGN KEY (book_id) REFERENCES books(id),
+                                     FOREIGN KEY (closed_by) REFERENCES users(id)
+);
+
+-- Обходные листы: подтверждение, что у выбывающего читателя нет долгов перед библиотекой
+CREATE TABLE IF NOT EXISTS clearances (
+                                          id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                          code TEXT UNIQUE NOT NULL, -- номер листа из последовательности clearance
+                                          reader_id INTEGER NOT NULL,
+                                          reader_name TEXT NOT NULL, -- ФИО на дату выдачи
+                                          reader_info TEXT, -- категория и класс на дату выдачи
+                                          reason TEXT,
+                                          verification_code TEXT NOT NULL, -- код проверки, напечатан на листе
+                                          file_hash TEXT NOT NULL, -- SHA-256 выданного PDF
+                                          pdf BLOB,
+                                          issued_by INTEGER,
+                                          issued_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                                          FOREIGN KEY (reader_id) REFERENCES readers(id),
+                                          FOREIGN KEY (issued_by) REFERENCES users(id)
+);
+
+-- Сессии сотрудников: refresh-токены (хранится только SHA-256) и их отзыв
+CREATE TABLE IF NOT EXISTS sessions (
+                                        id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                        user_id INTEGER NOT NULL,
+                                        refresh_hash TEXT NOT NULL UNIQUE,
+                                        previous_hash TEXT, -- предыдущий refresh-токен: его повторное предъявление означает кражу
+                                        user_agent TEXT,
+                                        ip TEXT,
+                                        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                                        last_used_at DATETIME,
+                                        expires_at DATETIME NOT NULL,
+                                        revoked_at DATETIME,
+                                        revoked_by INTEGER, -- пусто, если сотрудник вышел сам или сессия отозвана системой
+                                        revoke_reason TEXT, -- logout, user, admin, refresh_reuse, deactivated, password_changed
+                                        FOREIGN KEY (user_id) REFERENCES users(id),
+                                        FOREIGN KEY (revoked_by) REFERENCES users(id)
+);
+
+-- Неудачные попытки входа сотрудников по логину и по IP-адресу: задержка растет
+-- экспоненциально, после порога вход временно блокируется
+CREATE TABLE IF NOT EXISTS login_attempts (
+                                              id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                              kind TEXT NOT NULL, -- username, ip, clearance_ip (проверка обходных листов)
+                                              value TEXT NOT NULL, -- логин в нижнем регистре или IP-адрес
+                                              failures INTEGER NOT NULL DEFAULT 0,
+                                              last_failed_at DATETIME,
+                                              locked_until DATETIME, -- до этого времени вход отклоняется без проверки пароля
+                                              UNIQUE (kind, value)
+);
+
+-- Журнал операций (объединения, массовые изменения и т.п.)
+CREATE TABLE IF NOT EXISTS audit_log (
+                                         id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                         entity_type TEXT NOT NULL,
+                                         entity_id INTEGER,
+                                         action TEXT NOT NULL,
+                                         details TEXT, -- JSON
+                                         user_id INTEGER,
+                                         created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                                         FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
 -- Индексы для оптимизации
 CREATE INDEX IF NOT EXISTS idx_books_barcode ON books(barcode);
 CREATE INDEX IF NOT EXISTS idx_readers_barcode ON readers(barcode);
 CREATE INDEX IF NOT EXISTS idx_loans_status ON loans(status);
 CREATE INDEX IF NOT EXISTS idx_loans_reader ON loans(reader_id);
 CREATE INDEX IF NOT EXISTS idx_loans_book ON loans(book_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS idx_locations_parent ON locations(parent_id);
+CREATE INDEX IF NOT EXISTS idx_book_moves_book ON book_moves(book_id);
+CREATE INDEX IF NOT EXISTS idx_barcode_allocations_batch ON barcode_allocations(batch_id);
+CREATE INDEX IF NOT EXISTS idx_class_rollover_changes ON class_rollover_changes(rollover_id);
+CREATE INDEX IF NOT EXISTS idx_reader_barcode_aliases_reader ON reader_barcode_aliases(reader_id);
+CREATE INDEX IF NOT EXISTS idx_clearances_reader ON clearances(reader_id);
+CREATE INDEX IF NOT EXISTS idx_reader_login_codes_reader ON reader_login_codes(reader_id);
+CREATE INDEX IF NOT EXISTS idx_holds_reader ON holds(reader_id, status);
+CREATE INDEX IF NOT EXISTS idx_holds_book ON holds(book_id, status);
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_previous_hash ON sessions(previous_hash);
 
 -- Вставка начальных данных
-INSERT INTO users (username, password_hash, full_name, role)
+INSERT OR IGNORE INTO users (username, password_hash, full_name, role)
 VALUES ('admin', '$2a$12$8y8HG8bKxOqGj50zi/LdeempqTKXnVi0Xfcz/vMzFexKEXXIDnVN2', 'Администратор', 'admin');
 
-INSERT INTO settings (organization_name, organization_short_name)
-VALUES ('Муниципальное бюджетное общеобразовательное учреждение средняя образовательная школа №3', 'МБОУСОШ №3');
\ No newline at end of file
+INSERT OR IGNORE INTO settings (id, organization_name, organization_short_name)
+VALUES (1, 'Муниципальное бюджетное общеобразовательное учреждение средняя образовательная школа №3', 'МБОУСОШ №3');
\ No newline at end of file
@@ -1,7 +1,14 @@
 import axios, { AxiosInstance } from 'axios';
 
+// Запросы входа и обновления сессии: их 401 означает неверные данные или закрытую сессию,
+// обновлять по ним токен и уводить на страницу входа не нужно
+const SESSION_URLS = ['/auth/login', '/auth/refresh', '/auth/logout'];
+
 class ApiService {
     private api: AxiosInstance;
+    // Обновление токена, которое уже выполняется: параллельные запросы ждут его,
+    // а не предъявляют один и тот же refresh-токен повторно
+    private refreshing: Promise<string> | null = null;
 
     constructor() {
         this.api = axios.create({
@@ -28,10 +35,23 @@ class ApiService {
         // Интерсептор для обработки ошибок
         this.api.interceptors.response.use(
             (response) => response,
-            (error) => {
-                if (error.response?.status === 401) {
-                    // Токен истек или недействителен
+            async (error) => {
+                const original = error.config;
+                const sessionRequest = SESSION_URLS.includes(original?.url ?? '');
+                if (error.response?.status === 401 && original && !original._retry && !sessionRequest) {
+                    // Токен доступа истек — получаем новый по refresh-токену и повторяем запрос
+                    original._retry = true;
+                    try {
+                        const token = await this.refreshToken();
+                        original.headers.Authorization = `Bearer ${token}`;
+                        return this.api(original);
+                    } catch {
+                        // Сессия закрыта — нужен повторный вход
+                    }
+                }
+                if (error.response?.status === 401 && !sessionRequest) {
                     localStorage.removeItem('token');
+                    localStorage.removeItem('refresh_token');
                     localStorage.removeItem('user');
                     window.location.href = '/login';
                 }
@@ -40,6 +60,25 @@ class ApiService {
         );
     }
 
+    private refreshToken(): Promise<string> {
+        if (!this.refreshing) {
+            const refreshToken = localStorage.getItem('refresh_token');
+            this.refreshing = (refreshToken
+                ? this.api.post('/auth/refresh', { refresh_token: refreshToken }).then((response) => {
+                      const { token, refresh_token } = response.data;
+                      localStorage.setItem('token', token);
+                      localStorage.setItem('refresh_token', refresh_token);
+                      this.setAuthToken(token);
+                      return token as string;
+                  })
+                : Promise.reject(new Error('No refresh token'))
+            ).finally(() => {
+                this.refreshing = null;
+            });
+        }
+        return this.refreshing;
+    }
+
     setAuthToken(token: string | null) {
         if (token) {
             this.api.defaults.headers.common['Authorization'] = `Bearer ${token}`;
@@ -53,6 +92,10 @@ class ApiService {
         return this.api.post('/auth/login', { username, password });
     }
 
+    async logout(refreshToken: string) {
+        return this.api.post('/auth/logout', { refresh_token: refreshToken });
+    }
+
     // Books
     async getBooks(params?: any) {
         return this.api.get('/books', { params });
@@ -40,7 +40,7 @@ class AuthStore {
 
         try {
             const response = await api.post('/auth/login', { username, password });
-            const { token, user } = response.data;
+            const { token, refresh_token, user } = response.data;
 
             runInAction(() => {
                 this.token = token;
@@ -51,6 +51,7 @@ class AuthStore {
 
             // Сохраняем в localStorage
             localStorage.setItem('token', token);
+            localStorage.setItem('refresh_token', refresh_token);
             localStorage.setItem('user', JSON.stringify(user));
             api.setAuthToken(token);
 
@@ -65,11 +66,18 @@ class AuthStore {
     }
 
     logout() {
+        // Закрываем сессию на сервере, чтобы токены с этого компьютера больше не действовали
+        const refreshToken = localStorage.getItem('refresh_token');
+        if (refreshToken) {
+            api.logout(refreshToken).catch(() => undefined);
+        }
+
         this.token = null;
         this.user = null;
         this.isAuthenticated = false;
 
         localStorage.removeItem('token');
+        localStorage.removeItem('refresh_token');
         localStorage.removeItem('user');
         api.setAuthToken(null);
     }